@@ -25,8 +25,12 @@ import (
 type DatastoreType string
 
 const (
-	EtcdV3              DatastoreType = "etcdv3"
-	Kubernetes          DatastoreType = "kubernetes"
+	EtcdV3     DatastoreType = "etcdv3"
+	Kubernetes DatastoreType = "kubernetes"
+	// Memory is an in-process datastore.  All clients created in the process from configs
+	// with the same MemoryStoreName share a single store, which lives for the lifetime of
+	// the process.
+	Memory              DatastoreType = "memory"
 	KindCalicoAPIConfig               = "CalicoAPIConfig"
 )
 
@@ -46,6 +50,8 @@ type CalicoAPIConfigSpec struct {
 	EtcdConfig
 	// Inline the k8s config fields.
 	KubeConfig
+	// Inline the memory config fields.
+	MemoryConfig
 }
 
 type EtcdConfig struct {
@@ -84,6 +90,12 @@ type KubeConfig struct {
 	K8sClientQPS float32 `json:"k8sClientQPS"`
 }
 
+type MemoryConfig struct {
+	// MemoryStoreName selects the in-process store used by the memory datastore.  Clients
+	// with the same store name see the same data.
+	MemoryStoreName string `json:"memoryStoreName" envconfig:"MEMORY_STORE_NAME" default:""`
+}
+
 // NewCalicoAPIConfig creates a new (zeroed) CalicoAPIConfig struct with the
 // TypeMetadata initialised to the current version.
 func NewCalicoAPIConfig() *CalicoAPIConfig {
@@ -55,8 +55,12 @@ type GlobalNetworkPolicy struct {
 }
 
 type GlobalNetworkPolicySpec struct {
-	// Order is an optional field that specifies the order in which the policy is applied.
-	// Policies with higher "order" are applied after those with lower
+	// Tier is an optional field that specifies the name of the tier that this policy belongs to.
+	// If omitted, the policy belongs to the default tier.  The tier must exist before a policy
+	// may be created in it, and the policy name must be prefixed with the tier name and a ".".
+	Tier string `json:"tier,omitempty" validate:"omitempty,name"`
+	// Order is an optional field that specifies the order in which the policy is applied
+	// within its tier.  Policies with higher "order" are applied after those with lower
 	// order.  If the order is omitted, it may be considered to be "infinite" - i.e. the
 	// policy will be applied last.  Policies with identical order will be applied in
 	// alphanumerical order based on the Policy "Name".
@@ -76,6 +80,8 @@ type GlobalNetworkPolicySpec struct {
 	// 	label != "string_literal"   ->  not equal; also matches if label is not present
 	// 	label in { "a", "b", "c", ... }  ->  true if the value of label X is one of "a", "b", "c"
 	// 	label not in { "a", "b", "c", ... }  ->  true if the value of label X is not one of "a", "b", "c"
+	// 	label < 10, label <= 10, label > 10, label >= 10  ->  true if the value of label X is a number that compares as given
+	// 	label =~ "regex"  ->  true if some part of the value of label X matches the regular expression
 	// 	has(label_name)  -> True if that label is present
 	// 	! expr -> negation of expr
 	// 	expr && expr  -> Short-circuit and
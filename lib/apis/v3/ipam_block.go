@@ -46,6 +46,14 @@ type IPAMBlockSpec struct {
 
 	// +optional
 	Deleted bool `json:"deleted"`
+
+	// +optional
+	Released []ReleasedOrdinal `json:"released,omitempty"`
+}
+
+type ReleasedOrdinal struct {
+	Ordinal    int   `json:"ordinal"`
+	ReleasedAt int64 `json:"releasedAt"`
 }
 
 type AllocationAttribute struct {
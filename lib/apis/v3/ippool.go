@@ -26,6 +26,10 @@ const (
 	KindIPPoolList = "IPPoolList"
 )
 
+// IPPoolAnnotationDrainingTo is set on a disabled IPPool whose workloads are being migrated to
+// another pool.  Its value is the name of the pool that the workloads are migrating to.
+const IPPoolAnnotationDrainingTo = "projectcalico.org/draining-to"
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -64,6 +68,24 @@ type IPPoolSpec struct {
 	// Allows IPPool to allocate for a specific node by label selector.
 	NodeSelector string `json:"nodeSelector,omitempty" validate:"omitempty,selector"`
 
+	// Allows IPPool to allocate for workloads in specific namespaces by namespace label selector.
+	// If not specified, the pool may be used for workloads in any namespace.
+	NamespaceSelector string `json:"namespaceSelector,omitempty" validate:"omitempty,selector"`
+
+	// Allows IPPool to allocate for specific workloads by workload label selector.
+	// If not specified, the pool may be used for any workload.
+	WorkloadSelector string `json:"workloadSelector,omitempty" validate:"omitempty,selector"`
+
+	// The order in which addresses in a block, and blocks in the pool, are assigned.  If not
+	// specified, then this is defaulted to "LeastRecentlyReleased".
+	AssignmentMode AssignmentMode `json:"assignmentMode,omitempty" validate:"omitempty,assignmentMode"`
+
+	// The time for which a released address is quarantined before it may be automatically
+	// assigned again.  This avoids reusing an address that stale conntrack entries or DNS
+	// records may still reference.  If not specified, released addresses may be reused
+	// immediately.
+	ReleaseCooldown *metav1.Duration `json:"releaseCooldown,omitempty"`
+
 	// Deprecated: this field is only used for APIv1 backwards compatibility.
 	// Setting this field is not allowed, this field is for internal use only.
 	IPIP *apiv1.IPIPConfiguration `json:"ipip,omitempty" validate:"omitempty,mustBeNil"`
@@ -89,6 +111,31 @@ func (pool IPPool) SelectsNode(n Node) (bool, error) {
 	return sel.Evaluate(n.Labels), nil
 }
 
+// SelectsWorkload determines whether or not the IPPool's namespaceSelector and
+// workloadSelector match the labels of a workload and its namespace.
+func (pool IPPool) SelectsWorkload(namespaceLabels, workloadLabels map[string]string) (bool, error) {
+	for _, s := range []struct {
+		selector string
+		labels   map[string]string
+	}{
+		{pool.Spec.NamespaceSelector, namespaceLabels},
+		{pool.Spec.WorkloadSelector, workloadLabels},
+	} {
+		// No selector means that the pool matches.
+		if len(s.selector) == 0 {
+			continue
+		}
+		sel, err := selector.Parse(s.selector)
+		if err != nil {
+			return false, err
+		}
+		if !sel.Evaluate(s.labels) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 type VXLANMode string
 
 const (
@@ -97,6 +144,19 @@ const (
 	VXLANModeCrossSubnet           = "CrossSubnet"
 )
 
+type AssignmentMode string
+
+const (
+	// Assign the free address that was released the longest time ago, or that has never
+	// been assigned, and claim new blocks in a host-dependent pseudo-random order.
+	AssignmentModeLeastRecentlyReleased AssignmentMode = "LeastRecentlyReleased"
+	// Assign the lowest free address, and claim the lowest free block.
+	AssignmentModeSequential = "Sequential"
+	// Assign a random free address, and claim new blocks in a host-dependent pseudo-random
+	// order.
+	AssignmentModeRandom = "Random"
+)
+
 type IPIPMode string
 
 const (
@@ -61,3 +61,57 @@ var _ = Describe("IPPoolSpec nodeSelector", func() {
 		Expect(matches).To(Equal(true))
 	})
 })
+
+// These tests verify that the IPPool namespaceSelector and workloadSelector work as expected
+var _ = Describe("IPPoolSpec namespaceSelector and workloadSelector", func() {
+	var (
+		nsLabels map[string]string
+		wlLabels map[string]string
+		pool     IPPool
+	)
+	BeforeEach(func() {
+		nsLabels = map[string]string{"tenant": "a"}
+		wlLabels = map[string]string{"app": "web"}
+		pool = IPPool{}
+	})
+	It("should return true, nil for empty selectors", func() {
+		matches, err := pool.SelectsWorkload(nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(Equal(true))
+	})
+	It("should return false, err for invalid selector syntax", func() {
+		pool.Spec = IPPoolSpec{WorkloadSelector: "this is invalid selector syntax"}
+
+		matches, err := pool.SelectsWorkload(nsLabels, wlLabels)
+		Expect(err).To(HaveOccurred())
+		Expect(matches).To(Equal(false))
+	})
+	It("should return true, nil when both selectors match", func() {
+		pool.Spec = IPPoolSpec{NamespaceSelector: `tenant == "a"`, WorkloadSelector: `app == "web"`}
+
+		matches, err := pool.SelectsWorkload(nsLabels, wlLabels)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(Equal(true))
+	})
+	It("should return false, nil for mismatching namespace labels", func() {
+		pool.Spec = IPPoolSpec{NamespaceSelector: `tenant == "b"`, WorkloadSelector: `app == "web"`}
+
+		matches, err := pool.SelectsWorkload(nsLabels, wlLabels)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(Equal(false))
+	})
+	It("should return false, nil for mismatching workload labels", func() {
+		pool.Spec = IPPoolSpec{NamespaceSelector: `tenant == "a"`, WorkloadSelector: `app == "db"`}
+
+		matches, err := pool.SelectsWorkload(nsLabels, wlLabels)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(Equal(false))
+	})
+	It("should not match namespace labels against the workload selector", func() {
+		pool.Spec = IPPoolSpec{WorkloadSelector: `tenant == "a"`}
+
+		matches, err := pool.SelectsWorkload(nsLabels, wlLabels)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(Equal(false))
+	})
+})
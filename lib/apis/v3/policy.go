@@ -26,6 +26,16 @@ const (
 	PolicyTypeEgress  PolicyType = "Egress"
 )
 
+// StagedAction represents the action to take when a staged policy is enforced.
+type StagedAction string
+
+const (
+	// StagedActionSet creates the enforced policy, or replaces it if it already exists.
+	StagedActionSet StagedAction = "Set"
+	// StagedActionDelete deletes the enforced policy.
+	StagedActionDelete StagedAction = "Delete"
+)
+
 // A Rule encapsulates a set of match criteria and an action.  Both selector-based security Policy
 // and security Profiles reference rules - separated out as a list of rules for both
 // ingress and egress packet matching.
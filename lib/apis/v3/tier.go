@@ -0,0 +1,87 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	KindTier     = "Tier"
+	KindTierList = "TierList"
+
+	// DefaultTierName is the name of the tier that policies belong to when no tier is
+	// specified.  The default tier always exists implicitly; a Tier resource with this
+	// name may be created to control the order of the default tier.
+	DefaultTierName = "default"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Tier contains the configuration of a security policy tier.  Tiers are applied in order, and the
+// policies within a tier are applied in order.  A policy in one tier with a Pass action skips the
+// remaining policies in that tier and continues processing at the next tier.
+//
+// Policies in a tier other than the default tier must be named with the tier name and a "."
+// prefix, e.g. policy "allow-dns" in tier "platform" is named "platform.allow-dns".
+type Tier struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard object's metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// Specification of the Tier.
+	Spec TierSpec `json:"spec,omitempty"`
+}
+
+// TierSpec contains the specification for a security policy tier resource.
+type TierSpec struct {
+	// Order is an optional field that specifies the order in which the tier is applied.
+	// Tiers with higher "order" are applied after those with lower order.  If the order
+	// is omitted, it may be considered to be "infinite" - i.e. the tier will be applied
+	// last.  Tiers with identical order will be applied in alphanumerical order based
+	// on the Tier "Name".
+	Order *float64 `json:"order,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// TierList contains a list of Tier resources.
+type TierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Tier `json:"items"`
+}
+
+// NewTier creates a new (zeroed) Tier struct with the TypeMetadata initialised to the current
+// version.
+func NewTier() *Tier {
+	return &Tier{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       KindTier,
+			APIVersion: GroupVersionCurrent,
+		},
+	}
+}
+
+// NewTierList creates a new (zeroed) TierList struct with the TypeMetadata initialised to the current
+// version.
+func NewTierList() *TierList {
+	return &TierList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       KindTierList,
+			APIVersion: GroupVersionCurrent,
+		},
+	}
+}
@@ -20,6 +20,8 @@ import (
 
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
 )
 
@@ -124,6 +126,62 @@ type Client interface {
 	//Close()
 }
 
+// TxnOpType is the type of an operation in a transaction.
+type TxnOpType uint8
+
+const (
+	// TxnCreate creates the object, which must not already exist.
+	TxnCreate TxnOpType = iota
+	// TxnUpdate modifies the existing object.  The KVPair must contain revision
+	// information, and the update only succeeds if the revision is still current.
+	TxnUpdate
+	// TxnDelete removes the object.  If the KVPair contains revision information, the
+	// delete only succeeds if the revision is still current.
+	TxnDelete
+)
+
+func (t TxnOpType) String() string {
+	switch t {
+	case TxnCreate:
+		return "create"
+	case TxnUpdate:
+		return "update"
+	case TxnDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("Unknown<%v>", uint8(t))
+	}
+}
+
+// TxnOp is a single operation in a transaction.
+type TxnOp struct {
+	Type   TxnOpType
+	KVPair *model.KVPair
+}
+
+// TxnClient is implemented by backend clients that can perform a set of operations
+// atomically.
+type TxnClient interface {
+	// Txn performs the operations atomically: either every operation succeeds, or none
+	// of them are made.  Each key may appear in at most one operation.  On success,
+	// returns a KVPair for each operation with revision information filled-in; for a
+	// delete, this is the deleted object.  If the precondition of an operation fails,
+	// returns the same error as the equivalent single-object call, for example an
+	// ErrorResourceUpdateConflict identifying the key.
+	Txn(ctx context.Context, ops []TxnOp) ([]*model.KVPair, error)
+}
+
+// PatchClient is implemented by backend clients that can apply a patch to a resource in the
+// datastore, merging it with the current state of the resource.
+type PatchClient interface {
+	// Patch applies a JSON merge patch or JSON patch to the resource identified by the key.
+	// If the call contains revision information, the patch only succeeds if the revision is
+	// still current.  On success, returns a KVPair for the patched object with revision
+	// information filled-in.  Returns an ErrorOperationNotSupported if the resource, patch
+	// type or patch cannot be applied by the datastore.
+	Patch(ctx context.Context, key model.Key, patchType types.PatchType, data []byte, revision string) (*model.KVPair, error)
+}
+
 type Syncer interface {
 	// Starts the Syncer.  May start a background goroutine.
 	Start()
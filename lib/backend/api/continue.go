@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+// continueToken is the decoded form of the continuation token returned by a paginated
+// List in a key-value datastore.  The remaining pages are read at the revision of the first
+// page, so that the pages form a consistent snapshot.
+type continueToken struct {
+	Revision int64  `json:"rev"`
+	Start    string `json:"start"`
+}
+
+// EncodeContinue returns the continuation token for a List that continues from the start
+// key at the given revision.
+func EncodeContinue(revision int64, start string) string {
+	b, _ := json.Marshal(continueToken{Revision: revision, Start: start})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeContinue decodes a continuation token returned by EncodeContinue, returning the
+// revision and start key.  The start key must be within the listed prefix.
+func DecodeContinue(token, prefix string) (int64, string, error) {
+	var t continueToken
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(b, &t)
+	}
+	if err != nil || t.Revision <= 0 || !strings.HasPrefix(t.Start, prefix) {
+		return 0, "", cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "ListOptions.Continue",
+				Reason: "invalid continuation token",
+				Value:  token,
+			}},
+		}
+	}
+	return t.Revision, t.Start, nil
+}
@@ -23,6 +23,7 @@ import (
 	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
 	"github.com/projectcalico/libcalico-go/lib/backend/etcdv3"
 	"github.com/projectcalico/libcalico-go/lib/backend/k8s"
+	"github.com/projectcalico/libcalico-go/lib/backend/memory"
 )
 
 // NewClient creates a new backend datastore client.
@@ -33,6 +34,8 @@ func NewClient(config apiconfig.CalicoAPIConfig) (c bapi.Client, err error) {
 		c, err = etcdv3.NewEtcdV3Client(&config.Spec.EtcdConfig)
 	case apiconfig.Kubernetes:
 		c, err = k8s.NewKubeClient(&config.Spec)
+	case apiconfig.Memory:
+		c = memory.NewSharedMemoryClient(config.Spec.MemoryStoreName)
 	default:
 		err = fmt.Errorf("unknown datastore type: %v",
 			config.Spec.DatastoreType)
@@ -350,6 +350,118 @@ func (c *etcdV3Client) Delete(ctx context.Context, k model.Key, revision string)
 	return previousValue, nil
 }
 
+// Txn performs the operations in a single etcdv3 transaction, guarded by the same
+// conditions as the equivalent Create, Update and Delete requests.  If any condition
+// fails, no changes are made and the error for the first failed operation is returned.
+func (c *etcdV3Client) Txn(ctx context.Context, ops []api.TxnOp) ([]*model.KVPair, error) {
+	logCxt := log.WithField("ops", len(ops))
+	logCxt.Debug("Processing Txn request")
+
+	conds := []clientv3.Cmp{}
+	thenOps := []clientv3.Op{}
+	elseOps := []clientv3.Op{}
+	keys := make([]string, len(ops))
+	values := make([]string, len(ops))
+	revs := make([]int64, len(ops))
+	for i, op := range ops {
+		var err error
+		d := op.KVPair
+		switch op.Type {
+		case api.TxnCreate, api.TxnUpdate:
+			if keys[i], values[i], err = getKeyValueStrings(d); err != nil {
+				return nil, err
+			}
+			putOpts, err := c.getTTLOption(ctx, d)
+			if err != nil {
+				return nil, err
+			}
+			if op.Type == api.TxnCreate {
+				conds = append(conds, clientv3.Compare(clientv3.Version(keys[i]), "=", 0))
+			} else {
+				// ResourceVersion must be set for an Update.
+				if revs[i], err = parseRevision(d.Revision); err != nil {
+					return nil, err
+				}
+				conds = append(conds, clientv3.Compare(clientv3.ModRevision(keys[i]), "=", revs[i]))
+			}
+			thenOps = append(thenOps, clientv3.OpPut(keys[i], values[i], putOpts...))
+		case api.TxnDelete:
+			if keys[i], err = model.KeyToDefaultDeletePath(d.Key); err != nil {
+				return nil, err
+			}
+			if len(d.Revision) != 0 {
+				if revs[i], err = parseRevision(d.Revision); err != nil {
+					return nil, err
+				}
+				conds = append(conds, clientv3.Compare(clientv3.ModRevision(keys[i]), "=", revs[i]))
+			} else {
+				conds = append(conds, clientv3.Compare(clientv3.Version(keys[i]), ">", 0))
+			}
+			thenOps = append(thenOps, clientv3.OpDelete(keys[i], clientv3.WithPrevKV()))
+		default:
+			return nil, fmt.Errorf("unknown transaction operation %v", op.Type)
+		}
+		elseOps = append(elseOps, clientv3.OpGet(keys[i]))
+	}
+
+	logCxt.Debug("Performing etcdv3 transaction for Txn request")
+	txnResp, err := c.etcdClient.Txn(ctx).If(conds...).Then(thenOps...).Else(elseOps...).Commit()
+	if err != nil {
+		logCxt.WithError(err).Warning("Txn failed")
+		return nil, cerrors.ErrorDatastoreError{Err: err}
+	}
+
+	if !txnResp.Succeeded {
+		// Work out which condition failed from the current value of each key.
+		for i, op := range ops {
+			k := op.KVPair.Key
+			getResp := txnResp.Responses[i].GetResponseRange()
+			var existing *model.KVPair
+			if len(getResp.Kvs) != 0 {
+				existing, _ = etcdToKVPair(k, getResp.Kvs[0])
+			}
+			switch {
+			case op.Type == api.TxnCreate && existing != nil:
+				logCxt.WithField("etcdv3-etcdKey", keys[i]).Debug("Txn failed due to resource already existing")
+				return nil, cerrors.ErrorResourceAlreadyExists{Identifier: k}
+			case op.Type != api.TxnCreate && existing == nil:
+				logCxt.WithField("etcdv3-etcdKey", keys[i]).Debug("Txn failed due to resource not existing")
+				return nil, cerrors.ErrorResourceDoesNotExist{Identifier: k}
+			case revs[i] != 0 && getResp.Kvs[0].ModRevision != revs[i]:
+				logCxt.WithField("etcdv3-etcdKey", keys[i]).Debug("Txn failed due to resource update conflict")
+				return nil, cerrors.ErrorResourceUpdateConflict{Identifier: k}
+			}
+		}
+		return nil, cerrors.ErrorDatastoreError{Err: errors.New("transaction conditions failed")}
+	}
+
+	results := make([]*model.KVPair, len(ops))
+	for i, op := range ops {
+		d := op.KVPair
+		if op.Type == api.TxnDelete {
+			// Parse the deleted value.  Don't propagate the error in this case since the
+			// delete did succeed.
+			delResp := txnResp.Responses[i].GetResponseDeleteRange()
+			if len(delResp.PrevKvs) != 0 {
+				results[i], _ = etcdToKVPair(d.Key, delResp.PrevKvs[0])
+			}
+			continue
+		}
+		v, err := model.ParseValue(d.Key, []byte(values[i]))
+		if err != nil {
+			return nil, cerrors.ErrorPartialFailure{Err: fmt.Errorf("Unexpected error parsing stored datastore entry '%v': %+v", values[i], err)}
+		}
+		results[i] = &model.KVPair{
+			Key:      d.Key,
+			Value:    v,
+			Revision: strconv.FormatInt(txnResp.Header.Revision, 10),
+			UID:      d.UID,
+			TTL:      d.TTL,
+		}
+	}
+	return results, nil
+}
+
 // Get an entry from the datastore.  This errors if the entry does not exist.
 func (c *etcdV3Client) Get(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
 	logCxt := log.WithFields(log.Fields{"model-etcdKey": k, "rev": revision})
@@ -402,16 +514,42 @@ func (c *etcdV3Client) List(ctx context.Context, l model.ListInterface, revision
 	logCxt = logCxt.WithField("etcdv3-etcdKey", key)
 
 	// We may also need to perform a get based on a particular revision.
+	var rev int64
 	if len(revision) != 0 {
-		rev, err := parseRevision(revision)
-		if err != nil {
+		var err error
+		if rev, err = parseRevision(revision); err != nil {
 			return nil, err
 		}
+	}
+
+	// If the List is limited, or continues a previous limited List, then range from the
+	// start key in the continuation token, at the revision of the first page.  A List of a
+	// single key is never paginated.
+	start := key
+	var limit int64
+	var paginated bool
+	if rlo, ok := l.(model.ResourceListOptions); ok && len(ops) != 0 {
+		limit = rlo.Limit
+		paginated = rlo.Limit > 0 || len(rlo.Continue) != 0
+		if len(rlo.Continue) != 0 {
+			var err error
+			if rev, start, err = api.DecodeContinue(rlo.Continue, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if paginated {
+		ops = []clientv3.OpOption{clientv3.WithRange(clientv3.GetPrefixRangeEnd(key))}
+		if limit > 0 {
+			ops = append(ops, clientv3.WithLimit(limit))
+		}
+	}
+	if rev != 0 {
 		ops = append(ops, clientv3.WithRev(rev))
 	}
 
 	logCxt.Debug("Calling Get on etcdv3 client")
-	resp, err := c.etcdClient.Get(ctx, key, ops...)
+	resp, err := c.etcdClient.Get(ctx, start, ops...)
 	if err != nil {
 		logCxt.WithError(err).Debug("Error returned from etcdv3 client")
 		return nil, cerrors.ErrorDatastoreError{Err: err}
@@ -426,16 +564,29 @@ func (c *etcdV3Client) List(ctx context.Context, l model.ListInterface, revision
 		}
 	}
 
+	listRev := resp.Header.Revision
+	var next string
+	if paginated {
+		// The pages are all read at the revision of the first page.
+		if rev != 0 {
+			listRev = rev
+		}
+		if resp.More && len(resp.Kvs) != 0 {
+			next = api.EncodeContinue(listRev, string(resp.Kvs[len(resp.Kvs)-1].Key)+"\x00")
+		}
+	}
+
 	// If we're listing profiles, we need to handle the statically defined
 	// default-allow profile in the resources package.
-	// We always include the default profile.
-	if key == profilesKey || key == defaultAllowProfileKey {
+	// We always include the default profile, on the last page of a paginated List.
+	if (key == profilesKey || key == defaultAllowProfileKey) && len(next) == 0 {
 		list = append(list, resources.DefaultAllowProfile())
 	}
 
 	return &model.KVPairList{
 		KVPairs:  list,
-		Revision: strconv.FormatInt(resp.Header.Revision, 10),
+		Revision: strconv.FormatInt(listRev, 10),
+		Continue: next,
 	}, nil
 }
 
@@ -38,6 +38,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -99,6 +100,12 @@ func NewKubeClient(ca *apiconfig.CalicoAPIConfigSpec) (api.Client, error) {
 		apiv3.KindIPPool,
 		resources.NewIPPoolClient(cs, crdClientV1),
 	)
+	kubeClient.registerResourceClient(
+		reflect.TypeOf(model.ResourceKey{}),
+		reflect.TypeOf(model.ResourceListOptions{}),
+		apiv3.KindIPReservation,
+		resources.NewIPReservationClient(cs, crdClientV1),
+	)
 	kubeClient.registerResourceClient(
 		reflect.TypeOf(model.ResourceKey{}),
 		reflect.TypeOf(model.ResourceListOptions{}),
@@ -111,6 +118,12 @@ func NewKubeClient(ca *apiconfig.CalicoAPIConfigSpec) (api.Client, error) {
 		apiv3.KindGlobalNetworkSet,
 		resources.NewGlobalNetworkSetClient(cs, crdClientV1),
 	)
+	kubeClient.registerResourceClient(
+		reflect.TypeOf(model.ResourceKey{}),
+		reflect.TypeOf(model.ResourceListOptions{}),
+		apiv3.KindTier,
+		resources.NewTierClient(cs, crdClientV1),
+	)
 	kubeClient.registerResourceClient(
 		reflect.TypeOf(model.ResourceKey{}),
 		reflect.TypeOf(model.ResourceListOptions{}),
@@ -123,6 +136,24 @@ func NewKubeClient(ca *apiconfig.CalicoAPIConfigSpec) (api.Client, error) {
 		apiv3.KindNetworkSet,
 		resources.NewNetworkSetClient(cs, crdClientV1),
 	)
+	kubeClient.registerResourceClient(
+		reflect.TypeOf(model.ResourceKey{}),
+		reflect.TypeOf(model.ResourceListOptions{}),
+		apiv3.KindStagedGlobalNetworkPolicy,
+		resources.NewStagedGlobalNetworkPolicyClient(cs, crdClientV1),
+	)
+	kubeClient.registerResourceClient(
+		reflect.TypeOf(model.ResourceKey{}),
+		reflect.TypeOf(model.ResourceListOptions{}),
+		apiv3.KindStagedNetworkPolicy,
+		resources.NewStagedNetworkPolicyClient(cs, crdClientV1),
+	)
+	kubeClient.registerResourceClient(
+		reflect.TypeOf(model.ResourceKey{}),
+		reflect.TypeOf(model.ResourceListOptions{}),
+		apiv3.KindStagedKubernetesNetworkPolicy,
+		resources.NewStagedKubernetesNetworkPolicyClient(cs, crdClientV1),
+	)
 	kubeClient.registerResourceClient(
 		reflect.TypeOf(model.ResourceKey{}),
 		reflect.TypeOf(model.ResourceListOptions{}),
@@ -335,9 +366,14 @@ func (c *KubeClient) Clean() error {
 		apiv3.KindFelixConfiguration,
 		apiv3.KindGlobalNetworkPolicy,
 		apiv3.KindNetworkPolicy,
+		apiv3.KindStagedGlobalNetworkPolicy,
+		apiv3.KindStagedNetworkPolicy,
+		apiv3.KindStagedKubernetesNetworkPolicy,
+		apiv3.KindTier,
 		apiv3.KindGlobalNetworkSet,
 		apiv3.KindNetworkSet,
 		apiv3.KindIPPool,
+		apiv3.KindIPReservation,
 		apiv3.KindHostEndpoint,
 		apiv3.KindKubeControllersConfiguration,
 	}
@@ -431,6 +467,8 @@ func buildCRDClientV1(cfg rest.Config) (*rest.RESTClient, error) {
 					&apiv3.FelixConfigurationList{},
 					&apiv3.IPPool{},
 					&apiv3.IPPoolList{},
+					&apiv3.IPReservation{},
+					&apiv3.IPReservationList{},
 					&apiv3.BGPPeer{},
 					&apiv3.BGPPeerList{},
 					&apiv3.BGPConfiguration{},
@@ -443,6 +481,14 @@ func buildCRDClientV1(cfg rest.Config) (*rest.RESTClient, error) {
 					&apiv3.GlobalNetworkPolicyList{},
 					&apiv3.NetworkPolicy{},
 					&apiv3.NetworkPolicyList{},
+					&apiv3.StagedGlobalNetworkPolicy{},
+					&apiv3.StagedGlobalNetworkPolicyList{},
+					&apiv3.StagedNetworkPolicy{},
+					&apiv3.StagedNetworkPolicyList{},
+					&apiv3.StagedKubernetesNetworkPolicy{},
+					&apiv3.StagedKubernetesNetworkPolicyList{},
+					&apiv3.Tier{},
+					&apiv3.TierList{},
 					&apiv3.NetworkSet{},
 					&apiv3.NetworkSetList{},
 					&apiv3.HostEndpoint{},
@@ -498,6 +544,21 @@ func (c *KubeClient) Update(ctx context.Context, d *model.KVPair) (*model.KVPair
 	return client.Update(ctx, d)
 }
 
+// Patch applies a patch to an existing entry in the datastore.  This is only supported for
+// resources that are stored as custom resources.
+func (c *KubeClient) Patch(ctx context.Context, key model.Key, patchType types.PatchType, data []byte, revision string) (*model.KVPair, error) {
+	log.Debugf("Performing 'Patch' for %+v", key)
+	client, ok := c.getResourceClientFromKey(key).(api.PatchClient)
+	if !ok {
+		log.Debug("Attempt to 'Patch' using kubernetes backend is not supported.")
+		return nil, cerrors.ErrorOperationNotSupported{
+			Identifier: key,
+			Operation:  "Patch",
+		}
+	}
+	return client.Patch(ctx, key, patchType, data, revision)
+}
+
 // Set an existing entry in the datastore.  This ignores whether an entry already
 // exists.  This is not exposed in the main client - but we keep here for the backend
 // API.
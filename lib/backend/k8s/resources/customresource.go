@@ -16,9 +16,12 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
@@ -149,6 +152,141 @@ func (c *customK8sResourceClient) Update(ctx context.Context, kvp *model.KVPair)
 	return kvp, nil
 }
 
+// Patch applies a JSON merge patch or JSON patch to an existing Custom K8s Resource instance
+// in the k8s API.  The patch may only modify the spec and labels of the resource, since the
+// rest of the Calico metadata is stored in an annotation.  If a revision is supplied, the
+// resource version is added to the patch so that the patch fails with an update conflict if
+// the resource has been modified since that revision.
+func (c *customK8sResourceClient) Patch(ctx context.Context, key model.Key, patchType types.PatchType, data []byte, revision string) (*model.KVPair, error) {
+	logContext := log.WithFields(log.Fields{
+		"Key":       key,
+		"PatchType": patchType,
+		"Resource":  c.resource,
+		"Revision":  revision,
+	})
+	logContext.Debug("Patch custom Kubernetes resource")
+	if c.versionconverter != nil || !patchable(patchType, data) {
+		return nil, cerrors.ErrorOperationNotSupported{
+			Identifier: key,
+			Operation:  "Patch",
+		}
+	}
+	name, err := c.keyToName(key)
+	if err != nil {
+		logContext.WithError(err).Debug("Error patching resource")
+		return nil, err
+	}
+	if revision != "" {
+		if data, err = addRevisionToPatch(patchType, data, revision); err != nil {
+			logContext.WithError(err).Debug("Error adding revision to patch")
+			return nil, err
+		}
+	}
+	namespace := key.(model.ResourceKey).Namespace
+
+	resOut := reflect.New(c.k8sResourceType).Interface().(Resource)
+	err = c.restClient.Patch(patchType).
+		Context(ctx).
+		NamespaceIfScoped(namespace, c.namespaced).
+		Resource(c.resource).
+		Name(name).
+		Body(data).
+		Do().Into(resOut)
+	if err != nil {
+		logContext.WithError(err).Debug("Error patching resource")
+		return nil, K8sErrorToCalico(err, key)
+	}
+
+	return c.convertResourceToKVPair(resOut)
+}
+
+// patchable returns true if the patch only modifies the spec and labels of a resource.
+func patchable(patchType types.PatchType, data []byte) bool {
+	switch patchType {
+	case types.MergePatchType:
+		var patch map[string]json.RawMessage
+		if err := json.Unmarshal(data, &patch); err != nil {
+			return false
+		}
+		for field, value := range patch {
+			switch field {
+			case "spec":
+			case "metadata":
+				var metadata map[string]json.RawMessage
+				if err := json.Unmarshal(value, &metadata); err != nil {
+					return false
+				}
+				for field := range metadata {
+					if field != "labels" {
+						return false
+					}
+				}
+			default:
+				return false
+			}
+		}
+		return true
+	case types.JSONPatchType:
+		var patch []struct {
+			Path string  `json:"path"`
+			From *string `json:"from"`
+		}
+		if err := json.Unmarshal(data, &patch); err != nil {
+			return false
+		}
+		for _, op := range patch {
+			if !patchablePath(op.Path) || op.From != nil && !patchablePath(*op.From) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// addRevisionToPatch returns the patch with the resource version set to the revision.  The k8s
+// API rejects an update whose resource version does not match the current resource with a
+// conflict error, so this makes the revision a precondition of the patch.  For a JSON patch,
+// the resource version is set with a replace operation rather than checked with a test
+// operation, since a failed test is reported as an invalid patch rather than as a conflict.
+func addRevisionToPatch(patchType types.PatchType, data []byte, revision string) ([]byte, error) {
+	switch patchType {
+	case types.MergePatchType:
+		var patch map[string]interface{}
+		if err := json.Unmarshal(data, &patch); err != nil {
+			return nil, err
+		}
+		metadata, _ := patch["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+			patch["metadata"] = metadata
+		}
+		metadata["resourceVersion"] = revision
+		return json.Marshal(patch)
+	case types.JSONPatchType:
+		var patch []interface{}
+		if err := json.Unmarshal(data, &patch); err != nil {
+			return nil, err
+		}
+		patch = append([]interface{}{map[string]interface{}{
+			"op":    "replace",
+			"path":  "/metadata/resourceVersion",
+			"value": revision,
+		}}, patch...)
+		return json.Marshal(patch)
+	}
+	return nil, fmt.Errorf("patch type %q is not supported", patchType)
+}
+
+func patchablePath(path string) bool {
+	for _, prefix := range []string{"/spec", "/metadata/labels"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *customK8sResourceClient) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
 	return c.Delete(ctx, kvp.Key, kvp.Revision, kvp.UID)
 }
@@ -277,14 +415,27 @@ func (c *customK8sResourceClient) List(ctx context.Context, list model.ListInter
 	reslOut := reflect.New(c.k8sListType).Interface().(ResourceList)
 
 	// If it is a namespaced resource, then we'll need the namespace.
-	namespace := list.(model.ResourceListOptions).Namespace
+	rlo := list.(model.ResourceListOptions)
+	namespace := rlo.Namespace
 
-	// Perform the request.
-	err := c.restClient.Get().
+	// Perform the request, filtering server-side where possible.
+	req := c.restClient.Get().
 		Context(ctx).
 		NamespaceIfScoped(namespace, c.namespaced).
-		Resource(c.resource).
-		Do().Into(reslOut)
+		Resource(c.resource)
+	if labelSelector := k8sLabelSelector(rlo.LabelSelector, nil); labelSelector != "" {
+		req = req.Param("labelSelector", labelSelector)
+	}
+	if fieldSelector := k8sFieldSelector(rlo.FieldSelector, customResourceFields); !fieldSelector.Empty() {
+		req = req.Param("fieldSelector", fieldSelector.String())
+	}
+	if rlo.Limit > 0 {
+		req = req.Param("limit", strconv.FormatInt(rlo.Limit, 10))
+	}
+	if len(rlo.Continue) != 0 {
+		req = req.Param("continue", rlo.Continue)
+	}
+	err := req.Do().Into(reslOut)
 	if err != nil {
 		// Don't return errors for "not found".  This just
 		// means there are no matching Custom K8s Resources, and we should return
@@ -314,6 +465,7 @@ func (c *customK8sResourceClient) List(ctx context.Context, list model.ListInter
 	return &model.KVPairList{
 		KVPairs:  kvps,
 		Revision: reslOut.GetListMeta().GetResourceVersion(),
+		Continue: reslOut.GetListMeta().GetContinue(),
 	}, nil
 }
 
@@ -324,14 +476,19 @@ func (c *customK8sResourceClient) Watch(ctx context.Context, list model.ListInte
 	if !ok {
 		return nil, fmt.Errorf("ListInterface is not a ResourceListOptions: %s", list)
 	}
-	fieldSelector := fields.Everything()
+	var nameSelector []fields.Selector
 	if len(rlo.Name) != 0 {
 		// We've been asked to watch a specific customresource.
 		log.WithField("name", rlo.Name).Debug("Watching a single customresource")
-		fieldSelector = fields.OneTermEqualSelector("metadata.name", rlo.Name)
+		nameSelector = append(nameSelector, fields.OneTermEqualSelector("metadata.name", rlo.Name))
 	}
+	fieldSelector := k8sFieldSelector(rlo.FieldSelector, customResourceFields, nameSelector...)
+	labelSelector := k8sLabelSelector(rlo.LabelSelector, nil)
 
-	k8sWatchClient := cache.NewListWatchFromClient(c.restClient, c.resource, rlo.Namespace, fieldSelector)
+	k8sWatchClient := cache.NewFilteredListWatchFromClient(c.restClient, c.resource, rlo.Namespace, func(options *metav1.ListOptions) {
+		options.FieldSelector = fieldSelector.String()
+		options.LabelSelector = labelSelector
+	})
 	k8sWatch, err := k8sWatchClient.WatchFunc(opts)
 	if err != nil {
 		return nil, K8sErrorToCalico(err, list)
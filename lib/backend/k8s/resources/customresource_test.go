@@ -20,8 +20,10 @@ import (
 	"github.com/projectcalico/libcalico-go/lib/net"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -132,3 +134,39 @@ var _ = Describe("Custom resource conversion methods (tested using BGPPeer)", fu
 		Expect(kvp.Value).To(Equal(kvp1.Value))
 	})
 })
+
+var _ = Describe("Custom resource native patches", func() {
+	DescribeTable("patchable",
+		func(pt types.PatchType, data string, expected bool) {
+			Expect(patchable(pt, []byte(data))).To(Equal(expected))
+		},
+		Entry("merge patch of the spec", types.MergePatchType, `{"spec":{"node":"node1"}}`, true),
+		Entry("merge patch of the labels", types.MergePatchType, `{"metadata":{"labels":{"app":null}}}`, true),
+		Entry("merge patch of the annotations", types.MergePatchType, `{"metadata":{"annotations":{"a":"b"}}}`, false),
+		Entry("merge patch of the kind", types.MergePatchType, `{"kind":"NetworkSet"}`, false),
+		Entry("malformed merge patch", types.MergePatchType, `[]`, false),
+		Entry("JSON patch of the spec", types.JSONPatchType, `[{"op":"add","path":"/spec/nets/-","value":"10.0.0.0/8"}]`, true),
+		Entry("JSON patch of the labels", types.JSONPatchType, `[{"op":"remove","path":"/metadata/labels/app"}]`, true),
+		Entry("JSON patch of a field with a spec prefix", types.JSONPatchType, `[{"op":"remove","path":"/specification"}]`, false),
+		Entry("JSON patch moving from the metadata", types.JSONPatchType, `[{"op":"move","from":"/metadata/name","path":"/spec/node"}]`, false),
+		Entry("malformed JSON patch", types.JSONPatchType, `{}`, false),
+		Entry("strategic merge patch", types.StrategicMergePatchType, `{"spec":{}}`, false),
+	)
+
+	DescribeTable("addRevisionToPatch",
+		func(pt types.PatchType, data, expected string) {
+			patch, err := addRevisionToPatch(pt, []byte(data), "1234")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(patch).To(MatchJSON(expected))
+		},
+		Entry("merge patch of the spec", types.MergePatchType,
+			`{"spec":{"node":"node1"}}`,
+			`{"metadata":{"resourceVersion":"1234"},"spec":{"node":"node1"}}`),
+		Entry("merge patch of the labels", types.MergePatchType,
+			`{"metadata":{"labels":{"app":null}}}`,
+			`{"metadata":{"labels":{"app":null},"resourceVersion":"1234"}}`),
+		Entry("JSON patch", types.JSONPatchType,
+			`[{"op":"remove","path":"/metadata/labels/app"}]`,
+			`[{"op":"replace","path":"/metadata/resourceVersion","value":"1234"},{"op":"remove","path":"/metadata/labels/app"}]`),
+	)
+})
@@ -86,6 +86,15 @@ func (c ipamBlockClient) toV1(kvpv3 *model.KVPair) (*model.KVPair, error) {
 		})
 	}
 
+	// Convert release records.
+	var released []model.ReleasedOrdinal
+	for _, r := range ab.Spec.Released {
+		released = append(released, model.ReleasedOrdinal{
+			Ordinal:    r.Ordinal,
+			ReleasedAt: r.ReleasedAt,
+		})
+	}
+
 	return &model.KVPair{
 		Key: model.BlockKey{
 			CIDR: *cidr,
@@ -97,6 +106,7 @@ func (c ipamBlockClient) toV1(kvpv3 *model.KVPair) (*model.KVPair, error) {
 			Unallocated: ab.Spec.Unallocated,
 			Attributes:  attrs,
 			Deleted:     ab.Spec.Deleted,
+			Released:    released,
 		},
 		Revision: kvpv3.Revision,
 		UID:      &ab.UID,
@@ -123,6 +133,15 @@ func (c ipamBlockClient) toV3(kvpv1 *model.KVPair) *model.KVPair {
 		})
 	}
 
+	// Convert release records.
+	var released []apiv3.ReleasedOrdinal
+	for _, r := range ab.Released {
+		released = append(released, apiv3.ReleasedOrdinal{
+			Ordinal:    r.Ordinal,
+			ReleasedAt: r.ReleasedAt,
+		})
+	}
+
 	return &model.KVPair{
 		Key: model.ResourceKey{
 			Name: name,
@@ -144,6 +163,7 @@ func (c ipamBlockClient) toV3(kvpv1 *model.KVPair) *model.KVPair {
 				Affinity:    ab.Affinity,
 				Attributes:  attrs,
 				Deleted:     ab.Deleted,
+				Released:    released,
 			},
 		},
 		Revision: kvpv1.Revision,
@@ -0,0 +1,52 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("Test IPAMBlock conversion", func() {
+	It("should convert the released ordinals in both directions", func() {
+		handle := "handle"
+		zero := 0
+		cidr := net.MustParseCIDR("10.0.0.0/30")
+		kvp := &model.KVPair{
+			Key: model.BlockKey{CIDR: cidr},
+			Value: &model.AllocationBlock{
+				CIDR:        cidr,
+				Allocations: []*int{&zero, nil, nil, nil},
+				Unallocated: []int{1, 2, 3},
+				Attributes:  []model.AllocationAttribute{{AttrPrimary: &handle}},
+				Released:    []model.ReleasedOrdinal{{Ordinal: 2, ReleasedAt: 1600000000}},
+			},
+			Revision: "1234",
+		}
+
+		c := ipamBlockClient{}
+		v3 := c.toV3(kvp)
+		Expect(v3.Value.(*apiv3.IPAMBlock).Spec.Released).To(Equal([]apiv3.ReleasedOrdinal{{Ordinal: 2, ReleasedAt: 1600000000}}))
+
+		v1, err := c.toV1(v3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v1.Key).To(Equal(kvp.Key))
+		Expect(v1.Value).To(Equal(kvp.Value))
+	})
+})
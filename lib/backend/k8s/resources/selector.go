@@ -0,0 +1,135 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+)
+
+// k8sLabelSelector translates as much of a Calico selector as can be expressed as a
+// Kubernetes label selector, so that a List or Watch can be filtered server-side.  The
+// terms of a top-level conjunction are translated if possible and dropped otherwise, as are
+// terms on labels for which exclude returns true, so the result matches a superset of the
+// resources that the Calico selector matches.  Returns an empty string if no term can be
+// translated.
+func k8sLabelSelector(sel string, exclude func(label string) bool) string {
+	if len(sel) == 0 {
+		return ""
+	}
+	parsed, err := parser.Parse(sel)
+	if err != nil {
+		return ""
+	}
+	v := &rootVisitor{}
+	parsed.AcceptVisitor(v)
+
+	terms := []interface{}{v.root}
+	if and, ok := v.root.(*parser.AndNode); ok {
+		terms = nil
+		for _, op := range and.Operands {
+			terms = append(terms, op)
+		}
+	}
+
+	k8sSel := labels.NewSelector()
+	for _, term := range terms {
+		var label string
+		var op selection.Operator
+		var values []string
+		switch t := term.(type) {
+		case *parser.LabelEqValueNode:
+			label, op, values = t.LabelName, selection.Equals, []string{t.Value}
+		case *parser.LabelNeValueNode:
+			label, op, values = t.LabelName, selection.NotEquals, []string{t.Value}
+		case *parser.LabelInSetNode:
+			label, op, values = t.LabelName, selection.In, []string(t.Value)
+		case *parser.LabelNotInSetNode:
+			label, op, values = t.LabelName, selection.NotIn, []string(t.Value)
+		case *parser.HasNode:
+			label, op = t.LabelName, selection.Exists
+		case *parser.NotNode:
+			has, ok := t.Operand.(*parser.HasNode)
+			if !ok {
+				continue
+			}
+			label, op = has.LabelName, selection.DoesNotExist
+		default:
+			continue
+		}
+		if exclude != nil && exclude(label) {
+			continue
+		}
+		req, err := labels.NewRequirement(label, op, values)
+		if err != nil {
+			// Not a valid Kubernetes label or value, so it cannot match server-side.
+			log.WithError(err).Debug("Unable to translate selector term to a Kubernetes label selector")
+			continue
+		}
+		k8sSel = k8sSel.Add(*req)
+	}
+	return k8sSel.String()
+}
+
+// rootVisitor records the root node of a selector, which is the first node visited.
+type rootVisitor struct {
+	root interface{}
+}
+
+func (v *rootVisitor) Visit(n interface{}) {
+	if v.root == nil {
+		v.root = n
+	}
+}
+
+// k8sFieldSelector translates the terms of a field selector on fields that have a
+// Kubernetes equivalent, given by fieldMap, so that a List or Watch can be filtered
+// server-side.  Other terms are dropped, so the result matches a superset of the resources
+// that the field selector matches.  Any additional Kubernetes field selectors are included
+// in the result.
+func k8sFieldSelector(sel string, fieldMap map[string]string, additional ...fields.Selector) fields.Selector {
+	terms := append([]fields.Selector{}, additional...)
+	if len(sel) == 0 {
+		return fields.AndSelectors(terms...)
+	}
+	parsed, err := fields.ParseSelector(sel)
+	if err != nil {
+		return fields.AndSelectors(terms...)
+	}
+	for _, req := range parsed.Requirements() {
+		field, ok := fieldMap[req.Field]
+		if !ok {
+			continue
+		}
+		switch req.Operator {
+		case selection.Equals, selection.DoubleEquals:
+			terms = append(terms, fields.OneTermEqualSelector(field, req.Value))
+		case selection.NotEquals:
+			terms = append(terms, fields.OneTermNotEqualSelector(field, req.Value))
+		}
+	}
+	return fields.AndSelectors(terms...)
+}
+
+// customResourceFields maps the fields that can be filtered server-side for a custom
+// resource.
+var customResourceFields = map[string]string{
+	"metadata.name":      "metadata.name",
+	"metadata.namespace": "metadata.namespace",
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"k8s.io/apimachinery/pkg/fields"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server-side selector translation", func() {
+	DescribeTable("Calico label selectors",
+		func(sel, expected string) {
+			Expect(k8sLabelSelector(sel, nil)).To(Equal(expected))
+		},
+		Entry("empty", "", ""),
+		Entry("all()", "all()", ""),
+		Entry("equality", "app == 'web'", "app=web"),
+		Entry("inequality", "app != 'web'", "app!=web"),
+		Entry("has", "has(app)", "app"),
+		Entry("not has", "!has(app)", "!app"),
+		Entry("in", "app in {'a', 'b'}", "app in (a,b)"),
+		Entry("not in", "app not in {'a'}", "app notin (a)"),
+		Entry("conjunction", "app == 'web' && has(tier)", "app=web,tier"),
+		Entry("conjunction with untranslatable terms", "app == 'web' && tier contains 'x' && !(a == 'b')", "app=web"),
+		Entry("disjunction", "app == 'web' || has(tier)", ""),
+		Entry("invalid Kubernetes value", "app == 'not valid!'", ""),
+		Entry("invalid selector", "app ==", ""),
+	)
+
+	It("should exclude labels from the translation", func() {
+		Expect(podLabelSelector("projectcalico.org/namespace == 'ns1' && app == 'web'")).To(Equal("app=web"))
+	})
+
+	DescribeTable("field selectors",
+		func(sel string, additional []fields.Selector, expected string) {
+			Expect(k8sFieldSelector(sel, podFields, additional...).String()).To(Equal(expected))
+		},
+		Entry("empty", "", nil, ""),
+		Entry("translated field", "spec.node=node1", nil, "spec.nodeName=node1"),
+		Entry("untranslated field", "spec.node!=node1,spec.interfaceName=eth0", nil, "spec.nodeName!=node1"),
+		Entry("additional selector", "spec.node==node1", []fields.Selector{fields.OneTermEqualSelector("metadata.name", "pod1")}, "metadata.name=pod1,spec.nodeName=node1"),
+		Entry("additional selector only", "spec.interfaceName=eth0", []fields.Selector{fields.OneTermEqualSelector("metadata.name", "pod1")}, "metadata.name=pod1"),
+	)
+})
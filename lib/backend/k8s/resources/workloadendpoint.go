@@ -286,7 +286,13 @@ func (c *WorkloadEndpointClient) listUsingName(ctx context.Context, listOptions
 
 // list lists all the Workload endpoints for the namespace given in listOptions.
 func (c *WorkloadEndpointClient) list(listOptions model.ResourceListOptions, revision string) (*model.KVPairList, error) {
-	podList, err := c.clientSet.CoreV1().Pods(listOptions.Namespace).List(metav1.ListOptions{ResourceVersion: revision})
+	podList, err := c.clientSet.CoreV1().Pods(listOptions.Namespace).List(metav1.ListOptions{
+		ResourceVersion: revision,
+		LabelSelector:   podLabelSelector(listOptions.LabelSelector),
+		FieldSelector:   k8sFieldSelector(listOptions.FieldSelector, podFields).String(),
+		Limit:           listOptions.Limit,
+		Continue:        listOptions.Continue,
+	})
 	if err != nil {
 		return nil, K8sErrorToCalico(err, listOptions)
 	}
@@ -306,9 +312,11 @@ func (c *WorkloadEndpointClient) list(listOptions model.ResourceListOptions, rev
 		ret = append(ret, kvps...)
 	}
 
+	// The limit applies to the Pods, so a page may hold more or fewer WorkloadEndpoints.
 	return &model.KVPairList{
 		KVPairs:  ret,
 		Revision: revision,
+		Continue: podList.Continue,
 	}, nil
 }
 
@@ -323,6 +331,7 @@ func (c *WorkloadEndpointClient) Watch(ctx context.Context, list model.ListInter
 	if !ok {
 		return nil, fmt.Errorf("ListInterface is not a ResourceListOptions: %s", list)
 	}
+	var nameSelector []fields.Selector
 	if len(rlo.Name) != 0 {
 		if len(rlo.Namespace) == 0 {
 			return nil, errors.New("cannot watch a specific WorkloadEndpoint without a namespace")
@@ -333,8 +342,10 @@ func (c *WorkloadEndpointClient) Watch(ctx context.Context, list model.ListInter
 			return nil, err
 		}
 		log.WithField("name", wepids.Pod).Debug("Watching a single workloadendpoint")
-		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", wepids.Pod).String()
+		nameSelector = append(nameSelector, fields.OneTermEqualSelector("metadata.name", wepids.Pod))
 	}
+	opts.FieldSelector = k8sFieldSelector(rlo.FieldSelector, podFields, nameSelector...).String()
+	opts.LabelSelector = podLabelSelector(rlo.LabelSelector)
 
 	ns := rlo.Namespace
 	k8sWatch, err := c.clientSet.CoreV1().Pods(ns).Watch(opts)
@@ -355,3 +366,19 @@ func (c *WorkloadEndpointClient) Watch(ctx context.Context, list model.ListInter
 	}
 	return newK8sWatcherConverterOneToMany(ctx, "Pod", converter, k8sWatch), nil
 }
+
+// podFields maps the WorkloadEndpoint fields that can be filtered server-side to the
+// equivalent Pod fields.
+var podFields = map[string]string{
+	"metadata.namespace": "metadata.namespace",
+	"spec.node":          "spec.nodeName",
+}
+
+// podLabelSelector translates a WorkloadEndpoint label selector to a Pod label selector.
+// The labels that are added to a WorkloadEndpoint when it is converted from a Pod are not
+// translated.
+func podLabelSelector(sel string) string {
+	return k8sLabelSelector(sel, func(label string) bool {
+		return strings.HasPrefix(label, "projectcalico.org/")
+	})
+}
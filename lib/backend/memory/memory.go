@@ -0,0 +1,675 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/resources"
+)
+
+const (
+	profilesKey            = "/calico/resources/v3/projectcalico.org/profiles/"
+	defaultAllowProfileKey = "/calico/resources/v3/projectcalico.org/profiles/projectcalico-default-allow"
+
+	// maxHistory is the number of events retained by the store to service Watch and
+	// revisioned Get/List requests.  Requests for revisions older than the retained
+	// history fail in the same way as requests for a compacted etcd revision.
+	maxHistory = 10000
+)
+
+var (
+	ErrCompacted      = errors.New("required revision has been compacted")
+	ErrFutureRevision = errors.New("required revision is a future revision")
+)
+
+// entry is a single stored value.  Entries are never modified once stored; an update
+// replaces the entry.
+type entry struct {
+	value     []byte
+	createRev int64
+	modRev    int64
+}
+
+// event is a single modification of the store.  For a put, kv contains the new entry;
+// for a delete kv is nil.  prev contains the entry replaced or deleted by the event,
+// or nil if the event created the key.
+type event struct {
+	key  string
+	rev  int64
+	kv   *entry
+	prev *entry
+}
+
+// memoryClient is a fully in-process implementation of the backend api.Client.  Values
+// are stored using the same paths and serialization as the etcdv3 backend, and the
+// revision semantics mirror etcdv3:  a single monotonically increasing store revision
+// is bumped on every modification or transaction, and the ModRevision of an entry is
+// used as the KVPair revision.
+type memoryClient struct {
+	lock     sync.Mutex
+	revision int64
+	entries  map[string]*entry
+
+	// history holds the most recent events, oldest first.  compactRev is the revision
+	// of the newest event that has been discarded from the history.
+	history    []*event
+	compactRev int64
+
+	watchers map[*watcher]struct{}
+}
+
+// sharedStores holds the named stores returned by NewSharedMemoryClient.
+var sharedStores = struct {
+	sync.Mutex
+	clients map[string]*memoryClient
+}{clients: map[string]*memoryClient{}}
+
+// NewMemoryClient creates a new, empty, in-memory backend client.  Each client is an
+// independent datastore.
+func NewMemoryClient() api.Client {
+	return newMemoryClient()
+}
+
+// NewSharedMemoryClient returns the in-memory backend client for the named store, creating
+// an empty store the first time the name is used.  All callers using the same name share the
+// same data, revisions and watchers for the lifetime of the process.
+func NewSharedMemoryClient(name string) api.Client {
+	sharedStores.Lock()
+	defer sharedStores.Unlock()
+
+	c, ok := sharedStores.clients[name]
+	if !ok {
+		c = newMemoryClient()
+		sharedStores.clients[name] = c
+	}
+	return c
+}
+
+func newMemoryClient() *memoryClient {
+	return &memoryClient{
+		entries:  map[string]*entry{},
+		watchers: map[*watcher]struct{}{},
+	}
+}
+
+// Create an entry in the datastore.  If the entry already exists, this will return
+// an ErrorResourceAlreadyExists error and the current entry.
+func (c *memoryClient) Create(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	logCxt := log.WithFields(log.Fields{"model-key": d.Key, "value": d.Value, "ttl": d.TTL, "rev": d.Revision})
+	logCxt.Debug("Processing Create request")
+
+	key, value, err := getKeyValueStrings(d)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if existing, ok := c.entries[key]; ok {
+		logCxt.Debug("Create failed due to resource already existing")
+		kvp, _ := entryToKVPair(d.Key, existing)
+		return kvp, cerrors.ErrorResourceAlreadyExists{Identifier: d.Key}
+	}
+
+	return c.put(d, key, value, c.nextRevision())
+}
+
+// Update an entry in the datastore.  If the entry does not exist, this will return
+// an ErrorResourceDoesNotExist error.  The ResourceVersion must be specified, and if
+// incorrect will return an ErrorResourceUpdateConflict error and the current entry.
+func (c *memoryClient) Update(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	logCxt := log.WithFields(log.Fields{"model-key": d.Key, "value": d.Value, "ttl": d.TTL, "rev": d.Revision})
+	logCxt.Debug("Processing Update request")
+
+	key, value, err := getKeyValueStrings(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// ResourceVersion must be set for an Update.
+	rev, err := parseRevision(d.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	existing, ok := c.entries[key]
+	if !ok {
+		logCxt.Debug("Update failed due to resource not existing")
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: d.Key}
+	}
+	if existing.modRev != rev {
+		logCxt.Debug("Update failed due to resource update conflict")
+		kvp, _ := entryToKVPair(d.Key, existing)
+		return kvp, cerrors.ErrorResourceUpdateConflict{Identifier: d.Key}
+	}
+
+	return c.put(d, key, value, c.nextRevision())
+}
+
+// Apply updates or creates the entry in the datastore.  Revision information is ignored.
+func (c *memoryClient) Apply(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	logCxt := log.WithFields(log.Fields{"model-key": d.Key, "value": d.Value, "ttl": d.TTL, "rev": d.Revision})
+	logCxt.Debug("Processing Apply request")
+
+	key, value, err := getKeyValueStrings(d)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.put(d, key, value, c.nextRevision())
+}
+
+func (c *memoryClient) DeleteKVP(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	return c.Delete(ctx, kvp.Key, kvp.Revision)
+}
+
+// Delete an entry in the datastore.  This errors if the entry does not exists.
+func (c *memoryClient) Delete(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
+	logCxt := log.WithFields(log.Fields{"model-key": k, "rev": revision})
+	logCxt.Debug("Processing Delete request")
+
+	key, err := model.KeyToDefaultDeletePath(k)
+	if err != nil {
+		return nil, err
+	}
+
+	var rev int64
+	if len(revision) != 0 {
+		if rev, err = parseRevision(revision); err != nil {
+			return nil, err
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	existing, ok := c.entries[key]
+	if !ok {
+		logCxt.Debug("Delete failed due to resource not existing")
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: k}
+	}
+	if len(revision) != 0 && existing.modRev != rev {
+		logCxt.Debug("Delete failed due to resource update conflict")
+		latestValue, err := entryToKVPair(k, existing)
+		if err != nil {
+			return nil, err
+		}
+		return latestValue, cerrors.ErrorResourceUpdateConflict{Identifier: k}
+	}
+
+	c.delete(key, c.nextRevision())
+
+	// Parse the deleted value.  Don't propagate the error in this case since the
+	// delete did succeed.
+	previousValue, _ := entryToKVPair(k, existing)
+	return previousValue, nil
+}
+
+// Txn performs the operations atomically, guarded by the same conditions as the
+// equivalent Create, Update and Delete requests.  If any condition fails, no changes
+// are made and the error for the first failed operation is returned.  As with an etcdv3
+// transaction, the store revision is bumped once and every change is given that revision.
+func (c *memoryClient) Txn(ctx context.Context, ops []api.TxnOp) ([]*model.KVPair, error) {
+	log.WithField("ops", len(ops)).Debug("Processing Txn request")
+
+	keys := make([]string, len(ops))
+	values := make([]string, len(ops))
+	revs := make([]int64, len(ops))
+	for i, op := range ops {
+		var err error
+		d := op.KVPair
+		switch op.Type {
+		case api.TxnCreate, api.TxnUpdate:
+			if keys[i], values[i], err = getKeyValueStrings(d); err != nil {
+				return nil, err
+			}
+			if op.Type == api.TxnUpdate {
+				// ResourceVersion must be set for an Update.
+				if revs[i], err = parseRevision(d.Revision); err != nil {
+					return nil, err
+				}
+			}
+		case api.TxnDelete:
+			if keys[i], err = model.KeyToDefaultDeletePath(d.Key); err != nil {
+				return nil, err
+			}
+			if len(d.Revision) != 0 {
+				if revs[i], err = parseRevision(d.Revision); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown transaction operation %v", op.Type)
+		}
+		for j := 0; j < i; j++ {
+			if keys[j] == keys[i] {
+				return nil, fmt.Errorf("duplicate key %v in transaction", d.Key)
+			}
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Check every condition before making any changes.
+	for i, op := range ops {
+		existing, ok := c.entries[keys[i]]
+		switch {
+		case op.Type == api.TxnCreate && ok:
+			return nil, cerrors.ErrorResourceAlreadyExists{Identifier: op.KVPair.Key}
+		case op.Type != api.TxnCreate && !ok:
+			return nil, cerrors.ErrorResourceDoesNotExist{Identifier: op.KVPair.Key}
+		case revs[i] != 0 && existing.modRev != revs[i]:
+			return nil, cerrors.ErrorResourceUpdateConflict{Identifier: op.KVPair.Key}
+		}
+	}
+
+	rev := c.nextRevision()
+	results := make([]*model.KVPair, len(ops))
+	for i, op := range ops {
+		if op.Type == api.TxnDelete {
+			existing := c.entries[keys[i]]
+			c.delete(keys[i], rev)
+			results[i], _ = entryToKVPair(op.KVPair.Key, existing)
+			continue
+		}
+		kvp, err := c.put(op.KVPair, keys[i], values[i], rev)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = kvp
+	}
+	return results, nil
+}
+
+// Get an entry from the datastore.  This errors if the entry does not exist.
+func (c *memoryClient) Get(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
+	logCxt := log.WithFields(log.Fields{"model-key": k, "rev": revision})
+	logCxt.Debug("Processing Get request")
+
+	key, err := model.KeyToDefaultPath(k)
+	if err != nil {
+		logCxt.Error("Unable to convert model.Key to a path")
+		return nil, err
+	}
+
+	// Handle the static default-allow profile. Always return the default profile.
+	if key == defaultAllowProfileKey {
+		logCxt.Debug("Returning default-allow profile for get")
+		return resources.DefaultAllowProfile(), nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entries, _, err := c.snapshot(revision, func(path string) bool { return path == key })
+	if err != nil {
+		return nil, err
+	}
+	e, ok := entries[key]
+	if !ok {
+		logCxt.Debug("No entry found")
+		return nil, cerrors.ErrorResourceDoesNotExist{Identifier: k}
+	}
+
+	return entryToKVPair(k, e)
+}
+
+// List entries in the datastore.  This may return an empty list of there are
+// no entries matching the request in the ListInterface.
+func (c *memoryClient) List(ctx context.Context, l model.ListInterface, revision string) (*model.KVPairList, error) {
+	logCxt := log.WithFields(log.Fields{"list-interface": l, "rev": revision})
+	logCxt.Debug("Processing List request")
+
+	key, isPrefix := calculateListKeyAndPrefix(logCxt, l)
+
+	// If the List is limited, or continues a previous limited List, then list from the
+	// start key in the continuation token, at the revision of the first page.  As with
+	// etcdv3, a List of a single key is never paginated.
+	match := keyMatcher(key, isPrefix)
+	var limit int64
+	if rlo, ok := l.(model.ResourceListOptions); ok && isPrefix {
+		limit = rlo.Limit
+		if len(rlo.Continue) != 0 {
+			rev, start, err := api.DecodeContinue(rlo.Continue, key)
+			if err != nil {
+				return nil, err
+			}
+			revision = strconv.FormatInt(rev, 10)
+			match = func(path string) bool { return strings.HasPrefix(path, key) && path >= start }
+		}
+	}
+
+	c.lock.Lock()
+	entries, rev, err := c.snapshot(revision, match)
+	c.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var next string
+	if limit > 0 && int64(len(entries)) > limit {
+		paths := make([]string, 0, len(entries))
+		for path := range entries {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths[limit:] {
+			delete(entries, path)
+		}
+		next = api.EncodeContinue(rev, paths[limit-1]+"\x00")
+	}
+
+	list := convertListResponse(entries, l)
+
+	// If we're listing profiles, we need to handle the statically defined
+	// default-allow profile in the resources package.
+	// We always include the default profile, on the last page of a paginated List.
+	if (key == profilesKey || key == defaultAllowProfileKey) && len(next) == 0 {
+		list = append(list, resources.DefaultAllowProfile())
+	}
+
+	return &model.KVPairList{
+		KVPairs:  list,
+		Revision: strconv.FormatInt(rev, 10),
+		Continue: next,
+	}, nil
+}
+
+// EnsureInitialized makes sure that the datastore is initialized for use by
+// Calico.  There is nothing to initialize for an in-memory datastore.
+func (c *memoryClient) EnsureInitialized() error {
+	return nil
+}
+
+// Clean removes all of the Calico data from the datastore.
+func (c *memoryClient) Clean() error {
+	log.Warning("Cleaning in-memory datastore of all Calico data")
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, "/calico/") {
+			c.delete(key, c.nextRevision())
+		}
+	}
+	return nil
+}
+
+// IsClean returns true if there are no /calico/ prefixed entries in the
+// datastore.  This is not part of the exposed API, but is public to allow
+// direct consumers of the backend API to access this.
+func (c *memoryClient) IsClean() (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, "/calico/") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// nextRevision bumps the store revision and returns the new revision.  The caller must
+// hold the lock.
+func (c *memoryClient) nextRevision() int64 {
+	c.revision++
+	return c.revision
+}
+
+// put stores the serialized value at the supplied path with the supplied revision and
+// notifies any watchers.  The caller must hold the lock.
+func (c *memoryClient) put(d *model.KVPair, key, value string, rev int64) (*model.KVPair, error) {
+	e := &entry{
+		value:     []byte(value),
+		createRev: rev,
+		modRev:    rev,
+	}
+	prev := c.entries[key]
+	if prev != nil {
+		e.createRev = prev.createRev
+	}
+	c.entries[key] = e
+	c.record(&event{key: key, rev: rev, kv: e, prev: prev})
+
+	if d.TTL != 0 {
+		// Expire the entry provided it has not been modified in the meantime.  This
+		// is equivalent to the etcdv3 behavior of granting a new lease per write.
+		modRev := e.modRev
+		time.AfterFunc(d.TTL, func() {
+			c.expire(key, modRev)
+		})
+	}
+
+	v, err := model.ParseValue(d.Key, e.value)
+	if err != nil {
+		return nil, cerrors.ErrorPartialFailure{Err: fmt.Errorf("Unexpected error parsing stored datastore entry '%v': %+v", value, err)}
+	}
+	return &model.KVPair{
+		Key:      d.Key,
+		Value:    v,
+		Revision: strconv.FormatInt(e.modRev, 10),
+		UID:      d.UID,
+		TTL:      d.TTL,
+	}, nil
+}
+
+// delete removes the entry at the supplied path, recording the deletion with the supplied
+// revision and notifying any watchers.  The caller must hold the lock.
+func (c *memoryClient) delete(key string, rev int64) {
+	prev := c.entries[key]
+	delete(c.entries, key)
+	c.record(&event{key: key, rev: rev, prev: prev})
+}
+
+// expire deletes the entry at the supplied path if it has not been modified since
+// the TTL was set.
+func (c *memoryClient) expire(key string, modRev int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[key]; ok && e.modRev == modRev {
+		log.WithField("key", key).Debug("Entry TTL expired")
+		c.delete(key, c.nextRevision())
+	}
+}
+
+// record adds the event to the history, discarding the oldest event if the history
+// is full, and passes the event to each watcher.  The caller must hold the lock.
+func (c *memoryClient) record(e *event) {
+	if len(c.history) == maxHistory {
+		c.compactRev = c.history[0].rev
+		c.history[0] = nil
+		c.history = c.history[1:]
+	}
+	c.history = append(c.history, e)
+
+	for w := range c.watchers {
+		w.queue(e)
+	}
+}
+
+// snapshot returns the entries matching the filter as they were at the requested
+// revision, along with the revision of the snapshot.  If no revision is specified,
+// the current entries are returned.  The caller must hold the lock.
+func (c *memoryClient) snapshot(revision string, match func(string) bool) (map[string]*entry, int64, error) {
+	entries := map[string]*entry{}
+	for path, e := range c.entries {
+		if match(path) {
+			entries[path] = e
+		}
+	}
+	if len(revision) == 0 {
+		return entries, c.revision, nil
+	}
+
+	rev, err := parseRevision(revision)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rev > c.revision {
+		return nil, 0, cerrors.ErrorDatastoreError{Err: ErrFutureRevision}
+	}
+	if rev < c.compactRev {
+		return nil, 0, cerrors.ErrorDatastoreError{Err: ErrCompacted}
+	}
+
+	// Roll back the events newer than the requested revision, newest first.
+	for i := len(c.history) - 1; i >= 0 && c.history[i].rev > rev; i-- {
+		e := c.history[i]
+		if !match(e.key) {
+			continue
+		}
+		if e.prev == nil {
+			delete(entries, e.key)
+		} else {
+			entries[e.key] = e.prev
+		}
+	}
+	return entries, rev, nil
+}
+
+// keyMatcher returns a function that matches paths against the supplied key, either
+// as a prefix or exactly.
+func keyMatcher(key string, isPrefix bool) func(string) bool {
+	if isPrefix {
+		return func(path string) bool { return strings.HasPrefix(path, key) }
+	}
+	return func(path string) bool { return path == key }
+}
+
+// calculateListKeyAndPrefix returns the path to query for the supplied list options,
+// and whether that path is a prefix.  This follows the same processing as the etcdv3
+// backend.
+func calculateListKeyAndPrefix(logCxt *log.Entry, l model.ListInterface) (string, bool) {
+	key := model.ListOptionsToDefaultPathRoot(l)
+	if model.IsListOptionsLastSegmentPrefix(l) {
+		// The last segment is a prefix, perform a prefix match without adding a segment
+		// delimiter.
+		logCxt.Debug("List options is a name prefix, don't add a / to the path")
+		return key, true
+	} else if !model.ListOptionsIsFullyQualified(l) {
+		// The key is not a fully qualified key - it must be a prefix.
+		logCxt.Debug("List options is a parent prefix, ensure path ends in /")
+		if !strings.HasSuffix(key, "/") {
+			key += "/"
+		}
+		return key, true
+	}
+	return key, false
+}
+
+// convertListResponse converts the stored entries to a slice of model.KVPair with
+// parsed values, in key order (which matches the ordering returned by etcd).  Entries
+// whose path does not represent the resource specified by the ListInterface, or whose
+// value cannot be parsed, are skipped.
+func convertListResponse(entries map[string]*entry, l model.ListInterface) []*model.KVPair {
+	paths := make([]string, 0, len(entries))
+	for path := range entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	list := []*model.KVPair{}
+	for _, path := range paths {
+		if k := l.KeyFromDefaultPath(path); k != nil {
+			if kv, err := entryToKVPair(k, entries[path]); err == nil {
+				list = append(list, kv)
+			}
+		}
+	}
+	return list
+}
+
+// entryToKVPair converts a stored entry in to a model.KVPair.
+func entryToKVPair(key model.Key, e *entry) (*model.KVPair, error) {
+	v, err := model.ParseValue(key, e.value)
+	if err != nil {
+		return nil, cerrors.ErrorParsingDatastoreEntry{
+			RawKey:   key.String(),
+			RawValue: string(e.value),
+			Err:      err,
+		}
+	}
+
+	return &model.KVPair{
+		Key:      key,
+		Value:    v,
+		Revision: strconv.FormatInt(e.modRev, 10),
+	}, nil
+}
+
+// getKeyValueStrings returns the path and serialized value calculated from the
+// KVPair.
+func getKeyValueStrings(d *model.KVPair) (string, string, error) {
+	logCxt := log.WithFields(log.Fields{"model-key": d.Key, "value": d.Value})
+	key, err := model.KeyToDefaultPath(d.Key)
+	if err != nil {
+		logCxt.WithError(err).Error("Failed to convert model-key to path")
+		return "", "", cerrors.ErrorDatastoreError{
+			Err:        err,
+			Identifier: d.Key,
+		}
+	}
+	bytes, err := model.SerializeValue(d)
+	if err != nil {
+		logCxt.WithError(err).Error("Failed to serialize value")
+		return "", "", cerrors.ErrorDatastoreError{
+			Err:        err,
+			Identifier: d.Key,
+		}
+	}
+
+	return key, string(bytes), nil
+}
+
+// parseRevision parses the model.KVPair revision string and converts to the
+// equivalent int64 store revision.
+func parseRevision(revs string) (int64, error) {
+	rev, err := strconv.ParseInt(revs, 10, 64)
+	if err != nil {
+		log.WithField("Revision", revs).Debug("Unable to parse Revision")
+		return 0, cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{
+				{
+					Name:  "ResourceVersion",
+					Value: revs,
+				},
+			},
+		}
+	}
+	return rev, nil
+}
@@ -0,0 +1,371 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/memory"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+func networkSetKVP(name string, nets ...string) *model.KVPair {
+	return &model.KVPair{
+		Key: model.ResourceKey{Kind: apiv3.KindGlobalNetworkSet, Name: name},
+		Value: &apiv3.GlobalNetworkSet{
+			TypeMeta:   metav1.TypeMeta{Kind: apiv3.KindGlobalNetworkSet, APIVersion: apiv3.GroupVersionCurrent},
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       apiv3.GlobalNetworkSetSpec{Nets: nets},
+		},
+	}
+}
+
+var _ = Describe("In-memory backend client", func() {
+	ctx := context.Background()
+	var c api.Client
+
+	BeforeEach(func() {
+		c = memory.NewMemoryClient()
+	})
+
+	It("should be constructed by the backend factory", func() {
+		be, err := backend.NewClient(apiconfig.CalicoAPIConfig{
+			Spec: apiconfig.CalicoAPIConfigSpec{DatastoreType: apiconfig.Memory},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be).NotTo(BeNil())
+	})
+
+	It("should share a store between clients created from the same config", func() {
+		config := apiconfig.CalicoAPIConfig{
+			Spec: apiconfig.CalicoAPIConfigSpec{
+				DatastoreType: apiconfig.Memory,
+				MemoryConfig:  apiconfig.MemoryConfig{MemoryStoreName: "shared-store-test"},
+			},
+		}
+		be1, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be2, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be1.Clean()).NotTo(HaveOccurred())
+
+		_, err = be1.Create(ctx, networkSetKVP("ns1", "10.0.0.0/8"))
+		Expect(err).NotTo(HaveOccurred())
+		kvp, err := be2.Get(ctx, model.ResourceKey{Kind: apiv3.KindGlobalNetworkSet, Name: "ns1"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp.Value.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"10.0.0.0/8"}))
+
+		By("Keeping stores with different names independent")
+		config.Spec.MemoryStoreName = "other-store-test"
+		be3, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = be3.Get(ctx, model.ResourceKey{Kind: apiv3.KindGlobalNetworkSet, Name: "ns1"}, "")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+
+	It("should handle create, update, get and delete with revision checks", func() {
+		By("Creating an entry")
+		kvp1, err := c.Create(ctx, networkSetKVP("ns1", "10.0.0.0/8"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp1.Revision).To(Equal("1"))
+
+		By("Failing to create a duplicate entry")
+		existing, err := c.Create(ctx, networkSetKVP("ns1", "11.0.0.0/8"))
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceAlreadyExists{}))
+		Expect(existing.Value.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"10.0.0.0/8"}))
+
+		By("Updating the entry with the current revision")
+		update := networkSetKVP("ns1", "12.0.0.0/8")
+		update.Revision = kvp1.Revision
+		kvp2, err := c.Update(ctx, update)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp2.Revision).To(Equal("2"))
+
+		By("Failing to update the entry with a stale revision")
+		update = networkSetKVP("ns1", "13.0.0.0/8")
+		update.Revision = kvp1.Revision
+		_, err = c.Update(ctx, update)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+
+		By("Failing to update a non-existent entry")
+		update = networkSetKVP("ns2", "13.0.0.0/8")
+		update.Revision = kvp1.Revision
+		_, err = c.Update(ctx, update)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+
+		By("Getting the current and previous revisions of the entry")
+		kvp, err := c.Get(ctx, kvp1.Key, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp.Revision).To(Equal("2"))
+		Expect(kvp.Value.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"12.0.0.0/8"}))
+		kvp, err = c.Get(ctx, kvp1.Key, "1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp.Revision).To(Equal("1"))
+		Expect(kvp.Value.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"10.0.0.0/8"}))
+
+		By("Failing to delete the entry with a stale revision")
+		_, err = c.Delete(ctx, kvp1.Key, kvp1.Revision)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+
+		By("Deleting the entry")
+		kvp, err = c.Delete(ctx, kvp1.Key, kvp2.Revision)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp.Value.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"12.0.0.0/8"}))
+		_, err = c.Get(ctx, kvp1.Key, "")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		_, err = c.Delete(ctx, kvp1.Key, "")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+
+	It("should apply a transaction atomically", func() {
+		txn := c.(api.TxnClient)
+		kvp1, err := c.Create(ctx, networkSetKVP("ns1", "10.0.0.0/8"))
+		Expect(err).NotTo(HaveOccurred())
+		kvp2, err := c.Create(ctx, networkSetKVP("ns2", "11.0.0.0/8"))
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Failing a transaction with a stale revision and making no changes")
+		update := networkSetKVP("ns1", "12.0.0.0/8")
+		update.Revision = kvp1.Revision
+		stale := networkSetKVP("ns2")
+		stale.Revision = "1"
+		_, err = txn.Txn(ctx, []api.TxnOp{
+			{Type: api.TxnCreate, KVPair: networkSetKVP("ns3", "13.0.0.0/8")},
+			{Type: api.TxnUpdate, KVPair: update},
+			{Type: api.TxnDelete, KVPair: stale},
+		})
+		Expect(err).To(Equal(cerrors.ErrorResourceUpdateConflict{Identifier: stale.Key}))
+		l, err := c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.KVPairs).To(HaveLen(2))
+		Expect(l.Revision).To(Equal(kvp2.Revision))
+
+		By("Failing a transaction that creates an existing entry")
+		_, err = txn.Txn(ctx, []api.TxnOp{{Type: api.TxnCreate, KVPair: networkSetKVP("ns1")}})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceAlreadyExists{}))
+
+		By("Failing a transaction that modifies an entry twice")
+		_, err = txn.Txn(ctx, []api.TxnOp{
+			{Type: api.TxnDelete, KVPair: networkSetKVP("ns1")},
+			{Type: api.TxnCreate, KVPair: networkSetKVP("ns1")},
+		})
+		Expect(err).To(HaveOccurred())
+
+		By("Applying a valid transaction")
+		del := networkSetKVP("ns2")
+		del.Revision = kvp2.Revision
+		kvps, err := txn.Txn(ctx, []api.TxnOp{
+			{Type: api.TxnCreate, KVPair: networkSetKVP("ns3", "13.0.0.0/8")},
+			{Type: api.TxnUpdate, KVPair: update},
+			{Type: api.TxnDelete, KVPair: del},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(HaveLen(3))
+		Expect(kvps[0].Revision).To(Equal("3"))
+		Expect(kvps[1].Revision).To(Equal("3"))
+		Expect(kvps[2].Value.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"11.0.0.0/8"}))
+		l, err = c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Revision).To(Equal("3"))
+		Expect(l.KVPairs).To(HaveLen(2))
+		Expect(l.KVPairs[0].Value.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"12.0.0.0/8"}))
+		Expect(l.KVPairs[1].Key.(model.ResourceKey).Name).To(Equal("ns3"))
+	})
+
+	It("should list entries by kind and name prefix", func() {
+		for _, name := range []string{"abc", "abd", "xyz"} {
+			_, err := c.Apply(ctx, networkSetKVP(name))
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		kvps, err := c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps.KVPairs).To(HaveLen(3))
+		Expect(kvps.Revision).To(Equal("3"))
+
+		kvps, err = c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet, Name: "ab", Prefix: true}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps.KVPairs).To(HaveLen(2))
+		Expect(kvps.KVPairs[0].Key.(model.ResourceKey).Name).To(Equal("abc"))
+		Expect(kvps.KVPairs[1].Key.(model.ResourceKey).Name).To(Equal("abd"))
+
+		kvps, err = c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet}, "1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps.KVPairs).To(HaveLen(1))
+
+		_, err = c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet}, "10")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should list entries in pages from a consistent snapshot", func() {
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			_, err := c.Apply(ctx, networkSetKVP(name))
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		list := model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet, Limit: 2}
+		kvps, err := c.List(ctx, list, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps.KVPairs).To(HaveLen(2))
+		Expect(kvps.KVPairs[1].Key.(model.ResourceKey).Name).To(Equal("b"))
+		Expect(kvps.Revision).To(Equal("5"))
+		Expect(kvps.Continue).NotTo(BeEmpty())
+
+		// Changes made after the first page are not seen by the later pages.
+		_, err = c.Apply(ctx, networkSetKVP("bb"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.Delete(ctx, model.ResourceKey{Kind: apiv3.KindGlobalNetworkSet, Name: "e"}, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for len(kvps.Continue) != 0 {
+			list.Continue = kvps.Continue
+			kvps, err = c.List(ctx, list, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvps.Revision).To(Equal("5"))
+			for _, kvp := range kvps.KVPairs {
+				names = append(names, kvp.Key.(model.ResourceKey).Name)
+			}
+		}
+		Expect(names).To(Equal([]string{"c", "d", "e"}))
+
+		list.Continue = api.EncodeContinue(5, "/calico/resources/v3/projectcalico.org/profiles/")
+		_, err = c.List(ctx, list, "")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+	})
+
+	It("should include the default-allow profile on the last page", func() {
+		_, err := c.Create(ctx, &model.KVPair{
+			Key:   model.ResourceKey{Kind: apiv3.KindProfile, Name: "p1"},
+			Value: apiv3.NewProfile(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		kvps, err := c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindProfile, Limit: 1}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps.KVPairs).To(HaveLen(2))
+		Expect(kvps.KVPairs[0].Key.(model.ResourceKey).Name).To(Equal("p1"))
+		Expect(kvps.KVPairs[1].Key.(model.ResourceKey).Name).To(Equal("projectcalico-default-allow"))
+		Expect(kvps.Continue).To(BeEmpty())
+	})
+
+	It("should always include the default-allow profile", func() {
+		kvps, err := c.List(ctx, model.ResourceListOptions{Kind: apiv3.KindProfile}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps.KVPairs).To(HaveLen(1))
+	})
+
+	It("should expire entries with a TTL", func() {
+		kvp := networkSetKVP("ns1")
+		kvp.TTL = 100 * time.Millisecond
+		_, err := c.Create(ctx, kvp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			_, err := c.Get(ctx, kvp.Key, "")
+			return err
+		}).Should(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+
+	It("should clean the datastore", func() {
+		_, err := c.Create(ctx, networkSetKVP("ns1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Clean()).NotTo(HaveOccurred())
+		clean, err := c.(interface{ IsClean() (bool, error) }).IsClean()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clean).To(BeTrue())
+	})
+
+	Describe("watching", func() {
+		list := model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet}
+
+		It("should send existing entries followed by updates", func() {
+			kvp1, err := c.Create(ctx, networkSetKVP("ns1"))
+			Expect(err).NotTo(HaveOccurred())
+
+			w, err := c.Watch(ctx, list, "")
+			Expect(err).NotTo(HaveOccurred())
+			defer w.Stop()
+
+			var e api.WatchEvent
+			Eventually(w.ResultChan()).Should(Receive(&e))
+			Expect(e.Type).To(Equal(api.WatchAdded))
+			Expect(e.New.Revision).To(Equal(kvp1.Revision))
+
+			kvp1.Value = networkSetKVP("ns1", "10.0.0.0/8").Value
+			kvp2, err := c.Update(ctx, kvp1)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(w.ResultChan()).Should(Receive(&e))
+			Expect(e.Type).To(Equal(api.WatchModified))
+			Expect(e.Old.Revision).To(Equal("1"))
+			Expect(e.New.Revision).To(Equal("2"))
+
+			_, err = c.Delete(ctx, kvp2.Key, "")
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(w.ResultChan()).Should(Receive(&e))
+			Expect(e.Type).To(Equal(api.WatchDeleted))
+			Expect(e.Old.Revision).To(Equal("2"))
+			Expect(e.New).To(BeNil())
+		})
+
+		It("should replay events after the requested revision", func() {
+			for _, name := range []string{"ns1", "ns2", "ns3"} {
+				_, err := c.Create(ctx, networkSetKVP(name))
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			w, err := c.Watch(ctx, list, "1")
+			Expect(err).NotTo(HaveOccurred())
+
+			var e api.WatchEvent
+			Eventually(w.ResultChan()).Should(Receive(&e))
+			Expect(e.New.Key.(model.ResourceKey).Name).To(Equal("ns2"))
+			Eventually(w.ResultChan()).Should(Receive(&e))
+			Expect(e.New.Key.(model.ResourceKey).Name).To(Equal("ns3"))
+			Consistently(w.ResultChan()).ShouldNot(Receive())
+
+			w.Stop()
+			Eventually(w.HasTerminated).Should(BeTrue())
+			Eventually(w.ResultChan()).Should(BeClosed())
+		})
+
+		It("should only send events for the watched resources", func() {
+			w, err := c.Watch(ctx, model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet, Name: "ns2"}, "")
+			Expect(err).NotTo(HaveOccurred())
+			defer w.Stop()
+
+			for _, name := range []string{"ns1", "ns2", "ns3"} {
+				_, err := c.Create(ctx, networkSetKVP(name))
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			var e api.WatchEvent
+			Eventually(w.ResultChan()).Should(Receive(&e))
+			Expect(e.New.Key.(model.ResourceKey).Name).To(Equal("ns2"))
+			Consistently(w.ResultChan()).ShouldNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,242 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+)
+
+const (
+	resultsBufSize = 100
+)
+
+// Watch entries in the datastore matching the resources specified by the ListInterface.
+func (c *memoryClient) Watch(ctx context.Context, l model.ListInterface, revision string) (api.WatchInterface, error) {
+	logCxt := log.WithFields(log.Fields{"list": l, "rev": revision})
+	key, isPrefix := calculateListKeyAndPrefix(logCxt, l)
+
+	var rev int64
+	if len(revision) != 0 {
+		var err error
+		if rev, err = parseRevision(revision); err != nil {
+			return nil, err
+		}
+	}
+
+	wc := &watcher{
+		client:     c,
+		list:       l,
+		match:      keyMatcher(key, isPrefix),
+		notify:     make(chan struct{}, 1),
+		resultChan: make(chan api.WatchEvent, resultsBufSize),
+	}
+	wc.ctx, wc.cancel = context.WithCancel(ctx)
+
+	// Queue up the initial events and register the watcher while holding the lock so
+	// that no events are missed between the two.
+	c.lock.Lock()
+	if rev == 0 {
+		// No initial revision supplied, so send the current configuration as a set of
+		// added events.  The default-allow profile is never included in a watch.
+		logCxt.Debug("Sending create events for each existing entry")
+		entries, _, err := c.snapshot("", wc.match)
+		if err != nil {
+			c.lock.Unlock()
+			return nil, err
+		}
+		for _, kvp := range convertListResponse(entries, l) {
+			wc.pending = append(wc.pending, &api.WatchEvent{Type: api.WatchAdded, New: kvp})
+		}
+	} else {
+		if rev > c.revision {
+			c.lock.Unlock()
+			return nil, cerrors.ErrorDatastoreError{Err: ErrFutureRevision}
+		}
+		if rev < c.compactRev {
+			c.lock.Unlock()
+			return nil, cerrors.ErrorDatastoreError{Err: ErrCompacted}
+		}
+		for _, e := range c.history {
+			if e.rev > rev {
+				wc.queueLocked(e)
+			}
+		}
+	}
+	c.watchers[wc] = struct{}{}
+	c.lock.Unlock()
+
+	go wc.watchLoop()
+	return wc, nil
+}
+
+// watcher implements watch.Interface.
+type watcher struct {
+	client     *memoryClient
+	ctx        context.Context
+	cancel     context.CancelFunc
+	resultChan chan api.WatchEvent
+	list       model.ListInterface
+	match      func(string) bool
+	terminated uint32
+
+	// pending holds the converted events that have not yet been sent on the results
+	// channel.  The store never blocks on a slow watcher.
+	lock    sync.Mutex
+	pending []*api.WatchEvent
+	notify  chan struct{}
+}
+
+// Stop stops the watcher and releases associated resources.
+// This calls through to the context cancel function.
+func (wc *watcher) Stop() {
+	wc.cancel()
+}
+
+// ResultChan returns a channel used to receive WatchEvents.
+func (wc *watcher) ResultChan() <-chan api.WatchEvent {
+	return wc.resultChan
+}
+
+// HasTerminated returns true when the watcher has completed termination processing.
+func (wc *watcher) HasTerminated() bool {
+	return atomic.LoadUint32(&wc.terminated) != 0
+}
+
+// queue converts the store event and, if relevant to this watcher, adds it to the
+// pending events.  Called by the store with the store lock held.
+func (wc *watcher) queue(e *event) {
+	if wc.queueLocked(e) {
+		select {
+		case wc.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// queueLocked converts the store event and, if relevant to this watcher, adds it to the
+// pending events, returning true if an event was queued.
+func (wc *watcher) queueLocked(e *event) bool {
+	if !wc.match(e.key) {
+		return false
+	}
+	ae, err := convertWatchEvent(e, wc.list)
+	if ae == nil && err == nil {
+		return false
+	} else if err != nil {
+		// An error parsing the event is returned as an error, but don't exit the
+		// watcher as restarting the watcher is unlikely to fix the conversion error.
+		ae = &api.WatchEvent{Type: api.WatchError, Error: err}
+	}
+
+	wc.lock.Lock()
+	wc.pending = append(wc.pending, ae)
+	wc.lock.Unlock()
+	return true
+}
+
+// watchLoop sends the pending events on the results channel until the watcher is
+// stopped.
+func (wc *watcher) watchLoop() {
+	// When this loop exits, make sure we terminate the watcher resources.
+	defer wc.terminateWatcher()
+
+	for {
+		wc.lock.Lock()
+		events := wc.pending
+		wc.pending = nil
+		wc.lock.Unlock()
+
+		for _, e := range events {
+			if len(wc.resultChan) == resultsBufSize {
+				log.Warningf("Watch events backing up: %d events", resultsBufSize)
+			}
+			select {
+			case wc.resultChan <- *e:
+			case <-wc.ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-wc.notify:
+		case <-wc.ctx.Done():
+			return
+		}
+	}
+}
+
+// terminateWatcher terminates the resources associated with the watcher.
+func (wc *watcher) terminateWatcher() {
+	log.Debug("Terminating in-memory watcher")
+	wc.cancel()
+
+	// Deregister from the store so that no further events are queued.
+	wc.client.lock.Lock()
+	delete(wc.client.watchers, wc)
+	wc.client.lock.Unlock()
+
+	// Close the results channel.
+	close(wc.resultChan)
+
+	// Increment the terminated counter using a goroutine safe operation.
+	atomic.AddUint32(&wc.terminated, 1)
+}
+
+// convertWatchEvent converts a store event to an api.WatchEvent, or nil if the
+// event did not correspond to an event that we are interested in.
+func convertWatchEvent(e *event, l model.ListInterface) (*api.WatchEvent, error) {
+	k := l.KeyFromDefaultPath(e.key)
+	if k == nil {
+		log.WithField("key", e.key).Debug("key filtered")
+		return nil, nil
+	}
+
+	var eventType api.WatchEventType
+	switch {
+	case e.kv == nil:
+		eventType = api.WatchDeleted
+	case e.prev == nil:
+		eventType = api.WatchAdded
+	default:
+		eventType = api.WatchModified
+	}
+
+	var oldKV, newKV *model.KVPair
+	var err error
+	if e.kv != nil {
+		if newKV, err = entryToKVPair(k, e.kv); err != nil {
+			return nil, err
+		}
+	}
+	if e.prev != nil {
+		if oldKV, err = entryToKVPair(k, e.prev); err != nil {
+			return nil, err
+		}
+	}
+
+	return &api.WatchEvent{
+		Old:  oldKV,
+		New:  newKV,
+		Type: eventType,
+	}, nil
+}
@@ -36,6 +36,7 @@ const (
 	IPAMBlockAttributeTypeIPIP      = "ipipTunnelAddress"
 	IPAMBlockAttributeTypeVXLAN     = "vxlanTunnelAddress"
 	IPAMBlockAttributeTypeWireguard = "wireguardTunnelAddress"
+	IPAMBlockAttributeSticky        = "sticky"
 )
 
 var (
@@ -108,12 +109,22 @@ type AllocationBlock struct {
 	Attributes  []AllocationAttribute `json:"attributes"`
 	Deleted     bool                  `json:"deleted"`
 
+	// Released records when recently released ordinals were released, for pools that
+	// quarantine released addresses.
+	Released []ReleasedOrdinal `json:"released,omitempty"`
+
 	// HostAffinity is deprecated in favor of Affinity.
 	// This is only to keep compatibility with existing deployments.
 	// The data format should be `Affinity: host:hostname` (not `hostAffinity: hostname`).
 	HostAffinity *string `json:"hostAffinity,omitempty"`
 }
 
+type ReleasedOrdinal struct {
+	Ordinal int `json:"ordinal"`
+	// The time at which the ordinal was released, in seconds since the epoch.
+	ReleasedAt int64 `json:"releasedAt"`
+}
+
 func (b *AllocationBlock) MarkDeleted() {
 	b.Deleted = true
 }
@@ -102,6 +102,9 @@ type KVPair struct {
 type KVPairList struct {
 	KVPairs  []*KVPair
 	Revision string
+	// Continue is set if the List was limited and there are more results.  It is passed in
+	// the list options of the next List to continue from the end of this one.
+	Continue string
 }
 
 // KeyToDefaultPath converts one of the Keys from this package into a unique
@@ -217,6 +220,16 @@ func KeyFromDefaultPath(path string) Key {
 		return PolicyKey{
 			Name: unescapeName(m[2]),
 		}
+	} else if m := matchStagedPolicy.FindStringSubmatch(path); m != nil {
+		log.Debugf("Path is a staged policy: %v", path)
+		return StagedPolicyKey{
+			Name: unescapeName(m[2]),
+		}
+	} else if m := matchTier.FindStringSubmatch(path); m != nil {
+		log.Debugf("Path is a tier: %v", path)
+		return TierKey{
+			Name: unescapeName(m[1]),
+		}
 	} else if m := matchProfile.FindStringSubmatch(path); m != nil {
 		log.Debugf("Path is a profile: %v (%v)", path, m[2])
 		pk := ProfileKey{unescapeName(m[1])}
@@ -152,6 +152,18 @@ var _ = DescribeTable(
 		PolicyKey{Name: "biff/bop"},
 		false,
 	),
+	Entry(
+		"staged policy with a /",
+		"/calico/v1/stagedpolicy/tier/default/policy/biff%2fbop",
+		StagedPolicyKey{Name: "biff/bop"},
+		false,
+	),
+	Entry(
+		"tier with a /",
+		"/calico/v1/policy/tier/biff%2fbop/metadata",
+		TierKey{Name: "biff/bop"},
+		false,
+	),
 	Entry(
 		"workload with a /",
 		"/calico/v1/host/foobar/workload/open%2fstack/work%2fload/endpoint/end%2fpoint",
@@ -91,6 +91,7 @@ func (options PolicyListOptions) KeyFromDefaultPath(path string) Key {
 
 type Policy struct {
 	Namespace      string            `json:"namespace,omitempty" validate:"omitempty"`
+	Tier           string            `json:"tier,omitempty" validate:"omitempty"`
 	Order          *float64          `json:"order,omitempty" validate:"omitempty"`
 	InboundRules   []Rule            `json:"inbound_rules,omitempty" validate:"omitempty,dive"`
 	OutboundRules  []Rule            `json:"outbound_rules,omitempty" validate:"omitempty,dive"`
@@ -104,6 +105,9 @@ type Policy struct {
 
 func (p Policy) String() string {
 	parts := make([]string, 0)
+	if p.Tier != "" {
+		parts = append(parts, fmt.Sprintf("tier:%v", p.Tier))
+	}
 	if p.Order != nil {
 		parts = append(parts, fmt.Sprintf("order:%v", *p.Order))
 	}
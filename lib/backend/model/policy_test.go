@@ -36,4 +36,12 @@ var _ = Describe("Policy functions", func() {
 		}
 		Expect(p.String()).To(Equal(`order:10.5,selector:"apples=='oranges'",inbound:Deny,outbound:Allow,untracked:false,pre_dnat:true,apply_on_forward:true,types:Ingress;Egress`))
 	})
+
+	It("Policy in a tier should stringify correctly", func() {
+		p := model.Policy{
+			Tier:     "platform",
+			Selector: "all()",
+		}
+		Expect(p.String()).To(Equal(`tier:platform,selector:"all()",inbound:,outbound:,untracked:false,pre_dnat:false,apply_on_forward:false,types:`))
+	})
 })
@@ -93,6 +93,11 @@ func init() {
 		"ippools",
 		reflect.TypeOf(apiv3.IPPool{}),
 	)
+	registerResourceInfo(
+		apiv3.KindIPReservation,
+		"ipreservations",
+		reflect.TypeOf(apiv3.IPReservation{}),
+	)
 	registerResourceInfo(
 		apiv3.KindNetworkPolicy,
 		"networkpolicies",
@@ -113,6 +118,26 @@ func init() {
 		"profiles",
 		reflect.TypeOf(apiv3.Profile{}),
 	)
+	registerResourceInfo(
+		apiv3.KindStagedGlobalNetworkPolicy,
+		"stagedglobalnetworkpolicies",
+		reflect.TypeOf(apiv3.StagedGlobalNetworkPolicy{}),
+	)
+	registerResourceInfo(
+		apiv3.KindStagedKubernetesNetworkPolicy,
+		"stagedkubernetesnetworkpolicies",
+		reflect.TypeOf(apiv3.StagedKubernetesNetworkPolicy{}),
+	)
+	registerResourceInfo(
+		apiv3.KindStagedNetworkPolicy,
+		"stagednetworkpolicies",
+		reflect.TypeOf(apiv3.StagedNetworkPolicy{}),
+	)
+	registerResourceInfo(
+		apiv3.KindTier,
+		"tiers",
+		reflect.TypeOf(apiv3.Tier{}),
+	)
 	registerResourceInfo(
 		apiv3.KindWorkloadEndpoint,
 		"workloadendpoints",
@@ -176,6 +201,16 @@ type ResourceListOptions struct {
 	Kind string
 	// Whether the name is prefix rather than the full name.
 	Prefix bool
+	// LabelSelector and FieldSelector are the filters from the v3 ListOptions.  A backend
+	// may use them to filter server-side, but is not required to; the caller filters the
+	// results.
+	LabelSelector string
+	FieldSelector string
+	// Limit is the maximum number of results to return, or 0 for no limit, and Continue is
+	// the continuation token returned by the previous page.  Backends that do not support
+	// pagination ignore these and return every result.
+	Limit    int64
+	Continue string
 }
 
 // If the Kind, Namespace and Name are specified, but the Name is a prefix then the
@@ -44,6 +44,10 @@ func New(client api.Client, cfg apiconfig.CalicoAPIConfigSpec, callbacks api.Syn
 				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkPolicy},
 				UpdateProcessor: updateprocessors.NewGlobalNetworkPolicyUpdateProcessor(),
 			},
+			{
+				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindTier},
+				UpdateProcessor: updateprocessors.NewTierUpdateProcessor(),
+			},
 			{
 				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindGlobalNetworkSet},
 				UpdateProcessor: updateprocessors.NewGlobalNetworkSetUpdateProcessor(),
@@ -68,6 +72,20 @@ func New(client api.Client, cfg apiconfig.CalicoAPIConfigSpec, callbacks api.Syn
 				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindNetworkPolicy},
 				UpdateProcessor: updateprocessors.NewNetworkPolicyUpdateProcessor(),
 			},
+			// Staged policies are sent as StagedPolicyKeys, so they are only previewed by
+			// consumers that handle that key type, and never enforced as ordinary policies.
+			{
+				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindStagedGlobalNetworkPolicy},
+				UpdateProcessor: updateprocessors.NewStagedGlobalNetworkPolicyUpdateProcessor(),
+			},
+			{
+				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindStagedNetworkPolicy},
+				UpdateProcessor: updateprocessors.NewStagedNetworkPolicyUpdateProcessor(),
+			},
+			{
+				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindStagedKubernetesNetworkPolicy},
+				UpdateProcessor: updateprocessors.NewStagedKubernetesNetworkPolicyUpdateProcessor(),
+			},
 			{
 				ListInterface:   model.ResourceListOptions{Kind: apiv3.KindNetworkSet},
 				UpdateProcessor: updateprocessors.NewNetworkSetUpdateProcessor(),
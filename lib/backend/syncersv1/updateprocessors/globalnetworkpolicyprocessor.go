@@ -63,6 +63,7 @@ func convertGlobalNetworkPolicyV2ToV1Value(val interface{}) (interface{}, error)
 
 	v1value := &model.Policy{
 		Namespace:      "", // Empty string used to signal a GlobalNetworkPolicy.
+		Tier:           spec.Tier,
 		Order:          spec.Order,
 		InboundRules:   RulesAPIV2ToBackend(spec.Ingress, ""),
 		OutboundRules:  RulesAPIV2ToBackend(spec.Egress, ""),
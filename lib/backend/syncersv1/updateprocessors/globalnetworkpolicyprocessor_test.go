@@ -105,6 +105,22 @@ var _ = Describe("Test the GlobalNetworkPolicy update processor", func() {
 			}))
 		})
 
+		It("should accept a GlobalNetworkPolicy in a tier", func() {
+			tieredGNPKey := model.ResourceKey{Kind: apiv3.KindGlobalNetworkPolicy, Name: "platform.tiered"}
+			tieredGNP := apiv3.NewGlobalNetworkPolicy()
+			tieredGNP.Spec.Tier = "platform"
+
+			kvps, err := up.Process(&model.KVPair{Key: tieredGNPKey, Value: tieredGNP, Revision: testRev})
+			Expect(err).NotTo(HaveOccurred())
+
+			v1Key := model.PolicyKey{Name: "platform.tiered"}
+			Expect(kvps).To(Equal([]*model.KVPair{{
+				Key:      v1Key,
+				Value:    &model.Policy{Tier: "platform"},
+				Revision: testRev,
+			}}))
+		})
+
 		It("should accept a GlobalNetworkPolicy with a full configuration", func() {
 			kvps, err := up.Process(&model.KVPair{Key: fullGNPKey, Value: fullGNP, Revision: testRev})
 			Expect(err).NotTo(HaveOccurred())
@@ -62,6 +62,7 @@ func convertNetworkPolicyV2ToV1Value(val interface{}) (interface{}, error) {
 
 	v1value := &model.Policy{
 		Namespace:      v3res.Namespace,
+		Tier:           spec.Tier,
 		Order:          spec.Order,
 		InboundRules:   RulesAPIV2ToBackend(spec.Ingress, v3res.Namespace),
 		OutboundRules:  RulesAPIV2ToBackend(spec.Egress, v3res.Namespace),
@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors
+
+import (
+	"errors"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+)
+
+// Create a new SyncerUpdateProcessor to sync StagedKubernetesNetworkPolicy data in v1 format for
+// consumption by Felix.
+func NewStagedKubernetesNetworkPolicyUpdateProcessor() watchersyncer.SyncerUpdateProcessor {
+	c := conversion.NewConverter()
+	return NewSimpleUpdateProcessor(
+		apiv3.KindStagedKubernetesNetworkPolicy,
+		func(v3key model.ResourceKey) (model.Key, error) {
+			return convertStagedKubernetesNetworkPolicyV2ToV1Key(c, v3key)
+		},
+		func(val interface{}) (interface{}, error) {
+			return convertStagedKubernetesNetworkPolicyV2ToV1Value(c, val)
+		},
+	)
+}
+
+func convertStagedKubernetesNetworkPolicyV2ToV1Key(c conversion.Converter, v3key model.ResourceKey) (model.Key, error) {
+	if v3key.Name == "" || v3key.Namespace == "" {
+		return model.StagedPolicyKey{}, errors.New("Missing Name or Namespace field to create a v1 StagedKubernetesNetworkPolicy Key")
+	}
+	return model.StagedPolicyKey{
+		Name: v3key.Namespace + "/" + c.StagedKubernetesNetworkPolicyToStagedName(v3key.Name),
+	}, nil
+}
+
+func convertStagedKubernetesNetworkPolicyV2ToV1Value(c conversion.Converter, val interface{}) (interface{}, error) {
+	staged, ok := val.(*apiv3.StagedKubernetesNetworkPolicy)
+	if !ok {
+		return nil, errors.New("Value is not a valid StagedKubernetesNetworkPolicy resource value")
+	}
+
+	// A staged delete has no policy to preview, so filter it out.
+	stagedAction, k8snp := apiv3.ConvertStagedKubernetesPolicyToK8SEnforced(staged)
+	if stagedAction == apiv3.StagedActionDelete {
+		return nil, nil
+	}
+
+	// Convert the Kubernetes NetworkPolicy in the same way as an enforced Kubernetes
+	// NetworkPolicy.
+	kvp, err := c.K8sNetworkPolicyToCalico(k8snp)
+	if err != nil {
+		return nil, err
+	}
+	return convertNetworkPolicyV2ToV1Value(kvp.Value)
+}
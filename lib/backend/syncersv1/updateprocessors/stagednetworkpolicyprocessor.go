@@ -0,0 +1,52 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors
+
+import (
+	"errors"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+)
+
+// Create a new SyncerUpdateProcessor to sync StagedNetworkPolicy data in v1 format for
+// consumption by Felix.
+func NewStagedNetworkPolicyUpdateProcessor() watchersyncer.SyncerUpdateProcessor {
+	return NewSimpleUpdateProcessor(apiv3.KindStagedNetworkPolicy, convertStagedNetworkPolicyV2ToV1Key, convertStagedNetworkPolicyV2ToV1Value)
+}
+
+func convertStagedNetworkPolicyV2ToV1Key(v3key model.ResourceKey) (model.Key, error) {
+	if v3key.Name == "" || v3key.Namespace == "" {
+		return model.StagedPolicyKey{}, errors.New("Missing Name or Namespace field to create a v1 StagedNetworkPolicy Key")
+	}
+	return model.StagedPolicyKey{
+		Name: v3key.Namespace + "/" + v3key.Name,
+	}, nil
+}
+
+func convertStagedNetworkPolicyV2ToV1Value(val interface{}) (interface{}, error) {
+	staged, ok := val.(*apiv3.StagedNetworkPolicy)
+	if !ok {
+		return nil, errors.New("Value is not a valid StagedNetworkPolicy resource value")
+	}
+
+	// A staged delete has no policy to preview, so filter it out.
+	stagedAction, v3res := apiv3.ConvertStagedPolicyToEnforced(staged)
+	if stagedAction == apiv3.StagedActionDelete {
+		return nil, nil
+	}
+	return convertNetworkPolicyV2ToV1Value(v3res)
+}
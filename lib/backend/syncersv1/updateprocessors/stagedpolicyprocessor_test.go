@@ -0,0 +1,152 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+)
+
+var _ = Describe("Test the StagedGlobalNetworkPolicy update processor", func() {
+	key := model.ResourceKey{Kind: apiv3.KindStagedGlobalNetworkPolicy, Name: "staged"}
+	v1Key := model.StagedPolicyKey{Name: "staged"}
+	up := updateprocessors.NewStagedGlobalNetworkPolicyUpdateProcessor()
+
+	It("should convert a staged set in the same way as the enforced policy", func() {
+		staged := apiv3.NewStagedGlobalNetworkPolicy()
+		staged.Name = "staged"
+		staged.Spec.StagedAction = apiv3.StagedActionSet
+		staged.Spec.Selector = "has(foo)"
+		staged.Spec.Types = []apiv3.PolicyType{apiv3.PolicyTypeIngress}
+
+		kvps, err := up.Process(&model.KVPair{Key: key, Value: staged, Revision: testRev})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{
+			Key: v1Key,
+			Value: &model.Policy{
+				Selector: "has(foo)",
+				Types:    []string{"ingress"},
+			},
+			Revision: testRev,
+		}}))
+
+		By("deleting the staged policy")
+		kvps, err = up.Process(&model.KVPair{Key: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key}}))
+	})
+
+	It("should filter out a staged delete", func() {
+		staged := apiv3.NewStagedGlobalNetworkPolicy()
+		staged.Name = "staged"
+		staged.Spec.StagedAction = apiv3.StagedActionDelete
+
+		kvps, err := up.Process(&model.KVPair{Key: key, Value: staged, Revision: testRev})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key}}))
+	})
+
+	It("should NOT accept a StagedGlobalNetworkPolicy with the wrong Value type", func() {
+		kvps, err := up.Process(&model.KVPair{Key: key, Value: apiv3.NewGlobalNetworkPolicy(), Revision: testRev})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key}}))
+	})
+})
+
+var _ = Describe("Test the StagedNetworkPolicy update processor", func() {
+	ns := "namespace1"
+	key := model.ResourceKey{Kind: apiv3.KindStagedNetworkPolicy, Name: "staged", Namespace: ns}
+	v1Key := model.StagedPolicyKey{Name: ns + "/staged"}
+	up := updateprocessors.NewStagedNetworkPolicyUpdateProcessor()
+
+	It("should convert a staged set in the same way as the enforced policy", func() {
+		staged := apiv3.NewStagedNetworkPolicy()
+		staged.Name = "staged"
+		staged.Namespace = ns
+		staged.Spec.Tier = "platform"
+
+		kvps, err := up.Process(&model.KVPair{Key: key, Value: staged, Revision: testRev})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{
+			Key: v1Key,
+			Value: &model.Policy{
+				Namespace:      ns,
+				Tier:           "platform",
+				Selector:       "projectcalico.org/namespace == 'namespace1'",
+				ApplyOnForward: true,
+			},
+			Revision: testRev,
+		}}))
+	})
+
+	It("should filter out a staged delete", func() {
+		staged := apiv3.NewStagedNetworkPolicy()
+		staged.Name = "staged"
+		staged.Namespace = ns
+		staged.Spec.StagedAction = apiv3.StagedActionDelete
+
+		kvps, err := up.Process(&model.KVPair{Key: key, Value: staged, Revision: testRev})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key}}))
+	})
+})
+
+var _ = Describe("Test the StagedKubernetesNetworkPolicy update processor", func() {
+	ns := "namespace1"
+	key := model.ResourceKey{Kind: apiv3.KindStagedKubernetesNetworkPolicy, Name: "staged", Namespace: ns}
+	v1Key := model.StagedPolicyKey{Name: ns + "/knp.default.staged"}
+	up := updateprocessors.NewStagedKubernetesNetworkPolicyUpdateProcessor()
+
+	It("should convert a staged set in the same way as the enforced Kubernetes policy", func() {
+		staged := apiv3.NewStagedKubernetesNetworkPolicy()
+		staged.Name = "staged"
+		staged.Namespace = ns
+		staged.Spec.PodSelector = metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}
+		staged.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{}}
+		staged.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+
+		By("converting the equivalent enforced policy")
+		_, np := apiv3.ConvertStagedKubernetesPolicyToK8SEnforced(staged)
+		enforced, err := conversion.NewConverter().K8sNetworkPolicyToCalico(np)
+		Expect(err).NotTo(HaveOccurred())
+		enforced.Key = model.ResourceKey{Kind: apiv3.KindNetworkPolicy, Name: "knp.default.staged", Namespace: ns}
+		expected, err := updateprocessors.NewNetworkPolicyUpdateProcessor().Process(enforced)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expected).To(HaveLen(1))
+
+		kvps, err := up.Process(&model.KVPair{Key: key, Value: staged, Revision: testRev})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key, Value: expected[0].Value, Revision: testRev}}))
+	})
+
+	It("should filter out a staged delete", func() {
+		staged := apiv3.NewStagedKubernetesNetworkPolicy()
+		staged.Name = "staged"
+		staged.Namespace = ns
+		staged.Spec.StagedAction = apiv3.StagedActionDelete
+
+		kvps, err := up.Process(&model.KVPair{Key: key, Value: staged, Revision: testRev})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{Key: v1Key}}))
+	})
+})
@@ -0,0 +1,109 @@
+// Copyright (c) 2016-2018 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package updateprocessors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("Test the Tier update processor", func() {
+	v3TierKey := model.ResourceKey{
+		Kind: apiv3.KindTier,
+		Name: "platform",
+	}
+	v1TierKey := model.TierKey{
+		Name: "platform",
+	}
+
+	It("should handle conversion of valid Tiers", func() {
+		up := updateprocessors.NewTierUpdateProcessor()
+
+		By("converting a Tier with no order")
+		res := apiv3.NewTier()
+		res.Name = v3TierKey.Name
+
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3TierKey,
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{
+			Key:      v1TierKey,
+			Value:    &model.Tier{},
+			Revision: "abcde",
+		}}))
+
+		By("converting a Tier with an order")
+		order := 10.5
+		res.Spec.Order = &order
+
+		kvps, err = up.Process(&model.KVPair{
+			Key:      v3TierKey,
+			Value:    res,
+			Revision: "abcdef",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{
+			Key:      v1TierKey,
+			Value:    &model.Tier{Order: &order},
+			Revision: "abcdef",
+		}}))
+
+		By("deleting the Tier")
+		kvps, err = up.Process(&model.KVPair{
+			Key: v3TierKey,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{
+			Key: v1TierKey,
+		}}))
+	})
+
+	It("should fail to convert an invalid resource", func() {
+		up := updateprocessors.NewTierUpdateProcessor()
+
+		By("trying to convert with the wrong key type")
+		res := apiv3.NewTier()
+
+		_, err := up.Process(&model.KVPair{
+			Key: model.GlobalBGPPeerKey{
+				PeerIP: cnet.MustParseIP("1.2.3.4"),
+			},
+			Value:    res,
+			Revision: "abcde",
+		})
+		Expect(err).To(HaveOccurred())
+
+		By("trying to convert with the wrong value type")
+		wres := apiv3.NewHostEndpoint()
+
+		kvps, err := up.Process(&model.KVPair{
+			Key:      v3TierKey,
+			Value:    wres,
+			Revision: "abcde",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvps).To(Equal([]*model.KVPair{{
+			Key: v1TierKey,
+		}}))
+	})
+})
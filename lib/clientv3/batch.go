@@ -0,0 +1,718 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+)
+
+// BatchAction is the action performed by a BatchOperation.
+type BatchAction string
+
+const (
+	// BatchCreate creates the resource, which must not already exist.
+	BatchCreate BatchAction = "Create"
+	// BatchUpdate updates the existing resource.  The ResourceVersion must be set.
+	BatchUpdate BatchAction = "Update"
+	// BatchApply updates the resource if it exists, ignoring the ResourceVersion, and
+	// creates it otherwise.
+	BatchApply BatchAction = "Apply"
+	// BatchDelete deletes the resource.  Only the name and namespace of the resource are
+	// required; if the ResourceVersion is set, the delete only succeeds if it is current.
+	BatchDelete BatchAction = "Delete"
+)
+
+// BatchStatus is the outcome of a BatchOperation.
+type BatchStatus string
+
+const (
+	BatchApplied        BatchStatus = "Applied"
+	BatchFailed         BatchStatus = "Failed"
+	BatchNotApplied     BatchStatus = "NotApplied"
+	BatchRolledBack     BatchStatus = "RolledBack"
+	BatchRollbackFailed BatchStatus = "RollbackFailed"
+)
+
+// BatchOperation is a single operation in a batch.
+type BatchOperation struct {
+	Action BatchAction
+	// Resource is a pointer to one of the resource types supported in a batch: Tier,
+	// GlobalNetworkPolicy, NetworkPolicy, StagedGlobalNetworkPolicy, StagedNetworkPolicy,
+	// StagedKubernetesNetworkPolicy, GlobalNetworkSet, NetworkSet, Profile, HostEndpoint,
+	// IPPool or IPReservation.  The resource is not modified.
+	Resource runtime.Object
+}
+
+// BatchOperationResult reports the outcome of a single operation in a batch.
+type BatchOperationResult struct {
+	Action    BatchAction
+	Kind      string
+	Namespace string
+	Name      string
+	Status    BatchStatus
+	// Object is the stored resource, or for a Delete the deleted resource.  It is only
+	// set if the Status is Applied.
+	Object runtime.Object
+	// Error is the error that caused the operation to fail, or the error that prevented
+	// the operation from being rolled back.
+	Error error
+}
+
+// BatchResult reports the outcome of a batch.
+type BatchResult struct {
+	// Atomic is true if the datastore applied the batch in a single transaction, in which
+	// case either every operation was applied or none of them were.  Otherwise the
+	// operations were applied in order, and rolled back on failure.
+	Atomic     bool
+	Operations []BatchOperationResult
+}
+
+// BatchInterface has methods to apply a set of resources as a single batch.
+type BatchInterface interface {
+	// Apply validates every operation before any change is made, and then applies the
+	// operations.  On etcdv3 the operations are applied in a single transaction, guarded
+	// by the revisions of the resources that were read when validating them.  On
+	// Kubernetes the operations are applied in order, and if one fails the operations
+	// already applied are undone on a best-effort basis, in reverse order.
+	//
+	// The returned BatchResult reports the outcome of each operation, and is returned
+	// whenever the operations could be validated.  The error is the first error that
+	// occurred, if any.
+	Apply(ctx context.Context, ops []BatchOperation, opts options.SetOptions) (*BatchResult, error)
+}
+
+// batch implements BatchInterface
+type batch struct {
+	client client
+}
+
+// Apply applies the operations as a single batch.
+func (r batch) Apply(ctx context.Context, ops []BatchOperation, opts options.SetOptions) (*BatchResult, error) {
+	txn, atomic := r.client.backend.(bapi.TxnClient)
+	res := &BatchResult{Atomic: atomic, Operations: make([]BatchOperationResult, len(ops))}
+	for i, op := range ops {
+		res.Operations[i] = BatchOperationResult{Action: op.Action, Status: BatchNotApplied}
+		if in, ok := op.Resource.(resource); ok {
+			res.Operations[i].Kind = batchKindName(in)
+			res.Operations[i].Namespace = in.GetObjectMeta().GetNamespace()
+			res.Operations[i].Name = in.GetObjectMeta().GetName()
+		}
+	}
+
+	// Run each operation through the resource clients against a recorder, which validates
+	// and defaults the resources, and records the writes without making them.  The
+	// recorder returns the recorded writes on subsequent reads, so that, for example, a
+	// policy may be created in a tier created earlier in the batch.
+	rec := &batchRecorder{Client: r.client.backend}
+	rc := client{config: r.client.config, backend: rec, resources: &resources{backend: rec}}
+	objects := make([]resource, len(ops))
+	primary := make([]int, len(ops))
+	for i, op := range ops {
+		rec.owner = i
+		out, err := r.prepare(ctx, rc, op, opts)
+		if err != nil {
+			res.Operations[i].Status = BatchFailed
+			res.Operations[i].Error = err
+			return res, err
+		}
+		objects[i] = out
+
+		// The write of the resource itself is the last one made by the operation; any
+		// earlier ones are side effects, such as enabling IPIP when creating an IP pool.
+		primary[i] = -1
+		if n := len(rec.records); n > 0 && rec.records[n-1].owner == i {
+			primary[i] = n - 1
+		}
+	}
+	if len(rec.records) == 0 {
+		return res, nil
+	}
+
+	var kvps []*model.KVPair
+	var err error
+	if atomic {
+		kvps, err = r.commitTxn(ctx, txn, rec.records, res)
+	} else {
+		kvps, err = r.commitOrdered(ctx, rec.records, res)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	for i := range ops {
+		res.Operations[i].Status = BatchApplied
+		if primary[i] >= 0 && ops[i].Action != BatchDelete {
+			objects[i].GetObjectMeta().SetResourceVersion(kvps[primary[i]].Revision)
+		}
+		res.Operations[i].Object = objects[i]
+	}
+	return res, nil
+}
+
+// prepare performs the operation using the supplied client, returning the resource that
+// the resource client returned.
+func (r batch) prepare(ctx context.Context, c client, op BatchOperation, opts options.SetOptions) (resource, error) {
+	if op.Resource == nil {
+		return nil, cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "Resource",
+				Reason: "field must be set for a batch operation",
+			}},
+		}
+	}
+
+	// Take a copy of the resource since the resource clients may modify it.
+	in, ok := op.Resource.DeepCopyObject().(resource)
+	if !ok {
+		return nil, cerrors.ErrorOperationNotSupported{
+			Operation:  string(op.Action),
+			Identifier: reflect.TypeOf(op.Resource).String(),
+			Reason:     "not a Calico resource",
+		}
+	}
+	kind := batchKindName(in)
+	k, ok := batchKinds[kind]
+	if !ok {
+		return nil, cerrors.ErrorOperationNotSupported{
+			Operation:  string(op.Action),
+			Identifier: kind,
+			Reason:     "resource kind is not supported in a batch",
+		}
+	}
+
+	meta := in.GetObjectMeta()
+	switch op.Action {
+	case BatchCreate:
+		return k.create(ctx, c, in, opts)
+	case BatchUpdate:
+		return k.update(ctx, c, in, opts)
+	case BatchApply:
+		existing, err := k.get(ctx, c, meta.GetNamespace(), meta.GetName())
+		if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok {
+			return k.create(ctx, c, in, opts)
+		} else if err != nil {
+			return nil, err
+		}
+		meta.SetResourceVersion(existing.GetObjectMeta().GetResourceVersion())
+		meta.SetCreationTimestamp(existing.GetObjectMeta().GetCreationTimestamp())
+		meta.SetUID(existing.GetObjectMeta().GetUID())
+		return k.update(ctx, c, in, opts)
+	case BatchDelete:
+		if k.delete == nil {
+			return nil, cerrors.ErrorOperationNotSupported{
+				Operation:  string(op.Action),
+				Identifier: meta.GetName(),
+				Reason:     "deleting a " + kind + " has side effects that cannot be made in a batch",
+			}
+		}
+		return k.delete(ctx, c, meta.GetNamespace(), meta.GetName(), options.DeleteOptions{ResourceVersion: meta.GetResourceVersion()})
+	}
+	return nil, cerrors.ErrorValidation{
+		ErroredFields: []cerrors.ErroredField{{
+			Name:   "Action",
+			Reason: "unknown batch action",
+			Value:  op.Action,
+		}},
+	}
+}
+
+// commitTxn makes the recorded writes in a single transaction.
+func (r batch) commitTxn(ctx context.Context, txn bapi.TxnClient, records []*batchRecord, res *BatchResult) ([]*model.KVPair, error) {
+	ops := make([]bapi.TxnOp, len(records))
+	for i, rec := range records {
+		ops[i] = rec.op
+	}
+	kvps, err := txn.Txn(ctx, ops)
+	if err != nil {
+		// Report the error against the operation that made the failed write if the error
+		// identifies it, and otherwise against every operation.
+		owner := -1
+		if rec := batchRecordForError(records, err); rec != nil {
+			owner = rec.owner
+		}
+		for i := range res.Operations {
+			if owner < 0 || owner == i {
+				res.Operations[i].Status = BatchFailed
+				res.Operations[i].Error = err
+			}
+		}
+		return nil, err
+	}
+	return kvps, nil
+}
+
+// commitOrdered makes the recorded writes one at a time.  If a write fails, the writes
+// already made are undone in reverse order.
+func (r batch) commitOrdered(ctx context.Context, records []*batchRecord, res *BatchResult) ([]*model.KVPair, error) {
+	kvps := make([]*model.KVPair, len(records))
+	for i, rec := range records {
+		var err error
+		switch rec.op.Type {
+		case bapi.TxnCreate:
+			kvps[i], err = r.client.backend.Create(ctx, rec.op.KVPair)
+		case bapi.TxnUpdate:
+			kvps[i], err = r.client.backend.Update(ctx, rec.op.KVPair)
+		case bapi.TxnDelete:
+			kvps[i], err = r.client.backend.DeleteKVP(ctx, rec.op.KVPair)
+		}
+		if err == nil {
+			continue
+		}
+
+		log.WithError(err).WithField("key", rec.op.KVPair.Key).Warning("Batch write failed, rolling back")
+		res.Operations[rec.owner].Status = BatchFailed
+		res.Operations[rec.owner].Error = err
+		for j := i - 1; j >= 0; j-- {
+			prior := records[j]
+			rollbackErr := r.rollback(ctx, prior, kvps[j])
+			if prior.owner == rec.owner {
+				// A side effect of the failed operation.
+				if rollbackErr != nil {
+					log.WithError(rollbackErr).WithField("key", prior.op.KVPair.Key).Error("Failed to roll back batch write")
+				}
+				continue
+			}
+			op := &res.Operations[prior.owner]
+			if rollbackErr != nil {
+				log.WithError(rollbackErr).WithField("key", prior.op.KVPair.Key).Error("Failed to roll back batch write")
+				op.Status = BatchRollbackFailed
+				op.Error = rollbackErr
+			} else if op.Status != BatchRollbackFailed {
+				op.Status = BatchRolledBack
+			}
+		}
+		return nil, err
+	}
+	return kvps, nil
+}
+
+// rollback undoes a recorded write, given the KVPair returned when the write was made.
+func (r batch) rollback(ctx context.Context, rec *batchRecord, written *model.KVPair) error {
+	var err error
+	switch rec.op.Type {
+	case bapi.TxnCreate:
+		_, err = r.client.backend.DeleteKVP(ctx, &model.KVPair{Key: rec.op.KVPair.Key, Revision: written.Revision})
+	case bapi.TxnUpdate:
+		prev := batchCopy(rec.prev)
+		prev.Revision = written.Revision
+		_, err = r.client.backend.Update(ctx, prev)
+	case bapi.TxnDelete:
+		prev := batchCopy(rec.prev)
+		prev.Revision = ""
+		if res, ok := prev.Value.(resource); ok {
+			res.GetObjectMeta().SetResourceVersion("")
+		}
+		_, err = r.client.backend.Create(ctx, prev)
+	}
+	return err
+}
+
+// batchKindName returns the kind of a resource, which is the name of its type.
+func batchKindName(in resource) string {
+	return reflect.Indirect(reflect.ValueOf(in)).Type().Name()
+}
+
+// batchKind performs the operations of a batch on one kind of resource using the resource
+// client for that kind.
+type batchKind struct {
+	create func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error)
+	update func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error)
+	delete func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error)
+	get    func(ctx context.Context, c client, ns, name string) (resource, error)
+}
+
+// batchResource converts the result of a resource client call to a resource, returning a
+// nil resource on error.
+func batchResource(out resource, err error) (resource, error) {
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var batchKinds = map[string]*batchKind{
+	apiv3.KindTier: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.Tiers().Create(ctx, in.(*apiv3.Tier), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.Tiers().Update(ctx, in.(*apiv3.Tier), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.Tiers().Delete(ctx, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.Tiers().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindGlobalNetworkPolicy: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.GlobalNetworkPolicies().Create(ctx, in.(*apiv3.GlobalNetworkPolicy), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.GlobalNetworkPolicies().Update(ctx, in.(*apiv3.GlobalNetworkPolicy), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.GlobalNetworkPolicies().Delete(ctx, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.GlobalNetworkPolicies().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindNetworkPolicy: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.NetworkPolicies().Create(ctx, in.(*apiv3.NetworkPolicy), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.NetworkPolicies().Update(ctx, in.(*apiv3.NetworkPolicy), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.NetworkPolicies().Delete(ctx, ns, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.NetworkPolicies().Get(ctx, ns, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindStagedGlobalNetworkPolicy: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.StagedGlobalNetworkPolicies().Create(ctx, in.(*apiv3.StagedGlobalNetworkPolicy), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.StagedGlobalNetworkPolicies().Update(ctx, in.(*apiv3.StagedGlobalNetworkPolicy), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.StagedGlobalNetworkPolicies().Delete(ctx, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.StagedGlobalNetworkPolicies().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindStagedNetworkPolicy: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.StagedNetworkPolicies().Create(ctx, in.(*apiv3.StagedNetworkPolicy), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.StagedNetworkPolicies().Update(ctx, in.(*apiv3.StagedNetworkPolicy), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.StagedNetworkPolicies().Delete(ctx, ns, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.StagedNetworkPolicies().Get(ctx, ns, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindStagedKubernetesNetworkPolicy: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.StagedKubernetesNetworkPolicies().Create(ctx, in.(*apiv3.StagedKubernetesNetworkPolicy), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.StagedKubernetesNetworkPolicies().Update(ctx, in.(*apiv3.StagedKubernetesNetworkPolicy), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.StagedKubernetesNetworkPolicies().Delete(ctx, ns, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.StagedKubernetesNetworkPolicies().Get(ctx, ns, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindGlobalNetworkSet: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.GlobalNetworkSets().Create(ctx, in.(*apiv3.GlobalNetworkSet), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.GlobalNetworkSets().Update(ctx, in.(*apiv3.GlobalNetworkSet), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.GlobalNetworkSets().Delete(ctx, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.GlobalNetworkSets().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindNetworkSet: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.NetworkSets().Create(ctx, in.(*apiv3.NetworkSet), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.NetworkSets().Update(ctx, in.(*apiv3.NetworkSet), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.NetworkSets().Delete(ctx, ns, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.NetworkSets().Get(ctx, ns, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindProfile: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.Profiles().Create(ctx, in.(*apiv3.Profile), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.Profiles().Update(ctx, in.(*apiv3.Profile), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.Profiles().Delete(ctx, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.Profiles().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindHostEndpoint: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.HostEndpoints().Create(ctx, in.(*apiv3.HostEndpoint), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.HostEndpoints().Update(ctx, in.(*apiv3.HostEndpoint), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.HostEndpoints().Delete(ctx, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.HostEndpoints().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindIPPool: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.IPPools().Create(ctx, in.(*apiv3.IPPool), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.IPPools().Update(ctx, in.(*apiv3.IPPool), opts))
+		},
+		// Deleting an IP pool releases the block affinities in the pool, which cannot be
+		// done in a batch.
+		delete: nil,
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.IPPools().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+	apiv3.KindIPReservation: {
+		create: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.IPReservations().Create(ctx, in.(*apiv3.IPReservation), opts))
+		},
+		update: func(ctx context.Context, c client, in resource, opts options.SetOptions) (resource, error) {
+			return batchResource(c.IPReservations().Update(ctx, in.(*apiv3.IPReservation), opts))
+		},
+		delete: func(ctx context.Context, c client, ns, name string, opts options.DeleteOptions) (resource, error) {
+			return batchResource(c.IPReservations().Delete(ctx, name, opts))
+		},
+		get: func(ctx context.Context, c client, ns, name string) (resource, error) {
+			return batchResource(c.IPReservations().Get(ctx, name, options.GetOptions{}))
+		},
+	},
+}
+
+// batchRecord is a write recorded by a batchRecorder.
+type batchRecord struct {
+	// owner is the index of the operation that made the write.
+	owner int
+	op    bapi.TxnOp
+	path  string
+	// prev is the stored object replaced or deleted by an update or delete.
+	prev *model.KVPair
+}
+
+// batchRecorder is a backend client that records the writes made through it instead of
+// making them.  Each write is checked against the datastore, and guarded by the revision
+// of the object that it replaces or deletes.  Reads return the recorded writes in place
+// of the stored objects.
+type batchRecorder struct {
+	bapi.Client
+
+	// owner is the index of the operation currently being recorded.
+	owner   int
+	records []*batchRecord
+}
+
+func (r *batchRecorder) Create(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	existing, err := r.Get(ctx, d.Key, "")
+	if err == nil {
+		return existing, cerrors.ErrorResourceAlreadyExists{Identifier: d.Key}
+	} else if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok {
+		return nil, err
+	}
+	return r.record(bapi.TxnCreate, d, nil)
+}
+
+func (r *batchRecorder) Update(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	prev, err := r.Get(ctx, d.Key, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(d.Revision) != 0 && d.Revision != prev.Revision {
+		return prev, cerrors.ErrorResourceUpdateConflict{Identifier: d.Key}
+	}
+	d = batchCopy(d)
+	d.Revision = prev.Revision
+	return r.record(bapi.TxnUpdate, d, prev)
+}
+
+func (r *batchRecorder) Apply(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	return nil, cerrors.ErrorOperationNotSupported{
+		Operation:  "Apply",
+		Identifier: d.Key,
+		Reason:     "not supported in a batch",
+	}
+}
+
+func (r *batchRecorder) Delete(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
+	return r.DeleteKVP(ctx, &model.KVPair{Key: k, Revision: revision})
+}
+
+func (r *batchRecorder) DeleteKVP(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	prev, err := r.Get(ctx, d.Key, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(d.Revision) != 0 && d.Revision != prev.Revision {
+		return prev, cerrors.ErrorResourceUpdateConflict{Identifier: d.Key}
+	}
+	d = batchCopy(d)
+	d.Revision = prev.Revision
+	if _, err := r.record(bapi.TxnDelete, d, prev); err != nil {
+		return nil, err
+	}
+	return batchCopy(prev), nil
+}
+
+func (r *batchRecorder) Get(ctx context.Context, k model.Key, revision string) (*model.KVPair, error) {
+	if len(revision) == 0 {
+		path, err := model.KeyToDefaultPath(k)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range r.records {
+			if rec.path != path {
+				continue
+			}
+			if rec.op.Type == bapi.TxnDelete {
+				return nil, cerrors.ErrorResourceDoesNotExist{Identifier: k}
+			}
+			return batchCopy(rec.op.KVPair), nil
+		}
+	}
+	return r.Client.Get(ctx, k, revision)
+}
+
+func (r *batchRecorder) List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+	kvps, err := r.Client.List(ctx, list, revision)
+	if err != nil || len(revision) != 0 {
+		return kvps, err
+	}
+
+	index := map[string]int{}
+	for i, kvp := range kvps.KVPairs {
+		path, err := model.KeyToDefaultPath(kvp.Key)
+		if err != nil {
+			return nil, err
+		}
+		index[path] = i
+	}
+	for _, rec := range r.records {
+		if list.KeyFromDefaultPath(rec.path) == nil {
+			continue
+		}
+		i, ok := index[rec.path]
+		switch {
+		case rec.op.Type == bapi.TxnDelete:
+			if ok {
+				kvps.KVPairs[i] = nil
+			}
+		case ok:
+			kvps.KVPairs[i] = batchCopy(rec.op.KVPair)
+		default:
+			index[rec.path] = len(kvps.KVPairs)
+			kvps.KVPairs = append(kvps.KVPairs, batchCopy(rec.op.KVPair))
+		}
+	}
+
+	out := &model.KVPairList{Revision: kvps.Revision}
+	for _, kvp := range kvps.KVPairs {
+		if kvp != nil {
+			out.KVPairs = append(out.KVPairs, kvp)
+		}
+	}
+	return out, nil
+}
+
+// record records a write.  A resource may only be written once in a batch.
+func (r *batchRecorder) record(t bapi.TxnOpType, d, prev *model.KVPair) (*model.KVPair, error) {
+	path, err := model.KeyToDefaultPath(d.Key)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range r.records {
+		if rec.path == path {
+			return nil, cerrors.ErrorOperationNotSupported{
+				Operation:  t.String(),
+				Identifier: d.Key,
+				Reason:     "a resource may only be modified once in a batch",
+			}
+		}
+	}
+	r.records = append(r.records, &batchRecord{
+		owner: r.owner,
+		op:    bapi.TxnOp{Type: t, KVPair: batchCopy(d)},
+		path:  path,
+		prev:  prev,
+	})
+	return batchCopy(d), nil
+}
+
+// batchRecordForError returns the record of the write identified by an error returned by
+// the datastore, or nil if the error does not identify a recorded write.
+func batchRecordForError(records []*batchRecord, err error) *batchRecord {
+	var id interface{}
+	switch e := err.(type) {
+	case cerrors.ErrorResourceAlreadyExists:
+		id = e.Identifier
+	case cerrors.ErrorResourceDoesNotExist:
+		id = e.Identifier
+	case cerrors.ErrorResourceUpdateConflict:
+		id = e.Identifier
+	}
+	k, ok := id.(model.Key)
+	if !ok {
+		return nil
+	}
+	path, err := model.KeyToDefaultPath(k)
+	if err != nil {
+		return nil
+	}
+	for _, rec := range records {
+		if rec.path == path {
+			return rec
+		}
+	}
+	return nil
+}
+
+// batchCopy returns a copy of a KVPair, deep copying the value if it is a resource.
+func batchCopy(d *model.KVPair) *model.KVPair {
+	c := *d
+	if o, ok := d.Value.(runtime.Object); ok {
+		c.Value = o.DeepCopyObject()
+	}
+	return &c
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+// orderedBackend hides any transaction support of the wrapped backend, and fails the
+// creation of the named resource.
+type orderedBackend struct {
+	bapi.Client
+	failCreate string
+}
+
+func (b *orderedBackend) Create(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	if d.Key.(model.ResourceKey).Name == b.failCreate {
+		return nil, cerrors.ErrorDatastoreError{Err: errors.New("injected failure")}
+	}
+	return b.Client.Create(ctx, d)
+}
+
+var _ = testutils.E2eDatastoreDescribe("Batch tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+	order := 100.0
+	atomic := config.Spec.DatastoreType != apiconfig.Kubernetes
+
+	tier := func() *apiv3.Tier {
+		return &apiv3.Tier{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+			Spec:       apiv3.TierSpec{Order: &order},
+		}
+	}
+	policy := func() *apiv3.GlobalNetworkPolicy {
+		return &apiv3.GlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform.allow-dns"},
+			Spec: apiv3.GlobalNetworkPolicySpec{
+				Tier:     "platform",
+				Selector: "all()",
+				Egress:   []apiv3.Rule{{Action: apiv3.Allow}},
+			},
+		}
+	}
+	networkSet := func(name string, nets ...string) *apiv3.GlobalNetworkSet {
+		return &apiv3.GlobalNetworkSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       apiv3.GlobalNetworkSetSpec{Nets: nets},
+		}
+	}
+
+	var c client
+	BeforeEach(func() {
+		i, err := New(config)
+		Expect(err).NotTo(HaveOccurred())
+		c = i.(client)
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+	})
+
+	It("should apply a batch of resources", func() {
+		_, err := c.GlobalNetworkSets().Create(ctx, networkSet("existing", "10.0.0.0/8"), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.GlobalNetworkSets().Create(ctx, networkSet("obsolete", "11.0.0.0/8"), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		res, err := c.Batch().Apply(ctx, []BatchOperation{
+			{Action: BatchCreate, Resource: tier()},
+			{Action: BatchCreate, Resource: policy()},
+			{Action: BatchApply, Resource: networkSet("existing", "12.0.0.0/8")},
+			{Action: BatchDelete, Resource: networkSet("obsolete")},
+			{Action: BatchApply, Resource: &apiv3.IPPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+				Spec:       apiv3.IPPoolSpec{CIDR: "192.168.0.0/16", IPIPMode: apiv3.IPIPModeAlways},
+			}},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Atomic).To(Equal(atomic))
+		Expect(res.Operations).To(HaveLen(5))
+		for _, op := range res.Operations {
+			Expect(op.Status).To(Equal(BatchApplied))
+			Expect(op.Error).NotTo(HaveOccurred())
+		}
+		Expect(res.Operations[1].Kind).To(Equal(apiv3.KindGlobalNetworkPolicy))
+		Expect(res.Operations[1].Name).To(Equal("platform.allow-dns"))
+
+		By("Returning the stored resources")
+		gnp := res.Operations[1].Object.(*apiv3.GlobalNetworkPolicy)
+		Expect(gnp.Name).To(Equal("platform.allow-dns"))
+		Expect(gnp.Spec.Types).To(Equal([]apiv3.PolicyType{apiv3.PolicyTypeEgress}))
+		stored, err := c.GlobalNetworkPolicies().Get(ctx, "platform.allow-dns", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.ResourceVersion).To(Equal(gnp.ResourceVersion))
+		Expect(res.Operations[3].Object.(*apiv3.GlobalNetworkSet).Spec.Nets).To(Equal([]string{"11.0.0.0/8"}))
+
+		By("Checking the datastore")
+		gns, err := c.GlobalNetworkSets().Get(ctx, "existing", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gns.Spec.Nets).To(Equal([]string{"12.0.0.0/8"}))
+		_, err = c.GlobalNetworkSets().Get(ctx, "obsolete", options.GetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		_, err = c.IPPools().Get(ctx, "pool1", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Checking that the side effects of the batch were applied")
+		fc, err := c.FelixConfigurations().Get(ctx, "default", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fc.Spec.IPIPEnabled).NotTo(BeNil())
+		Expect(*fc.Spec.IPIPEnabled).To(BeTrue())
+	})
+
+	It("should make no changes if an operation is invalid", func() {
+		_, err := c.GlobalNetworkSets().Create(ctx, networkSet("existing", "10.0.0.0/8"), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Failing a batch with a resource that already exists")
+		res, err := c.Batch().Apply(ctx, []BatchOperation{
+			{Action: BatchCreate, Resource: tier()},
+			{Action: BatchCreate, Resource: networkSet("existing", "12.0.0.0/8")},
+			{Action: BatchCreate, Resource: policy()},
+		}, options.SetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceAlreadyExists{}))
+		Expect(res.Operations[0].Status).To(Equal(BatchNotApplied))
+		Expect(res.Operations[1].Status).To(Equal(BatchFailed))
+		Expect(res.Operations[1].Error).To(Equal(err))
+		Expect(res.Operations[2].Status).To(Equal(BatchNotApplied))
+		_, err = c.Tiers().Get(ctx, "platform", options.GetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+
+		By("Failing a batch with a policy in a tier that does not exist")
+		res, err = c.Batch().Apply(ctx, []BatchOperation{
+			{Action: BatchCreate, Resource: policy()},
+		}, options.SetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+		Expect(res.Operations[0].Status).To(Equal(BatchFailed))
+
+		By("Failing a batch that modifies a resource twice")
+		_, err = c.Batch().Apply(ctx, []BatchOperation{
+			{Action: BatchDelete, Resource: networkSet("existing")},
+			{Action: BatchCreate, Resource: networkSet("existing", "12.0.0.0/8")},
+		}, options.SetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+
+		By("Failing a batch that deletes an IP pool")
+		_, err = c.Batch().Apply(ctx, []BatchOperation{
+			{Action: BatchDelete, Resource: &apiv3.IPPool{ObjectMeta: metav1.ObjectMeta{Name: "pool1"}}},
+		}, options.SetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+
+		By("Failing a batch with an unsupported kind")
+		_, err = c.Batch().Apply(ctx, []BatchOperation{
+			{Action: BatchCreate, Resource: &apiv3.BGPPeer{ObjectMeta: metav1.ObjectMeta{Name: "peer1"}}},
+		}, options.SetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+
+		gns, err := c.GlobalNetworkSets().Get(ctx, "existing", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gns.Spec.Nets).To(Equal([]string{"10.0.0.0/8"}))
+	})
+
+	It("should fail a batch if a resource is modified after it is validated", func() {
+		if !atomic {
+			Skip("Transactions are not supported by the Kubernetes backend")
+		}
+		existing, err := c.GlobalNetworkSets().Create(ctx, networkSet("existing", "10.0.0.0/8"), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("Validating an update, and then updating the resource before the batch is committed")
+		rec := &batchRecorder{Client: c.backend}
+		rc := client{config: c.config, backend: rec, resources: &resources{backend: rec}}
+		update := existing.DeepCopy()
+		update.Spec.Nets = []string{"12.0.0.0/8"}
+		_, err = rc.GlobalNetworkSets().Update(ctx, update, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		existing.Spec.Nets = []string{"13.0.0.0/8"}
+		_, err = c.GlobalNetworkSets().Update(ctx, existing, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		res := &BatchResult{Operations: make([]BatchOperationResult, 1)}
+		_, err = batch{client: c}.commitTxn(ctx, c.backend.(bapi.TxnClient), rec.records, res)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+		Expect(res.Operations[0].Status).To(Equal(BatchFailed))
+
+		gns, err := c.GlobalNetworkSets().Get(ctx, "existing", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gns.Spec.Nets).To(Equal([]string{"13.0.0.0/8"}))
+	})
+
+	It("should roll back a batch on a backend without transactions", func() {
+		_, err := c.GlobalNetworkSets().Create(ctx, networkSet("existing", "10.0.0.0/8"), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.GlobalNetworkSets().Create(ctx, networkSet("obsolete", "11.0.0.0/8"), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		be := &orderedBackend{Client: c.backend, failCreate: "failing"}
+		oc := client{config: c.config, backend: be, resources: &resources{backend: be}}
+		res, err := oc.Batch().Apply(ctx, []BatchOperation{
+			{Action: BatchCreate, Resource: tier()},
+			{Action: BatchApply, Resource: networkSet("existing", "12.0.0.0/8")},
+			{Action: BatchDelete, Resource: networkSet("obsolete")},
+			{Action: BatchCreate, Resource: networkSet("failing", "13.0.0.0/8")},
+			{Action: BatchCreate, Resource: networkSet("later", "14.0.0.0/8")},
+		}, options.SetOptions{})
+		Expect(err).To(HaveOccurred())
+		Expect(res.Atomic).To(BeFalse())
+		Expect(res.Operations[0].Status).To(Equal(BatchRolledBack))
+		Expect(res.Operations[1].Status).To(Equal(BatchRolledBack))
+		Expect(res.Operations[2].Status).To(Equal(BatchRolledBack))
+		Expect(res.Operations[3].Status).To(Equal(BatchFailed))
+		Expect(res.Operations[3].Error).To(Equal(err))
+		Expect(res.Operations[4].Status).To(Equal(BatchNotApplied))
+
+		By("Checking that the datastore is restored")
+		_, err = c.Tiers().Get(ctx, "platform", options.GetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		gns, err := c.GlobalNetworkSets().Get(ctx, "existing", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gns.Spec.Nets).To(Equal([]string{"10.0.0.0/8"}))
+		gns, err = c.GlobalNetworkSets().Get(ctx, "obsolete", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gns.Spec.Nets).To(Equal([]string{"11.0.0.0/8"}))
+		_, err = c.GlobalNetworkSets().Get(ctx, "later", options.GetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+})
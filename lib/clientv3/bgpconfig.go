@@ -17,6 +17,8 @@ package clientv3
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	"github.com/projectcalico/libcalico-go/lib/options"
@@ -32,6 +34,7 @@ type BGPConfigurationInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.BGPConfiguration, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.BGPConfigurationList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.BGPConfiguration, error)
 }
 
 // bgpConfigurations implements BGPConfigurationInterface
@@ -113,6 +116,26 @@ func (r bgpConfigurations) Watch(ctx context.Context, opts options.ListOptions)
 	return r.client.resources.Watch(ctx, opts, apiv3.KindBGPConfiguration, nil)
 }
 
+// Patch applies a JSON merge patch or JSON patch to the named BGPConfiguration. Returns the stored
+// representation of the patched BGPConfiguration, and an error if there is any.
+func (r bgpConfigurations) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.BGPConfiguration, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindBGPConfiguration,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.BGPConfiguration), options.SetOptions{})
+		},
+	})
+	if out != nil {
+		return out.(*apiv3.BGPConfiguration), err
+	}
+	return nil, err
+}
+
 func (r bgpConfigurations) ValidateDefaultOnlyFields(res *apiv3.BGPConfiguration) error {
 	errFields := []cerrors.ErroredField{}
 	if res.ObjectMeta.GetName() != "default" {
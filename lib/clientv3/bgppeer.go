@@ -17,6 +17,8 @@ package clientv3
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -31,6 +33,7 @@ type BGPPeerInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.BGPPeer, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.BGPPeerList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.BGPPeer, error)
 }
 
 // bgpPeers implements BGPPeerInterface
@@ -99,3 +102,24 @@ func (r bgpPeers) List(ctx context.Context, opts options.ListOptions) (*apiv3.BG
 func (r bgpPeers) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindBGPPeer, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named BGPPeer. Returns the stored
+// representation of the patched BGPPeer, and an error if there is any.
+func (r bgpPeers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.BGPPeer, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindBGPPeer,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.BGPPeer), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.BGPPeer), err
+	}
+	return nil, err
+}
@@ -79,6 +79,11 @@ func (c client) Nodes() NodeInterface {
 	return nodes{client: c}
 }
 
+// Tiers returns an interface for managing policy tier resources.
+func (c client) Tiers() TierInterface {
+	return tiers{client: c}
+}
+
 // NetworkPolicies returns an interface for managing policy resources.
 func (c client) NetworkPolicies() NetworkPolicyInterface {
 	return networkPolicies{client: c}
@@ -89,11 +94,31 @@ func (c client) GlobalNetworkPolicies() GlobalNetworkPolicyInterface {
 	return globalNetworkPolicies{client: c}
 }
 
+// StagedGlobalNetworkPolicies returns an interface for managing staged policy resources.
+func (c client) StagedGlobalNetworkPolicies() StagedGlobalNetworkPolicyInterface {
+	return stagedGlobalNetworkPolicies{client: c}
+}
+
+// StagedNetworkPolicies returns an interface for managing staged policy resources.
+func (c client) StagedNetworkPolicies() StagedNetworkPolicyInterface {
+	return stagedNetworkPolicies{client: c}
+}
+
+// StagedKubernetesNetworkPolicies returns an interface for managing staged kubernetes policy resources.
+func (c client) StagedKubernetesNetworkPolicies() StagedKubernetesNetworkPolicyInterface {
+	return stagedKubernetesNetworkPolicies{client: c}
+}
+
 // IPPools returns an interface for managing IP pool resources.
 func (c client) IPPools() IPPoolInterface {
 	return ipPools{client: c}
 }
 
+// IPReservations returns an interface for managing IP reservation resources.
+func (c client) IPReservations() IPReservationInterface {
+	return ipReservations{client: c}
+}
+
 // Profiles returns an interface for managing profile resources.
 func (c client) Profiles() ProfileInterface {
 	return profiles{client: c}
@@ -150,6 +175,11 @@ func (c client) KubeControllersConfiguration() KubeControllersConfigurationInter
 	return kubeControllersConfiguration{client: c}
 }
 
+// Batch returns an interface for applying a set of resources as a single batch.
+func (c client) Batch() BatchInterface {
+	return batch{client: c}
+}
+
 type poolAccessor struct {
 	client *client
 }
@@ -18,6 +18,8 @@ import (
 	"context"
 	"errors"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -32,6 +34,7 @@ type ClusterInformationInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.ClusterInformation, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.ClusterInformationList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.ClusterInformation, error)
 }
 
 // clusterInformation implements ClusterInformationInterface
@@ -106,3 +109,24 @@ func (r clusterInformation) List(ctx context.Context, opts options.ListOptions)
 func (r clusterInformation) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindClusterInformation, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named ClusterInformation. Returns the stored
+// representation of the patched ClusterInformation, and an error if there is any.
+func (r clusterInformation) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.ClusterInformation, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindClusterInformation,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.ClusterInformation), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.ClusterInformation), err
+	}
+	return nil, err
+}
@@ -17,6 +17,8 @@ package clientv3
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -31,6 +33,7 @@ type FelixConfigurationInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.FelixConfiguration, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.FelixConfigurationList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.FelixConfiguration, error)
 }
 
 // felixConfigurations implements FelixConfigurationInterface
@@ -102,3 +105,24 @@ func (r felixConfigurations) List(ctx context.Context, opts options.ListOptions)
 func (r felixConfigurations) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindFelixConfiguration, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named FelixConfiguration. Returns the stored
+// representation of the patched FelixConfiguration, and an error if there is any.
+func (r felixConfigurations) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.FelixConfiguration, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindFelixConfiguration,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.FelixConfiguration), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.FelixConfiguration), err
+	}
+	return nil, err
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+// listFilter filters the resources returned by a List or Watch using the LabelSelector and
+// FieldSelector of the ListOptions.
+type listFilter struct {
+	labels selector.Selector
+	fields fields.Selector
+}
+
+// newListFilter returns the filter for the supplied options, or nil if the options do not
+// filter the resources.
+func newListFilter(opts options.ListOptions) (*listFilter, error) {
+	if len(opts.LabelSelector) == 0 && len(opts.FieldSelector) == 0 {
+		return nil, nil
+	}
+	f := &listFilter{}
+	if len(opts.LabelSelector) != 0 {
+		sel, err := selector.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, cerrors.ErrorValidation{
+				ErroredFields: []cerrors.ErroredField{{
+					Name:   "ListOptions.LabelSelector",
+					Reason: err.Error(),
+					Value:  opts.LabelSelector,
+				}},
+			}
+		}
+		f.labels = sel
+	}
+	if len(opts.FieldSelector) != 0 {
+		sel, err := fields.ParseSelector(opts.FieldSelector)
+		if err != nil {
+			return nil, cerrors.ErrorValidation{
+				ErroredFields: []cerrors.ErroredField{{
+					Name:   "ListOptions.FieldSelector",
+					Reason: err.Error(),
+					Value:  opts.FieldSelector,
+				}},
+			}
+		}
+		f.fields = sel
+	}
+	return f, nil
+}
+
+// matches returns true if the resource matches the filter.  A nil filter matches every
+// resource.
+func (f *listFilter) matches(res resource) bool {
+	if f == nil {
+		return true
+	}
+	if f.labels != nil && !f.labels.Evaluate(res.GetObjectMeta().GetLabels()) {
+		return false
+	}
+	if f.fields != nil && !f.fields.Matches(resourceFields(res, f.fields.Requirements())) {
+		return false
+	}
+	return true
+}
+
+// resourceFields returns the values of the fields of the resource that are required by the
+// field selector.
+func resourceFields(res resource, reqs fields.Requirements) fields.Set {
+	set := fields.Set{}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(res)
+	if err != nil {
+		logWithResource(res).WithError(err).Warning("Unable to convert resource to match field selector")
+		return set
+	}
+	for _, req := range reqs {
+		val, found, err := unstructured.NestedFieldNoCopy(obj, strings.Split(req.Field, ".")...)
+		if err != nil || !found {
+			continue
+		}
+		switch val.(type) {
+		case string, bool, int64, float64:
+			set[req.Field] = fmt.Sprint(val)
+		}
+	}
+	return set
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+var _ = testutils.E2eDatastoreDescribe("List and Watch filter tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+
+	hostEndpoint := func(name, node string, labels map[string]string) *apiv3.HostEndpoint {
+		return &apiv3.HostEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec: apiv3.HostEndpointSpec{
+				Node:          node,
+				InterfaceName: "eth0",
+			},
+		}
+	}
+	names := func(l *apiv3.HostEndpointList) []string {
+		var n []string
+		for _, hep := range l.Items {
+			n = append(n, hep.Name)
+		}
+		return n
+	}
+
+	var c clientv3.Interface
+	BeforeEach(func() {
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+
+		for _, hep := range []*apiv3.HostEndpoint{
+			hostEndpoint("hep-1", "node1", map[string]string{"env": "prod", "app": "web"}),
+			hostEndpoint("hep-2", "node1", map[string]string{"env": "dev", "app": "web"}),
+			hostEndpoint("hep-3", "node2", map[string]string{"env": "prod"}),
+		} {
+			_, err := c.HostEndpoints().Create(ctx, hep, options.SetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should filter a List by label and field selectors", func() {
+		l, err := c.HostEndpoints().List(ctx, options.ListOptions{LabelSelector: "env == 'prod'"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(l)).To(ConsistOf("hep-1", "hep-3"))
+
+		l, err = c.HostEndpoints().List(ctx, options.ListOptions{LabelSelector: "has(app) && env != 'prod'"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(l)).To(ConsistOf("hep-2"))
+
+		l, err = c.HostEndpoints().List(ctx, options.ListOptions{LabelSelector: "env == 'dev' || !has(app)"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(l)).To(ConsistOf("hep-2", "hep-3"))
+
+		l, err = c.HostEndpoints().List(ctx, options.ListOptions{FieldSelector: "spec.node=node1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(l)).To(ConsistOf("hep-1", "hep-2"))
+
+		l, err = c.HostEndpoints().List(ctx, options.ListOptions{LabelSelector: "env == 'prod'", FieldSelector: "spec.node!=node1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(l)).To(ConsistOf("hep-3"))
+
+		l, err = c.HostEndpoints().List(ctx, options.ListOptions{FieldSelector: "metadata.name=hep-2,spec.interfaceName=eth0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(l)).To(ConsistOf("hep-2"))
+	})
+
+	It("should reject invalid selectors", func() {
+		_, err := c.HostEndpoints().List(ctx, options.ListOptions{LabelSelector: "env =="})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+		_, err = c.HostEndpoints().Watch(ctx, options.ListOptions{FieldSelector: "spec.node"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+	})
+
+	It("should filter a Watch by label selector", func() {
+		l, err := c.HostEndpoints().List(ctx, options.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		w, err := c.HostEndpoints().Watch(ctx, options.ListOptions{
+			LabelSelector:   "env == 'prod'",
+			ResourceVersion: l.ResourceVersion,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Stop()
+
+		var e watch.Event
+		By("Not receiving events for resources that do not match")
+		hep2, err := c.HostEndpoints().Get(ctx, "hep-2", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		hep2.Spec.InterfaceName = "eth1"
+		hep2, err = c.HostEndpoints().Update(ctx, hep2, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(w.ResultChan()).ShouldNot(Receive())
+
+		By("Receiving an added event when a resource is modified to match")
+		hep2.Labels["env"] = "prod"
+		hep2, err = c.HostEndpoints().Update(ctx, hep2, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(w.ResultChan()).Should(Receive(&e))
+		Expect(e.Type).To(Equal(watch.Added))
+		Expect(e.Object.(*apiv3.HostEndpoint).Name).To(Equal("hep-2"))
+
+		By("Receiving a modified event when a matching resource is modified")
+		hep2.Spec.InterfaceName = "eth2"
+		hep2, err = c.HostEndpoints().Update(ctx, hep2, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(w.ResultChan()).Should(Receive(&e))
+		Expect(e.Type).To(Equal(watch.Modified))
+
+		By("Receiving a deleted event when a resource is modified to no longer match")
+		hep2.Labels["env"] = "dev"
+		_, err = c.HostEndpoints().Update(ctx, hep2, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(w.ResultChan()).Should(Receive(&e))
+		Expect(e.Type).To(Equal(watch.Deleted))
+		Expect(e.Previous.(*apiv3.HostEndpoint).Name).To(Equal("hep-2"))
+
+		By("Receiving deleted events only for matching resources")
+		_, err = c.HostEndpoints().Delete(ctx, "hep-2", options.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.HostEndpoints().Delete(ctx, "hep-3", options.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(w.ResultChan()).Should(Receive(&e))
+		Expect(e.Type).To(Equal(watch.Deleted))
+		Expect(e.Previous.(*apiv3.HostEndpoint).Name).To(Equal("hep-3"))
+		Consistently(w.ResultChan()).ShouldNot(Receive())
+	})
+	It("should filter default tier policies by their name", func() {
+		for _, name := range []string{"policy-1", "policy-2"} {
+			_, err := c.GlobalNetworkPolicies().Create(ctx, &apiv3.GlobalNetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			}, options.SetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		l, err := c.GlobalNetworkPolicies().List(ctx, options.ListOptions{FieldSelector: "metadata.name=policy-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).To(HaveLen(1))
+		Expect(l.Items[0].Name).To(Equal("policy-1"))
+
+		l, err = c.GlobalNetworkPolicies().List(ctx, options.ListOptions{FieldSelector: "metadata.name!=policy-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).To(HaveLen(1))
+		Expect(l.Items[0].Name).To(Equal("policy-2"))
+
+		w, err := c.GlobalNetworkPolicies().Watch(ctx, options.ListOptions{
+			FieldSelector:   "metadata.name=policy-2",
+			ResourceVersion: l.ResourceVersion,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Stop()
+
+		_, err = c.GlobalNetworkPolicies().Delete(ctx, "policy-1", options.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.GlobalNetworkPolicies().Delete(ctx, "policy-2", options.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		var e watch.Event
+		Eventually(w.ResultChan()).Should(Receive(&e))
+		Expect(e.Type).To(Equal(watch.Deleted))
+		Expect(e.Previous.(*apiv3.GlobalNetworkPolicy).Name).To(Equal("policy-2"))
+		Consistently(w.ResultChan()).ShouldNot(Receive())
+	})
+})
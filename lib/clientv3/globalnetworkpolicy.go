@@ -18,6 +18,9 @@ import (
 	"context"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -32,6 +35,7 @@ type GlobalNetworkPolicyInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.GlobalNetworkPolicy, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.GlobalNetworkPolicyList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.GlobalNetworkPolicy, error)
 }
 
 // globalNetworkPolicies implements GlobalNetworkPolicyInterface
@@ -54,6 +58,10 @@ func (r globalNetworkPolicies) Create(ctx context.Context, res *apiv3.GlobalNetw
 		return nil, err
 	}
 
+	if err := checkPolicyTier(ctx, r.client, res.Spec.Tier); err != nil {
+		return nil, err
+	}
+
 	// Properly prefix the name
 	res.GetObjectMeta().SetName(convertPolicyNameForStorage(res.GetObjectMeta().GetName()))
 	out, err := r.client.resources.Create(ctx, opts, apiv3.KindGlobalNetworkPolicy, res)
@@ -83,6 +91,10 @@ func (r globalNetworkPolicies) Update(ctx context.Context, res *apiv3.GlobalNetw
 		return nil, err
 	}
 
+	if err := checkPolicyTier(ctx, r.client, res.Spec.Tier); err != nil {
+		return nil, err
+	}
+
 	// Properly prefix the name
 	res.GetObjectMeta().SetName(convertPolicyNameForStorage(res.GetObjectMeta().GetName()))
 	out, err := r.client.resources.Update(ctx, opts, apiv3.KindGlobalNetworkPolicy, res)
@@ -127,6 +139,7 @@ func (r globalNetworkPolicies) List(ctx context.Context, opts options.ListOption
 	if opts.Name != "" {
 		opts.Name = convertPolicyNameForStorage(opts.Name)
 	}
+	opts.FieldSelector = convertPolicyFieldSelectorForStorage(opts.FieldSelector)
 
 	if err := r.client.resources.List(ctx, opts, apiv3.KindGlobalNetworkPolicy, apiv3.KindGlobalNetworkPolicyList, res); err != nil {
 		return nil, err
@@ -148,10 +161,31 @@ func (r globalNetworkPolicies) Watch(ctx context.Context, opts options.ListOptio
 	if opts.Name != "" {
 		opts.Name = convertPolicyNameForStorage(opts.Name)
 	}
+	opts.FieldSelector = convertPolicyFieldSelectorForStorage(opts.FieldSelector)
 
 	return r.client.resources.Watch(ctx, opts, apiv3.KindGlobalNetworkPolicy, &policyConverter{})
 }
 
+// Patch applies a JSON merge patch or JSON patch to the named GlobalNetworkPolicy. Returns the stored
+// representation of the patched GlobalNetworkPolicy, and an error if there is any.
+func (r globalNetworkPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.GlobalNetworkPolicy, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindGlobalNetworkPolicy,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.GlobalNetworkPolicy), options.SetOptions{})
+		},
+	})
+	if out != nil {
+		return out.(*apiv3.GlobalNetworkPolicy), err
+	}
+	return nil, err
+}
+
 func defaultPolicyTypesField(ingressRules, egressRules []apiv3.Rule, types *[]apiv3.PolicyType) {
 	if len(*types) == 0 {
 		// Default the Types field according to what inbound and outbound rules are present
@@ -182,9 +216,38 @@ func convertPolicyNameForStorage(name string) string {
 	if strings.HasPrefix(name, "ossg.") {
 		return name
 	}
+	// Policies in a tier other than the default tier are already prefixed with their tier
+	// name, and policy names in the default tier cannot contain a ".".
+	if strings.Contains(name, ".") {
+		return name
+	}
 	return "default." + name
 }
 
+// convertPolicyFieldSelectorForStorage converts the policy names in the metadata.name terms of
+// a field selector to the names used in storage, so that the selector can be matched against
+// the stored policies.
+func convertPolicyFieldSelectorForStorage(sel string) string {
+	if len(sel) == 0 {
+		return sel
+	}
+	parsed, err := fields.ParseSelector(sel)
+	if err != nil {
+		// Leave an invalid selector unchanged so that it is rejected by the List or Watch.
+		return sel
+	}
+	converted, err := parsed.Transform(func(field, value string) (string, string, error) {
+		if field == "metadata.name" {
+			value = convertPolicyNameForStorage(value)
+		}
+		return field, value, nil
+	})
+	if err != nil {
+		return sel
+	}
+	return converted.String()
+}
+
 func convertPolicyNameFromStorage(name string) string {
 	// Do nothing on names prefixed with "knp."
 	if strings.HasPrefix(name, "knp.") {
@@ -194,8 +257,9 @@ func convertPolicyNameFromStorage(name string) string {
 	if strings.HasPrefix(name, "ossg.") {
 		return name
 	}
-	parts := strings.SplitN(name, ".", 2)
-	return parts[len(parts)-1]
+	// Only the default tier prefix is removed, policies in other tiers keep their tier
+	// name prefix.
+	return strings.TrimPrefix(name, "default.")
 }
 
 type policyConverter struct{}
@@ -17,6 +17,8 @@ package clientv3
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -31,6 +33,7 @@ type HostEndpointInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.HostEndpoint, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.HostEndpointList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.HostEndpoint, error)
 }
 
 // hostEndpoints implements HostEndpointInterface
@@ -99,3 +102,24 @@ func (r hostEndpoints) List(ctx context.Context, opts options.ListOptions) (*api
 func (r hostEndpoints) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindHostEndpoint, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named HostEndpoint. Returns the stored
+// representation of the patched HostEndpoint, and an error if there is any.
+func (r hostEndpoints) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.HostEndpoint, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindHostEndpoint,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.HostEndpoint), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.HostEndpoint), err
+	}
+	return nil, err
+}
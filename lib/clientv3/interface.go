@@ -23,12 +23,22 @@ import (
 type Interface interface {
 	// Nodes returns an interface for managing node resources.
 	Nodes() NodeInterface
+	// Tiers returns an interface for managing policy tier resources.
+	Tiers() TierInterface
 	// GlobalNetworkPolicies returns an interface for managing global network policy resources.
 	GlobalNetworkPolicies() GlobalNetworkPolicyInterface
 	// NetworkPolicies returns an interface for managing namespaced network policy resources.
 	NetworkPolicies() NetworkPolicyInterface
+	// StagedGlobalNetworkPolicies returns an interface for managing staged global network policy resources.
+	StagedGlobalNetworkPolicies() StagedGlobalNetworkPolicyInterface
+	// StagedNetworkPolicies returns an interface for managing staged namespaced network policy resources.
+	StagedNetworkPolicies() StagedNetworkPolicyInterface
+	// StagedKubernetesNetworkPolicies returns an interface for managing staged kubernetes network policy resources.
+	StagedKubernetesNetworkPolicies() StagedKubernetesNetworkPolicyInterface
 	// IPPools returns an interface for managing IP pool resources.
 	IPPools() IPPoolInterface
+	// IPReservations returns an interface for managing IP reservation resources.
+	IPReservations() IPReservationInterface
 	// Profiles returns an interface for managing profile resources.
 	Profiles() ProfileInterface
 	// GlobalNetworkSets returns an interface for managing global network sets resources.
@@ -52,6 +62,8 @@ type Interface interface {
 	// KubeControllersConfiguration returns an interface for managing the
 	// KubeControllersConfiguration resource.
 	KubeControllersConfiguration() KubeControllersConfigurationInterface
+	// Batch returns an interface for applying a set of resources as a single batch.
+	Batch() BatchInterface
 
 	// EnsureInitialized is used to ensure the backend datastore is correctly
 	// initialized for use by Calico.  This method may be called multiple times, and
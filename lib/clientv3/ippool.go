@@ -21,6 +21,7 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/backend/model"
@@ -39,6 +40,17 @@ type IPPoolInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.IPPool, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.IPPoolList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.IPPool, error)
+
+	// StartMigration starts migrating the workloads of the named pool to the target pool.  The pool
+	// is disabled, so that no more addresses are assigned from it, and marked as draining to the target.
+	StartMigration(ctx context.Context, name, target string) (*apiv3.IPPool, error)
+	// MigrationStatus reports the addresses that are still allocated from the named pool.
+	MigrationStatus(ctx context.Context, name string) (*IPPoolMigrationStatus, error)
+	// CompleteMigration releases the block affinities of the named draining pool and deletes it.
+	// It returns an ErrorResourceInUse if workload or node tunnel addresses are still allocated
+	// from the pool.
+	CompleteMigration(ctx context.Context, name string) (*apiv3.IPPool, error)
 }
 
 // ipPools implements IPPoolInterface
@@ -296,6 +308,26 @@ func (r ipPools) Watch(ctx context.Context, opts options.ListOptions) (watch.Int
 	return r.client.resources.Watch(ctx, opts, apiv3.KindIPPool, nil)
 }
 
+// Patch applies a JSON merge patch or JSON patch to the named IPPool. Returns the stored
+// representation of the patched IPPool, and an error if there is any.
+func (r ipPools) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.IPPool, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindIPPool,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.IPPool), options.SetOptions{})
+		},
+	})
+	if out != nil {
+		return out.(*apiv3.IPPool), err
+	}
+	return nil, err
+}
+
 // validateAndSetDefaults validates IPPool fields and sets default values that are
 // not assigned.
 // The old pool will be unassigned for a Create.
@@ -0,0 +1,183 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/ipam"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/options"
+)
+
+// IPPoolMigrationStatus reports the progress of migrating the workloads of an IPPool to another pool.
+type IPPoolMigrationStatus struct {
+	// The pool being migrated, and the pool its workloads are migrating to.  Target is empty if
+	// the pool is not draining.
+	Pool   string
+	Target string
+
+	// The workload addresses still allocated from the pool.
+	Allocations []ipam.AllocatedIP
+
+	// The number of workload addresses still allocated from the pool, by node and by handle.
+	ByNode   map[string]int
+	ByHandle map[string]int
+
+	// The IPIP, VXLAN and Wireguard tunnel addresses of nodes still allocated from the pool.  These
+	// are not moved by migrating workloads; each node must be given a tunnel address from another
+	// pool before the migration can be completed.
+	TunnelAddresses []ipam.AllocatedIP
+}
+
+// Draining returns true if the pool is disabled and marked as draining to another pool.
+func (s IPPoolMigrationStatus) Draining() bool {
+	return s.Target != ""
+}
+
+// Empty returns true if no workload or tunnel addresses are allocated from the pool.
+func (s IPPoolMigrationStatus) Empty() bool {
+	return len(s.Allocations) == 0 && len(s.TunnelAddresses) == 0
+}
+
+// StartMigration disables the named pool and marks it as draining to the target pool.  The target
+// pool must be an enabled pool of the same IP version.
+func (r ipPools) StartMigration(ctx context.Context, name, target string) (*apiv3.IPPool, error) {
+	if name == target {
+		return nil, cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "target",
+				Value:  target,
+				Reason: "an IPPool cannot be migrated to itself",
+			}},
+		}
+	}
+	pool, err := r.Get(ctx, name, options.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	targetPool, err := r.Get(ctx, target, options.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if targetPool.Spec.Disabled {
+		return nil, cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "target",
+				Value:  target,
+				Reason: "IPPools can only be migrated to an enabled IPPool",
+			}},
+		}
+	}
+	_, poolNet, _ := cnet.ParseCIDR(pool.Spec.CIDR)
+	_, targetNet, _ := cnet.ParseCIDR(targetPool.Spec.CIDR)
+	if poolNet.Version() != targetNet.Version() {
+		return nil, cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "target",
+				Value:  target,
+				Reason: "IPPools can only be migrated to an IPPool of the same IP version",
+			}},
+		}
+	}
+
+	log.WithFields(log.Fields{"Name": name, "Target": target}).Info("Disabling pool to migrate its workloads")
+	pool.Spec.Disabled = true
+	if pool.Annotations == nil {
+		pool.Annotations = map[string]string{}
+	}
+	pool.Annotations[apiv3.IPPoolAnnotationDrainingTo] = target
+	return r.Update(ctx, pool, options.SetOptions{})
+}
+
+// MigrationStatus reports the addresses that are still allocated from the named pool.
+func (r ipPools) MigrationStatus(ctx context.Context, name string) (*IPPoolMigrationStatus, error) {
+	pool, err := r.Get(ctx, name, options.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return r.migrationStatus(ctx, pool)
+}
+
+func (r ipPools) migrationStatus(ctx context.Context, pool *apiv3.IPPool) (*IPPoolMigrationStatus, error) {
+	// We've already validated the CIDR so we know it will parse.
+	_, cidr, _ := cnet.ParseCIDR(pool.Spec.CIDR)
+	allocs, err := r.client.IPAM().GetAllocations(ctx, *cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &IPPoolMigrationStatus{
+		Pool:     pool.Name,
+		ByNode:   map[string]int{},
+		ByHandle: map[string]int{},
+	}
+	if pool.Spec.Disabled {
+		status.Target = pool.Annotations[apiv3.IPPoolAnnotationDrainingTo]
+	}
+	for _, a := range allocs {
+		if a.Attrs[ipam.AttributeType] != "" {
+			// Tunnel addresses belong to a node rather than to a workload.
+			status.TunnelAddresses = append(status.TunnelAddresses, a)
+			continue
+		}
+		status.Allocations = append(status.Allocations, a)
+		status.ByNode[a.Host]++
+		status.ByHandle[a.Handle]++
+	}
+	return status, nil
+}
+
+// CompleteMigration releases the block affinities of the named draining pool and deletes it, once
+// no workload or tunnel addresses are allocated from it.
+func (r ipPools) CompleteMigration(ctx context.Context, name string) (*apiv3.IPPool, error) {
+	pool, err := r.Get(ctx, name, options.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	status, err := r.migrationStatus(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Draining() {
+		return nil, cerrors.ErrorOperationNotSupported{
+			Operation:  "CompleteMigration",
+			Identifier: name,
+			Reason:     "the IPPool is not being migrated",
+		}
+	}
+	if len(status.Allocations) > 0 {
+		return nil, cerrors.ErrorResourceInUse{
+			Identifier: name,
+			Reason:     fmt.Sprintf("%d workload addresses are still allocated from the IPPool", len(status.Allocations)),
+		}
+	}
+	if len(status.TunnelAddresses) > 0 {
+		return nil, cerrors.ErrorResourceInUse{
+			Identifier: name,
+			Reason: fmt.Sprintf("%d node tunnel addresses are still allocated from the IPPool and must be moved to another IPPool first",
+				len(status.TunnelAddresses)),
+		}
+	}
+
+	// Delete releases the pool's block affinities before deleting it.  Use the resource version we
+	// checked so that we don't delete a pool that has been re-enabled in the meantime.
+	return r.Delete(ctx, name, options.DeleteOptions{ResourceVersion: pool.ResourceVersion})
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/backend/k8s"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/ipam"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = testutils.E2eDatastoreDescribe("IPPool migration tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+	host := "node-1"
+	handle := "migration-handle"
+
+	var c clientv3.Interface
+	var kc *kubernetes.Clientset
+	createPool := func(name, cidr string) {
+		_, err := c.IPPools().Create(ctx, &apiv3.IPPool{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       apiv3.IPPoolSpec{CIDR: cidr, BlockSize: 28},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+
+		// In KDD, Calico nodes are backed by Kubernetes nodes.
+		if kbe, ok := be.(*k8s.KubeClient); ok {
+			kc = kbe.ClientSet
+			_, err = kc.CoreV1().Nodes().Create(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: host}})
+		} else {
+			kc = nil
+			_, err = c.Nodes().Create(ctx, &apiv3.Node{ObjectMeta: metav1.ObjectMeta{Name: host}}, options.SetOptions{})
+		}
+		Expect(err).NotTo(HaveOccurred())
+		createPool("old", "10.0.0.0/26")
+		v4, _, err := c.IPAM().AutoAssign(ctx, ipam.AutoAssignArgs{
+			Num4:     2,
+			HandleID: &handle,
+			Attrs:    map[string]string{ipam.AttributeNode: host},
+			Hostname: host,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(2))
+		createPool("new", "10.1.0.0/26")
+	})
+
+	AfterEach(func() {
+		if kc != nil {
+			kc.CoreV1().Nodes().Delete(host, &metav1.DeleteOptions{})
+		}
+	})
+
+	It("should drain the pool and delete it once it is empty", func() {
+		pool, err := c.IPPools().StartMigration(ctx, "old", "new")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pool.Spec.Disabled).To(BeTrue())
+		Expect(pool.Annotations).To(HaveKeyWithValue(apiv3.IPPoolAnnotationDrainingTo, "new"))
+
+		status, err := c.IPPools().MigrationStatus(ctx, "old")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Draining()).To(BeTrue())
+		Expect(status.Target).To(Equal("new"))
+		Expect(status.Allocations).To(HaveLen(2))
+		Expect(status.ByNode).To(Equal(map[string]int{host: 2}))
+		Expect(status.ByHandle).To(Equal(map[string]int{handle: 2}))
+
+		_, err = c.IPPools().CompleteMigration(ctx, "old")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceInUse{}))
+
+		By("assigning new addresses from the target pool")
+		v4, _, err := c.IPAM().AutoAssign(ctx, ipam.AutoAssignArgs{Num4: 1, Hostname: host})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(1))
+		newPool := cnet.MustParseCIDR("10.1.0.0/26")
+		Expect(newPool.Contains(v4[0].IP)).To(BeTrue())
+
+		By("completing the migration once the workloads have moved")
+		Expect(c.IPAM().ReleaseByHandle(ctx, handle)).NotTo(HaveOccurred())
+		status, err = c.IPPools().MigrationStatus(ctx, "old")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Empty()).To(BeTrue())
+		_, err = c.IPPools().CompleteMigration(ctx, "old")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.IPPools().Get(ctx, "old", options.GetOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+
+	It("should report node tunnel addresses separately from workload addresses", func() {
+		tunnelHandle := "vxlan-tunnel-addr-" + host
+		v4, _, err := c.IPAM().AutoAssign(ctx, ipam.AutoAssignArgs{
+			Num4:     1,
+			HandleID: &tunnelHandle,
+			Attrs:    map[string]string{ipam.AttributeNode: host, ipam.AttributeType: ipam.AttributeTypeVXLAN},
+			Hostname: host,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(1))
+		_, err = c.IPPools().StartMigration(ctx, "old", "new")
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := c.IPPools().MigrationStatus(ctx, "old")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Allocations).To(HaveLen(2))
+		Expect(status.ByNode).To(Equal(map[string]int{host: 2}))
+		Expect(status.ByHandle).To(Equal(map[string]int{handle: 2}))
+		Expect(status.TunnelAddresses).To(HaveLen(1))
+		Expect(status.TunnelAddresses[0].IP.String()).To(Equal(v4[0].IP.String()))
+
+		By("refusing to complete the migration until the tunnel address has moved")
+		Expect(c.IPAM().ReleaseByHandle(ctx, handle)).NotTo(HaveOccurred())
+		status, err = c.IPPools().MigrationStatus(ctx, "old")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Allocations).To(BeEmpty())
+		Expect(status.Empty()).To(BeFalse())
+		_, err = c.IPPools().CompleteMigration(ctx, "old")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceInUse{}))
+
+		Expect(c.IPAM().ReleaseByHandle(ctx, tunnelHandle)).NotTo(HaveOccurred())
+		_, err = c.IPPools().CompleteMigration(ctx, "old")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should only migrate to another enabled pool", func() {
+		_, err := c.IPPools().StartMigration(ctx, "old", "old")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+		_, err = c.IPPools().StartMigration(ctx, "old", "missing")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		_, err = c.IPPools().StartMigration(ctx, "new", "old")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.IPPools().StartMigration(ctx, "old", "new")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+	})
+
+	It("should not complete the migration of a pool that is not draining", func() {
+		_, err := c.IPPools().CompleteMigration(ctx, "new")
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+	})
+})
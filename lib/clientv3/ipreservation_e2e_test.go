@@ -0,0 +1,173 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3_test
+
+import (
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/ipam"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+var _ = testutils.E2eDatastoreDescribe("IPReservation tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+	name1 := "appliances"
+	name2 := "gateways"
+	spec1 := apiv3.IPReservationSpec{ReservedCIDRs: []string{"10.0.0.0/30", "10.0.0.10"}}
+	spec2 := apiv3.IPReservationSpec{ReservedCIDRs: []string{"fd00::/120"}}
+
+	var c clientv3.Interface
+	BeforeEach(func() {
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+	})
+
+	It("should handle CRUD and watch of IPReservation resources", func() {
+		By("Creating an IPReservation with an invalid CIDR")
+		_, outError := c.IPReservations().Create(ctx, &apiv3.IPReservation{
+			ObjectMeta: metav1.ObjectMeta{Name: name1},
+			Spec:       apiv3.IPReservationSpec{ReservedCIDRs: []string{"10.0.0.0/33"}},
+		}, options.SetOptions{})
+		Expect(outError).To(HaveOccurred())
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		By("Starting a watcher")
+		w, err := c.IPReservations().Watch(ctx, options.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		testWatcher := testutils.NewTestResourceWatch(config.Spec.DatastoreType, w)
+		defer testWatcher.Stop()
+
+		By("Creating IPReservations name1/spec1 and name2/spec2")
+		res1, outError := c.IPReservations().Create(ctx, &apiv3.IPReservation{
+			ObjectMeta: metav1.ObjectMeta{Name: name1},
+			Spec:       spec1,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindIPReservation, testutils.ExpectNoNamespace, name1, spec1))
+
+		res2, outError := c.IPReservations().Create(ctx, &apiv3.IPReservation{
+			ObjectMeta: metav1.ObjectMeta{Name: name2},
+			Spec:       spec2,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res2).To(MatchResource(apiv3.KindIPReservation, testutils.ExpectNoNamespace, name2, spec2))
+
+		By("Getting and listing the IPReservations")
+		res, outError := c.IPReservations().Get(ctx, name1, options.GetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res).To(MatchResource(apiv3.KindIPReservation, testutils.ExpectNoNamespace, name1, spec1))
+
+		outList, outError := c.IPReservations().List(ctx, options.ListOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(outList.Items).To(ConsistOf(
+			testutils.Resource(apiv3.KindIPReservation, testutils.ExpectNoNamespace, name1, spec1),
+			testutils.Resource(apiv3.KindIPReservation, testutils.ExpectNoNamespace, name2, spec2),
+		))
+
+		By("Updating IPReservation name1 with spec2")
+		created1 := res1.DeepCopy()
+		res1.Spec = spec2
+		res1, outError = c.IPReservations().Update(ctx, res1, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindIPReservation, testutils.ExpectNoNamespace, name1, spec2))
+
+		By("Deleting IPReservation name2")
+		_, outError = c.IPReservations().Delete(ctx, name2, options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+
+		By("Checking the watcher received the events")
+		testWatcher.ExpectEvents(apiv3.KindIPReservation, []watch.Event{
+			{Type: watch.Added, Object: created1},
+			{Type: watch.Added, Object: res2},
+			{Type: watch.Modified, Previous: created1, Object: res1},
+			{Type: watch.Deleted, Previous: res2},
+		})
+	})
+})
+
+var _ = testutils.E2eDatastoreDescribe("IPReservation IPAM tests", testutils.DatastoreEtcdV3|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+	host := "node-1"
+
+	var c clientv3.Interface
+	BeforeEach(func() {
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+
+		_, err = c.Nodes().Create(ctx, &apiv3.Node{ObjectMeta: metav1.ObjectMeta{Name: host}}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.IPPools().Create(ctx, &apiv3.IPPool{
+			ObjectMeta: metav1.ObjectMeta{Name: "pool1"},
+			Spec:       apiv3.IPPoolSpec{CIDR: "10.0.0.0/28", BlockSize: 28},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.IPReservations().Create(ctx, &apiv3.IPReservation{
+			ObjectMeta: metav1.ObjectMeta{Name: "appliances"},
+			Spec:       apiv3.IPReservationSpec{ReservedCIDRs: []string{"10.0.0.0/30", "10.0.0.10"}},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should not auto-assign reserved addresses", func() {
+		v4, _, err := c.IPAM().AutoAssign(ctx, ipam.AutoAssignArgs{Num4: 16, Hostname: host})
+		Expect(err).NotTo(HaveOccurred())
+
+		assigned := []string{}
+		for _, ip := range v4 {
+			assigned = append(assigned, ip.IP.String())
+		}
+		Expect(assigned).To(ConsistOf(
+			"10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7", "10.0.0.8", "10.0.0.9",
+			"10.0.0.11", "10.0.0.12", "10.0.0.13", "10.0.0.14", "10.0.0.15",
+		))
+	})
+
+	It("should assign reserved addresses that are requested explicitly", func() {
+		err := c.IPAM().AssignIP(ctx, ipam.AssignIPArgs{IP: cnet.IP{IP: net.ParseIP("10.0.0.10")}, Hostname: host})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("assigning the remaining reserved addresses after the reservation is deleted")
+		_, err = c.IPReservations().Delete(ctx, "appliances", options.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		v4, _, err := c.IPAM().AutoAssign(ctx, ipam.AutoAssignArgs{Num4: 16, Hostname: host})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(15))
+	})
+})
@@ -18,6 +18,8 @@ import (
 	"context"
 	"errors"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -32,6 +34,7 @@ type KubeControllersConfigurationInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.KubeControllersConfiguration, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.KubeControllersConfigurationList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.KubeControllersConfiguration, error)
 }
 
 // KubeControllersConfiguration implements KubeControllersConfigurationInterface
@@ -106,3 +109,24 @@ func (r kubeControllersConfiguration) List(ctx context.Context, opts options.Lis
 func (r kubeControllersConfiguration) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindKubeControllersConfiguration, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named KubeControllersConfiguration. Returns the stored
+// representation of the patched KubeControllersConfiguration, and an error if there is any.
+func (r kubeControllersConfiguration) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.KubeControllersConfiguration, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindKubeControllersConfiguration,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.KubeControllersConfiguration), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.KubeControllersConfiguration), err
+	}
+	return nil, err
+}
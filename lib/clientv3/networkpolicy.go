@@ -17,6 +17,8 @@ package clientv3
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -31,6 +33,7 @@ type NetworkPolicyInterface interface {
 	Get(ctx context.Context, namespace, name string, opts options.GetOptions) (*apiv3.NetworkPolicy, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.NetworkPolicyList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.NetworkPolicy, error)
 }
 
 // networkPolicies implements NetworkPolicyInterface
@@ -53,6 +56,10 @@ func (r networkPolicies) Create(ctx context.Context, res *apiv3.NetworkPolicy, o
 		return nil, err
 	}
 
+	if err := checkPolicyTier(ctx, r.client, res.Spec.Tier); err != nil {
+		return nil, err
+	}
+
 	// Properly prefix the name
 	res.GetObjectMeta().SetName(convertPolicyNameForStorage(res.GetObjectMeta().GetName()))
 	out, err := r.client.resources.Create(ctx, opts, apiv3.KindNetworkPolicy, res)
@@ -82,6 +89,10 @@ func (r networkPolicies) Update(ctx context.Context, res *apiv3.NetworkPolicy, o
 		return nil, err
 	}
 
+	if err := checkPolicyTier(ctx, r.client, res.Spec.Tier); err != nil {
+		return nil, err
+	}
+
 	// Properly prefix the name
 	res.GetObjectMeta().SetName(convertPolicyNameForStorage(res.GetObjectMeta().GetName()))
 	out, err := r.client.resources.Update(ctx, opts, apiv3.KindNetworkPolicy, res)
@@ -126,6 +137,7 @@ func (r networkPolicies) List(ctx context.Context, opts options.ListOptions) (*a
 	if opts.Name != "" {
 		opts.Name = convertPolicyNameForStorage(opts.Name)
 	}
+	opts.FieldSelector = convertPolicyFieldSelectorForStorage(opts.FieldSelector)
 
 	if err := r.client.resources.List(ctx, opts, apiv3.KindNetworkPolicy, apiv3.KindNetworkPolicyList, res); err != nil {
 		return nil, err
@@ -147,6 +159,27 @@ func (r networkPolicies) Watch(ctx context.Context, opts options.ListOptions) (w
 	if opts.Name != "" {
 		opts.Name = convertPolicyNameForStorage(opts.Name)
 	}
+	opts.FieldSelector = convertPolicyFieldSelectorForStorage(opts.FieldSelector)
 
 	return r.client.resources.Watch(ctx, opts, apiv3.KindNetworkPolicy, &policyConverter{})
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named NetworkPolicy. Returns the stored
+// representation of the patched NetworkPolicy, and an error if there is any.
+func (r networkPolicies) Patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.NetworkPolicy, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindNetworkPolicy,
+		namespace: namespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, namespace, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.NetworkPolicy), options.SetOptions{})
+		},
+	})
+	if out != nil {
+		return out.(*apiv3.NetworkPolicy), err
+	}
+	return nil, err
+}
@@ -17,6 +17,8 @@ package clientv3
 import (
 	"context"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/options"
 	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
@@ -31,6 +33,7 @@ type NetworkSetInterface interface {
 	Get(ctx context.Context, namespace, name string, opts options.GetOptions) (*apiv3.NetworkSet, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.NetworkSetList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.NetworkSet, error)
 }
 
 // networkSets implements NetworkSetInterface
@@ -97,3 +100,24 @@ func (r networkSets) List(ctx context.Context, opts options.ListOptions) (*apiv3
 func (r networkSets) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindNetworkSet, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named NetworkSet. Returns the stored
+// representation of the patched NetworkSet, and an error if there is any.
+func (r networkSets) Patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.NetworkSet, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindNetworkSet,
+		namespace: namespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, namespace, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.NetworkSet), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.NetworkSet), err
+	}
+	return nil, err
+}
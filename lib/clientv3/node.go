@@ -16,10 +16,10 @@ package clientv3
 
 import (
 	"context"
-
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/errors"
@@ -39,6 +39,7 @@ type NodeInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.Node, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.NodeList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.Node, error)
 }
 
 // nodes implements NodeInterface
@@ -245,3 +246,24 @@ func (r nodes) List(ctx context.Context, opts options.ListOptions) (*apiv3.NodeL
 func (r nodes) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindNode, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named Node. Returns the stored
+// representation of the patched Node, and an error if there is any.
+func (r nodes) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.Node, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindNode,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.Node), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.Node), err
+	}
+	return nil, err
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = testutils.E2eDatastoreDescribe("List pagination tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+
+	networkSet := func(name string) *apiv3.GlobalNetworkSet {
+		return &apiv3.GlobalNetworkSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       apiv3.GlobalNetworkSetSpec{Nets: []string{"10.0.0.0/8"}},
+		}
+	}
+
+	var c clientv3.Interface
+	BeforeEach(func() {
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+
+		for _, name := range []string{"gns-1", "gns-2", "gns-3", "gns-4", "gns-5"} {
+			_, err := c.GlobalNetworkSets().Create(ctx, networkSet(name), options.SetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should List every resource in pages of the requested size", func() {
+		opts := options.ListOptions{Limit: 2}
+		var names []string
+		pages := 0
+		for {
+			l, err := c.GlobalNetworkSets().List(ctx, opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(l.Items)).To(BeNumerically("<=", 2))
+			for _, gns := range l.Items {
+				names = append(names, gns.Name)
+			}
+			pages++
+			if l.Continue == "" {
+				break
+			}
+			opts.Continue = l.Continue
+		}
+		Expect(pages).To(Equal(3))
+		Expect(names).To(Equal([]string{"gns-1", "gns-2", "gns-3", "gns-4", "gns-5"}))
+	})
+
+	It("should List the later pages from the snapshot of the first page", func() {
+		l, err := c.GlobalNetworkSets().List(ctx, options.ListOptions{Limit: 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).To(HaveLen(3))
+		Expect(l.Continue).NotTo(BeEmpty())
+
+		_, err = c.GlobalNetworkSets().Create(ctx, networkSet("gns-6"), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		l, err = c.GlobalNetworkSets().List(ctx, options.ListOptions{Limit: 3, Continue: l.Continue})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).To(HaveLen(2))
+		Expect(l.Items[0].Name).To(Equal("gns-4"))
+		Expect(l.Items[1].Name).To(Equal("gns-5"))
+		Expect(l.Continue).To(BeEmpty())
+	})
+
+	It("should List everything without a limit", func() {
+		l, err := c.GlobalNetworkSets().List(ctx, options.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l.Items).To(HaveLen(5))
+		Expect(l.Continue).To(BeEmpty())
+	})
+
+	It("should reject invalid pagination options", func() {
+		_, err := c.GlobalNetworkSets().List(ctx, options.ListOptions{Limit: -1})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		_, err = c.GlobalNetworkSets().List(ctx, options.ListOptions{Limit: 2, Continue: "abc", ResourceVersion: "1"})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		_, err = c.GlobalNetworkSets().List(ctx, options.ListOptions{Limit: 2, Continue: "not-a-token"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,162 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
+)
+
+// patcher is supplied by a typed client to Patch a resource.  The resource is read and
+// written using the typed client, so that the patched resource is defaulted and validated in
+// the same way as an Update.
+type patcher struct {
+	kind      string
+	namespace string
+	name      string
+
+	// get returns the current resource.
+	get func(ctx context.Context) (resource, error)
+	// update updates the patched resource.
+	update func(ctx context.Context, res resource) (resource, error)
+	// native is true if the patch may be applied by a datastore that supports patching, which
+	// is only the case if an Update of the kind does nothing but validate the resource.
+	native bool
+}
+
+// Patch applies a JSON merge patch or JSON patch to a resource.  The current resource is
+// patched and updated, and this is retried if the resource is updated concurrently, unless
+// the options specify the resource version to patch.  If the datastore supports patching
+// and the patcher allows it, the patch is instead applied by the datastore once the patched
+// resource has been validated, on condition that the resource has not been modified since.
+func (c *resources) Patch(ctx context.Context, opts options.PatchOptions, pt types.PatchType, data []byte, p patcher) (resource, error) {
+	key := model.ResourceKey{Kind: p.kind, Namespace: p.namespace, Name: p.name}
+	if pt != types.JSONPatchType && pt != types.MergePatchType {
+		return nil, cerrors.ErrorOperationNotSupported{
+			Operation:  "Patch",
+			Identifier: key,
+			Reason:     fmt.Sprintf("patch type %q is not supported", pt),
+		}
+	}
+
+	var err error
+	for i := 0; i < maxApplyRetries; i++ {
+		var current, patched, out resource
+		if current, err = p.get(ctx); err != nil {
+			return nil, err
+		}
+		rv := current.GetObjectMeta().GetResourceVersion()
+		if opts.ResourceVersion != "" && opts.ResourceVersion != rv {
+			return nil, cerrors.ErrorResourceUpdateConflict{Identifier: key}
+		}
+		if patched, err = applyPatch(current, pt, data); err != nil {
+			return nil, err
+		}
+
+		if pc, ok := c.backend.(bapi.PatchClient); ok && p.native {
+			if err = validator.Validate(patched); err != nil {
+				return nil, err
+			}
+			// The datastore applies the patch to its current resource, so the patch is made
+			// conditional on the revision of the resource that was validated.
+			var kvp *model.KVPair
+			kvp, err = pc.Patch(ctx, key, pt, data, rv)
+			if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok && opts.ResourceVersion == "" {
+				log.WithField("Retry", i).WithField("Key", key).Debug("Patch conflict - retry patch")
+				continue
+			} else if _, ok := err.(cerrors.ErrorOperationNotSupported); !ok {
+				if err != nil {
+					return nil, err
+				}
+				return c.kvPairToResource(kvp), nil
+			}
+			log.WithField("Key", key).Debug("Datastore cannot apply patch - patching with an update")
+		}
+
+		out, err = p.update(ctx, patched)
+		if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok && opts.ResourceVersion == "" {
+			log.WithField("Retry", i).WithField("Key", key).Debug("Update conflict - retry patch")
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	// Return the error from the final attempt.
+	log.WithError(err).WithField("Key", key).Info("Too many conflict failures attempting to patch resource")
+	return nil, err
+}
+
+// applyPatch returns a copy of the resource with the patch applied.  The patch must not change
+// the name or namespace of the resource.
+func applyPatch(res resource, pt types.PatchType, data []byte) (resource, error) {
+	patched, err := patchResource(res, pt, data)
+	if err != nil {
+		return nil, cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "Patch",
+				Reason: fmt.Sprintf("unable to apply %s: %v", pt, err),
+				Value:  string(data),
+			}},
+		}
+	}
+	return patched, nil
+}
+
+func patchResource(res resource, pt types.PatchType, data []byte) (resource, error) {
+	original, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	var patchedJSON []byte
+	switch pt {
+	case types.JSONPatchType:
+		patch, err := jsonpatch.DecodePatch(data)
+		if err != nil {
+			return nil, err
+		}
+		if patchedJSON, err = patch.Apply(original); err != nil {
+			return nil, err
+		}
+	case types.MergePatchType:
+		if patchedJSON, err = jsonpatch.MergePatch(original, data); err != nil {
+			return nil, err
+		}
+	}
+
+	patched := reflect.New(reflect.TypeOf(res).Elem()).Interface().(resource)
+	if err := json.Unmarshal(patchedJSON, patched); err != nil {
+		return nil, err
+	}
+	meta, patchedMeta := res.GetObjectMeta(), patched.GetObjectMeta()
+	if patchedMeta.GetName() != meta.GetName() || patchedMeta.GetNamespace() != meta.GetNamespace() {
+		return nil, fmt.Errorf("the patch must not change the name or namespace")
+	}
+	patchedMeta.SetResourceVersion(meta.GetResourceVersion())
+	return patched, nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+// racingBackend hides any patch support of the wrapped backend, and labels the named
+// HostEndpoint before its first update, as a concurrent writer would.
+type racingBackend struct {
+	bapi.Client
+	raceUpdate string
+	raced      bool
+}
+
+func (b *racingBackend) Update(ctx context.Context, d *model.KVPair) (*model.KVPair, error) {
+	if !b.raced && d.Key.(model.ResourceKey).Name == b.raceUpdate {
+		b.raced = true
+		current, err := b.Client.Get(ctx, d.Key, "")
+		if err != nil {
+			return nil, err
+		}
+		hep := current.Value.(*apiv3.HostEndpoint).DeepCopy()
+		hep.Labels["racer"] = "true"
+		current.Value = hep
+		if _, err := b.Client.Update(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+	return b.Client.Update(ctx, d)
+}
+
+var _ = testutils.E2eDatastoreDescribe("Patch tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+
+	hostEndpoint := func() *apiv3.HostEndpoint {
+		return &apiv3.HostEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: "hep-1", Labels: map[string]string{"env": "prod"}},
+			Spec: apiv3.HostEndpointSpec{
+				Node:          "node1",
+				InterfaceName: "eth0",
+			},
+		}
+	}
+
+	var c client
+	BeforeEach(func() {
+		i, err := New(config)
+		Expect(err).NotTo(HaveOccurred())
+		c = i.(client)
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+	})
+
+	It("should apply a JSON merge patch", func() {
+		created, err := c.HostEndpoints().Create(ctx, hostEndpoint(), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		out, err := c.HostEndpoints().Patch(ctx, "hep-1", types.MergePatchType,
+			[]byte(`{"metadata":{"labels":{"app":"web"}}}`), options.PatchOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Labels).To(Equal(map[string]string{"env": "prod", "app": "web"}))
+		Expect(out.Spec).To(Equal(created.Spec))
+		Expect(out.ResourceVersion).NotTo(Equal(created.ResourceVersion))
+
+		got, err := c.HostEndpoints().Get(ctx, "hep-1", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Labels).To(Equal(out.Labels))
+	})
+
+	It("should apply a JSON patch", func() {
+		_, err := c.FelixConfigurations().Create(ctx, &apiv3.FelixConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec:       apiv3.FelixConfigurationSpec{LogSeverityScreen: "Info"},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		out, err := c.FelixConfigurations().Patch(ctx, "default", types.JSONPatchType,
+			[]byte(`[{"op":"replace","path":"/spec/logSeverityScreen","value":"Debug"}]`), options.PatchOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Spec.LogSeverityScreen).To(Equal("Debug"))
+	})
+
+	It("should patch a policy in the default tier", func() {
+		_, err := c.GlobalNetworkPolicies().Create(ctx, &apiv3.GlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-dns"},
+			Spec:       apiv3.GlobalNetworkPolicySpec{Selector: "all()"},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		out, err := c.GlobalNetworkPolicies().Patch(ctx, "allow-dns", types.MergePatchType,
+			[]byte(`{"spec":{"selector":"has(app)"}}`), options.PatchOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Name).To(Equal("allow-dns"))
+		Expect(out.Spec.Selector).To(Equal("has(app)"))
+	})
+
+	It("should retry a patch that races with another update", func() {
+		_, err := c.HostEndpoints().Create(ctx, hostEndpoint(), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		rb := &racingBackend{Client: c.backend, raceUpdate: "hep-1"}
+		rc := client{config: c.config, backend: rb, resources: &resources{backend: rb}}
+		out, err := rc.HostEndpoints().Patch(ctx, "hep-1", types.MergePatchType,
+			[]byte(`{"metadata":{"labels":{"app":"web"}}}`), options.PatchOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rb.raced).To(BeTrue())
+		Expect(out.Labels).To(Equal(map[string]string{"env": "prod", "app": "web", "racer": "true"}))
+	})
+
+	It("should not retry a patch of a specific resource version", func() {
+		created, err := c.HostEndpoints().Create(ctx, hostEndpoint(), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		rb := &racingBackend{Client: c.backend, raceUpdate: "hep-1"}
+		rc := client{config: c.config, backend: rb, resources: &resources{backend: rb}}
+		_, err = rc.HostEndpoints().Patch(ctx, "hep-1", types.MergePatchType,
+			[]byte(`{"metadata":{"labels":{"app":"web"}}}`), options.PatchOptions{ResourceVersion: created.ResourceVersion})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+
+		_, err = c.HostEndpoints().Patch(ctx, "hep-1", types.MergePatchType,
+			[]byte(`{"metadata":{"labels":{"app":"web"}}}`), options.PatchOptions{ResourceVersion: created.ResourceVersion})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceUpdateConflict{}))
+	})
+
+	It("should reject invalid patches", func() {
+		_, err := c.HostEndpoints().Patch(ctx, "hep-1", types.MergePatchType,
+			[]byte(`{"metadata":{"labels":{"app":"web"}}}`), options.PatchOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+
+		_, err = c.HostEndpoints().Create(ctx, hostEndpoint(), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("using an unsupported patch type")
+		_, err = c.HostEndpoints().Patch(ctx, "hep-1", types.StrategicMergePatchType,
+			[]byte(`{"metadata":{"labels":{"app":"web"}}}`), options.PatchOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+
+		By("using a malformed patch")
+		_, err = c.HostEndpoints().Patch(ctx, "hep-1", types.JSONPatchType,
+			[]byte(`{"metadata":{}}`), options.PatchOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		By("renaming the resource")
+		_, err = c.HostEndpoints().Patch(ctx, "hep-1", types.MergePatchType,
+			[]byte(`{"metadata":{"name":"hep-2"}}`), options.PatchOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		By("making the resource invalid")
+		_, err = c.HostEndpoints().Patch(ctx, "hep-1", types.MergePatchType,
+			[]byte(`{"spec":{"expectedIPs":["not-an-ip"]}}`), options.PatchOptions{})
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		got, err := c.HostEndpoints().Get(ctx, "hep-1", options.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got.Labels).To(Equal(hostEndpoint().Labels))
+		Expect(got.Spec.ExpectedIPs).To(BeEmpty())
+	})
+})
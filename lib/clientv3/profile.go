@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
 	"github.com/projectcalico/libcalico-go/lib/options"
@@ -34,6 +36,7 @@ type ProfileInterface interface {
 	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.Profile, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.ProfileList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.Profile, error)
 }
 
 // profiles implements ProfileInterface
@@ -124,3 +127,23 @@ func (r profiles) List(ctx context.Context, opts options.ListOptions) (*apiv3.Pr
 func (r profiles) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
 	return r.client.resources.Watch(ctx, opts, apiv3.KindProfile, nil)
 }
+
+// Patch applies a JSON merge patch or JSON patch to the named Profile. Returns the stored
+// representation of the patched Profile, and an error if there is any.
+func (r profiles) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.Profile, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindProfile,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.Profile), options.SetOptions{})
+		},
+	})
+	if out != nil {
+		return out.(*apiv3.Profile), err
+	}
+	return nil, err
+}
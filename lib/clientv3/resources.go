@@ -23,6 +23,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
 
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
@@ -60,6 +61,7 @@ type resourceInterface interface {
 	Get(ctx context.Context, opts options.GetOptions, kind, ns, name string) (resource, error)
 	List(ctx context.Context, opts options.ListOptions, kind, listkind string, inout resourceList) error
 	Watch(ctx context.Context, opts options.ListOptions, kind string, converter watcherConverter) (watch.Interface, error)
+	Patch(ctx context.Context, opts options.PatchOptions, pt types.PatchType, data []byte, p patcher) (resource, error)
 }
 
 // resources implements resourceInterface.
@@ -206,11 +208,22 @@ func (c *resources) Get(ctx context.Context, opts options.GetOptions, kind, ns,
 
 // List lists a resource from the backend datastore.
 func (c *resources) List(ctx context.Context, opts options.ListOptions, kind, listKind string, listObj resourceList) error {
+	if err := validatePagination(opts); err != nil {
+		return err
+	}
+	filter, err := newListFilter(opts)
+	if err != nil {
+		return err
+	}
 	list := model.ResourceListOptions{
-		Kind:      kind,
-		Name:      opts.Name,
-		Namespace: opts.Namespace,
-		Prefix:    opts.Prefix,
+		Kind:          kind,
+		Name:          opts.Name,
+		Namespace:     opts.Namespace,
+		Prefix:        opts.Prefix,
+		LabelSelector: opts.LabelSelector,
+		FieldSelector: opts.FieldSelector,
+		Limit:         opts.Limit,
+		Continue:      opts.Continue,
 	}
 
 	// Query the backend.
@@ -219,18 +232,25 @@ func (c *resources) List(ctx context.Context, opts options.ListOptions, kind, li
 		return err
 	}
 
-	// Convert the slice of KVPairs to a slice of Objects.
+	// Convert the slice of KVPairs to a slice of Objects, filtering out any that the backend
+	// did not filter.
 	resources := []runtime.Object{}
 	for _, kvp := range kvps.KVPairs {
-		resources = append(resources, c.kvPairToResource(kvp))
+		res := c.kvPairToResource(kvp)
+		if !filter.matches(res) {
+			continue
+		}
+		resources = append(resources, res)
 	}
 	err = meta.SetList(listObj, resources)
 	if err != nil {
 		return err
 	}
 
-	// Finally, set the resource version and api group version of the list object.
+	// Finally, set the resource version, continuation token and api group version of the
+	// list object.
 	listObj.GetListMeta().SetResourceVersion(kvps.Revision)
+	listObj.GetListMeta().SetContinue(kvps.Continue)
 	listObj.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{
 		Group:   apiv3.Group,
 		Version: apiv3.VersionCurrent,
@@ -240,12 +260,41 @@ func (c *resources) List(ctx context.Context, opts options.ListOptions, kind, li
 	return nil
 }
 
+// validatePagination checks the Limit and Continue fields of the list options.
+func validatePagination(opts options.ListOptions) error {
+	if opts.Limit < 0 {
+		return cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "ListOptions.Limit",
+				Reason: "limit must not be negative",
+				Value:  opts.Limit,
+			}},
+		}
+	}
+	if len(opts.Continue) != 0 && len(opts.ResourceVersion) != 0 {
+		return cerrors.ErrorValidation{
+			ErroredFields: []cerrors.ErroredField{{
+				Name:   "ListOptions.ResourceVersion",
+				Reason: "resource version must not be specified with a continuation token",
+				Value:  opts.ResourceVersion,
+			}},
+		}
+	}
+	return nil
+}
+
 // Watch watches a specific resource or resource type.
 func (c *resources) Watch(ctx context.Context, opts options.ListOptions, kind string, converter watcherConverter) (watch.Interface, error) {
+	filter, err := newListFilter(opts)
+	if err != nil {
+		return nil, err
+	}
 	list := model.ResourceListOptions{
-		Kind:      kind,
-		Name:      opts.Name,
-		Namespace: opts.Namespace,
+		Kind:          kind,
+		Name:          opts.Name,
+		Namespace:     opts.Namespace,
+		LabelSelector: opts.LabelSelector,
+		FieldSelector: opts.FieldSelector,
 	}
 
 	// Create the backend watcher.  We need to process the results to add revision data etc.
@@ -261,6 +310,7 @@ func (c *resources) Watch(ctx context.Context, opts options.ListOptions, kind st
 		context:   ctx,
 		backend:   backend,
 		converter: converter,
+		filter:    filter,
 	}
 	go w.run()
 	return w, nil
@@ -333,6 +383,11 @@ type watcher struct {
 	client     *resources
 	terminated uint32
 	converter  watcherConverter
+
+	// filter is the filter from the ListOptions, or nil if the events are not filtered.
+	// matched holds the keys of the resources that matched the filter when last seen.
+	filter  *listFilter
+	matched map[string]bool
 }
 
 func (w *watcher) Stop() {
@@ -359,6 +414,10 @@ func (w *watcher) run() {
 				return
 			}
 			e := w.convertEvent(event)
+			if !w.filterEvent(&e) {
+				continue
+			}
+			w.convertResources(&e)
 			select {
 			case w.results <- e:
 			case <-w.context.Done():
@@ -397,23 +456,89 @@ func (w *watcher) convertEvent(backendEvent bapi.WatchEvent) watch.Event {
 	}
 
 	if backendEvent.Old != nil {
-		res := w.client.kvPairToResource(backendEvent.Old)
-		if w.converter != nil {
-			res = w.converter.Convert(res)
-		}
-		apiEvent.Previous = res
+		apiEvent.Previous = w.client.kvPairToResource(backendEvent.Old)
 	}
 	if backendEvent.New != nil {
-		res := w.client.kvPairToResource(backendEvent.New)
-		if w.converter != nil {
-			apiEvent.Object = w.converter.Convert(res)
-		}
-		apiEvent.Object = res
+		apiEvent.Object = w.client.kvPairToResource(backendEvent.New)
 	}
 
 	return apiEvent
 }
 
+// convertResources applies the converter to the resources in the event.  This is done after
+// the event is filtered, so that the filter is matched against the resources as stored, in the
+// same way as for a List.
+func (w *watcher) convertResources(e *watch.Event) {
+	if w.converter == nil {
+		return
+	}
+	if e.Previous != nil {
+		e.Previous = w.converter.Convert(e.Previous.(resource))
+	}
+	if e.Object != nil {
+		e.Object = w.converter.Convert(e.Object.(resource))
+	}
+}
+
+// filterEvent applies the filter to an event, returning false if the event should not be
+// sent.  As with a filtered Kubernetes watch, a resource that is modified to match the filter
+// is reported as added, and a resource that is modified to no longer match is reported as
+// deleted.
+func (w *watcher) filterEvent(e *watch.Event) bool {
+	if w.filter == nil || e.Type == watch.Error || (e.Previous == nil && e.Object == nil) {
+		return true
+	}
+	if w.matched == nil {
+		w.matched = map[string]bool{}
+	}
+
+	// Determine whether the resource matched before this event.  If the previous version of
+	// the resource is not known, use whether it matched when last seen.
+	var key string
+	var before, after bool
+	if e.Previous != nil {
+		res := e.Previous.(resource)
+		key = resourceKeyString(res)
+		before = w.matched[key] || w.filter.matches(res)
+	}
+	if e.Object != nil {
+		res := e.Object.(resource)
+		key = resourceKeyString(res)
+		after = w.filter.matches(res)
+		if e.Previous == nil {
+			before = w.matched[key]
+		}
+	}
+	if after {
+		w.matched[key] = true
+	} else {
+		delete(w.matched, key)
+	}
+
+	switch {
+	case e.Type == watch.Deleted:
+		return before
+	case before && after:
+		e.Type = watch.Modified
+	case after:
+		e.Type = watch.Added
+		e.Previous = nil
+	case before:
+		e.Type = watch.Deleted
+		if e.Previous == nil {
+			e.Previous = e.Object
+		}
+		e.Object = nil
+	default:
+		return false
+	}
+	return true
+}
+
+func resourceKeyString(res resource) string {
+	return res.GetObjectMeta().GetNamespace() + "/" + res.GetObjectMeta().GetName()
+}
+
 // hasTerminated returns true if the watcher has terminated, release all resources.
 // Used for test purposes.
 func (w *watcher) hasTerminated() bool {
@@ -0,0 +1,236 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+var _ = testutils.E2eDatastoreDescribe("Staged policy tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+	order1 := 99.999
+	namespace1 := "namespace-1"
+	name1 := "staged-1"
+	name2 := "staged-2"
+	gnpSpec1 := apiv3.StagedGlobalNetworkPolicySpec{
+		StagedAction: apiv3.StagedActionSet,
+		Order:        &order1,
+		Ingress:      []apiv3.Rule{testutils.InRule1},
+		Selector:     "thing == 'value'",
+		Types:        []apiv3.PolicyType{apiv3.PolicyTypeIngress},
+	}
+	gnpSpec2 := apiv3.StagedGlobalNetworkPolicySpec{
+		StagedAction: apiv3.StagedActionDelete,
+	}
+	npSpec1 := apiv3.StagedNetworkPolicySpec{
+		StagedAction: apiv3.StagedActionSet,
+		Order:        &order1,
+		Egress:       []apiv3.Rule{testutils.EgressRule1},
+		Selector:     "thing == 'value'",
+		Types:        []apiv3.PolicyType{apiv3.PolicyTypeEgress},
+	}
+	knpSpec1 := apiv3.StagedKubernetesNetworkPolicySpec{
+		StagedAction: apiv3.StagedActionSet,
+		PodSelector:  metav1.LabelSelector{MatchLabels: map[string]string{"thing": "value"}},
+		PolicyTypes:  []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+	}
+
+	var c clientv3.Interface
+	BeforeEach(func() {
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+	})
+
+	It("should handle CRUD and watch of StagedGlobalNetworkPolicy resources", func() {
+		By("Creating a staged delete with a non-empty spec")
+		_, outError := c.StagedGlobalNetworkPolicies().Create(ctx, &apiv3.StagedGlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name1},
+			Spec: apiv3.StagedGlobalNetworkPolicySpec{
+				StagedAction: apiv3.StagedActionDelete,
+				Selector:     "thing == 'value'",
+			},
+		}, options.SetOptions{})
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		By("Starting a watcher")
+		w, err := c.StagedGlobalNetworkPolicies().Watch(ctx, options.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		testWatcher := testutils.NewTestResourceWatch(config.Spec.DatastoreType, w)
+		defer testWatcher.Stop()
+
+		By("Creating a staged set and a staged delete")
+		res1, outError := c.StagedGlobalNetworkPolicies().Create(ctx, &apiv3.StagedGlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name1},
+			Spec:       gnpSpec1,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindStagedGlobalNetworkPolicy, testutils.ExpectNoNamespace, name1, gnpSpec1))
+
+		res2, outError := c.StagedGlobalNetworkPolicies().Create(ctx, &apiv3.StagedGlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name2},
+			Spec:       gnpSpec2,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res2).To(MatchResource(apiv3.KindStagedGlobalNetworkPolicy, testutils.ExpectNoNamespace, name2, gnpSpec2))
+
+		By("Getting and listing the staged policies")
+		res, outError := c.StagedGlobalNetworkPolicies().Get(ctx, name1, options.GetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res).To(MatchResource(apiv3.KindStagedGlobalNetworkPolicy, testutils.ExpectNoNamespace, name1, gnpSpec1))
+
+		outList, outError := c.StagedGlobalNetworkPolicies().List(ctx, options.ListOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(outList.Items).To(ConsistOf(
+			testutils.Resource(apiv3.KindStagedGlobalNetworkPolicy, testutils.ExpectNoNamespace, name1, gnpSpec1),
+			testutils.Resource(apiv3.KindStagedGlobalNetworkPolicy, testutils.ExpectNoNamespace, name2, gnpSpec2),
+		))
+
+		By("Updating the staged set to a staged delete")
+		created1 := res1.DeepCopy()
+		res1.Spec = gnpSpec2
+		res1, outError = c.StagedGlobalNetworkPolicies().Update(ctx, res1, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindStagedGlobalNetworkPolicy, testutils.ExpectNoNamespace, name1, gnpSpec2))
+
+		By("Deleting the second staged policy")
+		_, outError = c.StagedGlobalNetworkPolicies().Delete(ctx, name2, options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		_, outError = c.StagedGlobalNetworkPolicies().Get(ctx, name2, options.GetOptions{})
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+
+		By("Checking the watcher received the events")
+		testWatcher.ExpectEvents(apiv3.KindStagedGlobalNetworkPolicy, []watch.Event{
+			{Type: watch.Added, Object: created1},
+			{Type: watch.Added, Object: res2},
+			{Type: watch.Modified, Previous: created1, Object: res1},
+			{Type: watch.Deleted, Previous: res2},
+		})
+	})
+
+	It("should only default the policy types for a staged set", func() {
+		res, outError := c.StagedGlobalNetworkPolicies().Create(ctx, &apiv3.StagedGlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name1},
+			Spec:       apiv3.StagedGlobalNetworkPolicySpec{Egress: []apiv3.Rule{testutils.EgressRule1}},
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res.Spec.Types).To(Equal([]apiv3.PolicyType{apiv3.PolicyTypeEgress}))
+
+		res, outError = c.StagedGlobalNetworkPolicies().Create(ctx, &apiv3.StagedGlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name2},
+			Spec:       gnpSpec2,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res.Spec.Types).To(BeEmpty())
+	})
+
+	It("should handle CRUD of StagedNetworkPolicy resources", func() {
+		By("Creating a staged policy")
+		res1, outError := c.StagedNetworkPolicies().Create(ctx, &apiv3.StagedNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace1, Name: name1},
+			Spec:       npSpec1,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindStagedNetworkPolicy, namespace1, name1, npSpec1))
+
+		By("Getting and listing the staged policy")
+		res, outError := c.StagedNetworkPolicies().Get(ctx, namespace1, name1, options.GetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res).To(MatchResource(apiv3.KindStagedNetworkPolicy, namespace1, name1, npSpec1))
+
+		outList, outError := c.StagedNetworkPolicies().List(ctx, options.ListOptions{Namespace: namespace1})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(outList.Items).To(ConsistOf(
+			testutils.Resource(apiv3.KindStagedNetworkPolicy, namespace1, name1, npSpec1),
+		))
+
+		By("Deleting the staged policy")
+		_, outError = c.StagedNetworkPolicies().Delete(ctx, namespace1, name1, options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		outList, outError = c.StagedNetworkPolicies().List(ctx, options.ListOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(outList.Items).To(BeEmpty())
+	})
+
+	It("should handle CRUD of StagedKubernetesNetworkPolicy resources", func() {
+		By("Creating a staged policy")
+		res1, outError := c.StagedKubernetesNetworkPolicies().Create(ctx, &apiv3.StagedKubernetesNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace1, Name: name1},
+			Spec:       knpSpec1,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindStagedKubernetesNetworkPolicy, namespace1, name1, knpSpec1))
+
+		By("Getting the staged policy")
+		res, outError := c.StagedKubernetesNetworkPolicies().Get(ctx, namespace1, name1, options.GetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res).To(MatchResource(apiv3.KindStagedKubernetesNetworkPolicy, namespace1, name1, knpSpec1))
+
+		By("Updating the staged policy with a non-empty staged delete")
+		res1.Spec.StagedAction = apiv3.StagedActionDelete
+		_, outError = c.StagedKubernetesNetworkPolicies().Update(ctx, res1, options.SetOptions{})
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		By("Deleting the staged policy")
+		_, outError = c.StagedKubernetesNetworkPolicies().Delete(ctx, namespace1, name1, options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		_, outError = c.StagedKubernetesNetworkPolicies().Get(ctx, namespace1, name1, options.GetOptions{})
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+	})
+
+	It("should not delete a tier containing staged policies", func() {
+		_, outError := c.StagedGlobalNetworkPolicies().Create(ctx, &apiv3.StagedGlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform.policy"},
+			Spec:       apiv3.StagedGlobalNetworkPolicySpec{Tier: "platform"},
+		}, options.SetOptions{})
+		Expect(outError).To(HaveOccurred())
+		Expect(outError.Error()).To(ContainSubstring("tier does not exist"))
+
+		_, outError = c.Tiers().Create(ctx, &apiv3.Tier{ObjectMeta: metav1.ObjectMeta{Name: "platform"}}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		_, outError = c.StagedNetworkPolicies().Create(ctx, &apiv3.StagedNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace1, Name: "platform.policy"},
+			Spec:       apiv3.StagedNetworkPolicySpec{Tier: "platform"},
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+
+		_, outError = c.Tiers().Delete(ctx, "platform", options.DeleteOptions{})
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+
+		_, outError = c.StagedNetworkPolicies().Delete(ctx, namespace1, "platform.policy", options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		_, outError = c.Tiers().Delete(ctx, "platform", options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,208 @@
+// Copyright (c) 2016-2018 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	validator "github.com/projectcalico/libcalico-go/lib/validator/v3"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+// TierInterface has methods to work with Tier resources.
+type TierInterface interface {
+	Create(ctx context.Context, res *apiv3.Tier, opts options.SetOptions) (*apiv3.Tier, error)
+	Update(ctx context.Context, res *apiv3.Tier, opts options.SetOptions) (*apiv3.Tier, error)
+	Delete(ctx context.Context, name string, opts options.DeleteOptions) (*apiv3.Tier, error)
+	Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.Tier, error)
+	List(ctx context.Context, opts options.ListOptions) (*apiv3.TierList, error)
+	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.Tier, error)
+}
+
+// tiers implements TierInterface
+type tiers struct {
+	client client
+}
+
+// Create takes the representation of a Tier and creates it.  Returns the stored
+// representation of the Tier, and an error, if there is any.
+func (r tiers) Create(ctx context.Context, res *apiv3.Tier, opts options.SetOptions) (*apiv3.Tier, error) {
+	if err := validator.Validate(res); err != nil {
+		return nil, err
+	}
+
+	out, err := r.client.resources.Create(ctx, opts, apiv3.KindTier, res)
+	if out != nil {
+		return out.(*apiv3.Tier), err
+	}
+	return nil, err
+}
+
+// Update takes the representation of a Tier and updates it. Returns the stored
+// representation of the Tier, and an error, if there is any.
+func (r tiers) Update(ctx context.Context, res *apiv3.Tier, opts options.SetOptions) (*apiv3.Tier, error) {
+	if err := validator.Validate(res); err != nil {
+		return nil, err
+	}
+
+	out, err := r.client.resources.Update(ctx, opts, apiv3.KindTier, res)
+	if out != nil {
+		return out.(*apiv3.Tier), err
+	}
+	return nil, err
+}
+
+// Delete takes name of the Tier and deletes it. Returns an error if one occurs.  A Tier
+// cannot be deleted while it contains any policies.
+func (r tiers) Delete(ctx context.Context, name string, opts options.DeleteOptions) (*apiv3.Tier, error) {
+	if name != apiv3.DefaultTierName {
+		if err := r.checkTierIsEmpty(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := r.client.resources.Delete(ctx, opts, apiv3.KindTier, noNamespace, name)
+	if out != nil {
+		return out.(*apiv3.Tier), err
+	}
+	return nil, err
+}
+
+// Get takes name of the Tier, and returns the corresponding Tier object,
+// and an error if there is any.
+func (r tiers) Get(ctx context.Context, name string, opts options.GetOptions) (*apiv3.Tier, error) {
+	out, err := r.client.resources.Get(ctx, opts, apiv3.KindTier, noNamespace, name)
+	if out != nil {
+		return out.(*apiv3.Tier), err
+	}
+	return nil, err
+}
+
+// List returns the list of Tier objects that match the supplied options.
+func (r tiers) List(ctx context.Context, opts options.ListOptions) (*apiv3.TierList, error) {
+	res := &apiv3.TierList{}
+	if err := r.client.resources.List(ctx, opts, apiv3.KindTier, apiv3.KindTierList, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Watch returns a watch.Interface that watches the Tiers that match the
+// supplied options.
+func (r tiers) Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
+	return r.client.resources.Watch(ctx, opts, apiv3.KindTier, nil)
+}
+
+// Patch applies a JSON merge patch or JSON patch to the named Tier. Returns the stored
+// representation of the patched Tier, and an error if there is any.
+func (r tiers) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.Tier, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindTier,
+		namespace: noNamespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.Tier), options.SetOptions{})
+		},
+		native: true,
+	})
+	if out != nil {
+		return out.(*apiv3.Tier), err
+	}
+	return nil, err
+}
+
+// checkTierIsEmpty returns an error if any GlobalNetworkPolicy or NetworkPolicy, or any staged
+// GlobalNetworkPolicy or NetworkPolicy, is in the named tier.
+func (r tiers) checkTierIsEmpty(ctx context.Context, name string) error {
+	gnps, err := r.client.GlobalNetworkPolicies().List(ctx, options.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, p := range gnps.Items {
+		if p.Spec.Tier == name {
+			return tierNotEmptyError(name, apiv3.KindGlobalNetworkPolicy, p.Name)
+		}
+	}
+
+	nps, err := r.client.NetworkPolicies().List(ctx, options.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, p := range nps.Items {
+		if p.Spec.Tier == name {
+			return tierNotEmptyError(name, apiv3.KindNetworkPolicy, p.Namespace+"/"+p.Name)
+		}
+	}
+
+	sgnps, err := r.client.StagedGlobalNetworkPolicies().List(ctx, options.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, p := range sgnps.Items {
+		if p.Spec.Tier == name {
+			return tierNotEmptyError(name, apiv3.KindStagedGlobalNetworkPolicy, p.Name)
+		}
+	}
+
+	snps, err := r.client.StagedNetworkPolicies().List(ctx, options.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, p := range snps.Items {
+		if p.Spec.Tier == name {
+			return tierNotEmptyError(name, apiv3.KindStagedNetworkPolicy, p.Namespace+"/"+p.Name)
+		}
+	}
+	return nil
+}
+
+func tierNotEmptyError(name, kind, policy string) error {
+	return cerrors.ErrorOperationNotSupported{
+		Operation:  "Delete",
+		Identifier: name,
+		Reason:     fmt.Sprintf("The tier still contains policies (%s %s)", kind, policy),
+	}
+}
+
+// checkPolicyTier returns an error if the policy tier is not the default tier and does
+// not exist.
+func checkPolicyTier(ctx context.Context, c client, tier string) error {
+	if tier == "" || tier == apiv3.DefaultTierName {
+		return nil
+	}
+	if _, err := c.resources.Get(ctx, options.GetOptions{}, apiv3.KindTier, noNamespace, tier); err != nil {
+		if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok {
+			return cerrors.ErrorValidation{
+				ErroredFields: []cerrors.ErroredField{{
+					Name:   "Spec.Tier",
+					Reason: "tier does not exist",
+					Value:  tier,
+				}},
+			}
+		}
+		return err
+	}
+	return nil
+}
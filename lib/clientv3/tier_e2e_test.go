@@ -0,0 +1,175 @@
+// Copyright (c) 2016-2018 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientv3_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+var _ = testutils.E2eDatastoreDescribe("Tier tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	ctx := context.Background()
+	order1 := 100.0
+	order2 := 200.0
+	name1 := "platform"
+	name2 := "apps"
+	spec1 := apiv3.TierSpec{Order: &order1}
+	spec2 := apiv3.TierSpec{Order: &order2}
+
+	var c clientv3.Interface
+	BeforeEach(func() {
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+	})
+
+	It("should handle CRUD and watch of Tier resources", func() {
+		By("Creating a Tier with an invalid name")
+		_, outError := c.Tiers().Create(ctx, &apiv3.Tier{
+			ObjectMeta: metav1.ObjectMeta{Name: "plat.form"},
+			Spec:       spec1,
+		}, options.SetOptions{})
+		Expect(outError).To(HaveOccurred())
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorValidation{}))
+
+		By("Starting a watcher")
+		w, err := c.Tiers().Watch(ctx, options.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		testWatcher := testutils.NewTestResourceWatch(config.Spec.DatastoreType, w)
+		defer testWatcher.Stop()
+
+		By("Creating Tiers name1/spec1 and name2/spec2")
+		res1, outError := c.Tiers().Create(ctx, &apiv3.Tier{
+			ObjectMeta: metav1.ObjectMeta{Name: name1},
+			Spec:       spec1,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindTier, testutils.ExpectNoNamespace, name1, spec1))
+
+		res2, outError := c.Tiers().Create(ctx, &apiv3.Tier{
+			ObjectMeta: metav1.ObjectMeta{Name: name2},
+			Spec:       spec2,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res2).To(MatchResource(apiv3.KindTier, testutils.ExpectNoNamespace, name2, spec2))
+
+		By("Getting and listing the Tiers")
+		res, outError := c.Tiers().Get(ctx, name1, options.GetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res).To(MatchResource(apiv3.KindTier, testutils.ExpectNoNamespace, name1, spec1))
+
+		outList, outError := c.Tiers().List(ctx, options.ListOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(outList.Items).To(ConsistOf(
+			testutils.Resource(apiv3.KindTier, testutils.ExpectNoNamespace, name1, spec1),
+			testutils.Resource(apiv3.KindTier, testutils.ExpectNoNamespace, name2, spec2),
+		))
+
+		By("Updating Tier name1 with spec2")
+		created1 := res1.DeepCopy()
+		res1.Spec = spec2
+		res1, outError = c.Tiers().Update(ctx, res1, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(res1).To(MatchResource(apiv3.KindTier, testutils.ExpectNoNamespace, name1, spec2))
+
+		By("Deleting Tier name2")
+		_, outError = c.Tiers().Delete(ctx, name2, options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+
+		By("Checking the watcher received the events")
+		testWatcher.ExpectEvents(apiv3.KindTier, []watch.Event{
+			{Type: watch.Added, Object: created1},
+			{Type: watch.Added, Object: res2},
+			{Type: watch.Modified, Previous: created1, Object: res1},
+			{Type: watch.Deleted, Previous: res2},
+		})
+	})
+
+	It("should only allow policies in a tier that exists", func() {
+		By("Creating a GlobalNetworkPolicy in a tier that does not exist")
+		_, outError := c.GlobalNetworkPolicies().Create(ctx, &apiv3.GlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform.policy"},
+			Spec:       apiv3.GlobalNetworkPolicySpec{Tier: name1},
+		}, options.SetOptions{})
+		Expect(outError).To(HaveOccurred())
+		Expect(outError.Error()).To(ContainSubstring("tier does not exist"))
+
+		By("Creating a NetworkPolicy in a tier that does not exist")
+		_, outError = c.NetworkPolicies().Create(ctx, &apiv3.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform.policy", Namespace: "default"},
+			Spec:       apiv3.NetworkPolicySpec{Tier: name1},
+		}, options.SetOptions{})
+		Expect(outError).To(HaveOccurred())
+		Expect(outError.Error()).To(ContainSubstring("tier does not exist"))
+
+		By("Creating the tier and policies in the tier")
+		_, outError = c.Tiers().Create(ctx, &apiv3.Tier{
+			ObjectMeta: metav1.ObjectMeta{Name: name1},
+			Spec:       spec1,
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+
+		gnp, outError := c.GlobalNetworkPolicies().Create(ctx, &apiv3.GlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform.policy"},
+			Spec:       apiv3.GlobalNetworkPolicySpec{Tier: name1},
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(gnp.Name).To(Equal("platform.policy"))
+
+		np, outError := c.NetworkPolicies().Create(ctx, &apiv3.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform.policy", Namespace: "default"},
+			Spec:       apiv3.NetworkPolicySpec{Tier: name1},
+		}, options.SetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(np.Name).To(Equal("platform.policy"))
+
+		By("Getting the policies by name")
+		gnp, outError = c.GlobalNetworkPolicies().Get(ctx, "platform.policy", options.GetOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		Expect(gnp.Spec.Tier).To(Equal(name1))
+
+		By("Attempting to delete the tier while it contains policies")
+		_, outError = c.Tiers().Delete(ctx, name1, options.DeleteOptions{})
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+
+		_, outError = c.GlobalNetworkPolicies().Delete(ctx, "platform.policy", options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		_, outError = c.Tiers().Delete(ctx, name1, options.DeleteOptions{})
+		Expect(outError).To(BeAssignableToTypeOf(cerrors.ErrorOperationNotSupported{}))
+
+		By("Deleting the tier once it is empty")
+		_, outError = c.NetworkPolicies().Delete(ctx, "default", "platform.policy", options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+		_, outError = c.Tiers().Delete(ctx, name1, options.DeleteOptions{})
+		Expect(outError).NotTo(HaveOccurred())
+	})
+})
@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 	"github.com/projectcalico/libcalico-go/lib/errors"
 	"github.com/projectcalico/libcalico-go/lib/names"
@@ -34,6 +36,7 @@ type WorkloadEndpointInterface interface {
 	Get(ctx context.Context, namespace, name string, opts options.GetOptions) (*apiv3.WorkloadEndpoint, error)
 	List(ctx context.Context, opts options.ListOptions) (*apiv3.WorkloadEndpointList, error)
 	Watch(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.WorkloadEndpoint, error)
 }
 
 // workloadEndpoints implements WorkloadEndpointInterface
@@ -119,6 +122,26 @@ func (r workloadEndpoints) Watch(ctx context.Context, opts options.ListOptions)
 	return r.client.resources.Watch(ctx, opts, apiv3.KindWorkloadEndpoint, nil)
 }
 
+// Patch applies a JSON merge patch or JSON patch to the named WorkloadEndpoint. Returns the stored
+// representation of the patched WorkloadEndpoint, and an error if there is any.
+func (r workloadEndpoints) Patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte, opts options.PatchOptions) (*apiv3.WorkloadEndpoint, error) {
+	out, err := r.client.resources.Patch(ctx, opts, pt, data, patcher{
+		kind:      apiv3.KindWorkloadEndpoint,
+		namespace: namespace,
+		name:      name,
+		get: func(ctx context.Context) (resource, error) {
+			return r.Get(ctx, namespace, name, options.GetOptions{})
+		},
+		update: func(ctx context.Context, res resource) (resource, error) {
+			return r.Update(ctx, res.(*apiv3.WorkloadEndpoint), options.SetOptions{})
+		},
+	})
+	if out != nil {
+		return out.(*apiv3.WorkloadEndpoint), err
+	}
+	return nil, err
+}
+
 // assignOrValidateName either assigns the name calculated from the Spec fields, or validates
 // the name against the spec fields.
 func (r workloadEndpoints) assignOrValidateName(res *apiv3.WorkloadEndpoint) error {
@@ -65,6 +65,17 @@ func (e ErrorResourceAlreadyExists) Error() string {
 	return fmt.Sprintf("resource already exists: %v", e.Identifier)
 }
 
+// Error indicating a resource is still in use.  Used when attempting to remove a
+// resource that other resources still depend on.
+type ErrorResourceInUse struct {
+	Identifier interface{}
+	Reason     string
+}
+
+func (e ErrorResourceInUse) Error() string {
+	return fmt.Sprintf("resource is in use: %v: %s", e.Identifier, e.Reason)
+}
+
 // Error indicating a problem connecting to the backend.
 type ErrorConnectionUnauthorized struct {
 	Err error
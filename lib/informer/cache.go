@@ -0,0 +1,258 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+const (
+	// NamespaceIndex is the name of the index of resources by namespace.
+	NamespaceIndex = "namespace"
+	// NodeIndex is the name of the index of resources by node.
+	NodeIndex = "node"
+)
+
+// IndexFunc returns the values under which a resource is indexed.
+type IndexFunc func(obj runtime.Object) []string
+
+// Indexers maps the name of an index to the function that computes its values.
+type Indexers map[string]IndexFunc
+
+// DefaultIndexers returns the namespace and node indexers.
+func DefaultIndexers() Indexers {
+	return Indexers{
+		NamespaceIndex: IndexByNamespace,
+		NodeIndex:      IndexByNode,
+	}
+}
+
+// IndexByNamespace indexes a namespaced resource by its namespace.
+func IndexByNamespace(obj runtime.Object) []string {
+	if m, err := meta.Accessor(obj); err == nil && m.GetNamespace() != "" {
+		return []string{m.GetNamespace()}
+	}
+	return nil
+}
+
+// IndexByNode indexes a resource by the node that it belongs to: the node of a
+// WorkloadEndpoint, HostEndpoint or BGPPeer, or the name of a Node.
+func IndexByNode(obj runtime.Object) []string {
+	var node string
+	switch r := obj.(type) {
+	case *apiv3.WorkloadEndpoint:
+		node = r.Spec.Node
+	case *apiv3.HostEndpoint:
+		node = r.Spec.Node
+	case *apiv3.BGPPeer:
+		node = r.Spec.Node
+	case *apiv3.Node:
+		node = r.Name
+	}
+	if node == "" {
+		return nil
+	}
+	return []string{node}
+}
+
+// IndexByLabel returns an IndexFunc that indexes a resource by the value of one of its labels.
+func IndexByLabel(label string) IndexFunc {
+	return func(obj runtime.Object) []string {
+		if m, err := meta.Accessor(obj); err == nil {
+			if value, ok := m.GetLabels()[label]; ok {
+				return []string{value}
+			}
+		}
+		return nil
+	}
+}
+
+// Lister reads resources from the local cache of an Informer.  The returned resources are
+// shared with the cache, and must not be modified.
+type Lister interface {
+	// Get returns the named resource.  The namespace is empty for a resource that is not
+	// namespaced.
+	Get(namespace, name string) (runtime.Object, bool)
+	// List returns every resource, ordered by namespace and name.
+	List() []runtime.Object
+	// ByIndex returns the resources with the given value in the named index, ordered by
+	// namespace and name.
+	ByIndex(index, value string) ([]runtime.Object, error)
+}
+
+// cache is an indexed store of resources, keyed by namespace and name.
+type cache struct {
+	lock     sync.RWMutex
+	items    map[string]runtime.Object
+	indexers Indexers
+	// indices maps the name of an index to the keys of the resources for each index value.
+	indices map[string]map[string]set.Set
+}
+
+func newCache(indexers Indexers) *cache {
+	c := &cache{
+		items:    map[string]runtime.Object{},
+		indexers: Indexers{},
+		indices:  map[string]map[string]set.Set{},
+	}
+	c.addIndexers(indexers)
+	return c
+}
+
+// keyFor returns the cache key of a resource.
+func keyFor(obj runtime.Object) string {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return key(m.GetNamespace(), m.GetName())
+}
+
+func key(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+func (c *cache) Get(namespace, name string) (runtime.Object, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	obj, ok := c.items[key(namespace, name)]
+	return obj, ok
+}
+
+func (c *cache) List() []runtime.Object {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	keys := make([]string, 0, len(c.items))
+	for k := range c.items {
+		keys = append(keys, k)
+	}
+	return c.sorted(keys)
+}
+
+func (c *cache) ByIndex(index, value string) ([]runtime.Object, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	values, ok := c.indices[index]
+	if !ok {
+		return nil, fmt.Errorf("index %q does not exist", index)
+	}
+	var keys []string
+	if s, ok := values[value]; ok {
+		s.Iter(func(item interface{}) error {
+			keys = append(keys, item.(string))
+			return nil
+		})
+	}
+	return c.sorted(keys), nil
+}
+
+// sorted returns the resources with the given keys, ordered by key.  The caller must hold the
+// lock.
+func (c *cache) sorted(keys []string) []runtime.Object {
+	sort.Strings(keys)
+	objs := make([]runtime.Object, 0, len(keys))
+	for _, k := range keys {
+		objs = append(objs, c.items[k])
+	}
+	return objs
+}
+
+// addIndexers adds indexers to the cache, and indexes the current resources.  An indexer
+// replaces an existing indexer of the same name.
+func (c *cache) addIndexers(indexers Indexers) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for name, f := range indexers {
+		c.indexers[name] = f
+		c.indices[name] = map[string]set.Set{}
+		for k, obj := range c.items {
+			c.indexOne(name, k, obj)
+		}
+	}
+}
+
+// update adds or replaces a resource, returning the previous resource if there was one.
+func (c *cache) update(obj runtime.Object) (runtime.Object, bool) {
+	k := keyFor(obj)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	old, exists := c.items[k]
+	if exists {
+		c.unindex(k, old)
+	}
+	c.items[k] = obj
+	for name := range c.indexers {
+		c.indexOne(name, k, obj)
+	}
+	return old, exists
+}
+
+// delete removes the resource with the given key, returning it if it was present.
+func (c *cache) delete(k string) (runtime.Object, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	old, exists := c.items[k]
+	if exists {
+		c.unindex(k, old)
+		delete(c.items, k)
+	}
+	return old, exists
+}
+
+// keys returns the keys of all the resources in the cache.
+func (c *cache) keys() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	keys := make([]string, 0, len(c.items))
+	for k := range c.items {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (c *cache) indexOne(name, k string, obj runtime.Object) {
+	values := c.indices[name]
+	for _, v := range c.indexers[name](obj) {
+		if values[v] == nil {
+			values[v] = set.New()
+		}
+		values[v].Add(k)
+	}
+}
+
+func (c *cache) unindex(k string, obj runtime.Object) {
+	for name, f := range c.indexers {
+		values := c.indices[name]
+		for _, v := range f(obj) {
+			if s, ok := values[v]; ok {
+				s.Discard(k)
+				if s.Len() == 0 {
+					delete(values, v)
+				}
+			}
+		}
+	}
+}
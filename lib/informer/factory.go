@@ -0,0 +1,106 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+)
+
+// cacheSyncPollInterval is the interval at which WaitForCacheSync checks the informers.
+var cacheSyncPollInterval = 100 * time.Millisecond
+
+// SharedInformerFactory creates a single Informer for each kind, so that the controllers in a
+// process can share the same cache and watch.
+type SharedInformerFactory struct {
+	client       clientv3.Interface
+	resyncPeriod time.Duration
+
+	lock      sync.Mutex
+	informers map[string]*Informer
+	started   map[string]bool
+}
+
+// NewSharedInformerFactory creates a SharedInformerFactory for the client.  The informers use
+// the resync period, and are created with the DefaultIndexers.
+func NewSharedInformerFactory(c clientv3.Interface, resyncPeriod time.Duration) *SharedInformerFactory {
+	return &SharedInformerFactory{
+		client:       c,
+		resyncPeriod: resyncPeriod,
+		informers:    map[string]*Informer{},
+		started:      map[string]bool{},
+	}
+}
+
+// InformerFor returns the Informer for the kind, creating it if required.  Additional indexers
+// may be added to the returned Informer.
+func (f *SharedInformerFactory) InformerFor(kind string) (*Informer, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if i, ok := f.informers[kind]; ok {
+		return i, nil
+	}
+	lw, err := NewListWatch(f.client, kind)
+	if err != nil {
+		return nil, err
+	}
+	i := NewInformer(lw, f.resyncPeriod, DefaultIndexers())
+	f.informers[kind] = i
+	return i, nil
+}
+
+// Start runs each Informer that has not already been started, until the context is done.
+func (f *SharedInformerFactory) Start(ctx context.Context) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for kind, i := range f.informers {
+		if !f.started[kind] {
+			go i.Run(ctx)
+			f.started[kind] = true
+		}
+	}
+}
+
+// WaitForCacheSync waits until every Informer has synced, returning false if the context is
+// done first.
+func (f *SharedInformerFactory) WaitForCacheSync(ctx context.Context) bool {
+	f.lock.Lock()
+	informers := make([]*Informer, 0, len(f.informers))
+	for _, i := range f.informers {
+		informers = append(informers, i)
+	}
+	f.lock.Unlock()
+	return WaitForCacheSync(ctx, informers...)
+}
+
+// WaitForCacheSync waits until the informers have synced, returning false if the context is
+// done first.
+func WaitForCacheSync(ctx context.Context, informers ...*Informer) bool {
+	for {
+		synced := true
+		for _, i := range informers {
+			synced = synced && i.HasSynced()
+		}
+		if synced {
+			return true
+		}
+		if !sleep(ctx, cacheSyncPollInterval) {
+			return false
+		}
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package informer maintains local caches of Calico resources using the clientv3 List and Watch
+methods, so that controllers do not each need to handle listing, watching and reconnecting.
+
+An Informer caches the resources of one kind, indexes them with the supplied Indexers, and
+notifies its EventHandlers of changes.  A SharedInformerFactory shares one Informer for each
+kind between the controllers in a process:
+
+	factory := informer.NewSharedInformerFactory(client, 10*time.Minute)
+	heps, err := factory.InformerFor(apiv3.KindHostEndpoint)
+	...
+	heps.AddEventHandler(informer.EventHandlerFuncs{AddFunc: onAdd})
+	factory.Start(ctx)
+	factory.WaitForCacheSync(ctx)
+	onNode, err := heps.Lister().ByIndex(informer.NodeIndex, "node1")
+*/
+package informer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+var (
+	ListRetryInterval = 1000 * time.Millisecond
+	WatchPollInterval = 5000 * time.Millisecond
+)
+
+// EventHandler is notified of changes to the resources in the cache of an Informer.  The
+// resources are shared with the cache, and must not be modified.  The handlers of an Informer
+// are called in turn from a single goroutine, so they should not block.
+type EventHandler interface {
+	OnAdd(obj runtime.Object)
+	// OnUpdate is called when a resource is modified, and for every resource in the cache when
+	// the Informer resyncs, in which case old and new are the same resource.
+	OnUpdate(old, new runtime.Object)
+	// OnDelete is called with the last known state of a deleted resource.
+	OnDelete(obj runtime.Object)
+}
+
+// EventHandlerFuncs implements EventHandler with optional functions.
+type EventHandlerFuncs struct {
+	AddFunc    func(obj runtime.Object)
+	UpdateFunc func(old, new runtime.Object)
+	DeleteFunc func(obj runtime.Object)
+}
+
+func (f EventHandlerFuncs) OnAdd(obj runtime.Object) {
+	if f.AddFunc != nil {
+		f.AddFunc(obj)
+	}
+}
+
+func (f EventHandlerFuncs) OnUpdate(old, new runtime.Object) {
+	if f.UpdateFunc != nil {
+		f.UpdateFunc(old, new)
+	}
+}
+
+func (f EventHandlerFuncs) OnDelete(obj runtime.Object) {
+	if f.DeleteFunc != nil {
+		f.DeleteFunc(obj)
+	}
+}
+
+// Informer maintains an indexed local cache of the resources of one kind by listing and
+// watching them, and notifies its event handlers of changes to the cache.
+//
+// The watch is recreated from the last known revision if it is closed.  If the watch reports an
+// error, or cannot be recreated, the resources are listed again and the cache is resynced:
+// handlers are notified of the resources that were added, modified or deleted in the
+// meantime.  If the kind cannot be watched, the Informer polls by listing the resources every
+// WatchPollInterval.
+type Informer struct {
+	logger       *logrus.Entry
+	lw           *ListWatch
+	resyncPeriod time.Duration
+	cache        *cache
+
+	// lock serialises the updates to the cache with their notifications, and with the
+	// registration of handlers.
+	lock     sync.Mutex
+	handlers []EventHandler
+	synced   bool
+
+	watch    watch.Interface
+	revision string
+}
+
+// NewInformer creates an Informer for the ListWatch.  If the resync period is not zero, the
+// handlers are notified of every resource in the cache at that interval.
+func NewInformer(lw *ListWatch, resyncPeriod time.Duration, indexers Indexers) *Informer {
+	return &Informer{
+		logger:       logrus.WithField("Kind", lw.Kind),
+		lw:           lw,
+		resyncPeriod: resyncPeriod,
+		cache:        newCache(indexers),
+	}
+}
+
+// Lister returns the Lister for the cache of the Informer.
+func (i *Informer) Lister() Lister {
+	return i.cache
+}
+
+// AddIndexers adds indexers to the cache of the Informer.  An indexer replaces an existing
+// indexer of the same name.
+func (i *Informer) AddIndexers(indexers Indexers) {
+	i.cache.addIndexers(indexers)
+}
+
+// AddEventHandler adds a handler to the Informer.  The handler is notified of the resources
+// that are already in the cache as added resources.
+func (i *Informer) AddEventHandler(h EventHandler) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.handlers = append(i.handlers, h)
+	for _, obj := range i.cache.List() {
+		h.OnAdd(obj)
+	}
+}
+
+// HasSynced returns true once the Informer has listed the resources, and so the cache is
+// complete.
+func (i *Informer) HasSynced() bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.synced
+}
+
+// Run lists and watches the resources until the context is done.
+func (i *Informer) Run(ctx context.Context) {
+	i.logger.Debug("Informer starting, start initial sync processing")
+	i.resyncAndCreateWatcher(ctx)
+
+	var resync <-chan time.Time
+	if i.resyncPeriod > 0 {
+		ticker := time.NewTicker(i.resyncPeriod)
+		defer ticker.Stop()
+		resync = ticker.C
+	}
+
+	for {
+		if i.watch == nil {
+			// The watcher will be nil if the context was cancelled during a resync.
+			i.logger.Debug("Watch is nil. Returning")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			i.logger.Debug("Context is done. Returning")
+			i.cleanExistingWatcher()
+			return
+		case <-resync:
+			i.resync()
+		case event, ok := <-i.watch.ResultChan():
+			if !ok {
+				// If the channel is closed then recreate the watch.
+				i.logger.Info("Watch channel closed - recreate watcher")
+				i.resyncAndCreateWatcher(ctx)
+				continue
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				i.handleAddedOrModified(event.Object)
+			case watch.Deleted:
+				i.handleDeleted(event.Previous)
+			case watch.Error:
+				// All errors are treated equally: log the error and trigger a full resync.
+				i.logger.WithError(event.Error).Warning("Watch error received")
+				i.revision = ""
+				i.resyncAndCreateWatcher(ctx)
+			default:
+				i.logger.WithField("EventType", event.Type).Error("Unknown event type received")
+			}
+		}
+	}
+}
+
+// resyncAndCreateWatcher loops listing the resources, if there is no current revision to
+// watch from, and creating the watcher, until it succeeds or the context is done.
+func (i *Informer) resyncAndCreateWatcher(ctx context.Context) {
+	i.cleanExistingWatcher()
+	performFullResync := i.revision == ""
+
+	for {
+		if ctx.Err() != nil {
+			i.logger.Debug("Context is done. Returning")
+			return
+		}
+
+		if performFullResync {
+			i.logger.Debug("Full resync is required")
+			l, err := i.lw.List(ctx, options.ListOptions{})
+			if err != nil {
+				i.logger.WithError(err).Info("Failed to list resources during resync")
+				if !sleep(ctx, ListRetryInterval) {
+					return
+				}
+				continue
+			}
+			if err := i.replace(l); err != nil {
+				i.logger.WithError(err).Error("Failed to process list of resources during resync")
+				if !sleep(ctx, ListRetryInterval) {
+					return
+				}
+				continue
+			}
+		}
+
+		w, err := i.lw.Watch(ctx, options.ListOptions{ResourceVersion: i.revision})
+		if err != nil {
+			switch err.(type) {
+			case cerrors.ErrorOperationNotSupported, cerrors.ErrorResourceDoesNotExist:
+				// The kind cannot be watched, at least for now, so poll it.
+				i.logger.Debug("Watch operation not supported")
+				if !sleep(ctx, WatchPollInterval) {
+					return
+				}
+			default:
+				i.logger.WithError(err).Info("Failed to create watcher")
+				if !sleep(ctx, ListRetryInterval) {
+					return
+				}
+			}
+			performFullResync = true
+			continue
+		}
+
+		i.logger.Debug("Resync completed, now watching for change events")
+		i.watch = w
+		return
+	}
+}
+
+func (i *Informer) cleanExistingWatcher() {
+	if i.watch != nil {
+		i.logger.Debug("Stopping previous watcher")
+		i.watch.Stop()
+		i.watch = nil
+	}
+}
+
+// replace updates the cache to hold the listed resources, notifying the handlers of the
+// resources that were added, modified or deleted.
+func (i *Informer) replace(l runtime.Object) error {
+	items, err := meta.ExtractList(l)
+	if err != nil {
+		return err
+	}
+	lm, err := meta.ListAccessor(l)
+	if err != nil {
+		return err
+	}
+
+	listed := map[string]bool{}
+	for _, obj := range items {
+		listed[keyFor(obj)] = true
+		i.handleAddedOrModified(obj)
+	}
+	for _, k := range i.cache.keys() {
+		if !listed[k] {
+			i.handleDeletedKey(k)
+		}
+	}
+	i.revision = lm.GetResourceVersion()
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if !i.synced {
+		i.logger.Info("Informer has synced")
+		i.synced = true
+	}
+	return nil
+}
+
+// handleAddedOrModified updates the cache with a resource and notifies the handlers, unless
+// the resource revision is already cached.
+func (i *Informer) handleAddedOrModified(obj runtime.Object) {
+	rv := resourceVersion(obj)
+	if rv != "" {
+		i.revision = rv
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if cached, ok := i.cache.Get(namespaceAndName(obj)); ok && resourceVersion(cached) == rv {
+		i.logger.WithField("Key", keyFor(obj)).Debug("Swallowing event for cached revision")
+		return
+	}
+	old, exists := i.cache.update(obj)
+	for _, h := range i.handlers {
+		if exists {
+			h.OnUpdate(old, obj)
+		} else {
+			h.OnAdd(obj)
+		}
+	}
+}
+
+// handleDeleted removes a deleted resource from the cache and notifies the handlers.
+func (i *Informer) handleDeleted(obj runtime.Object) {
+	if obj == nil {
+		i.logger.Warning("Deletion event without previous value")
+		return
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if _, exists := i.cache.delete(keyFor(obj)); exists {
+		for _, h := range i.handlers {
+			h.OnDelete(obj)
+		}
+	}
+}
+
+// handleDeletedKey removes a resource that was not listed during a resync from the cache and
+// notifies the handlers of its last known state.
+func (i *Informer) handleDeletedKey(k string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if old, exists := i.cache.delete(k); exists {
+		for _, h := range i.handlers {
+			h.OnDelete(old)
+		}
+	}
+}
+
+// resync notifies the handlers of every resource in the cache.
+func (i *Informer) resync() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.logger.Debug("Resyncing handlers")
+	for _, obj := range i.cache.List() {
+		for _, h := range i.handlers {
+			h.OnUpdate(obj, obj)
+		}
+	}
+}
+
+func resourceVersion(obj runtime.Object) string {
+	if m, err := meta.Accessor(obj); err == nil {
+		return m.GetResourceVersion()
+	}
+	return ""
+}
+
+func namespaceAndName(obj runtime.Object) (string, string) {
+	if m, err := meta.Accessor(obj); err == nil {
+		return m.GetNamespace(), m.GetName()
+	}
+	return "", ""
+}
+
+// sleep waits for the duration, returning false if the context is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	"github.com/projectcalico/libcalico-go/lib/informer"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = testutils.E2eDatastoreDescribe("Shared informer tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		c      clientv3.Interface
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		var err error
+		c, err = clientv3.New(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		be, err := backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		be.Clean()
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should cache and notify changes to the resources", func() {
+		_, err := c.HostEndpoints().Create(ctx, hostEndpoint("hep-1", "node1", ""), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		factory := informer.NewSharedInformerFactory(c, time.Hour)
+		inf, err := factory.InformerFor(apiv3.KindHostEndpoint)
+		Expect(err).NotTo(HaveOccurred())
+		shared, err := factory.InformerFor(apiv3.KindHostEndpoint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shared).To(BeIdenticalTo(inf))
+		_, err = factory.InformerFor("NoSuchKind")
+		Expect(err).To(HaveOccurred())
+
+		rec := &recorder{}
+		inf.AddEventHandler(rec)
+		factory.Start(ctx)
+		Expect(factory.WaitForCacheSync(ctx)).To(BeTrue())
+		Expect(names(inf.Lister().List())).To(Equal([]string{"hep-1"}))
+
+		hep2, err := c.HostEndpoints().Create(ctx, hostEndpoint("hep-2", "node2", ""), options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() []string {
+			objs, _ := inf.Lister().ByIndex(informer.NodeIndex, "node2")
+			return names(objs)
+		}).Should(Equal([]string{"hep-2"}))
+
+		hep2.Spec.Node = "node1"
+		_, err = c.HostEndpoints().Update(ctx, hep2, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() []string {
+			objs, _ := inf.Lister().ByIndex(informer.NodeIndex, "node1")
+			return names(objs)
+		}).Should(Equal([]string{"hep-1", "hep-2"}))
+
+		_, err = c.HostEndpoints().Delete(ctx, "hep-1", options.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() []string {
+			return names(inf.Lister().List())
+		}).Should(Equal([]string{"hep-2"}))
+
+		// The resource versions depend on the datastore, so only check the event types.
+		var events []string
+		for _, e := range rec.Events() {
+			events = append(events, e[:strings.LastIndex(e, " ")])
+		}
+		Expect(events).To(Equal([]string{"add hep-1", "add hep-2", "update hep-2", "delete hep-1"}))
+	})
+})
@@ -0,0 +1,271 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/informer"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+func hostEndpoint(name, node, rv string) *apiv3.HostEndpoint {
+	return &apiv3.HostEndpoint{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: rv, Labels: map[string]string{"node": node}},
+		Spec:       apiv3.HostEndpointSpec{Node: node, InterfaceName: "eth0"},
+	}
+}
+
+// fakeWatch is a watch.Interface whose events are sent by the test.
+type fakeWatch struct {
+	opts   options.ListOptions
+	events chan watch.Event
+}
+
+func (w *fakeWatch) Stop() {}
+
+func (w *fakeWatch) ResultChan() <-chan watch.Event {
+	return w.events
+}
+
+// fakeListWatch lists the HostEndpoints that the test sets, and hands each watch that it
+// creates to the test.
+type fakeListWatch struct {
+	lock    sync.Mutex
+	items   []apiv3.HostEndpoint
+	rv      string
+	listErr error
+	lists   int
+	watches chan *fakeWatch
+}
+
+func (f *fakeListWatch) set(rv string, heps ...*apiv3.HostEndpoint) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.rv = rv
+	f.items = nil
+	for _, hep := range heps {
+		f.items = append(f.items, *hep)
+	}
+}
+
+func (f *fakeListWatch) listCount() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.lists
+}
+
+func (f *fakeListWatch) listWatch() *informer.ListWatch {
+	return &informer.ListWatch{
+		Kind: apiv3.KindHostEndpoint,
+		List: func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			f.lock.Lock()
+			defer f.lock.Unlock()
+			f.lists++
+			if f.listErr != nil {
+				return nil, f.listErr
+			}
+			l := &apiv3.HostEndpointList{Items: append([]apiv3.HostEndpoint{}, f.items...)}
+			l.ResourceVersion = f.rv
+			return l, nil
+		},
+		Watch: func(ctx context.Context, opts options.ListOptions) (watch.Interface, error) {
+			w := &fakeWatch{opts: opts, events: make(chan watch.Event, 10)}
+			f.watches <- w
+			return w, nil
+		},
+	}
+}
+
+// recorder records the events that it is notified of.
+type recorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string, obj runtime.Object) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	hep := obj.(*apiv3.HostEndpoint)
+	r.events = append(r.events, fmt.Sprintf("%s %s %s", event, hep.Name, hep.ResourceVersion))
+}
+
+func (r *recorder) OnAdd(obj runtime.Object) {
+	r.record("add", obj)
+}
+
+func (r *recorder) OnUpdate(old, new runtime.Object) {
+	if old == new {
+		r.record("resync", new)
+		return
+	}
+	r.record("update", new)
+}
+
+func (r *recorder) OnDelete(obj runtime.Object) {
+	r.record("delete", obj)
+}
+
+func (r *recorder) Events() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func names(objs []runtime.Object) []string {
+	var n []string
+	for _, obj := range objs {
+		n = append(n, obj.(*apiv3.HostEndpoint).Name)
+	}
+	return n
+}
+
+var _ = Describe("Informer", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		flw    *fakeListWatch
+		rec    *recorder
+		inf    *informer.Informer
+	)
+
+	BeforeEach(func() {
+		informer.ListRetryInterval = 10 * time.Millisecond
+		ctx, cancel = context.WithCancel(context.Background())
+		flw = &fakeListWatch{watches: make(chan *fakeWatch, 10)}
+		flw.set("10", hostEndpoint("hep-1", "node1", "1"), hostEndpoint("hep-2", "node1", "2"))
+		rec = &recorder{}
+		inf = informer.NewInformer(flw.listWatch(), 0, informer.DefaultIndexers())
+		inf.AddEventHandler(rec)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	start := func() *fakeWatch {
+		go inf.Run(ctx)
+		var w *fakeWatch
+		Eventually(flw.watches).Should(Receive(&w))
+		Expect(inf.HasSynced()).To(BeTrue())
+		return w
+	}
+
+	It("should list and then watch the resources", func() {
+		w := start()
+		Expect(w.opts.ResourceVersion).To(Equal("10"))
+		Expect(rec.Events()).To(Equal([]string{"add hep-1 1", "add hep-2 2"}))
+
+		w.events <- watch.Event{Type: watch.Added, Object: hostEndpoint("hep-3", "node2", "11")}
+		w.events <- watch.Event{Type: watch.Modified, Previous: hostEndpoint("hep-1", "node1", "1"), Object: hostEndpoint("hep-1", "node2", "12")}
+		w.events <- watch.Event{Type: watch.Deleted, Previous: hostEndpoint("hep-2", "node1", "2")}
+		Eventually(rec.Events).Should(Equal([]string{"add hep-3 11", "update hep-1 12", "delete hep-2 2"}))
+
+		Expect(names(inf.Lister().List())).To(Equal([]string{"hep-1", "hep-3"}))
+		obj, ok := inf.Lister().Get("", "hep-1")
+		Expect(ok).To(BeTrue())
+		Expect(obj.(*apiv3.HostEndpoint).ResourceVersion).To(Equal("12"))
+		_, ok = inf.Lister().Get("", "hep-2")
+		Expect(ok).To(BeFalse())
+
+		onNode, err := inf.Lister().ByIndex(informer.NodeIndex, "node2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(onNode)).To(Equal([]string{"hep-1", "hep-3"}))
+		onNode, err = inf.Lister().ByIndex(informer.NodeIndex, "node1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(onNode).To(BeEmpty())
+		_, err = inf.Lister().ByIndex("no-such-index", "node1")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should ignore events for revisions that are already cached", func() {
+		w := start()
+		rec.Events()
+		w.events <- watch.Event{Type: watch.Modified, Object: hostEndpoint("hep-1", "node1", "1")}
+		w.events <- watch.Event{Type: watch.Deleted, Previous: hostEndpoint("hep-4", "node1", "3")}
+		w.events <- watch.Event{Type: watch.Added, Object: hostEndpoint("hep-3", "node1", "11")}
+		Eventually(rec.Events).Should(Equal([]string{"add hep-3 11"}))
+	})
+
+	It("should rewatch from the last revision when the watch is closed", func() {
+		w := start()
+		w.events <- watch.Event{Type: watch.Added, Object: hostEndpoint("hep-3", "node2", "11")}
+		close(w.events)
+
+		Eventually(flw.watches).Should(Receive(&w))
+		Expect(w.opts.ResourceVersion).To(Equal("11"))
+		Expect(flw.listCount()).To(Equal(1))
+	})
+
+	It("should relist and resync the cache after a watch error", func() {
+		w := start()
+		rec.Events()
+
+		flw.set("20", hostEndpoint("hep-1", "node2", "15"), hostEndpoint("hep-3", "node1", "16"))
+		flw.lock.Lock()
+		flw.listErr = errors.New("datastore unavailable")
+		flw.lock.Unlock()
+		w.events <- watch.Event{Type: watch.Error, Error: errors.New("watch failed")}
+		Eventually(flw.listCount).Should(BeNumerically(">", 2))
+
+		flw.lock.Lock()
+		flw.listErr = nil
+		flw.lock.Unlock()
+		Eventually(flw.watches).Should(Receive(&w))
+		Expect(w.opts.ResourceVersion).To(Equal("20"))
+		Expect(rec.Events()).To(Equal([]string{"update hep-1 15", "add hep-3 16", "delete hep-2 2"}))
+		Expect(names(inf.Lister().List())).To(Equal([]string{"hep-1", "hep-3"}))
+	})
+
+	It("should notify new handlers of the cached resources", func() {
+		start()
+		late := &recorder{}
+		inf.AddEventHandler(late)
+		Expect(late.Events()).To(Equal([]string{"add hep-1 1", "add hep-2 2"}))
+	})
+
+	It("should index resources with custom indexers", func() {
+		inf.AddIndexers(informer.Indexers{"by-node-label": informer.IndexByLabel("node")})
+		w := start()
+		w.events <- watch.Event{Type: watch.Added, Object: hostEndpoint("hep-3", "node2", "11")}
+		Eventually(func() []string {
+			objs, _ := inf.Lister().ByIndex("by-node-label", "node2")
+			return names(objs)
+		}).Should(Equal([]string{"hep-3"}))
+		objs, err := inf.Lister().ByIndex("by-node-label", "node1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(objs)).To(Equal([]string{"hep-1", "hep-2"}))
+	})
+
+	It("should notify the handlers of every resource at the resync period", func() {
+		inf = informer.NewInformer(flw.listWatch(), 50*time.Millisecond, nil)
+		inf.AddEventHandler(rec)
+		start()
+		Eventually(rec.Events).Should(ContainElement("resync hep-2 2"))
+	})
+})
@@ -0,0 +1,147 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informer
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/watch"
+)
+
+// ListFunc lists the resources of one kind, returning a v3 list such as a HostEndpointList.
+type ListFunc func(ctx context.Context, opts options.ListOptions) (runtime.Object, error)
+
+// WatchFunc watches the resources of one kind.
+type WatchFunc func(ctx context.Context, opts options.ListOptions) (watch.Interface, error)
+
+// ListWatch lists and watches the resources of one kind.
+type ListWatch struct {
+	Kind  string
+	List  ListFunc
+	Watch WatchFunc
+}
+
+// NewListWatch returns a ListWatch that lists and watches the resources of the kind using the
+// clientv3 client.
+func NewListWatch(c clientv3.Interface, kind string) (*ListWatch, error) {
+	var list ListFunc
+	var w WatchFunc
+	switch kind {
+	case apiv3.KindBGPConfiguration:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.BGPConfigurations().List(ctx, opts)
+		}
+		w = c.BGPConfigurations().Watch
+	case apiv3.KindBGPPeer:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.BGPPeers().List(ctx, opts)
+		}
+		w = c.BGPPeers().Watch
+	case apiv3.KindClusterInformation:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.ClusterInformation().List(ctx, opts)
+		}
+		w = c.ClusterInformation().Watch
+	case apiv3.KindFelixConfiguration:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.FelixConfigurations().List(ctx, opts)
+		}
+		w = c.FelixConfigurations().Watch
+	case apiv3.KindGlobalNetworkPolicy:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.GlobalNetworkPolicies().List(ctx, opts)
+		}
+		w = c.GlobalNetworkPolicies().Watch
+	case apiv3.KindGlobalNetworkSet:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.GlobalNetworkSets().List(ctx, opts)
+		}
+		w = c.GlobalNetworkSets().Watch
+	case apiv3.KindHostEndpoint:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.HostEndpoints().List(ctx, opts)
+		}
+		w = c.HostEndpoints().Watch
+	case apiv3.KindIPPool:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.IPPools().List(ctx, opts)
+		}
+		w = c.IPPools().Watch
+	case apiv3.KindIPReservation:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.IPReservations().List(ctx, opts)
+		}
+		w = c.IPReservations().Watch
+	case apiv3.KindKubeControllersConfiguration:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.KubeControllersConfiguration().List(ctx, opts)
+		}
+		w = c.KubeControllersConfiguration().Watch
+	case apiv3.KindNetworkPolicy:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.NetworkPolicies().List(ctx, opts)
+		}
+		w = c.NetworkPolicies().Watch
+	case apiv3.KindNetworkSet:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.NetworkSets().List(ctx, opts)
+		}
+		w = c.NetworkSets().Watch
+	case apiv3.KindNode:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.Nodes().List(ctx, opts)
+		}
+		w = c.Nodes().Watch
+	case apiv3.KindProfile:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.Profiles().List(ctx, opts)
+		}
+		w = c.Profiles().Watch
+	case apiv3.KindStagedGlobalNetworkPolicy:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.StagedGlobalNetworkPolicies().List(ctx, opts)
+		}
+		w = c.StagedGlobalNetworkPolicies().Watch
+	case apiv3.KindStagedKubernetesNetworkPolicy:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.StagedKubernetesNetworkPolicies().List(ctx, opts)
+		}
+		w = c.StagedKubernetesNetworkPolicies().Watch
+	case apiv3.KindStagedNetworkPolicy:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.StagedNetworkPolicies().List(ctx, opts)
+		}
+		w = c.StagedNetworkPolicies().Watch
+	case apiv3.KindTier:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.Tiers().List(ctx, opts)
+		}
+		w = c.Tiers().Watch
+	case apiv3.KindWorkloadEndpoint:
+		list = func(ctx context.Context, opts options.ListOptions) (runtime.Object, error) {
+			return c.WorkloadEndpoints().List(ctx, opts)
+		}
+		w = c.WorkloadEndpoints().Watch
+	default:
+		return nil, fmt.Errorf("cannot list and watch resources of kind %q", kind)
+	}
+	return &ListWatch{Kind: kind, List: list, Watch: w}, nil
+}
@@ -26,7 +26,8 @@ type Interface interface {
 	// must fall within a configured pool.  AssignIP will claim block affinity as needed
 	// in order to satisfy the assignment.  An error will be returned if the IP address
 	// is already assigned, or if StrictAffinity is enabled and the address is within
-	// a block that does not have affinity for the given host.
+	// a block that does not have affinity for the given host.  Addresses reserved by an
+	// IPReservation may be assigned explicitly.
 	AssignIP(ctx context.Context, args AssignIPArgs) error
 
 	// AutoAssign automatically assigns one or more IP addresses as specified by the
@@ -34,6 +35,9 @@ type Interface interface {
 	// and the list of the assigned IPv6 addresses in IPNet format.
 	// The returned IPNet represents the allocation block from which the IP was allocated,
 	// which is useful for dataplanes that need to know the subnet (such as Windows).
+	// Addresses reserved by an IPReservation are never automatically assigned.
+	// Addresses are chosen in the order given by the pool's AssignmentMode, and an address
+	// is not assigned again until the pool's ReleaseCooldown has passed since its release.
 	//
 	// In case of error, returns the IPs allocated so far along with the error.
 	AutoAssign(ctx context.Context, args AutoAssignArgs) ([]cnet.IPNet, []cnet.IPNet, error)
@@ -52,9 +56,21 @@ type Interface interface {
 
 	// ReleaseByHandle releases all IP addresses that have been assigned
 	// using the provided handle.  Returns an error if no addresses
-	// are assigned with the given handle.
+	// are assigned with the given handle.  Sticky addresses are not released,
+	// but stay reserved for the handle.
 	ReleaseByHandle(ctx context.Context, handleID string) error
 
+	// ReserveStickyIP reserves an IP address for the given handle, independently of
+	// any workload.  AutoAssign with the handle returns the reserved address on any
+	// host, borrowing it from a block affine to another host unless StrictAffinity is
+	// enabled, and ReleaseByHandle keeps it reserved for the handle.  Any further
+	// addresses that AutoAssign assigns to the handle are sticky too.
+	ReserveStickyIP(ctx context.Context, args ReserveStickyIPArgs) (*cnet.IP, error)
+
+	// ReleaseStickyIPs releases all IP addresses that have been assigned using the
+	// provided handle, including sticky addresses.
+	ReleaseStickyIPs(ctx context.Context, handleID string) error
+
 	// ClaimAffinity claims affinity to the given host for all blocks
 	// within the given CIDR.  The given CIDR must fall within a configured
 	// pool. If an empty string is passed as the host, then the value returned by os.Hostname is used.
@@ -96,9 +112,46 @@ type Interface interface {
 	// GetUtilization returns IP utilization info for the specified pools, or for all pools.
 	GetUtilization(ctx context.Context, args GetUtilizationArgs) ([]*PoolUtilization, error)
 
+	// GetUtilizationBreakdown returns IP utilization info for the specified pools, or for all
+	// pools, aggregated by affine host, by namespace and by handle prefix.  It also reports the
+	// IPs that were borrowed, i.e. allocated by a host other than the block's affinity.
+	GetUtilizationBreakdown(ctx context.Context, args GetUtilizationBreakdownArgs) (*UtilizationBreakdown, error)
+
+	// GetAllocations returns the IP addresses allocated within the given CIDR, sorted by IP.
+	// Addresses reserved by the blocks themselves are not included.
+	GetAllocations(ctx context.Context, cidr cnet.IPNet) ([]AllocatedIP, error)
+
+	// QueryAllocations returns the IP addresses that match all of the given criteria, together
+	// with their handle and attributes, sorted by IP.  Addresses reserved by the blocks themselves
+	// are not included.
+	QueryAllocations(ctx context.Context, args QueryAllocationsArgs) ([]AllocatedIP, error)
+
+	// ListHandles returns every handle that has IP addresses allocated with it, together with those
+	// addresses and their attributes, sorted by handle ID.
+	ListHandles(ctx context.Context) ([]HandleInfo, error)
+
+	// DefragmentBlocks identifies hosts that hold more affine blocks than they need for the
+	// addresses they have in use, and returns a plan to consolidate them.  If args.Execute is true,
+	// affinity is released for the surplus blocks that are empty, so other hosts can claim them.
+	DefragmentBlocks(ctx context.Context, args DefragmentBlocksArgs) (*DefragmentBlocksPlan, error)
+
 	// EnsureBlock returns single IPv4/IPv6 IPAM block for a host as specified by the provided BlockArgs.
 	// If there is no block allocated already for this host, allocate one and return its' CIDR.
 	// Otherwise, return the CIDR of the IPAM block allocated for this host.
 	// It returns IPv4, IPv6 block CIDR and any error encountered.
 	EnsureBlock(ctx context.Context, args BlockArgs) (*cnet.IPNet, *cnet.IPNet, error)
+
+	// CheckConsistency scans all allocation blocks, handles and block affinities and reports
+	// IP addresses whose handle is not used by any WorkloadEndpoint, handles whose counts
+	// disagree with the blocks, affinities for hosts that have no Node, empty blocks that
+	// are not affine to any host, and assigned addresses whose attributes are missing from
+	// their block.  If args.Repair is true, the inconsistencies are also repaired, except
+	// for the addresses with missing attributes.
+	//
+	// The check is not atomic with respect to other IPAM operations.  An address that is
+	// being assigned while the check runs may be reported as leaked, since the workload's
+	// WorkloadEndpoint is only created after its address is assigned.  When repairing, the
+	// check is therefore repeated after args.GracePeriod, and only the leaked addresses and
+	// handle mismatches that are found unchanged by both checks are repaired.
+	CheckConsistency(ctx context.Context, args CheckConsistencyArgs) (*ConsistencyReport, error)
 }
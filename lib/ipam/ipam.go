@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/bits"
 	"runtime"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -48,6 +49,7 @@ const (
 	AttributeTypeIPIP      = model.IPAMBlockAttributeTypeIPIP
 	AttributeTypeVXLAN     = model.IPAMBlockAttributeTypeVXLAN
 	AttributeTypeWireguard = model.IPAMBlockAttributeTypeWireguard
+	AttributeSticky        = model.IPAMBlockAttributeSticky
 )
 
 var (
@@ -82,7 +84,9 @@ type ipamClient struct {
 // and the list of the assigned IPv6 addresses.
 //
 // In case of error, returns the IPs allocated so far along with the error.
-func (c ipamClient) AutoAssign(ctx context.Context, args AutoAssignArgs) ([]net.IPNet, []net.IPNet, error) {
+func (c ipamClient) AutoAssign(ctx context.Context, args AutoAssignArgs) (v4list, v6list []net.IPNet, err error) {
+	defer observeOperation(opAutoAssign, time.Now(), &err)
+
 	// Determine the hostname to use - prefer the provided hostname if
 	// non-nil, otherwise use the hostname reported by os.
 	hostname, err := decideHostname(args.Hostname)
@@ -91,7 +95,20 @@ func (c ipamClient) AutoAssign(ctx context.Context, args AutoAssignArgs) ([]net.
 	}
 	log.Infof("Auto-assign %d ipv4, %d ipv6 addrs for host '%s'", args.Num4, args.Num6, hostname)
 
-	var v4list, v6list []net.IPNet
+	// Addresses reserved for a sticky handle are returned on whichever host the handle is used,
+	// and any further addresses assigned to the handle are sticky too.
+	if args.HandleID != nil {
+		var sticky bool
+		v4list, v6list, sticky, err = c.claimStickyIPs(ctx, *args.HandleID, args.Attrs, hostname, args.Num4, args.Num6)
+		if err != nil {
+			return nil, nil, err
+		}
+		args.Num4 -= len(v4list)
+		args.Num6 -= len(v6list)
+		if sticky {
+			args.Attrs = stickyAttributes(args.Attrs)
+		}
+	}
 
 	if args.Num4 != 0 {
 		// Assign IPv4 addresses.
@@ -101,7 +118,8 @@ func (c ipamClient) AutoAssign(ctx context.Context, args AutoAssignArgs) ([]net.
 				return nil, nil, fmt.Errorf("provided IPv4 IPPools list contains one or more IPv6 IPPools")
 			}
 		}
-		v4list, err = c.autoAssign(ctx, args.Num4, args.HandleID, args.Attrs, args.IPv4Pools, 4, hostname, args.MaxBlocksPerHost, args.HostReservedAttrIPv4s)
+		v4, err := c.autoAssign(ctx, args.Num4, args.HandleID, args.Attrs, args.IPv4Pools, 4, hostname, args.MaxBlocksPerHost, args.HostReservedAttrIPv4s, args.WorkloadLabels)
+		v4list = append(v4list, v4...)
 		if err != nil {
 			log.Errorf("Error assigning IPV4 addresses: %v", err)
 			return v4list, nil, err
@@ -116,7 +134,8 @@ func (c ipamClient) AutoAssign(ctx context.Context, args AutoAssignArgs) ([]net.
 				return nil, nil, fmt.Errorf("provided IPv6 IPPools list contains one or more IPv4 IPPools")
 			}
 		}
-		v6list, err = c.autoAssign(ctx, args.Num6, args.HandleID, args.Attrs, args.IPv6Pools, 6, hostname, args.MaxBlocksPerHost, args.HostReservedAttrIPv6s)
+		v6, err := c.autoAssign(ctx, args.Num6, args.HandleID, args.Attrs, args.IPv6Pools, 6, hostname, args.MaxBlocksPerHost, args.HostReservedAttrIPv6s, args.WorkloadLabels)
+		v6list = append(v6list, v6...)
 		if err != nil {
 			log.Errorf("Error assigning IPV6 addresses: %v", err)
 			return v4list, v6list, err
@@ -230,9 +249,10 @@ func detectOS(ctx context.Context) string {
 // determinePools compares a list of requested pools with the enabled pools and returns the intersect.
 // If any requested pool does not exist, or is not enabled, an error is returned.
 // If no pools are requested, all enabled pools are returned.
-// Also applies selector logic on node labels to determine if the pool is a match.
+// Also applies selector logic on node labels, and on workload and namespace labels, to determine
+// if the pool is a match.
 // Returns the set of matching pools as well as the full set of ip pools.
-func (c ipamClient) determinePools(ctx context.Context, requestedPoolNets []net.IPNet, version int, node v3.Node, maxPrefixLen int) (matchingPools, enabledPools []v3.IPPool, err error) {
+func (c ipamClient) determinePools(ctx context.Context, requestedPoolNets []net.IPNet, version int, node v3.Node, workload *WorkloadLabels, maxPrefixLen int) (matchingPools, enabledPools []v3.IPPool, err error) {
 	// Get all the enabled IP pools from the datastore.
 	enabledPools, err = c.pools.GetEnabledPools(version)
 	if err != nil {
@@ -297,6 +317,21 @@ func (c ipamClient) determinePools(ctx context.Context, requestedPoolNets []net.
 			continue
 		}
 		log.Debugf("IP pool matches this node: %s", pool.Name)
+
+		// Filter on the workload's labels, treating a request without them as having no labels.
+		var nsLabels, wlLabels map[string]string
+		if workload != nil {
+			nsLabels, wlLabels = workload.Namespace, workload.Workload
+		}
+		matches, err = pool.SelectsWorkload(nsLabels, wlLabels)
+		if err != nil {
+			log.WithError(err).WithField("pool", pool).Error("failed to determine if workload matches pool")
+			return
+		}
+		if !matches {
+			log.Debugf("IP pool does not match this workload: %s", pool.Name)
+			continue
+		}
 		matchingPools = append(matchingPools, pool)
 	}
 
@@ -311,7 +346,8 @@ func (c ipamClient) prepareAffinityBlocksForHost(
 	requestedPools []net.IPNet,
 	version int,
 	host string,
-	rsvdAttr *HostReservedAttr) ([]v3.IPPool, []net.IPNet, error) {
+	rsvdAttr *HostReservedAttr,
+	workload *WorkloadLabels) ([]v3.IPPool, []net.IPNet, error) {
 	// Retrieve node for given hostname to use for ip pool node selection
 	node, err := c.client.Get(ctx, model.ResourceKey{Kind: v3.KindNode, Name: host}, "")
 	if err != nil {
@@ -331,7 +367,7 @@ func (c ipamClient) prepareAffinityBlocksForHost(
 	}
 
 	// Determine the correct set of IP pools to use for this request.
-	pools, allPools, err := c.determinePools(ctx, requestedPools, version, *v3n, maxPrefixLen)
+	pools, allPools, err := c.determinePools(ctx, requestedPools, version, *v3n, workload, maxPrefixLen)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -538,7 +574,7 @@ func (s *blockAssignState) findOrClaimBlock(ctx context.Context, minFreeIps int)
 	return nil, false, errors.New("failed to find or claim a block")
 }
 
-func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, attrs map[string]string, requestedPools []net.IPNet, version int, host string, maxNumBlocks int, rsvdAttr *HostReservedAttr) ([]net.IPNet, error) {
+func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, attrs map[string]string, requestedPools []net.IPNet, version int, host string, maxNumBlocks int, rsvdAttr *HostReservedAttr, workload *WorkloadLabels) ([]net.IPNet, error) {
 
 	// First, get the existing host-affine blocks.
 	logCtx := log.WithFields(log.Fields{"host": host})
@@ -546,12 +582,13 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 		logCtx = logCtx.WithField("handle", *handleID)
 	}
 	logCtx.Info("Looking up existing affinities for host")
-	pools, affBlocks, err := c.prepareAffinityBlocksForHost(ctx, requestedPools, version, host, rsvdAttr)
+	pools, affBlocks, err := c.prepareAffinityBlocksForHost(ctx, requestedPools, version, host, rsvdAttr, workload)
 	if err != nil {
 		return nil, err
 	}
 
 	logCtx.Debugf("Found %d affine IPv%d blocks for host: %v", len(affBlocks), version, affBlocks)
+	sortBlocksForPools(affBlocks, pools)
 	ips := []net.IPNet{}
 
 	// Record how many blocks we own so we can check against the limit later.
@@ -562,6 +599,12 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 		return nil, err
 	}
 
+	// Addresses reserved by IPReservations are not auto-assigned.
+	reserved, err := c.getReservations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Merge in any global config, if it exists. We use the more restrictive value between
 	// the global max block limit, and the limit provided on this particular request.
 	if config.MaxBlocksPerHost > 0 && maxNumBlocks > 0 && maxNumBlocks > config.MaxBlocksPerHost {
@@ -612,9 +655,13 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 			numBlocksOwned++
 		}
 
-		// We have got a block b.
+		// We have got a block b.  Its pool determines the order in which its addresses are assigned.
+		pool, err := c.blockReaderWriter.getPoolForIP(net.IP{IP: b.Key.(model.BlockKey).CIDR.IP}, pools)
+		if err != nil {
+			return nil, err
+		}
 		for i := 0; i < datastoreRetries; i++ {
-			newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, config.StrictAffinity)
+			newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, config.StrictAffinity, reserved, pool)
 			if err != nil {
 				if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok {
 					log.WithError(err).Debug("CAS Error assigning from new block - retry")
@@ -664,7 +711,7 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 		logCtx.Info("Looking for blocks with free IP addresses")
 		for _, p := range pools {
 			logCtx.Debugf("Assigning from non-affine blocks in pool %s", p.Spec.CIDR)
-			newBlockCIDR := randomBlockGenerator(p, host)
+			newBlockCIDR := poolBlockGenerator(p, host)
 			for rem > 0 {
 				// Grab a new random block.
 				blockCIDR := newBlockCIDR()
@@ -682,7 +729,7 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 
 					// Attempt to assign from the block.
 					logCtx.Infof("Attempting to assign IPs from non-affine block %s", blockCIDR.String())
-					newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, false)
+					newIPs, err := c.assignFromExistingBlock(ctx, b, rem, handleID, attrs, host, false, reserved, &p)
 					if err != nil {
 						if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok {
 							logCtx.WithError(err).Debug("CAS error assigning from non-affine block - retry")
@@ -695,6 +742,7 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 						break
 					}
 					logCtx.Infof("Successfully assigned IPs from non-affine block %s", blockCIDR.String())
+					counterBorrowedIPs.Add(float64(len(newIPs)))
 					ips = append(ips, newIPs...)
 					rem = num - len(ips)
 					break
@@ -712,7 +760,9 @@ func (c ipamClient) autoAssign(ctx context.Context, num int, handleID *string, a
 // in order to satisfy the assignment.  An error will be returned if the IP address
 // is already assigned, or if StrictAffinity is enabled and the address is within
 // a block that does not have affinity for the given host.
-func (c ipamClient) AssignIP(ctx context.Context, args AssignIPArgs) error {
+func (c ipamClient) AssignIP(ctx context.Context, args AssignIPArgs) (err error) {
+	defer observeOperation(opAssignIP, time.Now(), &err)
+
 	hostname, err := decideHostname(args.Hostname)
 	if err != nil {
 		return err
@@ -804,6 +854,9 @@ func (c ipamClient) AssignIP(ctx context.Context, args AssignIPArgs) error {
 			}
 			return err
 		}
+		if block.Host() != hostname {
+			counterBorrowedIPs.Inc()
+		}
 		return nil
 	}
 	return errors.New("Max retries hit - excessive concurrent IPAM requests")
@@ -811,9 +864,16 @@ func (c ipamClient) AssignIP(ctx context.Context, args AssignIPArgs) error {
 
 // ReleaseIPs releases any of the given IP addresses that are currently assigned,
 // so that they are available to be used in another assignment.
-func (c ipamClient) ReleaseIPs(ctx context.Context, ips []net.IP) ([]net.IP, error) {
+func (c ipamClient) ReleaseIPs(ctx context.Context, ips []net.IP) (unallocated []net.IP, err error) {
+	defer observeOperation(opReleaseIPs, time.Now(), &err)
+
 	log.Infof("Releasing IP addresses: %v", ips)
-	unallocated := []net.IP{}
+	unallocated = []net.IP{}
+	pools, err := c.allPools()
+	if err != nil {
+		log.WithError(err).Warnf("Failed to list pools")
+		return nil, err
+	}
 
 	// Group IP addresses by block to minimize the number of writes
 	// to the datastore required to release the given addresses.
@@ -821,7 +881,7 @@ func (c ipamClient) ReleaseIPs(ctx context.Context, ips []net.IP) ([]net.IP, err
 	for _, ip := range ips {
 		var cidrStr string
 
-		pool, err := c.blockReaderWriter.getPoolForIP(ip, nil)
+		pool, err := c.blockReaderWriter.getPoolForIP(ip, pools)
 		if err != nil {
 			log.WithError(err).Warnf("Failed to get pool for IP")
 			return nil, err
@@ -856,7 +916,7 @@ func (c ipamClient) ReleaseIPs(ctx context.Context, ips []net.IP) ([]net.IP, err
 	// Release IPs for each block.
 	for cidrStr, ips := range ipsByBlock {
 		_, cidr, _ := net.ParseCIDR(cidrStr)
-		unalloc, err := c.releaseIPsFromBlock(ctx, ips, *cidr)
+		unalloc, err := c.releaseIPsFromBlock(ctx, ips, *cidr, pools, nil)
 		if err != nil {
 			log.Errorf("Error releasing IPs: %v", err)
 			return nil, err
@@ -866,8 +926,12 @@ func (c ipamClient) ReleaseIPs(ctx context.Context, ips []net.IP) ([]net.IP, err
 	return unallocated, nil
 }
 
-func (c ipamClient) releaseIPsFromBlock(ctx context.Context, ips []net.IP, blockCIDR net.IPNet) ([]net.IP, error) {
+// releaseIPsFromBlock releases the given addresses from the block.  pools are all of the
+// pools, which are listed if nil.  If releasable is not nil, only the addresses for which it
+// returns true, given the block that is being updated, are released.
+func (c ipamClient) releaseIPsFromBlock(ctx context.Context, ips []net.IP, blockCIDR net.IPNet, pools []v3.IPPool, releasable func(allocationBlock, net.IP) bool) ([]net.IP, error) {
 	logCtx := log.WithField("cidr", blockCIDR)
+	quarantine := c.poolQuarantinesReleases(blockCIDR, pools)
 	for i := 0; i < datastoreRetries; i++ {
 		logCtx.Info("Getting block so we can release IPs")
 
@@ -885,11 +949,20 @@ func (c ipamClient) releaseIPsFromBlock(ctx context.Context, ips []net.IP, block
 
 		// Release the IPs.
 		b := allocationBlock{obj.Value.(*model.AllocationBlock)}
-		unallocated, handles, err2 := b.release(ips)
+		toRelease := ips
+		if releasable != nil {
+			toRelease = []net.IP{}
+			for _, ip := range ips {
+				if releasable(b, ip) {
+					toRelease = append(toRelease, ip)
+				}
+			}
+		}
+		unallocated, handles, err2 := b.release(toRelease, quarantine)
 		if err2 != nil {
 			return nil, err2
 		}
-		if len(ips) == len(unallocated) {
+		if len(toRelease) == len(unallocated) {
 			// All the given IP addresses are already unallocated.
 			// Just return.
 			logCtx.Info("No IPs need to be released")
@@ -938,7 +1011,7 @@ func (c ipamClient) releaseIPsFromBlock(ctx context.Context, ips []net.IP, block
 	return nil, errors.New("Max retries hit - excessive concurrent IPAM requests")
 }
 
-func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KVPair, num int, handleID *string, attrs map[string]string, host string, affCheck bool) ([]net.IPNet, error) {
+func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KVPair, num int, handleID *string, attrs map[string]string, host string, affCheck bool, reserved reservations, pool *v3.IPPool) ([]net.IPNet, error) {
 	blockCIDR := block.Key.(model.BlockKey).CIDR
 	logCtx := log.WithFields(log.Fields{"host": host, "block": blockCIDR})
 	if handleID != nil {
@@ -949,7 +1022,7 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 	// Pull out the block.
 	b := allocationBlock{block.Value.(*model.AllocationBlock)}
 
-	ips, err := b.autoAssign(num, handleID, host, attrs, affCheck)
+	ips, err := b.autoAssign(num, handleID, host, attrs, affCheck, reserved, pool)
 	if err != nil {
 		logCtx.WithError(err).Errorf("Error in auto assign")
 		return nil, err
@@ -962,7 +1035,7 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 	// Increment handle count.
 	if handleID != nil {
 		logCtx.Debug("Incrementing handle")
-		c.incrementHandle(ctx, *handleID, blockCIDR, num)
+		c.incrementHandle(ctx, *handleID, blockCIDR, len(ips))
 	}
 
 	// Update the block using CAS by passing back the original
@@ -974,7 +1047,7 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 		logCtx.WithError(err).Infof("Failed to update block")
 		if handleID != nil {
 			logCtx.Debug("Decrementing handle since we failed to allocate IP(s)")
-			if err := c.decrementHandle(ctx, *handleID, blockCIDR, num); err != nil {
+			if err := c.decrementHandle(ctx, *handleID, blockCIDR, len(ips)); err != nil {
 				logCtx.WithError(err).Warnf("Failed to decrement handle")
 			}
 		}
@@ -989,7 +1062,9 @@ func (c ipamClient) assignFromExistingBlock(ctx context.Context, block *model.KV
 // pool.  Returns a list of blocks that were claimed, as well as a
 // list of blocks that were claimed by another host.
 // If an empty string is passed as the host, then the hostname is automatically detected.
-func (c ipamClient) ClaimAffinity(ctx context.Context, cidr net.IPNet, host string) ([]net.IPNet, []net.IPNet, error) {
+func (c ipamClient) ClaimAffinity(ctx context.Context, cidr net.IPNet, host string) (claimed, failed []net.IPNet, err error) {
+	defer observeOperation(opClaimAffinity, time.Now(), &err)
+
 	logCtx := log.WithFields(log.Fields{"host": host, "cidr": cidr})
 
 	// Verify the requested CIDR falls within a configured pool.
@@ -1014,8 +1089,8 @@ func (c ipamClient) ClaimAffinity(ctx context.Context, cidr net.IPNet, host stri
 		return nil, nil, err
 	}
 
-	failed := []net.IPNet{}
-	claimed := []net.IPNet{}
+	failed = []net.IPNet{}
+	claimed = []net.IPNet{}
 
 	// Get IPAM config.
 	cfg, err := c.GetIPAMConfig(ctx)
@@ -1320,25 +1395,35 @@ func (c ipamClient) IPsByHandle(ctx context.Context, handleID string) ([]net.IP,
 
 // ReleaseByHandle releases all IP addresses that have been assigned
 // using the provided handle.
-func (c ipamClient) ReleaseByHandle(ctx context.Context, handleID string) error {
+func (c ipamClient) ReleaseByHandle(ctx context.Context, handleID string) (err error) {
+	defer observeOperation(opReleaseByHandle, time.Now(), &err)
+
 	log.Infof("Releasing all IPs with handle '%s'", handleID)
 	obj, err := c.blockReaderWriter.queryHandle(ctx, handleID, "")
 	if err != nil {
 		return err
 	}
 	handle := allocationHandle{obj.Value.(*model.IPAMHandle)}
+	pools, err := c.allPools()
+	if err != nil {
+		return err
+	}
 
 	for blockStr, _ := range handle.Block {
 		_, blockCIDR, _ := net.ParseCIDR(blockStr)
-		if err := c.releaseByHandle(ctx, handleID, *blockCIDR); err != nil {
+		if err := c.releaseByHandle(ctx, handleID, *blockCIDR, pools, false); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (c ipamClient) releaseByHandle(ctx context.Context, handleID string, blockCIDR net.IPNet) error {
+// releaseByHandle releases the addresses assigned with the given handle in the given block.  Sticky
+// addresses are only released if releaseSticky is true.  pools are all of the pools, which are
+// listed if nil.
+func (c ipamClient) releaseByHandle(ctx context.Context, handleID string, blockCIDR net.IPNet, pools []v3.IPPool, releaseSticky bool) error {
 	logCtx := log.WithFields(log.Fields{"handle": handleID, "cidr": blockCIDR})
+	quarantine := c.poolQuarantinesReleases(blockCIDR, pools)
 	for i := 0; i < datastoreRetries; i++ {
 		logCtx.Debug("Querying block so we can release IPs by handle")
 		obj, err := c.blockReaderWriter.queryBlock(ctx, blockCIDR, "")
@@ -1355,14 +1440,17 @@ func (c ipamClient) releaseByHandle(ctx context.Context, handleID string, blockC
 
 		// Release the IP by handle.
 		block := allocationBlock{obj.Value.(*model.AllocationBlock)}
-		num := block.releaseByHandle(handleID)
-		if num == 0 {
+		if releaseSticky {
+			block.unstick(handleID)
+		}
+		num, kept := block.releaseByHandle(handleID, quarantine)
+		if num == 0 && kept == 0 {
 			// Block has no addresses with this handle, so
 			// all addresses are already unallocated.
 			logCtx.Debug("Block has no addresses with the given handle")
 			return nil
 		}
-		logCtx.Debugf("Block has %d IPs with the given handle", num)
+		logCtx.Debugf("Block has %d IPs with the given handle, keeping %d sticky IPs", num+kept, kept)
 
 		if block.empty() && block.Affinity == nil {
 			logCtx.Info("Deleting block because it is now empty and has no affinity")
@@ -1399,6 +1487,10 @@ func (c ipamClient) releaseByHandle(ctx context.Context, handleID string, blockC
 			}
 			logCtx.Debug("Successfully released IPs from block")
 		}
+		if num == 0 {
+			// Only sticky addresses were kept, so there is nothing more to release.
+			return nil
+		}
 		if err = c.decrementHandle(ctx, handleID, blockCIDR, num); err != nil {
 			logCtx.WithError(err).Warn("Failed to decrement handle")
 		}
@@ -1454,7 +1546,7 @@ func (c ipamClient) incrementHandle(ctx context.Context, handleID string, blockC
 		} else {
 			// This is a new handle - create it.
 			_, err = c.client.Create(ctx, obj)
-			if err != nil {
+			if err = countConflict("handle", err); err != nil {
 				log.WithError(err).Warning("Failed to create handle, retry")
 				continue
 			}
@@ -1731,6 +1823,35 @@ func decideHostname(host string) (string, error) {
 
 // GetUtilization returns IP utilization info for the specified pools, or for all pools.
 func (c ipamClient) GetUtilization(ctx context.Context, args GetUtilizationArgs) ([]*PoolUtilization, error) {
+	usage, err := c.utilizationPools(args.Pools)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read all allocation blocks.
+	blocks, err := c.client.List(ctx, model.BlockListOptions{}, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, kvp := range blocks.KVPairs {
+		b := kvp.Value.(*model.AllocationBlock)
+		log.Debugf("Got block: %v", b)
+
+		// Find which pool this block belongs to.
+		if poolUse := poolForBlock(usage, b); poolUse != nil {
+			poolUse.Blocks = append(poolUse.Blocks, BlockUtilization{
+				CIDR:      b.CIDR.IPNet,
+				Capacity:  b.NumAddresses(),
+				Available: len(b.Unallocated),
+			})
+		}
+	}
+	return usage, nil
+}
+
+// utilizationPools returns an empty PoolUtilization for each of the requested pools, which
+// may be given by name or CIDR, or for all pools if none are requested.
+func (c ipamClient) utilizationPools(pools []string) ([]*PoolUtilization, error) {
 	var usage []*PoolUtilization
 
 	// Read all pools.
@@ -1741,8 +1862,8 @@ func (c ipamClient) GetUtilization(ctx context.Context, args GetUtilizationArgs)
 	}
 
 	// Identify the ones we want and create a PoolUtilization for each of those.
-	wantAllPools := len(args.Pools) == 0
-	wantedPools := set.FromArray(args.Pools)
+	wantAllPools := len(pools) == 0
+	wantedPools := set.FromArray(pools)
 	for _, pool := range allPools {
 		if wantAllPools ||
 			wantedPools.Contains(pool.Name) ||
@@ -1764,30 +1885,19 @@ func (c ipamClient) GetUtilization(ctx context.Context, args GetUtilizationArgs)
 			CIDR: net.MustParseNetwork("0.0.0.0/0").IPNet,
 		})
 	}
+	return usage, nil
+}
 
-	// Read all allocation blocks.
-	blocks, err := c.client.List(ctx, model.BlockListOptions{}, "")
-	if err != nil {
-		return nil, err
-	}
-	for _, kvp := range blocks.KVPairs {
-		b := kvp.Value.(*model.AllocationBlock)
-		log.Debugf("Got block: %v", b)
-
-		// Find which pool this block belongs to.
-		for _, poolUse := range usage {
-			if b.CIDR.IsNetOverlap(poolUse.CIDR) {
-				log.Debugf("Block CIDR %v belongs to pool %v", b.CIDR, poolUse.Name)
-				poolUse.Blocks = append(poolUse.Blocks, BlockUtilization{
-					CIDR:      b.CIDR.IPNet,
-					Capacity:  b.NumAddresses(),
-					Available: len(b.Unallocated),
-				})
-				break
-			}
+// poolForBlock returns the PoolUtilization that the block belongs to, or nil if the block
+// does not belong to any of the pools.
+func poolForBlock(usage []*PoolUtilization, b *model.AllocationBlock) *PoolUtilization {
+	for _, poolUse := range usage {
+		if b.CIDR.IsNetOverlap(poolUse.CIDR) {
+			log.Debugf("Block CIDR %v belongs to pool %v", b.CIDR, poolUse.Name)
+			return poolUse
 		}
 	}
-	return usage, nil
+	return nil
 }
 
 // EnsureBlock returns single IPv4/IPv6 IPAM block for a host as specified by the provided BlockArgs.
@@ -1872,7 +1982,7 @@ func (c ipamClient) ensureBlock(ctx context.Context, rsvdAttr *HostReservedAttr,
 	logCtx := log.WithFields(log.Fields{"host": host})
 
 	logCtx.Info("Looking up existing affinities for host")
-	pools, affBlocks, err := c.prepareAffinityBlocksForHost(ctx, requestedPools, version, host, rsvdAttr)
+	pools, affBlocks, err := c.prepareAffinityBlocksForHost(ctx, requestedPools, version, host, rsvdAttr, nil)
 	if err != nil {
 		return nil, err
 	}
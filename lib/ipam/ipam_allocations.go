@@ -0,0 +1,151 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// GetAllocations returns the IP addresses allocated within the given CIDR, sorted by IP.
+func (c ipamClient) GetAllocations(ctx context.Context, cidr cnet.IPNet) ([]AllocatedIP, error) {
+	return c.QueryAllocations(ctx, QueryAllocationsArgs{CIDR: &cidr})
+}
+
+// QueryAllocations returns the IP addresses that match all of the given criteria, sorted by IP.
+func (c ipamClient) QueryAllocations(ctx context.Context, args QueryAllocationsArgs) ([]AllocatedIP, error) {
+	blocks, err := c.blockReaderWriter.listBlocks(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var cidr *cnet.IPNet
+	if args.CIDR != nil {
+		cidr = args.CIDR.Network()
+	}
+
+	allocs := []AllocatedIP{}
+	for _, kvp := range blocks.KVPairs {
+		b := kvp.Value.(*model.AllocationBlock)
+		if cidr != nil && !cidr.IsNetOverlap(b.CIDR.IPNet) {
+			continue
+		}
+		for _, a := range blockAllocations(b) {
+			if cidr != nil && !cidr.Contains(a.IP.IP) {
+				continue
+			}
+			if args.HandleID != nil && a.Handle != *args.HandleID {
+				continue
+			}
+			if !attributesMatch(a.Attrs, args.Attrs) {
+				continue
+			}
+			allocs = append(allocs, a)
+		}
+	}
+	sortAllocations(allocs)
+	return allocs, nil
+}
+
+// ListHandles returns every handle that has IP addresses allocated with it, sorted by handle ID.
+func (c ipamClient) ListHandles(ctx context.Context) ([]HandleInfo, error) {
+	allocs, err := c.QueryAllocations(ctx, QueryAllocationsArgs{})
+	if err != nil {
+		return nil, err
+	}
+
+	byHandle := map[string]*HandleInfo{}
+	handles := []*HandleInfo{}
+	for _, a := range allocs {
+		if a.Handle == "" {
+			continue
+		}
+		h, ok := byHandle[a.Handle]
+		if !ok {
+			h = &HandleInfo{HandleID: a.Handle}
+			byHandle[a.Handle] = h
+			handles = append(handles, h)
+		}
+		h.Allocations = append(h.Allocations, a)
+	}
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i].HandleID < handles[j].HandleID
+	})
+
+	infos := []HandleInfo{}
+	for _, h := range handles {
+		infos = append(infos, *h)
+	}
+	return infos, nil
+}
+
+// blockAllocations returns the addresses allocated in the block, excluding the addresses that are
+// reserved by the block itself.
+func blockAllocations(b *model.AllocationBlock) []AllocatedIP {
+	var allocs []AllocatedIP
+	for ordinal, attrIdx := range b.Allocations {
+		if attrIdx == nil {
+			continue
+		}
+		if *attrIdx >= len(b.Attributes) {
+			log.WithField("block", b.CIDR).Warnf("Missing attributes for IP with ordinal %d", ordinal)
+			continue
+		}
+		attrs := b.Attributes[*attrIdx]
+		handle := ""
+		if attrs.AttrPrimary != nil {
+			handle = *attrs.AttrPrimary
+		}
+		if strings.ToLower(handle) == WindowsReservedHandle {
+			continue
+		}
+		allocs = append(allocs, AllocatedIP{
+			IP:     b.OrdinalToIP(ordinal),
+			Block:  b.CIDR,
+			Handle: handle,
+			Host:   attrs.AttrSecondary[AttributeNode],
+			Attrs:  attrs.AttrSecondary,
+		})
+	}
+	return allocs
+}
+
+// attributesMatch returns true if the attributes include all of the wanted key/value pairs.
+func attributesMatch(attrs, wanted map[string]string) bool {
+	for k, v := range wanted {
+		if actual, ok := attrs[k]; !ok || actual != v {
+			return false
+		}
+	}
+	return true
+}
+
+// sortAllocations sorts the allocations by IP, with IPv4 addresses first.
+func sortAllocations(allocs []AllocatedIP) {
+	sort.Slice(allocs, func(i, j int) bool {
+		a, b := allocs[i].IP.IP, allocs[j].IP.IP
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = testutils.E2eDatastoreDescribe("IPAM allocation query tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	var bc bapi.Client
+	var ic Interface
+	var kc *kubernetes.Clientset
+
+	assign := func(ip, handle, namespace, pod string) {
+		Expect(ic.AssignIP(ctx, AssignIPArgs{
+			IP:       cnet.MustParseIP(ip),
+			HandleID: &handle,
+			Attrs:    map[string]string{AttributeNode: "node-1", AttributeNamespace: namespace, AttributePod: pod},
+			Hostname: "node-1",
+		})).NotTo(HaveOccurred())
+	}
+	ipsOf := func(allocs []AllocatedIP) []string {
+		ips := []string{}
+		for _, a := range allocs {
+			ips = append(ips, a.IP.String())
+		}
+		return ips
+	}
+
+	BeforeEach(func() {
+		var err error
+		bc, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		bc.Clean()
+		ic = NewIPAMClient(bc, staticPoolAccessor{{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/26", BlockSize: 28}}})
+		kc = kubeClientset(bc)
+		Expect(applyNode(bc, kc, "node-1", nil)).NotTo(HaveOccurred())
+
+		assign("10.0.0.33", "foo-web", "foo", "web")
+		assign("10.0.0.2", "foo-web", "foo", "web")
+		assign("10.0.0.3", "foo-db", "foo", "db")
+		assign("10.0.0.4", "bar-web", "bar", "web")
+	})
+
+	AfterEach(func() {
+		deleteNode(bc, kc, "node-1")
+		bc.Clean()
+	})
+
+	It("should query allocations by attribute, handle and CIDR", func() {
+		allocs, err := ic.QueryAllocations(ctx, QueryAllocationsArgs{Attrs: map[string]string{AttributeNamespace: "foo"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipsOf(allocs)).To(Equal([]string{"10.0.0.2", "10.0.0.3", "10.0.0.33"}))
+
+		allocs, err = ic.QueryAllocations(ctx, QueryAllocationsArgs{Attrs: map[string]string{AttributeNamespace: "foo", AttributePod: "web"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipsOf(allocs)).To(Equal([]string{"10.0.0.2", "10.0.0.33"}))
+		Expect(allocs[0].Handle).To(Equal("foo-web"))
+		Expect(allocs[0].Host).To(Equal("node-1"))
+		Expect(allocs[0].Block.String()).To(Equal("10.0.0.0/28"))
+		Expect(allocs[0].Attrs).To(HaveKeyWithValue(AttributePod, "web"))
+
+		handle := "bar-web"
+		allocs, err = ic.QueryAllocations(ctx, QueryAllocationsArgs{HandleID: &handle})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipsOf(allocs)).To(Equal([]string{"10.0.0.4"}))
+
+		cidr := cnet.MustParseCIDR("10.0.0.32/28")
+		allocs, err = ic.QueryAllocations(ctx, QueryAllocationsArgs{CIDR: &cidr, Attrs: map[string]string{AttributePod: "web"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipsOf(allocs)).To(Equal([]string{"10.0.0.33"}))
+
+		// A CIDR within a block only matches the addresses inside it.
+		cidr = cnet.MustParseCIDR("10.0.0.4/30")
+		allocs, err = ic.QueryAllocations(ctx, QueryAllocationsArgs{CIDR: &cidr})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipsOf(allocs)).To(Equal([]string{"10.0.0.4"}))
+		allocs, err = ic.GetAllocations(ctx, cnet.MustParseCIDR("10.0.0.0/30"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ipsOf(allocs)).To(Equal([]string{"10.0.0.2", "10.0.0.3"}))
+
+		allocs, err = ic.QueryAllocations(ctx, QueryAllocationsArgs{Attrs: map[string]string{AttributeNamespace: "baz"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allocs).To(BeEmpty())
+	})
+
+	It("should list handles with their addresses", func() {
+		handles, err := ic.ListHandles(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		ids := []string{}
+		for _, h := range handles {
+			ids = append(ids, h.HandleID)
+		}
+		Expect(ids).To(Equal([]string{"bar-web", "foo-db", "foo-web"}))
+		Expect(ipsOf(handles[2].Allocations)).To(Equal([]string{"10.0.0.2", "10.0.0.33"}))
+
+		Expect(ic.ReleaseByHandle(ctx, "foo-web")).NotTo(HaveOccurred())
+		handles, err = ic.ListHandles(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handles).To(HaveLen(2))
+	})
+})
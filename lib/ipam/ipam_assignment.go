@@ -0,0 +1,211 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// assignmentMode returns the assignment mode of the given pool, or the default mode if the
+// pool is nil or does not specify one.
+func assignmentMode(pool *v3.IPPool) v3.AssignmentMode {
+	if pool == nil || pool.Spec.AssignmentMode == "" {
+		return v3.AssignmentModeLeastRecentlyReleased
+	}
+	return pool.Spec.AssignmentMode
+}
+
+// releaseCooldown returns the time for which addresses released from the given pool are
+// quarantined, or zero if the pool is nil or does not quarantine released addresses.
+func releaseCooldown(pool *v3.IPPool) time.Duration {
+	if pool == nil || pool.Spec.ReleaseCooldown == nil || pool.Spec.ReleaseCooldown.Duration < 0 {
+		return 0
+	}
+	return pool.Spec.ReleaseCooldown.Duration
+}
+
+// poolBlockGenerator returns a generator of the block CIDRs within the given pool, in the
+// order in which new blocks should be claimed for the host.
+func poolBlockGenerator(pool v3.IPPool, host string) func() *cnet.IPNet {
+	if assignmentMode(&pool) != v3.AssignmentModeSequential {
+		return randomBlockGenerator(pool, host)
+	}
+	_, cidr, err := cnet.ParseCIDR(pool.Spec.CIDR)
+	if err != nil {
+		log.Errorf("Error parsing CIDR: %s %v", pool.Spec.CIDR, err)
+		return func() *cnet.IPNet { return nil }
+	}
+	return blockGenerator(&pool, *cidr)
+}
+
+// sortBlocksForPools orders the blocks that belong to pools that assign sequentially by
+// address, so that the lowest affine block of such a pool is used first.  The other blocks
+// keep their positions.
+func sortBlocksForPools(blocks []cnet.IPNet, pools []v3.IPPool) {
+	sequential := []*cnet.IPNet{}
+	for p := range pools {
+		if assignmentMode(&pools[p]) != v3.AssignmentModeSequential {
+			continue
+		}
+		if _, cidr, err := cnet.ParseCIDR(pools[p].Spec.CIDR); err == nil {
+			sequential = append(sequential, cidr)
+		}
+	}
+	if len(sequential) == 0 {
+		return
+	}
+
+	positions := []int{}
+	sorted := []cnet.IPNet{}
+	for i, b := range blocks {
+		for _, cidr := range sequential {
+			if cidr.Contains(b.IP) {
+				positions = append(positions, i)
+				sorted = append(sorted, b)
+				break
+			}
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].IP.To16(), sorted[j].IP.To16()) < 0
+	})
+	for i, p := range positions {
+		blocks[p] = sorted[i]
+	}
+}
+
+// freeOrdinals returns the unallocated ordinals of the block in the order in which they
+// should be assigned by the given mode.
+func (b *allocationBlock) freeOrdinals(mode v3.AssignmentMode) []int {
+	switch mode {
+	case v3.AssignmentModeSequential:
+		ordinals := append([]int{}, b.Unallocated...)
+		sort.Ints(ordinals)
+		return ordinals
+	case v3.AssignmentModeRandom:
+		ordinals := append([]int{}, b.Unallocated...)
+		rand.Shuffle(len(ordinals), func(i, j int) {
+			ordinals[i], ordinals[j] = ordinals[j], ordinals[i]
+		})
+		return ordinals
+	}
+
+	// Released ordinals are appended to the unallocated list, so the list is ordered
+	// from least to most recently released.
+	return b.Unallocated
+}
+
+// quarantinedOrdinals returns the ordinals that were released less than the cool-down ago,
+// and forgets the release of any other ordinals.
+func (b *allocationBlock) quarantinedOrdinals(cooldown time.Duration, now time.Time) map[int]bool {
+	quarantined := map[int]bool{}
+	released := []model.ReleasedOrdinal{}
+	for _, r := range b.Released {
+		if now.Sub(time.Unix(r.ReleasedAt, 0)) < cooldown {
+			quarantined[r.Ordinal] = true
+			released = append(released, r)
+		}
+	}
+	if len(released) == 0 {
+		released = nil
+	}
+	b.Released = released
+	return quarantined
+}
+
+// recordReleased records the time at which the given ordinals were released.
+func (b *allocationBlock) recordReleased(ordinals []int, now time.Time) {
+	b.forgetReleased(ordinals)
+	for _, o := range ordinals {
+		b.Released = append(b.Released, model.ReleasedOrdinal{Ordinal: o, ReleasedAt: now.Unix()})
+	}
+}
+
+// forgetReleased removes any record of the release of the given ordinals.
+func (b *allocationBlock) forgetReleased(ordinals []int) {
+	if len(b.Released) == 0 {
+		return
+	}
+	released := []model.ReleasedOrdinal{}
+	for _, r := range b.Released {
+		if !intInSlice(r.Ordinal, ordinals) {
+			released = append(released, r)
+		}
+	}
+	if len(released) == 0 {
+		released = nil
+	}
+	b.Released = released
+}
+
+// removeUnallocated removes the given ordinals from the unallocated list, keeping the order
+// of the remaining ordinals.
+func (b *allocationBlock) removeUnallocated(ordinals []int) {
+	remove := map[int]bool{}
+	for _, o := range ordinals {
+		remove[o] = true
+	}
+	unallocated := make([]int, 0, len(b.Unallocated))
+	for _, o := range b.Unallocated {
+		if !remove[o] {
+			unallocated = append(unallocated, o)
+		}
+	}
+	b.Unallocated = unallocated
+}
+
+// poolQuarantinesReleases returns whether addresses released from the given block are
+// quarantined by the block's pool.  pools are all of the pools, including disabled pools, and
+// are listed if nil.
+func (c ipamClient) poolQuarantinesReleases(blockCIDR cnet.IPNet, pools []v3.IPPool) bool {
+	logCtx := log.WithField("cidr", blockCIDR)
+	if pools == nil {
+		var err error
+		if pools, err = c.allPools(); err != nil {
+			logCtx.WithError(err).Warn("Failed to list pools, not quarantining released IPs")
+			return false
+		}
+	}
+	pool, err := c.blockReaderWriter.getPoolForIP(cnet.IP{IP: blockCIDR.IP}, pools)
+	if err != nil {
+		logCtx.WithError(err).Warn("Failed to get pool for block, not quarantining released IPs")
+		return false
+	}
+	return releaseCooldown(pool) > 0
+}
+
+// allPools returns all of the pools, including disabled pools, so that they can be listed once
+// when addresses are released from several blocks.  Addresses released from a disabled pool,
+// such as a pool that is being migrated away from, are still quarantined in case the pool is
+// enabled again.
+func (c ipamClient) allPools() ([]v3.IPPool, error) {
+	pools, err := c.pools.GetAllPools()
+	if err != nil {
+		return nil, err
+	}
+	if pools == nil {
+		pools = []v3.IPPool{}
+	}
+	return pools, nil
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = Describe("Block assignment modes", func() {
+	var b allocationBlock
+
+	poolWith := func(mode v3.AssignmentMode, cooldown time.Duration) *v3.IPPool {
+		return &v3.IPPool{Spec: v3.IPPoolSpec{
+			CIDR:            "10.0.0.0/24",
+			BlockSize:       29,
+			AssignmentMode:  mode,
+			ReleaseCooldown: &metav1.Duration{Duration: cooldown},
+		}}
+	}
+
+	assign := func(num int, pool *v3.IPPool) []string {
+		ips, err := b.autoAssign(num, nil, "host", nil, false, nil, pool)
+		Expect(err).NotTo(HaveOccurred())
+		assigned := []string{}
+		for _, ip := range ips {
+			assigned = append(assigned, ip.IP.String())
+		}
+		return assigned
+	}
+
+	release := func(ip string, quarantine bool) {
+		unallocated, _, err := b.release([]cnet.IP{cnet.MustParseIP(ip)}, quarantine)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unallocated).To(BeEmpty())
+	}
+
+	BeforeEach(func() {
+		b = newBlock(cnet.MustParseCIDR("10.0.0.0/29"), nil)
+	})
+
+	DescribeTable("should choose the next address according to the mode",
+		func(mode v3.AssignmentMode, expected string) {
+			pool := poolWith(mode, 0)
+			Expect(assign(3, pool)).To(Equal([]string{"10.0.0.0", "10.0.0.1", "10.0.0.2"}))
+			release("10.0.0.1", false)
+			release("10.0.0.0", false)
+			Expect(assign(1, pool)).To(Equal([]string{expected}))
+		},
+		Entry("Default", v3.AssignmentMode(""), "10.0.0.3"),
+		Entry("LeastRecentlyReleased", v3.AssignmentModeLeastRecentlyReleased, "10.0.0.3"),
+		Entry("Sequential", v3.AssignmentMode(v3.AssignmentModeSequential), "10.0.0.0"),
+	)
+
+	It("should assign the least recently released address once all others are used", func() {
+		Expect(assign(8, nil)).To(HaveLen(8))
+		release("10.0.0.5", false)
+		release("10.0.0.2", false)
+		Expect(assign(1, nil)).To(Equal([]string{"10.0.0.5"}))
+	})
+
+	It("should assign random free addresses", func() {
+		pool := poolWith(v3.AssignmentModeRandom, 0)
+		Expect(assign(2, nil)).To(Equal([]string{"10.0.0.0", "10.0.0.1"}))
+		assigned := assign(6, pool)
+		Expect(assigned).To(ConsistOf("10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"))
+		Expect(b.Unallocated).To(BeEmpty())
+	})
+
+	It("should not assign quarantined addresses until the cool-down has passed", func() {
+		pool := poolWith(v3.AssignmentModeSequential, time.Hour)
+		Expect(assign(2, pool)).To(Equal([]string{"10.0.0.0", "10.0.0.1"}))
+		release("10.0.0.0", true)
+		Expect(b.Released).To(HaveLen(1))
+		Expect(b.Released[0].Ordinal).To(Equal(0))
+
+		// The released address is skipped, and keeps its place in the unallocated list.
+		Expect(assign(1, pool)).To(Equal([]string{"10.0.0.2"}))
+		Expect(b.Unallocated).To(Equal([]int{3, 4, 5, 6, 7, 0}))
+
+		// Once the cool-down has passed, the address is assigned and its release is forgotten.
+		b.Released[0].ReleasedAt = time.Now().Add(-2 * time.Hour).Unix()
+		Expect(assign(1, pool)).To(Equal([]string{"10.0.0.0"}))
+		Expect(b.Released).To(BeNil())
+	})
+
+	It("should forget releases when the pool has no cool-down", func() {
+		Expect(assign(1, nil)).To(Equal([]string{"10.0.0.0"}))
+		release("10.0.0.0", true)
+		Expect(b.Released).To(HaveLen(1))
+		Expect(assign(1, poolWith(v3.AssignmentModeSequential, 0))).To(Equal([]string{"10.0.0.0"}))
+		Expect(b.Released).To(BeNil())
+	})
+
+	It("should forget the release of an explicitly assigned address", func() {
+		Expect(assign(1, nil)).To(Equal([]string{"10.0.0.0"}))
+		release("10.0.0.0", true)
+		Expect(b.assign(false, cnet.MustParseIP("10.0.0.0"), nil, nil, "host")).NotTo(HaveOccurred())
+		Expect(b.Released).To(BeNil())
+	})
+
+	It("should generate the blocks of a sequential pool in order", func() {
+		pool := *poolWith(v3.AssignmentModeSequential, 0)
+		pool.Spec.CIDR = "10.0.0.0/27"
+		blocks := poolBlockGenerator(pool, "host")
+		generated := []string{}
+		for cidr := blocks(); cidr != nil; cidr = blocks() {
+			generated = append(generated, cidr.String())
+		}
+		Expect(generated).To(Equal([]string{"10.0.0.0/29", "10.0.0.8/29", "10.0.0.16/29", "10.0.0.24/29"}))
+	})
+
+	It("should only sort the blocks of sequential pools", func() {
+		random := *poolWith(v3.AssignmentModeRandom, 0)
+		random.Spec.CIDR = "10.1.0.0/24"
+		blocks := []cnet.IPNet{}
+		for _, cidr := range []string{"10.1.0.64/29", "10.0.0.64/29", "10.1.0.0/29", "10.0.0.0/29"} {
+			blocks = append(blocks, cnet.MustParseCIDR(cidr))
+		}
+		sortBlocksForPools(blocks, []v3.IPPool{random, *poolWith(v3.AssignmentModeSequential, 0)})
+		sorted := []string{}
+		for _, b := range blocks {
+			sorted = append(sorted, b.String())
+		}
+		Expect(sorted).To(Equal([]string{"10.1.0.64/29", "10.0.0.0/29", "10.1.0.0/29", "10.0.0.64/29"}))
+	})
+})
+
+var _ = testutils.E2eDatastoreDescribe("IPAM assignment mode tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	var bc bapi.Client
+	var ic Interface
+	var kc *kubernetes.Clientset
+
+	autoAssign := func() string {
+		v4, _, err := ic.AutoAssign(ctx, AutoAssignArgs{Num4: 1, Hostname: "node-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(1))
+		return v4[0].IP.String()
+	}
+
+	BeforeEach(func() {
+		var err error
+		bc, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		bc.Clean()
+		ic = NewIPAMClient(bc, staticPoolAccessor{{Spec: v3.IPPoolSpec{
+			CIDR:            "10.0.0.0/26",
+			BlockSize:       28,
+			AssignmentMode:  v3.AssignmentModeSequential,
+			ReleaseCooldown: &metav1.Duration{Duration: time.Hour},
+		}}})
+		kc = kubeClientset(bc)
+		Expect(applyNode(bc, kc, "node-1", nil)).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		deleteNode(bc, kc, "node-1")
+		bc.Clean()
+	})
+
+	It("should claim the lowest block and not reuse a released address", func() {
+		Expect(autoAssign()).To(Equal("10.0.0.0"))
+		Expect(autoAssign()).To(Equal("10.0.0.1"))
+
+		_, err := ic.ReleaseIPs(ctx, []cnet.IP{{IP: net.ParseIP("10.0.0.0")}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(autoAssign()).To(Equal("10.0.0.2"))
+
+		kvp, err := bc.Get(ctx, model.BlockKey{CIDR: cnet.MustParseCIDR("10.0.0.0/28")}, "")
+		Expect(err).NotTo(HaveOccurred())
+		released := kvp.Value.(*model.AllocationBlock).Released
+		Expect(released).To(HaveLen(1))
+		Expect(released[0].Ordinal).To(Equal(0))
+	})
+
+	It("should quarantine addresses released from a disabled pool", func() {
+		Expect(autoAssign()).To(Equal("10.0.0.0"))
+
+		// Release the address once the pool has been disabled.
+		pools := ic.(*ipamClient).pools.(staticPoolAccessor)
+		disabled := NewIPAMClient(bc, disabledPoolAccessor{pools})
+		_, err := disabled.ReleaseIPs(ctx, []cnet.IP{{IP: net.ParseIP("10.0.0.0")}})
+		Expect(err).NotTo(HaveOccurred())
+
+		kvp, err := bc.Get(ctx, model.BlockKey{CIDR: cnet.MustParseCIDR("10.0.0.0/28")}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp.Value.(*model.AllocationBlock).Released).To(HaveLen(1))
+		Expect(autoAssign()).To(Equal("10.0.0.1"))
+	})
+})
+
+// disabledPoolAccessor is a pool accessor for pools that exist but are all disabled.
+type disabledPoolAccessor struct {
+	staticPoolAccessor
+}
+
+func (d disabledPoolAccessor) GetEnabledPools(ipVersion int) ([]v3.IPPool, error) {
+	return nil, nil
+}
@@ -21,6 +21,7 @@ import (
 	"net"
 	"reflect"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -84,7 +85,7 @@ func newBlock(cidr cnet.IPNet, rsvdAttr *HostReservedAttr) allocationBlock {
 }
 
 func (b *allocationBlock) autoAssign(
-	num int, handleID *string, host string, attrs map[string]string, affinityCheck bool) ([]cnet.IPNet, error) {
+	num int, handleID *string, host string, attrs map[string]string, affinityCheck bool, reserved reservations, pool *v3.IPPool) ([]cnet.IPNet, error) {
 
 	// Determine if we need to check for affinity.
 	if affinityCheck && b.Affinity != nil && !hostAffinityMatches(host, b.AllocationBlock) {
@@ -99,12 +100,22 @@ func (b *allocationBlock) autoAssign(
 		}
 	}
 
-	// Walk the allocations until we find enough addresses.
+	// Walk the free addresses, in the order given by the pool's assignment mode, until we
+	// find enough addresses.  Reserved and quarantined addresses are skipped, and keep their
+	// place in the unallocated list.
+	quarantined := b.quarantinedOrdinals(releaseCooldown(pool), time.Now())
 	ordinals := []int{}
-	for len(b.Unallocated) > 0 && len(ordinals) < num {
-		ordinals = append(ordinals, b.Unallocated[0])
-		b.Unallocated = b.Unallocated[1:]
+	for _, o := range b.freeOrdinals(assignmentMode(pool)) {
+		if len(ordinals) == num {
+			break
+		}
+		if quarantined[o] || reserved.contains(b.OrdinalToIP(o)) {
+			continue
+		}
+		ordinals = append(ordinals, o)
 	}
+	b.removeUnallocated(ordinals)
+	b.forgetReleased(ordinals)
 
 	// Create slice of IPs and perform the allocations.
 	ips := []cnet.IPNet{}
@@ -158,6 +169,7 @@ func (b *allocationBlock) assign(affinityCheck bool, address cnet.IP, handleID *
 			break
 		}
 	}
+	b.forgetReleased([]int{ordinal})
 	return nil
 }
 
@@ -198,7 +210,10 @@ func (b *allocationBlock) containsOnlyReservedIPs() bool {
 	return true
 }
 
-func (b *allocationBlock) release(addresses []cnet.IP) ([]cnet.IP, map[string]int, error) {
+// release releases the given addresses.  If quarantine is true, the time of the release is
+// recorded so that the addresses are not automatically assigned again until the pool's
+// release cool-down has passed.
+func (b *allocationBlock) release(addresses []cnet.IP, quarantine bool) ([]cnet.IP, map[string]int, error) {
 	// Store return values.
 	unallocated := []cnet.IP{}
 	countByHandle := map[string]int{}
@@ -286,6 +301,9 @@ func (b *allocationBlock) release(addresses []cnet.IP) ([]cnet.IP, map[string]in
 		b.Allocations[ordinal] = nil
 		b.Unallocated = append(b.Unallocated, ordinal)
 	}
+	if quarantine {
+		b.recordReleased(ordinals, time.Now())
+	}
 	return unallocated, countByHandle, nil
 }
 
@@ -344,35 +362,58 @@ func (b allocationBlock) attributeIndexesByHandle(handleID string) []int {
 	return indexes
 }
 
-func (b *allocationBlock) releaseByHandle(handleID string) int {
+// releaseByHandle releases the addresses assigned with the given handle, recording the time of
+// the release if quarantine is true.  Sticky addresses are not released, but stay assigned to
+// the handle without the attributes of the workload.  Returns the number of addresses that were
+// released, and the number of sticky addresses that were kept.
+func (b *allocationBlock) releaseByHandle(handleID string, quarantine bool) (int, int) {
 	attrIndexes := b.attributeIndexesByHandle(handleID)
 	log.Debugf("Attribute indexes to release: %v", attrIndexes)
 	if len(attrIndexes) == 0 {
 		// Nothing to release.
 		log.Debugf("No addresses assigned to handle '%s'", handleID)
-		return 0
+		return 0, 0
 	}
 
 	// There are addresses to release.
 	ordinals := []int{}
+	sticky := []int{}
 	var o int
 	for o = 0; o < b.NumAddresses(); o++ {
 		// Only check allocated ordinals.
-		if b.Allocations[o] != nil && intInSlice(*b.Allocations[o], attrIndexes) {
+		if b.Allocations[o] != nil && *b.Allocations[o] < len(b.Attributes) && intInSlice(*b.Allocations[o], attrIndexes) {
+			if isSticky(b.Attributes[*b.Allocations[o]]) {
+				sticky = append(sticky, o)
+				continue
+			}
 			// Release this ordinal.
 			ordinals = append(ordinals, o)
 		}
 	}
 
-	// Clean and reorder attributes.
-	b.deleteAttributes(attrIndexes, ordinals)
+	if len(sticky) == 0 {
+		// Clean and reorder attributes.
+		b.deleteAttributes(attrIndexes, ordinals)
+	} else {
+		// Keep the sticky addresses, then clean up any attributes that are no longer used.
+		for _, o := range sticky {
+			b.rebind(o, handleID, stickyAttributes(nil))
+		}
+		for _, o := range ordinals {
+			b.Allocations[o] = nil
+		}
+		b.deleteUnusedAttributes()
+	}
 
 	// Release the addresses.
 	for _, o := range ordinals {
 		b.Allocations[o] = nil
 		b.Unallocated = append(b.Unallocated, o)
 	}
-	return len(ordinals)
+	if quarantine {
+		b.recordReleased(ordinals, time.Now())
+	}
+	return len(ordinals), len(sticky)
 }
 
 func (b allocationBlock) ipsByHandle(handleID string) []cnet.IP {
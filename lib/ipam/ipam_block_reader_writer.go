@@ -116,7 +116,7 @@ func (rw blockReaderWriter) findUnclaimedBlock(ctx context.Context, host string,
 		// Use a block generator to iterate through all of the blocks
 		// that fall within the pool.
 		log.Debugf("Looking for blocks in pool %+v", pool)
-		blocks := randomBlockGenerator(pool, host)
+		blocks := poolBlockGenerator(pool, host)
 		for subnet := blocks(); subnet != nil; subnet = blocks() {
 			// Check if a block already exists for this subnet.
 			log.Debugf("Getting block: %s", subnet.String())
@@ -188,7 +188,7 @@ func (rw blockReaderWriter) claimAffineBlock(ctx context.Context, aff *model.KVP
 	}
 	logCtx.Info("Attempting to create a new block")
 	kvp, err := rw.client.Create(ctx, &o)
-	if err != nil {
+	if err = countConflict("block", err); err != nil {
 		if _, ok := err.(cerrors.ErrorResourceAlreadyExists); ok {
 			// Block already exists, check affinity.
 			logCtx.Info("The block already exists, getting it from data store")
@@ -228,6 +228,7 @@ func (rw blockReaderWriter) claimAffineBlock(ctx context.Context, aff *model.KVP
 
 	// We've successfully claimed the block - confirm the affinity.
 	log.Info("Successfully created block")
+	counterBlocksClaimed.Inc()
 	if _, err = rw.confirmAffinity(ctx, aff); err != nil {
 		return nil, err
 	}
@@ -356,13 +357,14 @@ func (rw blockReaderWriter) queryAffinity(ctx context.Context, host string, cidr
 
 // updateAffinity updates the given affinity.
 func (rw blockReaderWriter) updateAffinity(ctx context.Context, aff *model.KVPair) (*model.KVPair, error) {
-	return rw.client.Update(ctx, aff)
+	kvp, err := rw.client.Update(ctx, aff)
+	return kvp, countConflict("affinity", err)
 }
 
 // deleteAffinity deletes the given affinity.
 func (rw blockReaderWriter) deleteAffinity(ctx context.Context, aff *model.KVPair) error {
 	_, err := rw.client.DeleteKVP(ctx, aff)
-	return err
+	return countConflict("affinity", err)
 }
 
 // queryBlock gets a block for the given block CIDR key.
@@ -376,13 +378,14 @@ func (rw blockReaderWriter) listBlocks(ctx context.Context, revision string) (*m
 
 // updateBlock updates the given block.
 func (rw blockReaderWriter) updateBlock(ctx context.Context, b *model.KVPair) (*model.KVPair, error) {
-	return rw.client.Update(ctx, b)
+	kvp, err := rw.client.Update(ctx, b)
+	return kvp, countConflict("block", err)
 }
 
 // deleteBlock deletes the given block.
 func (rw blockReaderWriter) deleteBlock(ctx context.Context, b *model.KVPair) error {
 	_, err := rw.client.DeleteKVP(ctx, b)
-	return err
+	return countConflict("block", err)
 }
 
 // queryHandle gets a handle for the given handleID key.
@@ -392,13 +395,14 @@ func (rw blockReaderWriter) queryHandle(ctx context.Context, handleID, revision
 
 // updateHandle updates the given handle.
 func (rw blockReaderWriter) updateHandle(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
-	return rw.client.Update(ctx, kvp)
+	kvp, err := rw.client.Update(ctx, kvp)
+	return kvp, countConflict("handle", err)
 }
 
 // deleteHandle deletes the given handle.
 func (rw blockReaderWriter) deleteHandle(ctx context.Context, kvp *model.KVPair) error {
 	_, err := rw.client.DeleteKVP(ctx, kvp)
-	return err
+	return countConflict("handle", err)
 }
 
 // getPoolForIP returns the pool if the given IP is within a configured
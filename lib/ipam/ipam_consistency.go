@@ -0,0 +1,513 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+// DefaultConsistencyGracePeriod is the grace period used when repairing if
+// CheckConsistencyArgs.GracePeriod is not set.
+const DefaultConsistencyGracePeriod = time.Minute
+
+// CheckConsistency scans all allocation blocks, handles and block affinities and reports
+// any inconsistencies between them and the WorkloadEndpoints and Nodes in the datastore.
+// If args.Repair is true, the inconsistencies are also repaired.
+func (c ipamClient) CheckConsistency(ctx context.Context, args CheckConsistencyArgs) (*ConsistencyReport, error) {
+	report, handleRevs, err := c.checkConsistency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !args.Repair || report.Consistent() {
+		return report, nil
+	}
+
+	// An inconsistency may be caused by an IPAM operation that is in progress: an address
+	// is assigned before its WorkloadEndpoint is created, and a handle records an address
+	// before it is assigned in the block and until it has been released from the block.
+	// Only repair the inconsistencies that are still found, unchanged, after the grace period.
+	gracePeriod := args.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = DefaultConsistencyGracePeriod
+	}
+	select {
+	case <-time.After(gracePeriod):
+	case <-ctx.Done():
+		return report, ctx.Err()
+	}
+	confirmed, confirmedRevs, err := c.checkConsistency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	confirmInconsistencies(confirmed, report, confirmedRevs, handleRevs)
+	if confirmed.Consistent() {
+		return confirmed, nil
+	}
+	if err := c.repairConsistency(ctx, confirmed, confirmedRevs); err != nil {
+		return confirmed, err
+	}
+	confirmed.Repaired = true
+	return confirmed, nil
+}
+
+// handleUsage tracks the addresses assigned with a handle, and whether any of them is in use.
+type handleUsage struct {
+	live   bool
+	leaked []LeakedIP
+}
+
+// checkConsistency returns the inconsistencies in the IPAM data, along with the revision of
+// each handle that was checked.
+func (c ipamClient) checkConsistency(ctx context.Context) (*ConsistencyReport, map[string]string, error) {
+	report := &ConsistencyReport{}
+
+	// Read the nodes and workload endpoints that addresses and affinities may refer to.
+	nodes := set.New()
+	kvps, err := c.client.List(ctx, model.ResourceListOptions{Kind: v3.KindNode}, "")
+	if err != nil {
+		log.WithError(err).Error("Failed to list nodes")
+		return nil, nil, err
+	}
+	for _, kvp := range kvps.KVPairs {
+		nodes.Add(kvp.Key.(model.ResourceKey).Name)
+	}
+
+	weps, err := c.client.List(ctx, model.ResourceListOptions{Kind: v3.KindWorkloadEndpoint}, "")
+	if err != nil {
+		log.WithError(err).Error("Failed to list workload endpoints")
+		return nil, nil, err
+	}
+	wepIPs := set.New()
+	wepPods := set.New()
+	for _, kvp := range weps.KVPairs {
+		wep := kvp.Value.(*v3.WorkloadEndpoint)
+		if wep.Spec.Pod != "" {
+			wepPods.Add(wep.Namespace + "/" + wep.Spec.Pod)
+		}
+		for _, n := range wep.Spec.IPNetworks {
+			if _, ipNet, err := net.ParseCIDROrIP(n); err == nil {
+				wepIPs.Add(ipNet.IP.String())
+			}
+		}
+	}
+
+	// Walk the blocks, counting the addresses assigned with each handle, and determining
+	// whether each handle is used by a workload endpoint.
+	blocks, err := c.blockReaderWriter.listBlocks(ctx, "")
+	if err != nil {
+		log.WithError(err).Error("Failed to list allocation blocks")
+		return nil, nil, err
+	}
+	assigned := map[string]map[string]int{}
+	usage := map[string]*handleUsage{}
+	for _, kvp := range blocks.KVPairs {
+		b := allocationBlock{kvp.Value.(*model.AllocationBlock)}
+		if b.empty() && b.Affinity == nil {
+			report.EmptyBlocks = append(report.EmptyBlocks, b.CIDR)
+		}
+
+		for ordinal, attrIdx := range b.Allocations {
+			if attrIdx == nil {
+				continue
+			}
+			if *attrIdx >= len(b.Attributes) {
+				report.InvalidAllocations = append(report.InvalidAllocations, InvalidAllocation{
+					IP: b.OrdinalToIP(ordinal), Block: b.CIDR, AttributeIndex: *attrIdx,
+				})
+				continue
+			}
+			attr := b.Attributes[*attrIdx]
+			if attr.AttrPrimary == nil || strings.ToLower(*attr.AttrPrimary) == WindowsReservedHandle {
+				// Addresses without a handle cannot be attributed to a workload, and
+				// reserved addresses are not recorded in a handle.
+				continue
+			}
+			handleID := *attr.AttrPrimary
+			if assigned[handleID] == nil {
+				assigned[handleID] = map[string]int{}
+			}
+			assigned[handleID][b.CIDR.String()]++
+
+			ip := b.OrdinalToIP(ordinal)
+			leak := LeakedIP{IP: ip, Block: b.CIDR, Handle: handleID, Attrs: attr.AttrSecondary}
+			if attr.AttrSecondary[AttributeType] != "" {
+				// Tunnel addresses belong to a node rather than to a workload.
+				if node := attr.AttrSecondary[AttributeNode]; node != "" && !nodes.Contains(node) {
+					report.LeakedIPs = append(report.LeakedIPs, leak)
+				}
+				continue
+			}
+
+			if isSticky(attr) {
+				// Sticky addresses are kept for their handle while no workload uses them.
+				continue
+			}
+
+			u := usage[handleID]
+			if u == nil {
+				u = &handleUsage{}
+				usage[handleID] = u
+			}
+			u.leaked = append(u.leaked, leak)
+			pod := attr.AttrSecondary[AttributeNamespace] + "/" + attr.AttrSecondary[AttributePod]
+			if wepIPs.Contains(ip.String()) || wepPods.Contains(pod) {
+				u.live = true
+			}
+		}
+	}
+	for _, u := range usage {
+		if !u.live {
+			report.LeakedIPs = append(report.LeakedIPs, u.leaked...)
+		}
+	}
+
+	// Compare the handles with the counts from the blocks.
+	handles, err := c.client.List(ctx, model.IPAMHandleListOptions{}, "")
+	if err != nil {
+		log.WithError(err).Error("Failed to list IPAM handles")
+		return nil, nil, err
+	}
+	handleRevs := map[string]string{}
+	for _, kvp := range handles.KVPairs {
+		handleID := kvp.Key.(model.IPAMHandleKey).HandleID
+		h := kvp.Value.(*model.IPAMHandle)
+		handleRevs[handleID] = kvp.Revision
+		for blockStr, recorded := range h.Block {
+			if n := assigned[handleID][blockStr]; n != recorded {
+				_, blockCIDR, _ := net.ParseCIDR(blockStr)
+				report.HandleMismatches = append(report.HandleMismatches, HandleMismatch{
+					Handle: handleID, Block: *blockCIDR, Recorded: recorded, Assigned: n,
+				})
+			}
+		}
+		for blockStr, n := range assigned[handleID] {
+			if _, ok := h.Block[blockStr]; !ok {
+				_, blockCIDR, _ := net.ParseCIDR(blockStr)
+				report.HandleMismatches = append(report.HandleMismatches, HandleMismatch{
+					Handle: handleID, Block: *blockCIDR, Assigned: n,
+				})
+			}
+		}
+		delete(assigned, handleID)
+	}
+	for handleID, counts := range assigned {
+		// Addresses are assigned with a handle that does not exist.
+		for blockStr, n := range counts {
+			_, blockCIDR, _ := net.ParseCIDR(blockStr)
+			report.HandleMismatches = append(report.HandleMismatches, HandleMismatch{
+				Handle: handleID, Block: *blockCIDR, Assigned: n,
+			})
+		}
+	}
+
+	// Find affinities for hosts that have no node.
+	affs, err := c.client.List(ctx, model.BlockAffinityListOptions{}, "")
+	if err != nil {
+		log.WithError(err).Error("Failed to list block affinities")
+		return nil, nil, err
+	}
+	for _, kvp := range affs.KVPairs {
+		k := kvp.Key.(model.BlockAffinityKey)
+		if !nodes.Contains(k.Host) {
+			report.OrphanedAffinities = append(report.OrphanedAffinities, OrphanedAffinity{Host: k.Host, Block: k.CIDR})
+		}
+	}
+
+	sortReport(report)
+	log.WithFields(log.Fields{
+		"leakedIPs":          len(report.LeakedIPs),
+		"handleMismatches":   len(report.HandleMismatches),
+		"orphanedAffinities": len(report.OrphanedAffinities),
+		"emptyBlocks":        len(report.EmptyBlocks),
+	}).Info("Checked IPAM consistency")
+	return report, handleRevs, nil
+}
+
+// sortReport sorts the entries of the report so that the output is deterministic.
+func sortReport(r *ConsistencyReport) {
+	sort.Slice(r.LeakedIPs, func(i, j int) bool {
+		return r.LeakedIPs[i].IP.String() < r.LeakedIPs[j].IP.String()
+	})
+	sort.Slice(r.HandleMismatches, func(i, j int) bool {
+		if r.HandleMismatches[i].Handle != r.HandleMismatches[j].Handle {
+			return r.HandleMismatches[i].Handle < r.HandleMismatches[j].Handle
+		}
+		return r.HandleMismatches[i].Block.String() < r.HandleMismatches[j].Block.String()
+	})
+	sort.Slice(r.OrphanedAffinities, func(i, j int) bool {
+		if r.OrphanedAffinities[i].Host != r.OrphanedAffinities[j].Host {
+			return r.OrphanedAffinities[i].Host < r.OrphanedAffinities[j].Host
+		}
+		return r.OrphanedAffinities[i].Block.String() < r.OrphanedAffinities[j].Block.String()
+	})
+	sort.Slice(r.EmptyBlocks, func(i, j int) bool {
+		return r.EmptyBlocks[i].String() < r.EmptyBlocks[j].String()
+	})
+}
+
+// confirmInconsistencies removes the leaked addresses and handle mismatches from the report
+// that were not found, unchanged, by the earlier check.  A handle mismatch is only confirmed
+// if the handle has the same revision in both checks.
+func confirmInconsistencies(r, earlier *ConsistencyReport, revs, earlierRevs map[string]string) {
+	earlierLeaks := map[string]LeakedIP{}
+	for _, l := range earlier.LeakedIPs {
+		earlierLeaks[l.IP.String()] = l
+	}
+	leaked := []LeakedIP{}
+	for _, l := range r.LeakedIPs {
+		if e, ok := earlierLeaks[l.IP.String()]; ok && e.Handle == l.Handle && attrsEqual(e.Attrs, l.Attrs) {
+			leaked = append(leaked, l)
+		}
+	}
+	r.LeakedIPs = leaked
+
+	earlierMismatches := map[string]HandleMismatch{}
+	for _, m := range earlier.HandleMismatches {
+		earlierMismatches[m.Handle+"/"+m.Block.String()] = m
+	}
+	mismatches := []HandleMismatch{}
+	for _, m := range r.HandleMismatches {
+		e, ok := earlierMismatches[m.Handle+"/"+m.Block.String()]
+		if ok && e.Recorded == m.Recorded && e.Assigned == m.Assigned && revs[m.Handle] == earlierRevs[m.Handle] {
+			mismatches = append(mismatches, m)
+		}
+	}
+	r.HandleMismatches = mismatches
+}
+
+// attrsEqual returns true if the attribute maps have the same key/value pairs.
+func attrsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// repairConsistency repairs the inconsistencies in the report.  handleRevs holds the revision
+// of each handle when the report was made.
+func (c ipamClient) repairConsistency(ctx context.Context, report *ConsistencyReport, handleRevs map[string]string) error {
+	// Release the leaked addresses.  This also decrements their handles.
+	leaksByBlock := map[string][]LeakedIP{}
+	for _, l := range report.LeakedIPs {
+		leaksByBlock[l.Block.String()] = append(leaksByBlock[l.Block.String()], l)
+	}
+	if len(leaksByBlock) > 0 {
+		pools, err := c.allPools()
+		if err != nil {
+			return err
+		}
+		for _, leaks := range leaksByBlock {
+			log.Infof("Releasing %d leaked IPs from block %s", len(leaks), leaks[0].Block)
+			if err := c.releaseLeakedIPs(ctx, leaks[0].Block, leaks, pools); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Bring each mismatched handle, and each handle whose addresses have been released,
+	// into line with its block.
+	type handleBlock struct {
+		handle string
+		block  string
+	}
+	repaired := set.New()
+	repairHandle := func(handleID string, blockCIDR net.IPNet) error {
+		hb := handleBlock{handleID, blockCIDR.String()}
+		if repaired.Contains(hb) {
+			return nil
+		}
+		repaired.Add(hb)
+		return c.repairHandle(ctx, handleID, blockCIDR, handleRevs[handleID])
+	}
+	for _, m := range report.HandleMismatches {
+		if err := repairHandle(m.Handle, m.Block); err != nil {
+			return err
+		}
+	}
+	for _, l := range report.LeakedIPs {
+		if err := repairHandle(l.Handle, l.Block); err != nil {
+			return err
+		}
+	}
+
+	// Remove the IPAM data of hosts that have no node.
+	hosts := set.New()
+	for _, a := range report.OrphanedAffinities {
+		if hosts.Contains(a.Host) {
+			continue
+		}
+		hosts.Add(a.Host)
+		if err := c.RemoveIPAMHost(ctx, a.Host); err != nil {
+			return err
+		}
+	}
+
+	// Delete the blocks that are still empty and not affine to any host.
+	for _, cidr := range report.EmptyBlocks {
+		if err := c.deleteEmptyBlock(ctx, cidr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseLeakedIPs releases the leaked addresses in the block, provided that each address is
+// still assigned with the handle and attributes that it had when it was found to be leaked.
+// The addresses are checked against the block that is updated, so an address that has been
+// released and assigned again since the check is not released.
+func (c ipamClient) releaseLeakedIPs(ctx context.Context, blockCIDR net.IPNet, leaks []LeakedIP, pools []v3.IPPool) error {
+	byIP := map[string]LeakedIP{}
+	ips := []net.IP{}
+	for _, l := range leaks {
+		byIP[l.IP.String()] = l
+		ips = append(ips, l.IP)
+	}
+	_, err := c.releaseIPsFromBlock(ctx, ips, blockCIDR, pools, func(b allocationBlock, ip net.IP) bool {
+		l := byIP[ip.String()]
+		ordinal, err := b.IPToOrdinal(ip)
+		if err != nil || b.Allocations[ordinal] == nil || *b.Allocations[ordinal] >= len(b.Attributes) {
+			return false
+		}
+		attr := b.Attributes[*b.Allocations[ordinal]]
+		if attr.AttrPrimary == nil || *attr.AttrPrimary != l.Handle || !attrsEqual(attr.AttrSecondary, l.Attrs) {
+			log.WithField("ip", ip).Info("Address has been reassigned since it was found to be leaked, not releasing it")
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// repairHandle sets the number of addresses that the handle records for the block to the
+// number of addresses in the block that are assigned with the handle, creating or deleting
+// the handle as required.  rev is the revision of the handle when the mismatch was found.
+//
+// An assignment updates the handle before the block, so the handle is read before the block
+// and updated under the handle's revision; a concurrent assignment causes the update to fail
+// and be retried.  Since the handle also records an address while it is being assigned or
+// released, the recorded number is only lowered if the handle has not changed since the
+// mismatch was found.
+func (c ipamClient) repairHandle(ctx context.Context, handleID string, blockCIDR net.IPNet, rev string) error {
+	logCtx := log.WithFields(log.Fields{"handle": handleID, "cidr": blockCIDR})
+	for i := 0; i < datastoreRetries; i++ {
+		obj, err := c.blockReaderWriter.queryHandle(ctx, handleID, "")
+		if err != nil {
+			if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok {
+				return err
+			}
+			obj = &model.KVPair{
+				Key:   model.IPAMHandleKey{HandleID: handleID},
+				Value: &model.IPAMHandle{HandleID: handleID, Block: map[string]int{}},
+			}
+		}
+		handle := allocationHandle{obj.Value.(*model.IPAMHandle)}
+
+		num := 0
+		block, err := c.blockReaderWriter.queryBlock(ctx, blockCIDR, "")
+		if err == nil {
+			num = len(allocationBlock{block.Value.(*model.AllocationBlock)}.ipsByHandle(handleID))
+		} else if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok {
+			return err
+		}
+
+		recorded := handle.Block[blockCIDR.String()]
+		if recorded == num {
+			logCtx.Debug("Handle is consistent with block")
+			return nil
+		}
+		if recorded > num && obj.Revision != rev {
+			logCtx.Info("Handle has changed since it was checked, not lowering its count")
+			return nil
+		}
+		if num == 0 {
+			delete(handle.Block, blockCIDR.String())
+		} else {
+			handle.Block[blockCIDR.String()] = num
+		}
+
+		switch {
+		case handle.empty():
+			logCtx.Info("Deleting handle with no assigned addresses")
+			err = c.blockReaderWriter.deleteHandle(ctx, obj)
+		case obj.Revision == "":
+			logCtx.Infof("Creating handle for %d assigned addresses", num)
+			_, err = c.client.Create(ctx, obj)
+			err = countConflict("handle", err)
+		default:
+			logCtx.Infof("Updating handle to %d assigned addresses", num)
+			_, err = c.blockReaderWriter.updateHandle(ctx, obj)
+		}
+		if err != nil {
+			switch err.(type) {
+			case cerrors.ErrorResourceUpdateConflict, cerrors.ErrorResourceAlreadyExists:
+				logCtx.WithError(err).Debug("Conflict repairing handle - retry")
+				continue
+			case cerrors.ErrorResourceDoesNotExist:
+				// The handle was deleted while we were repairing it - check it again.
+				continue
+			}
+			return err
+		}
+		return nil
+	}
+	return errors.New("Max retries hit - excessive concurrent IPAM requests")
+}
+
+// deleteEmptyBlock deletes the block if it has no assigned addresses and no affinity.
+func (c ipamClient) deleteEmptyBlock(ctx context.Context, blockCIDR net.IPNet) error {
+	logCtx := log.WithField("cidr", blockCIDR)
+	for i := 0; i < datastoreRetries; i++ {
+		obj, err := c.blockReaderWriter.queryBlock(ctx, blockCIDR, "")
+		if err != nil {
+			if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok {
+				return nil
+			}
+			return err
+		}
+		b := allocationBlock{obj.Value.(*model.AllocationBlock)}
+		if !b.empty() || b.Affinity != nil {
+			logCtx.Info("Block is no longer empty and unaffine, not deleting it")
+			return nil
+		}
+
+		logCtx.Info("Deleting empty block with no affinity")
+		if err = c.blockReaderWriter.deleteBlock(ctx, obj); err != nil {
+			if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok {
+				continue
+			} else if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.New("Max retries hit - excessive concurrent IPAM requests")
+}
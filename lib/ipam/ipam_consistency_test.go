@@ -0,0 +1,304 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = testutils.E2eDatastoreDescribe("IPAM consistency tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	var bc bapi.Client
+	var ic Interface
+	var kc *kubernetes.Clientset
+	var pods []string
+
+	assign := func(ip, handle string, attrs map[string]string) {
+		err := ic.AssignIP(ctx, AssignIPArgs{
+			IP:       cnet.IP{IP: net.ParseIP(ip)},
+			HandleID: &handle,
+			Attrs:    attrs,
+			Hostname: "node-1",
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	createWEP := func(name, pod string, ipNetworks ...string) {
+		if kc != nil {
+			// Workload endpoints are backed by pods in KDD.
+			p := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: "default", Annotations: map[string]string{}},
+				Spec: corev1.PodSpec{
+					NodeName:   "node-1",
+					Containers: []corev1.Container{{Name: "container1", Image: "busybox"}},
+				},
+			}
+			if len(ipNetworks) > 0 {
+				p.Annotations[conversion.AnnotationPodIP] = ipNetworks[0]
+			}
+			_, err := kc.CoreV1().Pods("default").Create(p)
+			Expect(err).NotTo(HaveOccurred())
+			pods = append(pods, pod)
+			return
+		}
+		_, err := bc.Create(ctx, &model.KVPair{
+			Key: model.ResourceKey{Kind: v3.KindWorkloadEndpoint, Namespace: "default", Name: name},
+			Value: &v3.WorkloadEndpoint{
+				TypeMeta:   metav1.TypeMeta{Kind: v3.KindWorkloadEndpoint, APIVersion: v3.GroupVersionCurrent},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       v3.WorkloadEndpointSpec{Node: "node-1", Pod: pod, IPNetworks: ipNetworks},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	isAssigned := func(ip string) bool {
+		_, _, err := ic.GetAssignmentAttributes(ctx, cnet.IP{IP: net.ParseIP(ip)})
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		bc, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		bc.Clean()
+		ic = NewIPAMClient(bc, ipPools)
+		kc = kubeClientset(bc)
+
+		deleteAllPools()
+		applyPoolWithBlockSize("10.0.0.0/24", true, "all()", 26)
+		Expect(applyNode(bc, kc, "node-1", nil)).NotTo(HaveOccurred())
+		deleteNode(bc, kc, "node-2")
+	})
+
+	AfterEach(func() {
+		zero := int64(0)
+		for _, pod := range pods {
+			kc.CoreV1().Pods("default").Delete(pod, &metav1.DeleteOptions{GracePeriodSeconds: &zero})
+		}
+		pods = nil
+		deleteNode(bc, kc, "node-1")
+		deleteAllPools()
+		bc.Clean()
+	})
+
+	It("should report no inconsistencies when all addresses are in use", func() {
+		assign("10.0.0.1", "pod1", map[string]string{AttributeNamespace: "default", AttributePod: "pod1"})
+		createWEP("node--1-k8s-pod1-eth0", "pod1")
+		assign("10.0.0.2", "tunnel", map[string]string{AttributeNode: "node-1", AttributeType: AttributeTypeVXLAN})
+
+		report, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{Repair: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Consistent()).To(BeTrue())
+		Expect(report.Repaired).To(BeFalse())
+		Expect(isAssigned("10.0.0.1")).To(BeTrue())
+		Expect(isAssigned("10.0.0.2")).To(BeTrue())
+	})
+
+	It("should report an address whose attributes are missing from the block", func() {
+		assign("10.0.0.1", "pod1", map[string]string{AttributeNamespace: "default", AttributePod: "pod1"})
+		createWEP("node--1-k8s-pod1-eth0", "pod1")
+		kvp, err := bc.Get(ctx, model.BlockKey{CIDR: cnet.MustParseCIDR("10.0.0.0/26")}, "")
+		Expect(err).NotTo(HaveOccurred())
+		b := kvp.Value.(*model.AllocationBlock)
+		missing := len(b.Attributes)
+		b.Allocations[5] = &missing
+		_, err = bc.Update(ctx, kvp)
+		Expect(err).NotTo(HaveOccurred())
+
+		report, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Consistent()).To(BeFalse())
+		Expect(report.LeakedIPs).To(BeEmpty())
+		Expect(report.InvalidAllocations).To(HaveLen(1))
+		Expect(report.InvalidAllocations[0].IP.String()).To(Equal("10.0.0.5"))
+		Expect(report.InvalidAllocations[0].AttributeIndex).To(Equal(missing))
+	})
+
+	Context("with inconsistent IPAM data", func() {
+		BeforeEach(func() {
+			// An address used by a pod, whose handle overcounts the address.
+			assign("10.0.0.1", "pod1", map[string]string{AttributeNamespace: "default", AttributePod: "pod1"})
+			createWEP("node--1-k8s-pod1-eth0", "pod1")
+			kvp, err := bc.Get(ctx, model.IPAMHandleKey{HandleID: "pod1"}, "")
+			Expect(err).NotTo(HaveOccurred())
+			kvp.Value.(*model.IPAMHandle).Block["10.0.0.0/26"] = 3
+			_, err = bc.Update(ctx, kvp)
+			Expect(err).NotTo(HaveOccurred())
+
+			// An address used by a workload endpoint, whose handle has been deleted.
+			assign("10.0.0.2", "wep2", nil)
+			createWEP("node--1-k8s-wep2-eth0", "wep2", "10.0.0.2/32")
+			_, err = bc.Delete(ctx, model.IPAMHandleKey{HandleID: "wep2"}, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			// An address whose pod no longer exists, and a tunnel address for a deleted node.
+			assign("10.0.0.3", "gone", map[string]string{AttributeNamespace: "default", AttributePod: "gone"})
+			assign("10.0.0.4", "tunnel", map[string]string{AttributeNode: "node-gone", AttributeType: AttributeTypeVXLAN})
+
+			// An affinity for a host that has no node.
+			_, _, err = ic.ClaimAffinity(ctx, cnet.MustParseCIDR("10.0.0.64/26"), "node-2")
+			Expect(err).NotTo(HaveOccurred())
+
+			// An empty block with no affinity.
+			cidr := cnet.MustParseCIDR("10.0.0.128/26")
+			_, err = bc.Create(ctx, &model.KVPair{
+				Key:   model.BlockKey{CIDR: cidr},
+				Value: newBlock(cidr, nil).AllocationBlock,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should report the inconsistencies", func() {
+			report, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Consistent()).To(BeFalse())
+			Expect(report.Repaired).To(BeFalse())
+
+			Expect(report.LeakedIPs).To(HaveLen(2))
+			Expect(report.LeakedIPs[0].IP.String()).To(Equal("10.0.0.3"))
+			Expect(report.LeakedIPs[0].Handle).To(Equal("gone"))
+			Expect(report.LeakedIPs[0].Block.String()).To(Equal("10.0.0.0/26"))
+			Expect(report.LeakedIPs[0].Attrs).To(HaveKeyWithValue(AttributePod, "gone"))
+			Expect(report.LeakedIPs[1].IP.String()).To(Equal("10.0.0.4"))
+			Expect(report.LeakedIPs[1].Handle).To(Equal("tunnel"))
+
+			Expect(report.HandleMismatches).To(HaveLen(2))
+			Expect(report.HandleMismatches[0].Handle).To(Equal("pod1"))
+			Expect(report.HandleMismatches[0].Block.String()).To(Equal("10.0.0.0/26"))
+			Expect(report.HandleMismatches[0].Recorded).To(Equal(3))
+			Expect(report.HandleMismatches[0].Assigned).To(Equal(1))
+			Expect(report.HandleMismatches[1].Handle).To(Equal("wep2"))
+			Expect(report.HandleMismatches[1].Recorded).To(Equal(0))
+			Expect(report.HandleMismatches[1].Assigned).To(Equal(1))
+
+			Expect(report.OrphanedAffinities).To(HaveLen(1))
+			Expect(report.OrphanedAffinities[0].Host).To(Equal("node-2"))
+			Expect(report.OrphanedAffinities[0].Block.String()).To(Equal("10.0.0.64/26"))
+
+			Expect(report.EmptyBlocks).To(HaveLen(1))
+			Expect(report.EmptyBlocks[0].String()).To(Equal("10.0.0.128/26"))
+
+			By("not changing the datastore")
+			Expect(isAssigned("10.0.0.3")).To(BeTrue())
+			again, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(again).To(Equal(report))
+		})
+
+		It("should repair the inconsistencies", func() {
+			report, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{Repair: true, GracePeriod: time.Millisecond})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Consistent()).To(BeFalse())
+			Expect(report.Repaired).To(BeTrue())
+
+			Expect(isAssigned("10.0.0.1")).To(BeTrue())
+			Expect(isAssigned("10.0.0.2")).To(BeTrue())
+			Expect(isAssigned("10.0.0.3")).To(BeFalse())
+			Expect(isAssigned("10.0.0.4")).To(BeFalse())
+
+			ips, err := ic.IPsByHandle(ctx, "wep2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(HaveLen(1))
+			_, err = ic.IPsByHandle(ctx, "gone")
+			Expect(err).To(HaveOccurred())
+			Expect(getAffineBlocks(bc, "node-2")).To(BeEmpty())
+
+			report, err = ic.CheckConsistency(ctx, CheckConsistencyArgs{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Consistent()).To(BeTrue())
+		})
+
+		It("should wait for the default grace period if none is given", func() {
+			shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			report, err := ic.CheckConsistency(shortCtx, CheckConsistencyArgs{Repair: true})
+			Expect(err).To(Equal(context.DeadlineExceeded))
+			Expect(report.Repaired).To(BeFalse())
+			Expect(isAssigned("10.0.0.3")).To(BeTrue())
+		})
+
+		It("should not repair inconsistencies that are resolved during the grace period", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(100 * time.Millisecond)
+				createWEP("node--1-k8s-gone-eth0", "gone")
+				kvp, err := bc.Get(ctx, model.IPAMHandleKey{HandleID: "pod1"}, "")
+				Expect(err).NotTo(HaveOccurred())
+				kvp.Value.(*model.IPAMHandle).Block["10.0.0.0/26"] = 1
+				_, err = bc.Update(ctx, kvp)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			report, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{Repair: true, GracePeriod: time.Second})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Repaired).To(BeTrue())
+			Expect(report.LeakedIPs).To(HaveLen(1))
+			Expect(report.LeakedIPs[0].IP.String()).To(Equal("10.0.0.4"))
+			Expect(report.HandleMismatches).To(HaveLen(1))
+			Expect(report.HandleMismatches[0].Handle).To(Equal("wep2"))
+
+			Expect(isAssigned("10.0.0.3")).To(BeTrue())
+			Expect(isAssigned("10.0.0.4")).To(BeFalse())
+		})
+
+		It("should not release a leaked address that has been assigned again", func() {
+			report, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{})
+			Expect(err).NotTo(HaveOccurred())
+			leak := report.LeakedIPs[0]
+			Expect(leak.IP.String()).To(Equal("10.0.0.3"))
+
+			_, err = ic.ReleaseIPs(ctx, []cnet.IP{leak.IP})
+			Expect(err).NotTo(HaveOccurred())
+			assign("10.0.0.3", "gone", map[string]string{AttributeNamespace: "default", AttributePod: "new"})
+
+			err = ic.(*ipamClient).releaseLeakedIPs(ctx, leak.Block, []LeakedIP{leak}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isAssigned("10.0.0.3")).To(BeTrue())
+		})
+
+		It("should only lower a handle's count if the handle is unchanged since the check", func() {
+			blockCIDR := cnet.MustParseCIDR("10.0.0.0/26")
+			err := ic.(*ipamClient).repairHandle(ctx, "pod1", blockCIDR, "stale")
+			Expect(err).NotTo(HaveOccurred())
+			kvp, err := bc.Get(ctx, model.IPAMHandleKey{HandleID: "pod1"}, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp.Value.(*model.IPAMHandle).Block).To(HaveKeyWithValue("10.0.0.0/26", 3))
+
+			err = ic.(*ipamClient).repairHandle(ctx, "pod1", blockCIDR, kvp.Revision)
+			Expect(err).NotTo(HaveOccurred())
+			kvp, err = bc.Get(ctx, model.IPAMHandleKey{HandleID: "pod1"}, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kvp.Value.(*model.IPAMHandle).Block).To(HaveKeyWithValue("10.0.0.0/26", 1))
+		})
+	})
+})
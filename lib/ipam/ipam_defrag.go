@@ -0,0 +1,170 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+// DefragmentBlocks identifies hosts that hold more affine blocks than they need for the addresses
+// they have in use, and returns a plan to consolidate them.  If args.Execute is true, affinity is
+// released for the surplus blocks that are empty.
+func (c ipamClient) DefragmentBlocks(ctx context.Context, args DefragmentBlocksArgs) (*DefragmentBlocksPlan, error) {
+	cfg, err := c.GetIPAMConfig(ctx)
+	if err != nil {
+		log.WithError(err).Error("Failed to get IPAM Config")
+		return nil, err
+	}
+
+	// Use the more restrictive of the global block limit and the limit provided on this request.
+	maxBlocks := args.MaxBlocksPerHost
+	if cfg.MaxBlocksPerHost > 0 && (maxBlocks == 0 || maxBlocks > cfg.MaxBlocksPerHost) {
+		maxBlocks = cfg.MaxBlocksPerHost
+	}
+
+	blocks, err := c.blockReaderWriter.listBlocks(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the affine blocks by host and IP version.
+	type hostVersion struct {
+		host    string
+		version int
+	}
+	wantAllHosts := len(args.Hosts) == 0
+	wantedHosts := set.FromArray(args.Hosts)
+	affine := map[hostVersion][]allocationBlock{}
+	for _, kvp := range blocks.KVPairs {
+		b := allocationBlock{kvp.Value.(*model.AllocationBlock)}
+		host := b.Host()
+		if host == "" || !(wantAllHosts || wantedHosts.Contains(host)) {
+			continue
+		}
+		key := hostVersion{host: host, version: b.CIDR.Version()}
+		affine[key] = append(affine[key], b)
+	}
+
+	plan := &DefragmentBlocksPlan{}
+	for key, hostBlocks := range affine {
+		hd := planDefragmentation(key.host, key.version, hostBlocks, maxBlocks)
+		if len(hd.Release) == 0 && len(hd.Drain) == 0 {
+			continue
+		}
+		plan.Hosts = append(plan.Hosts, hd)
+	}
+	sort.Slice(plan.Hosts, func(i, j int) bool {
+		if plan.Hosts[i].Host != plan.Hosts[j].Host {
+			return plan.Hosts[i].Host < plan.Hosts[j].Host
+		}
+		return plan.Hosts[i].IPVersion < plan.Hosts[j].IPVersion
+	})
+
+	if !args.Execute {
+		return plan, nil
+	}
+	for i := range plan.Hosts {
+		hd := &plan.Hosts[i]
+		for _, cidr := range hd.Release {
+			logCtx := log.WithFields(log.Fields{"host": hd.Host, "cidr": cidr})
+			if err := c.ReleaseAffinity(ctx, cidr, hd.Host, true); err != nil {
+				if _, ok := err.(errBlockNotEmpty); ok {
+					// An address was assigned from the block since the plan was made.
+					logCtx.Info("Block is no longer empty, keeping its affinity")
+					continue
+				}
+				logCtx.WithError(err).Error("Failed to release block affinity")
+				return plan, err
+			}
+			logCtx.Info("Released affinity for surplus block")
+			hd.Released = append(hd.Released, cidr)
+		}
+	}
+	return plan, nil
+}
+
+// planDefragmentation plans the consolidation of the given blocks, which are all affine to the host
+// and of the same IP version.  The host keeps its most used blocks until they can hold all of the
+// addresses it has in use, keeping at least one block and no more than maxBlocks, if non-zero.
+// The remaining blocks are released if empty, or drained otherwise.
+func planDefragmentation(host string, version int, blocks []allocationBlock, maxBlocks int) HostDefragmentation {
+	hd := HostDefragmentation{Host: host, IPVersion: version, Blocks: len(blocks)}
+	inUse, reserved := map[string]int{}, map[string]int{}
+	for _, b := range blocks {
+		n, r := b.numInUse()
+		inUse[b.CIDR.String()] = n
+		reserved[b.CIDR.String()] = r
+		hd.InUse += n
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		ui, uj := inUse[blocks[i].CIDR.String()], inUse[blocks[j].CIDR.String()]
+		if ui != uj {
+			return ui > uj
+		}
+		return blocks[i].CIDR.String() < blocks[j].CIDR.String()
+	})
+
+	keep, capacity := 0, 0
+	for _, b := range blocks {
+		if keep > 0 && capacity >= hd.InUse {
+			break
+		}
+		if maxBlocks > 0 && keep >= maxBlocks {
+			break
+		}
+		keep++
+		capacity += b.NumAddresses() - reserved[b.CIDR.String()]
+	}
+	log.WithFields(log.Fields{"host": host, "version": version, "inUse": hd.InUse}).Debugf("Keeping %d of %d blocks", keep, len(blocks))
+
+	for _, b := range blocks[keep:] {
+		cidr := cnet.IPNet{IPNet: b.CIDR.IPNet}
+		if b.empty() {
+			hd.Release = append(hd.Release, cidr)
+		} else {
+			hd.Drain = append(hd.Drain, cidr)
+		}
+	}
+	return hd
+}
+
+// numInUse returns the number of addresses in use in the block, and separately the number of
+// addresses that are reserved by the block itself, which can never be assigned.
+func (b allocationBlock) numInUse() (inUse, reserved int) {
+	for ordinal, attrIdx := range b.Allocations {
+		if attrIdx == nil {
+			continue
+		}
+		if *attrIdx >= len(b.Attributes) {
+			log.WithField("block", b.CIDR).Warnf("Missing attributes for IP with ordinal %d", ordinal)
+			continue
+		}
+		attrs := b.Attributes[*attrIdx]
+		if attrs.AttrPrimary != nil && strings.ToLower(*attrs.AttrPrimary) == WindowsReservedHandle {
+			reserved++
+			continue
+		}
+		inUse++
+	}
+	return inUse, reserved
+}
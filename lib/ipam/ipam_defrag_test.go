@@ -0,0 +1,176 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = Describe("Block defragmentation planning", func() {
+	handle := "defrag-handle"
+	blockWith := func(cidr string, num int) allocationBlock {
+		b := newBlock(cnet.MustParseCIDR(cidr), nil)
+		if num > 0 {
+			_, err := b.autoAssign(num, &handle, "host", nil, false, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		return b
+	}
+	cidrs := func(s ...string) []cnet.IPNet {
+		var nets []cnet.IPNet
+		for _, c := range s {
+			nets = append(nets, cnet.MustParseCIDR(c))
+		}
+		return nets
+	}
+
+	It("should keep the most used blocks that can hold the addresses in use", func() {
+		hd := planDefragmentation("host", 4, []allocationBlock{
+			blockWith("10.0.0.0/28", 2),
+			blockWith("10.0.0.16/28", 0),
+			blockWith("10.0.0.32/28", 10),
+			blockWith("10.0.0.48/28", 5),
+		}, 0)
+		Expect(hd.Blocks).To(Equal(4))
+		Expect(hd.InUse).To(Equal(17))
+		Expect(hd.Release).To(Equal(cidrs("10.0.0.16/28")))
+		Expect(hd.Drain).To(Equal(cidrs("10.0.0.0/28")))
+	})
+
+	It("should keep one block when nothing is in use", func() {
+		hd := planDefragmentation("host", 4, []allocationBlock{
+			blockWith("10.0.0.16/28", 0),
+			blockWith("10.0.0.0/28", 0),
+		}, 0)
+		Expect(hd.Release).To(Equal(cidrs("10.0.0.16/28")))
+		Expect(hd.Drain).To(BeEmpty())
+	})
+
+	It("should not keep more than the maximum number of blocks", func() {
+		hd := planDefragmentation("host", 4, []allocationBlock{
+			blockWith("10.0.0.0/28", 10),
+			blockWith("10.0.0.16/28", 10),
+			blockWith("10.0.0.32/28", 1),
+		}, 1)
+		Expect(hd.Release).To(BeEmpty())
+		Expect(hd.Drain).To(Equal(cidrs("10.0.0.16/28", "10.0.0.32/28")))
+	})
+
+	It("should not count reserved addresses as capacity", func() {
+		rsvd := &HostReservedAttr{StartOfBlock: 3, EndOfBlock: 1, Handle: WindowsReservedHandle, Note: "windows host rsvd"}
+		reservedBlockWith := func(cidr string, num int) allocationBlock {
+			b := newBlock(cnet.MustParseCIDR(cidr), rsvd)
+			_, err := b.autoAssign(num, &handle, "host", nil, false, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			return b
+		}
+
+		// 14 addresses are in use, but each block can only hold 12.
+		hd := planDefragmentation("host", 4, []allocationBlock{
+			reservedBlockWith("10.0.0.0/28", 10),
+			reservedBlockWith("10.0.0.16/28", 4),
+		}, 0)
+		Expect(hd.InUse).To(Equal(14))
+		Expect(hd.Release).To(BeEmpty())
+		Expect(hd.Drain).To(BeEmpty())
+	})
+
+	It("should ignore allocations with missing attributes", func() {
+		b := blockWith("10.0.0.0/28", 2)
+		missing := len(b.Attributes)
+		b.Allocations[5] = &missing
+		hd := planDefragmentation("host", 4, []allocationBlock{b}, 0)
+		Expect(hd.InUse).To(Equal(2))
+	})
+})
+
+var _ = testutils.E2eDatastoreDescribe("IPAM block defragmentation tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	var bc bapi.Client
+	var ic Interface
+	var kc *kubernetes.Clientset
+
+	BeforeEach(func() {
+		var err error
+		bc, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		bc.Clean()
+		ic = NewIPAMClient(bc, staticPoolAccessor{{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/26", BlockSize: 28}}})
+		kc = kubeClientset(bc)
+		Expect(applyNode(bc, kc, "node-1", nil)).NotTo(HaveOccurred())
+		Expect(applyNode(bc, kc, "node-2", nil)).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		deleteNode(bc, kc, "node-1")
+		deleteNode(bc, kc, "node-2")
+		bc.Clean()
+	})
+
+	It("should release the empty surplus blocks so that other hosts can claim them", func() {
+		// node-1 claims the whole pool, but only uses two of its blocks, and node-2 cannot borrow.
+		Expect(ic.SetIPAMConfig(ctx, IPAMConfig{StrictAffinity: true, AutoAllocateBlocks: true})).NotTo(HaveOccurred())
+		claimed, _, err := ic.ClaimAffinity(ctx, cnet.MustParseCIDR("10.0.0.0/26"), "node-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed).To(HaveLen(4))
+		for _, ip := range []string{"10.0.0.1", "10.0.0.17"} {
+			Expect(ic.AssignIP(ctx, AssignIPArgs{IP: cnet.MustParseIP(ip), Hostname: "node-1"})).NotTo(HaveOccurred())
+		}
+		v4, _, err := ic.AutoAssign(ctx, AutoAssignArgs{Num4: 1, Hostname: "node-2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(BeEmpty())
+
+		plan, err := ic.DefragmentBlocks(ctx, DefragmentBlocksArgs{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Hosts).To(HaveLen(1))
+		Expect(plan.Hosts[0].Host).To(Equal("node-1"))
+		Expect(plan.Hosts[0].IPVersion).To(Equal(4))
+		Expect(plan.Hosts[0].Blocks).To(Equal(4))
+		Expect(plan.Hosts[0].InUse).To(Equal(2))
+		Expect(plan.Hosts[0].Drain).To(Equal([]cnet.IPNet{cnet.MustParseCIDR("10.0.0.16/28")}))
+		Expect(plan.Hosts[0].Release).To(Equal([]cnet.IPNet{cnet.MustParseCIDR("10.0.0.32/28"), cnet.MustParseCIDR("10.0.0.48/28")}))
+		Expect(plan.Hosts[0].Released).To(BeEmpty())
+
+		// Planning for another host finds nothing to do.
+		plan, err = ic.DefragmentBlocks(ctx, DefragmentBlocksArgs{Hosts: []string{"node-2"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Hosts).To(BeEmpty())
+
+		plan, err = ic.DefragmentBlocks(ctx, DefragmentBlocksArgs{Execute: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Hosts[0].Released).To(Equal(plan.Hosts[0].Release))
+		for _, cidr := range plan.Hosts[0].Released {
+			_, err = bc.Get(ctx, model.BlockAffinityKey{Host: "node-1", CIDR: cidr}, "")
+			Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		}
+
+		v4, _, err = ic.AutoAssign(ctx, AutoAssignArgs{Num4: 1, Hostname: "node-2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(1))
+	})
+})
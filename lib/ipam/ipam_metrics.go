@@ -0,0 +1,111 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cprometheus "github.com/projectcalico/libcalico-go/lib/prometheus"
+)
+
+// Names of the instrumented IPAM operations, used as the "operation" label.
+const (
+	opAutoAssign      = "AutoAssign"
+	opAssignIP        = "AssignIP"
+	opReleaseIPs      = "ReleaseIPs"
+	opReleaseByHandle = "ReleaseByHandle"
+	opClaimAffinity   = "ClaimAffinity"
+)
+
+var (
+	counterOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "calico_ipam_operations_total",
+		Help: "Number of IPAM operations, by operation and result.",
+	}, []string{"operation", "result"})
+	summaryLatency = map[string]prometheus.Summary{
+		opAutoAssign: cprometheus.NewSummary(prometheus.SummaryOpts{
+			Name: "calico_ipam_auto_assign_latency_seconds",
+			Help: "Time taken to auto-assign IP addresses.",
+		}),
+		opAssignIP: cprometheus.NewSummary(prometheus.SummaryOpts{
+			Name: "calico_ipam_assign_ip_latency_seconds",
+			Help: "Time taken to assign a specific IP address.",
+		}),
+		opReleaseIPs: cprometheus.NewSummary(prometheus.SummaryOpts{
+			Name: "calico_ipam_release_ips_latency_seconds",
+			Help: "Time taken to release IP addresses.",
+		}),
+		opReleaseByHandle: cprometheus.NewSummary(prometheus.SummaryOpts{
+			Name: "calico_ipam_release_by_handle_latency_seconds",
+			Help: "Time taken to release the IP addresses of a handle.",
+		}),
+		opClaimAffinity: cprometheus.NewSummary(prometheus.SummaryOpts{
+			Name: "calico_ipam_claim_affinity_latency_seconds",
+			Help: "Time taken to claim affinity for the blocks in a CIDR.",
+		}),
+	}
+	counterUpdateConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "calico_ipam_update_conflicts_total",
+		Help: "Number of conflicts creating or updating IPAM resources, which cause the operation to be retried.",
+	}, []string{"resource"})
+	counterBlocksClaimed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "calico_ipam_blocks_claimed_total",
+		Help: "Number of allocation blocks claimed by a host.",
+	})
+	counterBorrowedIPs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "calico_ipam_borrowed_ips_total",
+		Help: "Number of IP addresses assigned from a block that is not affine to the host.",
+	})
+)
+
+// RegisterMetrics registers the IPAM metrics with the given registerer.  The metrics are not
+// registered by default, so that the process using the IPAM client can choose where to expose them.
+func RegisterMetrics(r prometheus.Registerer) error {
+	collectors := []prometheus.Collector{counterOperations, counterUpdateConflicts, counterBlocksClaimed, counterBorrowedIPs}
+	for _, s := range summaryLatency {
+		collectors = append(collectors, s)
+	}
+	for _, c := range collectors {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeOperation records the result and latency of an IPAM operation.  It is intended to be
+// deferred at the start of the operation, with a pointer to the operation's returned error.
+func observeOperation(op string, start time.Time, err *error) {
+	result := "success"
+	if *err != nil {
+		result = "error"
+	}
+	counterOperations.WithLabelValues(op, result).Inc()
+	summaryLatency[op].Observe(time.Since(start).Seconds())
+}
+
+// countConflict counts the given error if it is a conflict writing the given resource - either a
+// compare-and-swap conflict on update or delete, or the resource already existing on create - and
+// returns it unchanged.
+func countConflict(resource string, err error) error {
+	switch err.(type) {
+	case cerrors.ErrorResourceUpdateConflict, cerrors.ErrorResourceAlreadyExists:
+		counterUpdateConflicts.WithLabelValues(resource).Inc()
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"math/big"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = Describe("IPAM metrics", func() {
+	It("should only count update and create conflicts", func() {
+		before := testutil.ToFloat64(counterUpdateConflicts.WithLabelValues("block"))
+		Expect(countConflict("block", cerrors.ErrorResourceDoesNotExist{})).To(HaveOccurred())
+		Expect(countConflict("block", nil)).NotTo(HaveOccurred())
+		Expect(countConflict("block", cerrors.ErrorResourceUpdateConflict{})).To(HaveOccurred())
+		Expect(countConflict("block", cerrors.ErrorResourceAlreadyExists{})).To(HaveOccurred())
+		Expect(testutil.ToFloat64(counterUpdateConflicts.WithLabelValues("block")) - before).To(Equal(2.0))
+	})
+
+	It("should register the metrics with the given registerer", func() {
+		r := prometheus.NewRegistry()
+		Expect(RegisterMetrics(r)).NotTo(HaveOccurred())
+		Expect(r.Register(counterBorrowedIPs)).To(HaveOccurred())
+		Expect(r.Register(summaryLatency[opAutoAssign])).To(HaveOccurred())
+
+		// The metrics are not registered globally.
+		Expect(prometheus.Register(counterOperations)).NotTo(HaveOccurred())
+		Expect(prometheus.Unregister(counterOperations)).To(BeTrue())
+	})
+})
+
+var _ = testutils.E2eDatastoreDescribe("IPAM metrics tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	var bc bapi.Client
+	var ic Interface
+	var kc *kubernetes.Clientset
+
+	// delta returns a function that reports how much the counter has changed since delta was called.
+	delta := func(c prometheus.Collector) func() float64 {
+		before := testutil.ToFloat64(c)
+		return func() float64 {
+			return testutil.ToFloat64(c) - before
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		bc, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		bc.Clean()
+		ic = NewIPAMClient(bc, staticPoolAccessor{{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/26", BlockSize: 28}}})
+		kc = kubeClientset(bc)
+		Expect(applyNode(bc, kc, "node-1", nil)).NotTo(HaveOccurred())
+		Expect(applyNode(bc, kc, "node-2", nil)).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		deleteNode(bc, kc, "node-1")
+		deleteNode(bc, kc, "node-2")
+		bc.Clean()
+	})
+	It("should count operations, claimed blocks and borrowed IPs", func() {
+		autoAssigned := delta(counterOperations.WithLabelValues(opAutoAssign, "success"))
+		assigned := delta(counterOperations.WithLabelValues(opAssignIP, "success"))
+		assignFailed := delta(counterOperations.WithLabelValues(opAssignIP, "error"))
+		released := delta(counterOperations.WithLabelValues(opReleaseIPs, "success"))
+		releasedByHandle := delta(counterOperations.WithLabelValues(opReleaseByHandle, "success"))
+		claimed := delta(counterOperations.WithLabelValues(opClaimAffinity, "success"))
+		blocksClaimed := delta(counterBlocksClaimed)
+		borrowed := delta(counterBorrowedIPs)
+
+		handle := "metrics-handle"
+		v4, _, err := ic.AutoAssign(ctx, AutoAssignArgs{Num4: 1, HandleID: &handle, Hostname: "node-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(1))
+		Expect(autoAssigned()).To(Equal(1.0))
+		Expect(blocksClaimed()).To(Equal(1.0))
+		Expect(borrowed()).To(Equal(0.0))
+
+		// Assigning the next address in node-1's block from node-2 borrows it.
+		ip := cnet.IP{IP: v4[0].IP}
+		next := cnet.IncrementIP(ip, big.NewInt(1))
+		Expect(ic.AssignIP(ctx, AssignIPArgs{IP: next, Hostname: "node-2"})).NotTo(HaveOccurred())
+		Expect(assigned()).To(Equal(1.0))
+		Expect(borrowed()).To(Equal(1.0))
+		Expect(ic.AssignIP(ctx, AssignIPArgs{IP: next, Hostname: "node-2"})).To(HaveOccurred())
+		Expect(assignFailed()).To(Equal(1.0))
+
+		_, err = ic.ReleaseIPs(ctx, []cnet.IP{next})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(released()).To(Equal(1.0))
+		Expect(ic.ReleaseByHandle(ctx, handle)).NotTo(HaveOccurred())
+		Expect(releasedByHandle()).To(Equal(1.0))
+
+		_, _, err = ic.ClaimAffinity(ctx, cnet.MustParseCIDR("10.0.0.0/26"), "node-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claimed()).To(Equal(1.0))
+		Expect(blocksClaimed()).To(Equal(4.0))
+	})
+
+	It("should count conflicts claiming a block", func() {
+		conflicts := delta(counterUpdateConflicts.WithLabelValues("block"))
+		rw := ic.(*ipamClient).blockReaderWriter
+		subnet := cnet.MustParseCIDR("10.0.0.0/28")
+
+		aff, err := rw.getPendingAffinity(ctx, "node-1", subnet)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = rw.claimAffineBlock(ctx, aff, IPAMConfig{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts()).To(Equal(0.0))
+
+		// node-2 loses the race to create the block.
+		aff, err = rw.getPendingAffinity(ctx, "node-2", subnet)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = rw.claimAffineBlock(ctx, aff, IPAMConfig{}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(conflicts()).To(Equal(1.0))
+	})
+})
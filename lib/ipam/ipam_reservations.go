@@ -0,0 +1,69 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	"github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// reservations is the set of CIDRs reserved by IPReservation resources.  Addresses within
+// these CIDRs are skipped by auto-assignment, but may still be assigned explicitly.
+type reservations []net.IPNet
+
+// contains returns true if the address is reserved.
+func (r reservations) contains(ip net.IP) bool {
+	for _, cidr := range r {
+		if cidr.Contains(ip.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// getReservations returns the CIDRs reserved by all IPReservation resources.
+func (c ipamClient) getReservations(ctx context.Context) (reservations, error) {
+	kvps, err := c.client.List(ctx, model.ResourceListOptions{Kind: v3.KindIPReservation}, "")
+	if err != nil {
+		if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok {
+			// The datastore does not have the IPReservation resource type, so nothing is
+			// reserved.
+			return nil, nil
+		}
+		log.WithError(err).Error("Failed to list IP reservations")
+		return nil, err
+	}
+
+	var r reservations
+	for _, kvp := range kvps.KVPairs {
+		res := kvp.Value.(*v3.IPReservation)
+		for _, cidr := range res.Spec.ReservedCIDRs {
+			_, n, err := net.ParseCIDROrIP(cidr)
+			if err != nil {
+				log.WithError(err).WithField("reservation", res.Name).Warnf("Ignoring invalid reserved CIDR %s", cidr)
+				continue
+			}
+			r = append(r, *n)
+		}
+	}
+	log.Debugf("Reserved CIDRs: %v", r)
+	return r, nil
+}
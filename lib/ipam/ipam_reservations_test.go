@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+var _ = Describe("Block auto-assignment with IP reservations", func() {
+
+	DescribeTable("should skip reserved addresses",
+		func(reserved []string, num int, expIPs []string, expUnallocated []int) {
+			var r reservations
+			for _, cidr := range reserved {
+				_, n, err := cnet.ParseCIDROrIP(cidr)
+				Expect(err).NotTo(HaveOccurred())
+				r = append(r, *n)
+			}
+			b := newBlock(cnet.MustParseCIDR("10.0.0.0/29"), nil)
+
+			ips, err := b.autoAssign(num, nil, "host", nil, false, r, nil)
+			Expect(err).NotTo(HaveOccurred())
+			assigned := []string{}
+			for _, ip := range ips {
+				assigned = append(assigned, ip.IP.String())
+			}
+			Expect(assigned).To(Equal(expIPs))
+			Expect(b.Unallocated).To(Equal(expUnallocated))
+		},
+
+		Entry("No reservations", nil, 2,
+			[]string{"10.0.0.0", "10.0.0.1"}, []int{2, 3, 4, 5, 6, 7}),
+		Entry("Reserved CIDR at the start of the block", []string{"10.0.0.0/30"}, 2,
+			[]string{"10.0.0.4", "10.0.0.5"}, []int{0, 1, 2, 3, 6, 7}),
+		Entry("Reserved IPs within the block", []string{"10.0.0.1", "10.0.0.3"}, 3,
+			[]string{"10.0.0.0", "10.0.0.2", "10.0.0.4"}, []int{1, 3, 5, 6, 7}),
+		Entry("Reserved CIDR covering the block", []string{"10.0.0.0/24"}, 2,
+			[]string{}, []int{0, 1, 2, 3, 4, 5, 6, 7}),
+		Entry("Reserved CIDR outside the block", []string{"10.0.1.0/24"}, 1,
+			[]string{"10.0.0.0"}, []int{1, 2, 3, 4, 5, 6, 7}),
+	)
+})
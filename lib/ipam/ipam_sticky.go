@@ -0,0 +1,292 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+)
+
+// ReserveStickyIP reserves an address for a handle, independently of any workload.  If the
+// handle already has a sticky address of the requested IP version, that address is returned.
+func (c ipamClient) ReserveStickyIP(ctx context.Context, args ReserveStickyIPArgs) (*cnet.IP, error) {
+	if args.HandleID == "" {
+		return nil, errors.New("a handle is required to reserve a sticky IP")
+	}
+	version := args.IPVersion
+	if args.IP != nil {
+		version = args.IP.Version()
+	}
+	if version != 4 && version != 6 {
+		return nil, fmt.Errorf("invalid IP version %d", args.IPVersion)
+	}
+	hostname, err := decideHostname(args.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	logCtx := log.WithFields(log.Fields{"handle": args.HandleID, "host": hostname})
+
+	// Return the existing reservation, if there is one.
+	ips, err := c.IPsByHandle(ctx, args.HandleID)
+	if err != nil {
+		if _, ok := err.(cerrors.ErrorResourceDoesNotExist); !ok {
+			return nil, err
+		}
+	}
+	for _, ip := range ips {
+		if ip.Version() != version {
+			continue
+		}
+		attrs, _, err := c.GetAssignmentAttributes(ctx, ip)
+		if err != nil {
+			return nil, err
+		}
+		if attrs[AttributeSticky] == "true" {
+			logCtx.WithField("ip", ip).Info("Handle already has a sticky IP")
+			return &ip, nil
+		}
+	}
+
+	handleID := args.HandleID
+	attrs := stickyAttributes(args.Attrs)
+	if args.IP != nil {
+		logCtx.WithField("ip", args.IP).Info("Reserving sticky IP")
+		err = c.AssignIP(ctx, AssignIPArgs{IP: *args.IP, HandleID: &handleID, Attrs: attrs, Hostname: hostname})
+		if err != nil {
+			return nil, err
+		}
+		return args.IP, nil
+	}
+
+	logCtx.Infof("Reserving sticky IPv%d address", version)
+	assigned, err := c.autoAssign(ctx, 1, &handleID, attrs, args.Pools, version, hostname, 0, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(assigned) == 0 {
+		return nil, fmt.Errorf("no IPv%d addresses available to reserve", version)
+	}
+	return &cnet.IP{IP: assigned[0].IP}, nil
+}
+
+// ReleaseStickyIPs releases the addresses assigned with the given handle, including sticky
+// addresses.
+func (c ipamClient) ReleaseStickyIPs(ctx context.Context, handleID string) error {
+	log.Infof("Releasing all IPs, including sticky IPs, with handle '%s'", handleID)
+	obj, err := c.blockReaderWriter.queryHandle(ctx, handleID, "")
+	if err != nil {
+		return err
+	}
+	handle := allocationHandle{obj.Value.(*model.IPAMHandle)}
+	pools, err := c.allPools()
+	if err != nil {
+		return err
+	}
+
+	for blockStr := range handle.Block {
+		_, blockCIDR, _ := cnet.ParseCIDR(blockStr)
+		if err := c.releaseByHandle(ctx, handleID, *blockCIDR, pools, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimStickyIPs binds up to num4 IPv4 and num6 IPv6 sticky addresses of the handle to a
+// workload on the given host, replacing their attributes with the given attributes.  Addresses
+// in blocks that are not affine to the host are borrowed, unless StrictAffinity is enabled, in
+// which case they are released so that new addresses can be assigned on the host.  Returns
+// the claimed addresses, and whether the handle has sticky addresses.
+func (c ipamClient) claimStickyIPs(ctx context.Context, handleID string, attrs map[string]string, host string, num4, num6 int) (v4, v6 []cnet.IPNet, sticky bool, err error) {
+	obj, err := c.blockReaderWriter.queryHandle(ctx, handleID, "")
+	if err != nil {
+		if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok {
+			// A new handle, so there are no sticky addresses.
+			return nil, nil, false, nil
+		}
+		return nil, nil, false, err
+	}
+	handle := allocationHandle{obj.Value.(*model.IPAMHandle)}
+
+	config, err := c.GetIPAMConfig(ctx)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	blocks := []string{}
+	for blockStr := range handle.Block {
+		blocks = append(blocks, blockStr)
+	}
+	sort.Strings(blocks)
+
+	logCtx := log.WithFields(log.Fields{"handle": handleID, "host": host})
+	for _, blockStr := range blocks {
+		_, blockCIDR, _ := cnet.ParseCIDR(blockStr)
+		claimed, found, err := c.claimStickyIPsFromBlock(ctx, handleID, attrs, host, *blockCIDR, config.StrictAffinity, num4-len(v4), num6-len(v6))
+		if err != nil {
+			return v4, v6, sticky, err
+		}
+		sticky = sticky || found
+		for _, ip := range claimed {
+			if ip.Version() == 4 {
+				v4 = append(v4, ip)
+			} else {
+				v6 = append(v6, ip)
+			}
+		}
+	}
+	if len(v4)+len(v6) > 0 {
+		logCtx.Infof("Claimed sticky IPs: %v %v", v4, v6)
+	}
+	return v4, v6, sticky, nil
+}
+
+// claimStickyIPsFromBlock claims the sticky addresses of the handle in a single block, returning
+// the claimed addresses and whether the block has any sticky addresses for the handle.
+func (c ipamClient) claimStickyIPsFromBlock(ctx context.Context, handleID string, attrs map[string]string, host string, blockCIDR cnet.IPNet, strictAffinity bool, num4, num6 int) ([]cnet.IPNet, bool, error) {
+	logCtx := log.WithFields(log.Fields{"handle": handleID, "host": host, "cidr": blockCIDR})
+	for i := 0; i < datastoreRetries; i++ {
+		obj, err := c.blockReaderWriter.queryBlock(ctx, blockCIDR, "")
+		if err != nil {
+			if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok {
+				// The handle is overestimating its addresses.
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		b := allocationBlock{obj.Value.(*model.AllocationBlock)}
+		ordinals := b.stickyOrdinals(handleID)
+		if len(ordinals) == 0 {
+			return nil, false, nil
+		}
+
+		borrowed := b.Affinity == nil || !hostAffinityMatches(host, b.AllocationBlock)
+		if borrowed {
+			if strictAffinity {
+				// The addresses cannot be borrowed, so release them.  New addresses will be
+				// assigned to the handle on this host instead.
+				logCtx.Warn("Strict affinity prevents borrowing sticky IPs from a block affine to another host, releasing them")
+				return nil, true, c.releaseByHandle(ctx, handleID, blockCIDR, nil, true)
+			}
+			logCtx.Info("Borrowing sticky IPs from a block that is not affine to this host")
+		}
+
+		claimed := []cnet.IPNet{}
+		for _, o := range ordinals {
+			ip := b.OrdinalToIP(o)
+			if ip.Version() == 4 && num4 <= 0 || ip.Version() == 6 && num6 <= 0 {
+				continue
+			}
+			if ip.Version() == 4 {
+				num4--
+			} else {
+				num6--
+			}
+			b.rebind(o, handleID, stickyAttributes(attrs))
+			claimed = append(claimed, cnet.IPNet{IPNet: b.CIDR.IPNet})
+			claimed[len(claimed)-1].IP = ip.IP
+		}
+		if len(claimed) == 0 {
+			return nil, true, nil
+		}
+
+		if _, err := c.blockReaderWriter.updateBlock(ctx, obj); err != nil {
+			if _, ok := err.(cerrors.ErrorResourceUpdateConflict); ok {
+				logCtx.WithError(err).Debug("CAS error claiming sticky IPs - retry")
+				continue
+			}
+			return nil, true, err
+		}
+		if borrowed {
+			counterBorrowedIPs.Add(float64(len(claimed)))
+		}
+		return claimed, true, nil
+	}
+	return nil, true, errors.New("Max retries hit - excessive concurrent IPAM requests")
+}
+
+// stickyAttributes returns a copy of the given attributes that marks an address as sticky.
+func stickyAttributes(attrs map[string]string) map[string]string {
+	sticky := map[string]string{AttributeSticky: "true"}
+	for k, v := range attrs {
+		if k != AttributeSticky {
+			sticky[k] = v
+		}
+	}
+	return sticky
+}
+
+// isSticky returns whether the attribute marks its addresses as sticky.
+func isSticky(attr model.AllocationAttribute) bool {
+	return attr.AttrSecondary[AttributeSticky] == "true"
+}
+
+// stickyOrdinals returns the ordinals of the sticky addresses assigned with the given handle.
+func (b allocationBlock) stickyOrdinals(handleID string) []int {
+	ordinals := []int{}
+	for o, attrIdx := range b.Allocations {
+		if attrIdx == nil || *attrIdx >= len(b.Attributes) {
+			continue
+		}
+		attr := b.Attributes[*attrIdx]
+		if attr.AttrPrimary != nil && *attr.AttrPrimary == handleID && isSticky(attr) {
+			ordinals = append(ordinals, o)
+		}
+	}
+	return ordinals
+}
+
+// rebind replaces the handle and attributes of an assigned address.
+func (b *allocationBlock) rebind(ordinal int, handleID string, attrs map[string]string) {
+	attrIndex := b.findOrAddAttribute(&handleID, attrs)
+	b.Allocations[ordinal] = &attrIndex
+	b.deleteUnusedAttributes()
+}
+
+// unstick removes the sticky marker from the addresses assigned with the given handle, so that
+// they are released with the handle.
+func (b *allocationBlock) unstick(handleID string) {
+	for _, o := range b.stickyOrdinals(handleID) {
+		attrs := map[string]string{}
+		for k, v := range b.Attributes[*b.Allocations[o]].AttrSecondary {
+			if k != AttributeSticky {
+				attrs[k] = v
+			}
+		}
+		b.rebind(o, handleID, attrs)
+	}
+}
+
+// deleteUnusedAttributes deletes any attributes that are not referenced by an allocation.
+func (b *allocationBlock) deleteUnusedAttributes() {
+	refCounts := b.attributeRefCounts()
+	unused := []int{}
+	for i := range b.Attributes {
+		if refCounts[i] == 0 {
+			unused = append(unused, i)
+		}
+	}
+	if len(unused) > 0 {
+		b.deleteAttributes(unused, nil)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	cerrors "github.com/projectcalico/libcalico-go/lib/errors"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = Describe("Block release of sticky IPs", func() {
+	It("should keep sticky IPs for their handle and clean up unused attributes", func() {
+		b := newBlock(cnet.MustParseCIDR("10.0.0.0/29"), nil)
+		handle := "sts-web-0"
+		_, err := b.autoAssign(1, &handle, "host", stickyAttributes(map[string]string{AttributePod: "web-0"}), false, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = b.autoAssign(1, &handle, "host", map[string]string{AttributePod: "web-0"}, false, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		released, kept := b.releaseByHandle(handle, false)
+		Expect(released).To(Equal(1))
+		Expect(kept).To(Equal(1))
+		Expect(b.stickyOrdinals(handle)).To(Equal([]int{0}))
+		Expect(b.Attributes).To(HaveLen(1))
+		Expect(b.Attributes[0].AttrSecondary).To(Equal(map[string]string{AttributeSticky: "true"}))
+
+		b.unstick(handle)
+		released, kept = b.releaseByHandle(handle, false)
+		Expect(released).To(Equal(1))
+		Expect(kept).To(Equal(0))
+		Expect(b.Attributes).To(BeEmpty())
+		Expect(b.empty()).To(BeTrue())
+	})
+
+	It("should skip addresses whose attributes are missing", func() {
+		b := newBlock(cnet.MustParseCIDR("10.0.0.0/29"), nil)
+		handle := "sts-web-0"
+		_, err := b.autoAssign(1, &handle, "host", stickyAttributes(map[string]string{AttributePod: "web-0"}), false, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		missing := len(b.Attributes)
+		b.Allocations[3] = &missing
+
+		Expect(b.stickyOrdinals(handle)).To(Equal([]int{0}))
+		released, kept := b.releaseByHandle(handle, false)
+		Expect(released).To(Equal(0))
+		Expect(kept).To(Equal(1))
+	})
+})
+
+var _ = testutils.E2eDatastoreDescribe("IPAM sticky IP tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	handle := "sts-web-0"
+	var bc bapi.Client
+	var ic Interface
+	var kc *kubernetes.Clientset
+
+	autoAssign := func(host string) cnet.IP {
+		v4, _, err := ic.AutoAssign(ctx, AutoAssignArgs{
+			Num4:     1,
+			HandleID: &handle,
+			Attrs:    map[string]string{AttributePod: "web-0", AttributeNode: host},
+			Hostname: host,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(1))
+		return cnet.IP{IP: v4[0].IP}
+	}
+
+	attrsOf := func(ip cnet.IP) map[string]string {
+		attrs, h, err := ic.GetAssignmentAttributes(ctx, ip)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*h).To(Equal(handle))
+		return attrs
+	}
+
+	BeforeEach(func() {
+		var err error
+		bc, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		bc.Clean()
+		ic = NewIPAMClient(bc, staticPoolAccessor{{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/26", BlockSize: 28}}})
+		kc = kubeClientset(bc)
+		Expect(applyNode(bc, kc, "node-1", nil)).NotTo(HaveOccurred())
+		Expect(applyNode(bc, kc, "node-2", nil)).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		deleteNode(bc, kc, "node-1")
+		deleteNode(bc, kc, "node-2")
+		bc.Clean()
+	})
+
+	It("should return the reserved IP on any host until the sticky IP is released", func() {
+		reserved, err := ic.ReserveStickyIP(ctx, ReserveStickyIPArgs{HandleID: handle, IPVersion: 4, Hostname: "node-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attrsOf(*reserved)).To(Equal(map[string]string{AttributeSticky: "true"}))
+
+		// Reserving again returns the same address.
+		again, err := ic.ReserveStickyIP(ctx, ReserveStickyIPArgs{HandleID: handle, IPVersion: 4, Hostname: "node-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.String()).To(Equal(reserved.String()))
+
+		// The workload gets the reserved address on another host, borrowing it.
+		Expect(autoAssign("node-2").String()).To(Equal(reserved.String()))
+		Expect(attrsOf(*reserved)).To(Equal(map[string]string{AttributeSticky: "true", AttributePod: "web-0", AttributeNode: "node-2"}))
+
+		// Releasing the workload keeps the reservation, which is not a leak.
+		Expect(ic.ReleaseByHandle(ctx, handle)).NotTo(HaveOccurred())
+		Expect(attrsOf(*reserved)).To(Equal(map[string]string{AttributeSticky: "true"}))
+		report, err := ic.CheckConsistency(ctx, CheckConsistencyArgs{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Consistent()).To(BeTrue())
+
+		Expect(autoAssign("node-1").String()).To(Equal(reserved.String()))
+
+		// Releasing the sticky IPs releases the address and the handle.
+		Expect(ic.ReleaseStickyIPs(ctx, handle)).NotTo(HaveOccurred())
+		_, _, err = ic.GetAssignmentAttributes(ctx, *reserved)
+		Expect(err).To(BeAssignableToTypeOf(cerrors.ErrorResourceDoesNotExist{}))
+		_, err = ic.IPsByHandle(ctx, handle)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should keep addresses assigned to a handle with a sticky IP", func() {
+		reserved, err := ic.ReserveStickyIP(ctx, ReserveStickyIPArgs{
+			HandleID: handle,
+			IP:       &cnet.IP{IP: cnet.MustParseIP("10.0.0.20").IP},
+			Hostname: "node-1",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reserved.String()).To(Equal("10.0.0.20"))
+
+		v4, _, err := ic.AutoAssign(ctx, AutoAssignArgs{Num4: 2, HandleID: &handle, Hostname: "node-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(2))
+		Expect(v4[0].IP.String()).To(Equal("10.0.0.20"))
+
+		Expect(ic.ReleaseByHandle(ctx, handle)).NotTo(HaveOccurred())
+		ips, err := ic.IPsByHandle(ctx, handle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ips).To(HaveLen(2))
+	})
+
+	It("should move the sticky IP to the new host when strict affinity is enabled", func() {
+		Expect(ic.SetIPAMConfig(ctx, IPAMConfig{StrictAffinity: true, AutoAllocateBlocks: true})).NotTo(HaveOccurred())
+		reserved, err := ic.ReserveStickyIP(ctx, ReserveStickyIPArgs{HandleID: handle, IPVersion: 4, Hostname: "node-1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		moved := autoAssign("node-2")
+		Expect(moved.String()).NotTo(Equal(reserved.String()))
+		_, _, err = ic.GetAssignmentAttributes(ctx, *reserved)
+		Expect(err).To(HaveOccurred())
+
+		// The new address is sticky.
+		Expect(ic.ReleaseByHandle(ctx, handle)).NotTo(HaveOccurred())
+		Expect(attrsOf(moved)).To(Equal(map[string]string{AttributeSticky: "true"}))
+	})
+})
@@ -2219,7 +2219,7 @@ var _ = DescribeTable("determinePools tests",
 		}
 
 		// Call determinePools
-		pools, _, err := ic.(*ipamClient).determinePools(context.Background(), reqPools, 4, node, 32)
+		pools, _, err := ic.(*ipamClient).determinePools(context.Background(), reqPools, 4, node, nil, 32)
 
 		// Assert on any returned error.
 		if expectErr {
@@ -2256,6 +2256,58 @@ var _ = DescribeTable("determinePools tests",
 	Entry("pool1 disabled, pool2 mismatching node selector, pool2 requested", false, true, "", `foo != "bar"`, false, true, []string{"20.0.0.0/24"}, false),
 )
 
+// staticPoolAccessor is a pool accessor that returns a fixed list of enabled IPv4 pools.
+type staticPoolAccessor []v3.IPPool
+
+func (s staticPoolAccessor) GetEnabledPools(ipVersion int) ([]v3.IPPool, error) {
+	return s, nil
+}
+
+func (s staticPoolAccessor) GetAllPools() ([]v3.IPPool, error) {
+	return s, nil
+}
+
+// Tests for determining IP pools to use based on workload and namespace labels.
+var _ = DescribeTable("determinePools workload selector tests",
+	func(pool1NSSelector, pool1WLSelector string, workload *WorkloadLabels, requestPool1 bool, expectation []string) {
+		// Seed data: pool2 never matches the node, pool3 is for tenant b.
+		pools := staticPoolAccessor{
+			{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/24", BlockSize: 26, NamespaceSelector: pool1NSSelector, WorkloadSelector: pool1WLSelector}},
+			{Spec: v3.IPPoolSpec{CIDR: "20.0.0.0/24", BlockSize: 26, NodeSelector: `foo != "bar"`}},
+			{Spec: v3.IPPoolSpec{CIDR: "30.0.0.0/24", BlockSize: 26, NamespaceSelector: `tenant == "b"`}},
+		}
+		ic := NewIPAMClient(nil, pools)
+		node := v3.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}}}
+
+		reqPools := []cnet.IPNet{}
+		if requestPool1 {
+			reqPools = append(reqPools, cnet.MustParseCIDR("10.0.0.0/24"))
+		}
+
+		matching, _, err := ic.(*ipamClient).determinePools(context.Background(), reqPools, 4, node, workload, 32)
+		Expect(err).NotTo(HaveOccurred())
+
+		actual := []string{}
+		for _, pool := range matching {
+			actual = append(actual, pool.Spec.CIDR)
+		}
+		Expect(actual).To(Equal(expectation))
+	},
+	Entry("No selectors, no labels", "", "", nil, false, []string{"10.0.0.0/24"}),
+	Entry("No selectors, tenant b labels", "", "",
+		&WorkloadLabels{Namespace: map[string]string{"tenant": "b"}}, false, []string{"10.0.0.0/24", "30.0.0.0/24"}),
+	Entry("Matching namespace selector", `tenant == "a"`, "",
+		&WorkloadLabels{Namespace: map[string]string{"tenant": "a"}}, false, []string{"10.0.0.0/24"}),
+	Entry("Mismatching namespace selector", `tenant == "a"`, "",
+		&WorkloadLabels{Namespace: map[string]string{"tenant": "c"}}, false, []string{}),
+	Entry("Namespace selector, no labels", `tenant == "a"`, "", nil, false, []string{}),
+	Entry("Matching namespace and workload selectors", `tenant == "a"`, `app == "web"`,
+		&WorkloadLabels{Namespace: map[string]string{"tenant": "a"}, Workload: map[string]string{"app": "web"}}, false, []string{"10.0.0.0/24"}),
+	Entry("Mismatching workload selector", `tenant == "a"`, `app == "web"`,
+		&WorkloadLabels{Namespace: map[string]string{"tenant": "a"}, Workload: map[string]string{"app": "db"}}, false, []string{}),
+	Entry("Mismatching selectors, pool1 requested", `tenant == "a"`, `app == "web"`, nil, true, []string{"10.0.0.0/24"}),
+)
+
 // assignIPutil is a utility function to help with assigning a single IP address to a hostname passed in.
 func assignIPutil(ic Interface, assignIP net.IP, host string) {
 	if len(assignIP) != 0 {
@@ -2353,6 +2405,15 @@ func applyNode(c bapi.Client, kc *kubernetes.Clientset, host string, labels map[
 	return nil
 }
 
+// kubeClientset returns the Kubernetes clientset of a KDD backend client, or nil for other
+// datastores.
+func kubeClientset(c bapi.Client) *kubernetes.Clientset {
+	if kc, ok := c.(*k8s.KubeClient); ok {
+		return kc.ClientSet
+	}
+	return nil
+}
+
 func deleteNode(c bapi.Client, kc *kubernetes.Clientset, host string) {
 	if kc != nil {
 		kc.CoreV1().Nodes().Delete(host, &metav1.DeleteOptions{})
@@ -16,6 +16,7 @@ package ipam
 
 import (
 	"net"
+	"time"
 
 	cnet "github.com/projectcalico/libcalico-go/lib/net"
 )
@@ -41,6 +42,33 @@ type AssignIPArgs struct {
 	HostReservedAttr *HostReservedAttr
 }
 
+// ReserveStickyIPArgs defines the set of arguments for reserving a sticky IP address
+// for a handle.
+type ReserveStickyIPArgs struct {
+	// The handle for which the address is reserved.  AutoAssign with this handle
+	// returns the reserved address, on any host.
+	HandleID string
+
+	// If specified, the IP address to reserve.  If not specified, an address of the
+	// given IP version is automatically assigned.
+	IP *cnet.IP
+
+	// The IP version of the address to automatically assign, either 4 or 6.
+	IPVersion int
+
+	// If specified, the previously configured pools from which to assign the address.
+	// If not specified, this defaults to all pools of the IP version.
+	Pools []cnet.IPNet
+
+	// A key/value mapping of metadata to store with the reservation.
+	Attrs map[string]string
+
+	// If specified, the hostname of the host on which the address will be
+	// allocated.  If not specified, this will default to the value provided
+	// by os.Hostname.
+	Hostname string
+}
+
 // AutoAssignArgs defines the set of arguments for assigning one or more
 // IP addresses.
 type AutoAssignArgs struct {
@@ -79,6 +107,21 @@ type AutoAssignArgs struct {
 
 	// If specified, the attributes of reserved IPv6 addresses in the block.
 	HostReservedAttrIPv6s *HostReservedAttr
+
+	// If specified, the labels of the workload and its namespace, used to select
+	// IP pools by their namespaceSelector and workloadSelector.  If not specified,
+	// only IP pools without those selectors, or whose selectors match empty labels,
+	// are used.  Ignored if IPv4Pools or IPv6Pools are specified.
+	WorkloadLabels *WorkloadLabels
+}
+
+// WorkloadLabels contains the labels of a workload and of its namespace.
+type WorkloadLabels struct {
+	// The labels of the workload's namespace.
+	Namespace map[string]string
+
+	// The labels of the workload.
+	Workload map[string]string
 }
 
 // IPAMConfig contains global configuration options for Calico IPAM.
@@ -131,6 +174,168 @@ type PoolUtilization struct {
 	Blocks []BlockUtilization
 }
 
+// GetUtilizationBreakdownArgs defines the set of arguments for requesting a breakdown of
+// IP utilization by host, namespace and handle prefix.
+type GetUtilizationBreakdownArgs struct {
+	// If specified, the pools whose utilization should be reported.  Each string here
+	// can be a pool name or CIDR.  If not specified, this defaults to all pools.
+	Pools []string
+
+	// If specified, the handle prefixes to aggregate allocations by.  Each allocation is
+	// counted against the longest prefix that matches its handle; allocations whose handle
+	// matches none of the prefixes are not included in the handle prefix breakdown.
+	HandlePrefixes []string
+}
+
+// HostUtilization reports IP utilization for the blocks affine to a single host.
+type HostUtilization struct {
+	// The host, or "" for blocks that are not affine to any host.
+	Host string
+
+	// Number of blocks affine to this host.
+	Blocks int
+
+	// Number of possible IPs in this host's blocks.
+	Capacity int
+
+	// Number of available IPs in this host's blocks.
+	Available int
+
+	// Number of IPs in this host's blocks that were allocated by other hosts.
+	Borrowed int
+}
+
+// AttributeUtilization reports the number of IPs allocated to a single namespace or
+// handle prefix.
+type AttributeUtilization struct {
+	// The namespace or handle prefix.  For namespaces, "" is used for allocations that
+	// do not record a namespace.
+	Value string
+
+	// Number of IPs allocated.
+	InUse int
+
+	// Number of those IPs that were borrowed from a block affine to a different host.
+	Borrowed int
+}
+
+// BorrowedIP describes an IP that was allocated by a host other than the one its block
+// is affine to.
+type BorrowedIP struct {
+	// The borrowed IP.
+	IP net.IP
+
+	// The CIDR of the block that the IP belongs to.
+	Block net.IPNet
+
+	// The host that the block is affine to, or "" if the block is not affine to any host.
+	Affinity string
+
+	// The host that allocated the IP.
+	Host string
+
+	// The handle and namespace recorded with the allocation, if any.
+	Handle    string
+	Namespace string
+}
+
+// UtilizationBreakdown reports IP utilization broken down by host, namespace and
+// handle prefix.
+type UtilizationBreakdown struct {
+	// Utilization for each host that has affine blocks, sorted by host.
+	Hosts []HostUtilization
+
+	// Allocated IPs for each namespace, sorted by namespace.
+	Namespaces []AttributeUtilization
+
+	// Allocated IPs for each of the requested handle prefixes, in the order requested.
+	HandlePrefixes []AttributeUtilization
+
+	// IPs allocated by a host other than the one their block is affine to, sorted by IP.
+	BorrowedIPs []BorrowedIP
+}
+
+// AllocatedIP describes an allocated IP address.
+type AllocatedIP struct {
+	// The allocated IP.
+	IP cnet.IP
+
+	// The CIDR of the block that the IP belongs to.
+	Block cnet.IPNet
+
+	// The handle recorded with the allocation, or "" if there is none.
+	Handle string
+
+	// The host that allocated the IP, and the attributes recorded with the allocation.
+	Host  string
+	Attrs map[string]string
+}
+
+// QueryAllocationsArgs defines the set of arguments for querying allocated IP addresses.
+type QueryAllocationsArgs struct {
+	// If specified, only addresses within this CIDR are returned.
+	CIDR *cnet.IPNet
+
+	// If specified, only addresses allocated with this handle are returned.
+	HandleID *string
+
+	// If specified, only addresses whose allocation attributes include all of these
+	// key/value pairs are returned, e.g. {AttributeNamespace: "foo", AttributePod: "bar"}.
+	Attrs map[string]string
+}
+
+// HandleInfo describes a handle and the IP addresses allocated with it.
+type HandleInfo struct {
+	HandleID string
+
+	// The addresses allocated with the handle, sorted by IP.
+	Allocations []AllocatedIP
+}
+
+// DefragmentBlocksArgs defines the set of arguments for planning, and optionally executing, the
+// consolidation of sparsely used affine blocks.
+type DefragmentBlocksArgs struct {
+	// If specified, the hosts whose blocks should be consolidated.  If not specified, this
+	// defaults to all hosts.
+	Hosts []string
+
+	// If non-zero, the maximum number of blocks of each IP version that a host may keep.  The
+	// more restrictive of this and the global IPAMConfig.MaxBlocksPerHost is used.
+	MaxBlocksPerHost int
+
+	// If true, affinity is released for the blocks in each plan's Release list.
+	Execute bool
+}
+
+// HostDefragmentation is the plan for consolidating the affine blocks of a single IP version
+// on a single host.  A host keeps the fewest blocks that can hold the addresses it has in use,
+// preferring the most used blocks, and never more than the MaxBlocksPerHost limit.
+type HostDefragmentation struct {
+	Host      string
+	IPVersion int
+
+	// Number of blocks affine to the host, and the number of addresses in use in them.
+	Blocks int
+	InUse  int
+
+	// Surplus blocks that are empty, so their affinity can be released.
+	Release []cnet.IPNet
+
+	// Surplus blocks that still have addresses in use.  Their affinity can be released once
+	// the workloads using those addresses have been moved or deleted.
+	Drain []cnet.IPNet
+
+	// The blocks whose affinity was released, when executing the plan.  This may be a subset
+	// of Release if addresses were assigned from a block after the plan was made.
+	Released []cnet.IPNet
+}
+
+// DefragmentBlocksPlan reports the hosts whose affine blocks can be consolidated.
+type DefragmentBlocksPlan struct {
+	// A plan for each host and IP version with surplus blocks, sorted by host and IP version.
+	Hosts []HostDefragmentation
+}
+
 type HostReservedAttr struct {
 	// Number of addresses reserved from start of the block.
 	StartOfBlock int
@@ -166,3 +371,102 @@ type BlockArgs struct {
 	// If specified, the attributes of reserved IPv6 addresses in this block.
 	HostReservedAttrIPv6s *HostReservedAttr
 }
+
+// CheckConsistencyArgs defines the set of arguments for checking the consistency of the
+// IPAM data in the datastore.
+type CheckConsistencyArgs struct {
+	// If true, the inconsistencies that are found are repaired: leaked IP addresses are
+	// released, handles are updated to match the blocks, affinities for deleted nodes are
+	// released and empty blocks with no affinity are deleted.
+	Repair bool
+
+	// When repairing, the check is repeated after this period, and leaked IP addresses and
+	// handle mismatches are only repaired if both checks find them unchanged.  This allows
+	// IPAM operations that were in progress during the first check to complete; for example,
+	// a workload's WorkloadEndpoint is only created after its address has been assigned.
+	// If zero, DefaultConsistencyGracePeriod is used.
+	GracePeriod time.Duration
+}
+
+// LeakedIP reports an IP address that is assigned, but whose handle is not in use.
+type LeakedIP struct {
+	// The leaked IP address.
+	IP cnet.IP
+
+	// The CIDR of the block that the address is assigned from.
+	Block cnet.IPNet
+
+	// The handle used to assign the address.
+	Handle string
+
+	// The attributes stored with the address upon assignment.
+	Attrs map[string]string
+}
+
+// HandleMismatch reports a handle whose count of addresses in a block disagrees with the
+// number of addresses assigned in the block with that handle.
+type HandleMismatch struct {
+	// The handle ID.
+	Handle string
+
+	// The CIDR of the block.
+	Block cnet.IPNet
+
+	// Number of addresses that the handle records for the block.  Zero if the handle does
+	// not exist or has no entry for the block.
+	Recorded int
+
+	// Number of addresses in the block that are assigned with the handle.
+	Assigned int
+}
+
+// InvalidAllocation reports an assigned address whose attributes are missing from its block.
+type InvalidAllocation struct {
+	// The assigned IP address.
+	IP cnet.IP
+
+	// The CIDR of the block.
+	Block cnet.IPNet
+
+	// The index of the address's attributes, which is beyond the attributes in the block.
+	AttributeIndex int
+}
+
+// OrphanedAffinity reports a block affinity for a host that has no Node.
+type OrphanedAffinity struct {
+	// The host that the block is affine to.
+	Host string
+
+	// The CIDR of the block.
+	Block cnet.IPNet
+}
+
+// ConsistencyReport reports the inconsistencies found in the IPAM data.
+type ConsistencyReport struct {
+	// IP addresses assigned with a handle that is not used by any WorkloadEndpoint, or
+	// tunnel addresses assigned to a node that no longer exists.
+	LeakedIPs []LeakedIP
+
+	// Handles whose bookkeeping disagrees with the allocation blocks.
+	HandleMismatches []HandleMismatch
+
+	// Block affinities for hosts that have no Node.
+	OrphanedAffinities []OrphanedAffinity
+
+	// Blocks that have no assigned addresses and are not affine to any host.
+	EmptyBlocks []cnet.IPNet
+
+	// Assigned addresses whose attributes are missing from their block.  The handle of such
+	// an address is unknown, so these are not repaired.
+	InvalidAllocations []InvalidAllocation
+
+	// True if the inconsistencies were repaired.
+	Repaired bool
+}
+
+// Consistent returns true if no inconsistencies were found.
+func (r *ConsistencyReport) Consistent() bool {
+	return len(r.LeakedIPs) == 0 && len(r.HandleMismatches) == 0 &&
+		len(r.OrphanedAffinities) == 0 && len(r.EmptyBlocks) == 0 &&
+		len(r.InvalidAllocations) == 0
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+)
+
+// GetUtilizationBreakdown returns IP utilization info for the specified pools, or for all pools,
+// aggregated by affine host, by namespace and by handle prefix.
+func (c ipamClient) GetUtilizationBreakdown(ctx context.Context, args GetUtilizationBreakdownArgs) (*UtilizationBreakdown, error) {
+	usage, err := c.utilizationPools(args.Pools)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := c.client.List(ctx, model.BlockListOptions{}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := map[string]*HostUtilization{}
+	namespaces := map[string]*AttributeUtilization{}
+	prefixes := map[string]*AttributeUtilization{}
+	breakdown := &UtilizationBreakdown{}
+	for _, prefix := range args.HandlePrefixes {
+		if _, ok := prefixes[prefix]; ok {
+			continue
+		}
+		prefixes[prefix] = &AttributeUtilization{Value: prefix}
+	}
+
+	for _, kvp := range blocks.KVPairs {
+		b := kvp.Value.(*model.AllocationBlock)
+		if poolForBlock(usage, b) == nil {
+			continue
+		}
+		affinity := b.Host()
+		log.Debugf("Aggregating utilization for block %v with affinity %q", b.CIDR, affinity)
+
+		hu, ok := hosts[affinity]
+		if !ok {
+			hu = &HostUtilization{Host: affinity}
+			hosts[affinity] = hu
+		}
+		hu.Blocks++
+		hu.Capacity += b.NumAddresses()
+		hu.Available += len(b.Unallocated)
+
+		for ordinal, attrIdx := range b.Allocations {
+			if attrIdx == nil {
+				continue
+			}
+			if *attrIdx >= len(b.Attributes) {
+				log.WithField("block", b.CIDR).Warnf("Missing attributes for IP with ordinal %d", ordinal)
+				continue
+			}
+			attrs := b.Attributes[*attrIdx]
+			handle := ""
+			if attrs.AttrPrimary != nil {
+				handle = *attrs.AttrPrimary
+			}
+			if strings.ToLower(handle) == WindowsReservedHandle {
+				// Reserved by the block itself, rather than allocated by a host.
+				continue
+			}
+			host := attrs.AttrSecondary[AttributeNode]
+			namespace := attrs.AttrSecondary[AttributeNamespace]
+
+			// This matches the definition used by AllocationBlock.NonAffineAllocations.
+			borrowed := affinity == "" || host != affinity
+			count := func(au *AttributeUtilization) {
+				au.InUse++
+				if borrowed {
+					au.Borrowed++
+				}
+			}
+
+			nu, ok := namespaces[namespace]
+			if !ok {
+				nu = &AttributeUtilization{Value: namespace}
+				namespaces[namespace] = nu
+			}
+			count(nu)
+			if prefix, ok := longestHandlePrefix(args.HandlePrefixes, handle); ok {
+				count(prefixes[prefix])
+			}
+
+			if borrowed {
+				hu.Borrowed++
+				breakdown.BorrowedIPs = append(breakdown.BorrowedIPs, BorrowedIP{
+					IP:        b.OrdinalToIP(ordinal).IP,
+					Block:     b.CIDR.IPNet,
+					Affinity:  affinity,
+					Host:      host,
+					Handle:    handle,
+					Namespace: namespace,
+				})
+			}
+		}
+	}
+
+	for _, hu := range hosts {
+		breakdown.Hosts = append(breakdown.Hosts, *hu)
+	}
+	sort.Slice(breakdown.Hosts, func(i, j int) bool {
+		return breakdown.Hosts[i].Host < breakdown.Hosts[j].Host
+	})
+	for _, nu := range namespaces {
+		breakdown.Namespaces = append(breakdown.Namespaces, *nu)
+	}
+	sort.Slice(breakdown.Namespaces, func(i, j int) bool {
+		return breakdown.Namespaces[i].Value < breakdown.Namespaces[j].Value
+	})
+	for _, prefix := range args.HandlePrefixes {
+		if pu, ok := prefixes[prefix]; ok {
+			breakdown.HandlePrefixes = append(breakdown.HandlePrefixes, *pu)
+			delete(prefixes, prefix)
+		}
+	}
+	sort.Slice(breakdown.BorrowedIPs, func(i, j int) bool {
+		a, b := breakdown.BorrowedIPs[i].IP, breakdown.BorrowedIPs[j].IP
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+	return breakdown, nil
+}
+
+// longestHandlePrefix returns the longest of the given prefixes that the handle starts with.
+func longestHandlePrefix(prefixes []string, handle string) (string, bool) {
+	longest, found := "", false
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(handle, prefix) && (!found || len(prefix) > len(longest)) {
+			longest, found = prefix, true
+		}
+	}
+	return longest, found
+}
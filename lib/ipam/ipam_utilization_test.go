@@ -0,0 +1,112 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	v3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend"
+	bapi "github.com/projectcalico/libcalico-go/lib/backend/api"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/testutils"
+)
+
+var _ = testutils.E2eDatastoreDescribe("IPAM utilization breakdown tests", testutils.DatastoreAll|testutils.DatastoreMemory, func(config apiconfig.CalicoAPIConfig) {
+	ctx := context.Background()
+	var bc bapi.Client
+	var ic Interface
+	var kc *kubernetes.Clientset
+
+	autoAssign := func(host, handle, namespace string, num int) []cnet.IPNet {
+		attrs := map[string]string{AttributeNode: host}
+		if namespace != "" {
+			attrs[AttributeNamespace] = namespace
+		}
+		v4, _, err := ic.AutoAssign(ctx, AutoAssignArgs{Num4: num, HandleID: &handle, Attrs: attrs, Hostname: host})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v4).To(HaveLen(num))
+		return v4
+	}
+
+	BeforeEach(func() {
+		var err error
+		bc, err = backend.NewClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		bc.Clean()
+		ic = NewIPAMClient(bc, staticPoolAccessor{{Spec: v3.IPPoolSpec{CIDR: "10.0.0.0/26", BlockSize: 28}}})
+		kc = kubeClientset(bc)
+		Expect(applyNode(bc, kc, "node-1", nil)).NotTo(HaveOccurred())
+		Expect(applyNode(bc, kc, "node-2", nil)).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		deleteNode(bc, kc, "node-1")
+		deleteNode(bc, kc, "node-2")
+		bc.Clean()
+	})
+
+	It("should aggregate by host, namespace and handle prefix and report borrowed IPs", func() {
+		node1IPs := autoAssign("node-1", "ns1-a", "ns1", 2)
+		autoAssign("node-1", "ns2-b", "ns2", 1)
+		autoAssign("node-2", "other", "", 1)
+
+		// Borrow the last address in node-1's block from node-2.
+		block := net.IPNet{IP: node1IPs[0].IP.Mask(net.CIDRMask(28, 32)), Mask: net.CIDRMask(28, 32)}
+		borrowed := cnet.IP{IP: net.IPv4(block.IP[0], block.IP[1], block.IP[2], block.IP[3]+15).To4()}
+		handle := "ns2-c"
+		Expect(ic.AssignIP(ctx, AssignIPArgs{
+			IP:       borrowed,
+			HandleID: &handle,
+			Attrs:    map[string]string{AttributeNode: "node-2", AttributeNamespace: "ns2"},
+			Hostname: "node-2",
+		})).NotTo(HaveOccurred())
+
+		breakdown, err := ic.GetUtilizationBreakdown(ctx, GetUtilizationBreakdownArgs{HandlePrefixes: []string{"ns", "ns2-", "unused"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(breakdown.Hosts).To(Equal([]HostUtilization{
+			{Host: "node-1", Blocks: 1, Capacity: 16, Available: 12, Borrowed: 1},
+			{Host: "node-2", Blocks: 1, Capacity: 16, Available: 15},
+		}))
+		Expect(breakdown.Namespaces).To(Equal([]AttributeUtilization{
+			{Value: "", InUse: 1},
+			{Value: "ns1", InUse: 2},
+			{Value: "ns2", InUse: 2, Borrowed: 1},
+		}))
+		Expect(breakdown.HandlePrefixes).To(Equal([]AttributeUtilization{
+			{Value: "ns", InUse: 2},
+			{Value: "ns2-", InUse: 2, Borrowed: 1},
+			{Value: "unused"},
+		}))
+		Expect(breakdown.BorrowedIPs).To(HaveLen(1))
+		Expect(breakdown.BorrowedIPs[0].IP.String()).To(Equal(borrowed.String()))
+		Expect(breakdown.BorrowedIPs[0].Block.String()).To(Equal(block.String()))
+		Expect(breakdown.BorrowedIPs[0].Affinity).To(Equal("node-1"))
+		Expect(breakdown.BorrowedIPs[0].Host).To(Equal("node-2"))
+		Expect(breakdown.BorrowedIPs[0].Handle).To(Equal("ns2-c"))
+		Expect(breakdown.BorrowedIPs[0].Namespace).To(Equal("ns2"))
+
+		// Restricting to a pool that doesn't exist reports nothing.
+		breakdown, err = ic.GetUtilizationBreakdown(ctx, GetUtilizationBreakdownArgs{Pools: []string{"10.1.0.0/16"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(breakdown).To(Equal(&UtilizationBreakdown{}))
+	})
+})
@@ -18,7 +18,8 @@ import apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
 
 func IsNamespaced(kind string) bool {
 	switch kind {
-	case apiv3.KindWorkloadEndpoint, apiv3.KindNetworkPolicy, apiv3.KindNetworkSet:
+	case apiv3.KindWorkloadEndpoint, apiv3.KindNetworkPolicy, apiv3.KindNetworkSet,
+		apiv3.KindStagedNetworkPolicy, apiv3.KindStagedKubernetesNetworkPolicy:
 		return true
 	default:
 		return false
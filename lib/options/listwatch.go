@@ -36,4 +36,30 @@ type ListOptions struct {
 	// as a mechanism for enumerating endpoints within a Pod (since the name construction for a
 	// Workload endpoint is hierarchically constructed).
 	Prefix bool
+
+	// LabelSelector restricts the List or Watch to the resources whose labels match the
+	// selector, which uses the Calico selector syntax, for example "app == 'web' && has(tier)".
+	// If blank, the labels are not filtered.
+	LabelSelector string
+
+	// FieldSelector restricts the List or Watch to the resources whose fields match the
+	// selector, which uses the Kubernetes field selector syntax.  Fields are identified by
+	// their path in the JSON representation of the resource, for example
+	// "spec.node=node1,metadata.namespace!=kube-system".  Only fields with a string, number
+	// or boolean value can be matched; other fields compare as empty.  If blank, the fields
+	// are not filtered.
+	FieldSelector string
+
+	// Limit is the maximum number of resources to return from a List, or 0 for no limit.
+	// If there are more resources, the returned list has a continuation token in its
+	// ListMeta.Continue, which is passed as Continue to List the next page.  A page may have
+	// fewer than Limit resources, or more for Profiles and for WorkloadEndpoints in
+	// Kubernetes, and some resource types do not support pagination and always return every
+	// resource, so the List is complete when the token is empty.
+	Limit int64
+
+	// Continue is the continuation token returned by the previous page of a List.  The
+	// remaining pages are consistent with the first page where the datastore supports it.
+	// The ResourceVersion must not be set.
+	Continue string
 }
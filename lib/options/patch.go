@@ -0,0 +1,24 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package options
+
+// PatchOptions is the standard options for patching a resource through the Calico API.
+type PatchOptions struct {
+	// When specified, the patch is only applied if the resource version of the resource
+	// matches.  When unset, the patch is applied to the current resource, and is retried if
+	// the resource is updated concurrently.
+	// +optional
+	ResourceVersion string
+}
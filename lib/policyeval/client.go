@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policyeval
+
+import (
+	"context"
+
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	"github.com/projectcalico/libcalico-go/lib/options"
+)
+
+// LoadSnapshot reads the tiers, policies, profiles, network sets and endpoints currently in the
+// datastore.  When using the Kubernetes datastore, the profiles include those generated from
+// namespaces and service accounts, and the network policies include those generated from
+// Kubernetes network policies, so the Kubernetes fields of the snapshot are left empty.
+func LoadSnapshot(ctx context.Context, c clientv3.Interface) (*Snapshot, error) {
+	s := &Snapshot{}
+	opts := options.ListOptions{}
+
+	tiers, err := c.Tiers().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.Tiers = tiers.Items
+
+	gnps, err := c.GlobalNetworkPolicies().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.GlobalNetworkPolicies = gnps.Items
+
+	nps, err := c.NetworkPolicies().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.NetworkPolicies = nps.Items
+
+	profiles, err := c.Profiles().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.Profiles = profiles.Items
+
+	gnss, err := c.GlobalNetworkSets().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.GlobalNetworkSets = gnss.Items
+
+	nss, err := c.NetworkSets().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.NetworkSets = nss.Items
+
+	weps, err := c.WorkloadEndpoints().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.WorkloadEndpoints = weps.Items
+
+	heps, err := c.HostEndpoints().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.HostEndpoints = heps.Items
+
+	return s, nil
+}
@@ -0,0 +1,349 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package policyeval evaluates network policy offline, without a Felix dataplane.
+
+An Evaluator is built from a Snapshot of the policy, profile, network set and endpoint
+resources, and answers whether a flow between two endpoints, or between an endpoint and an IP
+address, is allowed, and which policy rule decided it.
+
+The resources are converted to the v1 data model by the same update processors that feed
+Felix, and policy is applied with Felix's semantics:
+
+  - The egress policy of the source endpoint and the ingress policy of the destination endpoint
+    must both allow the flow.  A peer that is not a Calico endpoint applies no policy.
+  - Tiers are applied in order, and the policies within a tier are applied in order.  A policy
+    applies to an endpoint if its selector matches the endpoint and its Types include the
+    direction of the flow.
+  - Within a tier, the first matching Allow or Deny rule decides the flow; Log rules are
+    recorded and evaluation continues; a Pass rule skips to the next tier.  If a tier has
+    policies that apply to the endpoint but none of their rules match, the flow is denied.
+  - The endpoint's profiles are applied if no policy applies to it, or if the last tier that
+    applies passes the flow.  If no profile rule matches, the flow is denied.
+
+Untracked and pre-DNAT policies are not evaluated.
+
+An Evaluator can also preview the impact of a selector or policy, listing the endpoints and
+network sets that it selects with the same semantics.  LoadSnapshot reads a Snapshot from the
+datastore.
+*/
+package policyeval
+
+import (
+	"fmt"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+)
+
+// Peer identifies one end of a flow.
+type Peer struct {
+	// Kind is the kind of the Calico endpoint, either WorkloadEndpoint or HostEndpoint.  If
+	// empty, the peer is identified by its IP address alone, and is treated as the endpoint
+	// with that address if there is one.
+	Kind string
+	// Namespace of the WorkloadEndpoint.
+	Namespace string
+	// Name of the endpoint.
+	Name string
+	// IP address of the peer.  Required if Kind is empty.  If omitted for an endpoint, the
+	// first of the endpoint's addresses with the same IP version as the other peer is used.
+	IP string
+}
+
+// Flow describes a flow to be evaluated.
+type Flow struct {
+	Source      Peer
+	Destination Peer
+	// Protocol of the flow, either a name such as TCP or a protocol number.
+	Protocol numorstring.Protocol
+	// SourcePort and DestinationPort are used for protocols that support ports.
+	SourcePort      uint16
+	DestinationPort uint16
+	// ICMPType and ICMPCode are used for ICMP flows.
+	ICMPType *int
+	ICMPCode *int
+}
+
+// RuleHit identifies a rule that matched a flow.
+type RuleHit struct {
+	// Kind of the resource that contains the rule: GlobalNetworkPolicy, NetworkPolicy or
+	// Profile.  Kubernetes network policies are reported as the NetworkPolicy they are
+	// converted to.
+	Kind      string
+	Namespace string
+	Name      string
+	// Tier of the policy.  Empty for a profile.
+	Tier string
+	// Index of the rule within the Ingress or Egress rules of the resource.
+	Index  int
+	Action apiv3.Action
+}
+
+// Decision is the outcome of applying the policy of one endpoint to a flow in one direction.
+type Decision struct {
+	// Action is either Allow or Deny.
+	Action apiv3.Action
+	// Rule is the rule that decided the flow, or nil if the flow was denied because no rule
+	// matched.
+	Rule *RuleHit
+	// Reason explains a decision that was not made by a rule.
+	Reason string
+	// Trace lists the rules that matched the flow, in the order in which they were applied.
+	// This includes Log and Pass rules, and ends with the deciding rule if there is one.
+	Trace []RuleHit
+}
+
+// Result is the outcome of evaluating a flow.
+type Result struct {
+	// Allowed is true if the flow is allowed by both the source and the destination.
+	Allowed bool
+	// Egress is the decision of the source endpoint's egress policy, or nil if the source is
+	// not a Calico endpoint.
+	Egress *Decision
+	// Ingress is the decision of the destination endpoint's ingress policy, or nil if the
+	// destination is not a Calico endpoint.
+	Ingress *Decision
+}
+
+// Evaluator evaluates flows against a snapshot of policy.  It does not modify the snapshot, and
+// is safe for concurrent use once created.
+type Evaluator struct {
+	tiers       map[string]*tier
+	ordered     []*tier
+	profiles    map[string]*profile
+	endpoints   []*endpoint
+	networkSets []*endpoint
+}
+
+// NewEvaluator creates an Evaluator for the supplied snapshot.  An error is returned if any of
+// the resources cannot be converted.
+func NewEvaluator(s *Snapshot) (*Evaluator, error) {
+	e := &Evaluator{}
+	e.loadTiers(s)
+	if err := e.loadPolicies(s); err != nil {
+		return nil, err
+	}
+	if err := e.loadProfiles(s); err != nil {
+		return nil, err
+	}
+	if err := e.loadEndpoints(s); err != nil {
+		return nil, err
+	}
+	e.ordered = e.sortedTiers()
+	return e, nil
+}
+
+// Evaluate evaluates the flow, returning whether it is allowed and the policy decisions that
+// were made for it.
+func (e *Evaluator) Evaluate(fl Flow) (*Result, error) {
+	src, err := e.resolve(fl.Source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %v", err)
+	}
+	dst, err := e.resolve(fl.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination: %v", err)
+	}
+	if src.endpoint == nil && dst.endpoint == nil {
+		return nil, fmt.Errorf("neither the source nor the destination is a Calico endpoint")
+	}
+
+	// Fill in the addresses of endpoints that were identified by name only.
+	if src.ip == nil && dst.ip != nil {
+		src.ip = src.endpoint.address(dst.ip.Version())
+	}
+	if dst.ip == nil {
+		version := 0
+		if src.ip != nil {
+			version = src.ip.Version()
+		}
+		dst.ip = dst.endpoint.address(version)
+	}
+	if src.ip == nil && dst.ip != nil {
+		src.ip = src.endpoint.address(dst.ip.Version())
+	}
+	for _, p := range []peer{src, dst} {
+		if p.ip == nil && len(p.endpoint.nets) > 0 {
+			return nil, fmt.Errorf("%s has no address of the same IP version as the other peer", p.endpoint)
+		}
+	}
+
+	f := &flow{
+		src:      src,
+		dst:      dst,
+		srcPort:  fl.SourcePort,
+		dstPort:  fl.DestinationPort,
+		icmpType: fl.ICMPType,
+		icmpCode: fl.ICMPCode,
+	}
+	switch {
+	case src.ip != nil && dst.ip != nil && src.ip.Version() != dst.ip.Version():
+		return nil, fmt.Errorf("source and destination IP versions differ")
+	case src.ip != nil:
+		f.ipVersion = src.ip.Version()
+	case dst.ip != nil:
+		f.ipVersion = dst.ip.Version()
+	}
+	var ok bool
+	if f.protocol, ok = protocolNumber(fl.Protocol); !ok {
+		return nil, fmt.Errorf("unknown protocol %s", fl.Protocol)
+	}
+
+	res := &Result{Allowed: true}
+	if src.endpoint != nil {
+		res.Egress = e.decide(src.endpoint, apiv3.PolicyTypeEgress, f)
+		res.Allowed = res.Allowed && res.Egress.Action == apiv3.Allow
+	}
+	if dst.endpoint != nil {
+		res.Ingress = e.decide(dst.endpoint, apiv3.PolicyTypeIngress, f)
+		res.Allowed = res.Allowed && res.Ingress.Action == apiv3.Allow
+	}
+	return res, nil
+}
+
+// resolve finds the endpoint and parses the IP address of a peer.
+func (e *Evaluator) resolve(p Peer) (peer, error) {
+	var resolved peer
+	if p.IP != "" {
+		if resolved.ip = cnet.ParseIP(p.IP); resolved.ip == nil {
+			return resolved, fmt.Errorf("invalid IP address %s", p.IP)
+		}
+	}
+	switch p.Kind {
+	case apiv3.KindWorkloadEndpoint, apiv3.KindHostEndpoint:
+		for _, ep := range e.endpoints {
+			if ep.kind == p.Kind && ep.namespace == p.Namespace && ep.name == p.Name {
+				resolved.endpoint = ep
+				return resolved, nil
+			}
+		}
+		return resolved, fmt.Errorf("%s %s not found", p.Kind, p.Name)
+	case "":
+		if resolved.ip == nil {
+			return resolved, fmt.Errorf("either an endpoint or an IP address is required")
+		}
+		for _, ep := range e.endpoints {
+			if ep.contains(resolved.ip) {
+				resolved.endpoint = ep
+				break
+			}
+		}
+		return resolved, nil
+	}
+	return resolved, fmt.Errorf("unsupported kind %s", p.Kind)
+}
+
+// address returns the first of the endpoint's addresses with the given IP version, or with any
+// version if the version is 0.
+func (e *endpoint) address(version int) *cnet.IP {
+	if e == nil {
+		return nil
+	}
+	for _, n := range e.nets {
+		if version == 0 || n.Version() == version {
+			return &cnet.IP{IP: n.IP}
+		}
+	}
+	return nil
+}
+
+// decide applies the policy and profiles of an endpoint to the flow in one direction.
+func (e *Evaluator) decide(ep *endpoint, dir apiv3.PolicyType, f *flow) *Decision {
+	d := &Decision{}
+	for _, t := range e.ordered {
+		applied, passed := false, false
+	policies:
+		for _, p := range t.policies {
+			rules := p.inbound
+			if dir == apiv3.PolicyTypeEgress {
+				rules = p.outbound
+			}
+			if dir == apiv3.PolicyTypeIngress && !p.ingress || dir == apiv3.PolicyTypeEgress && !p.egress {
+				continue
+			}
+			if !p.selector.EvaluateLabels(ep) {
+				continue
+			}
+			applied = true
+			for i, r := range rules {
+				if !e.matches(r, f) {
+					continue
+				}
+				hit := RuleHit{
+					Kind:      p.kind,
+					Namespace: p.namespace,
+					Name:      p.name,
+					Tier:      t.name,
+					Index:     i,
+					Action:    r.action(),
+				}
+				d.Trace = append(d.Trace, hit)
+				switch hit.Action {
+				case apiv3.Allow, apiv3.Deny:
+					d.Action = hit.Action
+					d.Rule = &hit
+					return d
+				case apiv3.Pass:
+					passed = true
+					break policies
+				}
+			}
+		}
+		if applied && !passed {
+			d.Action = apiv3.Deny
+			d.Reason = fmt.Sprintf("no %s rule matched in tier %s", dir, t.name)
+			return d
+		}
+	}
+
+	// No policy applied, or the last tier that applied passed the flow, so apply the profiles.
+	for _, id := range ep.profileIDs {
+		p, ok := e.profiles[id]
+		if !ok {
+			continue
+		}
+		rules := p.inbound
+		if dir == apiv3.PolicyTypeEgress {
+			rules = p.outbound
+		}
+	profileRules:
+		for i, r := range rules {
+			if !e.matches(r, f) {
+				continue
+			}
+			hit := RuleHit{
+				Kind:   apiv3.KindProfile,
+				Name:   p.name,
+				Index:  i,
+				Action: r.action(),
+			}
+			d.Trace = append(d.Trace, hit)
+			switch hit.Action {
+			case apiv3.Allow, apiv3.Deny:
+				d.Action = hit.Action
+				d.Rule = &hit
+				return d
+			case apiv3.Pass:
+				// A Pass rule in a profile skips to the next profile.
+				break profileRules
+			}
+		}
+	}
+	d.Action = apiv3.Deny
+	d.Reason = fmt.Sprintf("no %s policy or profile rule matched", dir)
+	return d
+}
@@ -0,0 +1,308 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policyeval_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	kapiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	"github.com/projectcalico/libcalico-go/lib/policyeval"
+)
+
+var (
+	tcp     = numorstring.ProtocolFromString("TCP")
+	order10 = 10.0
+	order1  = 1.0
+
+	frontend = policyeval.Peer{Kind: apiv3.KindWorkloadEndpoint, Namespace: "frontend", Name: "node1-k8s-fe-eth0"}
+	backend  = policyeval.Peer{Kind: apiv3.KindWorkloadEndpoint, Namespace: "backend", Name: "node1-k8s-be-eth0"}
+	host     = policyeval.Peer{Kind: apiv3.KindHostEndpoint, Name: "node1-eth0"}
+)
+
+func flow(src, dst policyeval.Peer, port uint16) policyeval.Flow {
+	return policyeval.Flow{Source: src, Destination: dst, Protocol: tcp, SourcePort: 40000, DestinationPort: port}
+}
+
+var _ = Describe("Policy evaluator", func() {
+	var snapshot *policyeval.Snapshot
+
+	BeforeEach(func() {
+		snapshot = &policyeval.Snapshot{
+			Namespaces: []kapiv1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "frontend", Labels: map[string]string{"team": "web"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "backend", Labels: map[string]string{"team": "data"}}},
+			},
+			ServiceAccounts: []kapiv1.ServiceAccount{
+				{ObjectMeta: metav1.ObjectMeta{Name: "api-sa", Namespace: "backend", Labels: map[string]string{"role": "api"}}},
+			},
+			WorkloadEndpoints: []apiv3.WorkloadEndpoint{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: frontend.Name, Namespace: frontend.Namespace, Labels: map[string]string{"app": "fe"}},
+					Spec: apiv3.WorkloadEndpointSpec{
+						Orchestrator: "k8s",
+						IPNetworks:   []string{"10.0.0.1/32"},
+						Profiles:     []string{"kns.frontend"},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: backend.Name, Namespace: backend.Namespace, Labels: map[string]string{
+						"app":                       "be",
+						apiv3.LabelServiceAccount:   "api-sa",
+						"projectcalico.org/ignored": "x",
+					}},
+					Spec: apiv3.WorkloadEndpointSpec{
+						Orchestrator: "k8s",
+						IPNetworks:   []string{"10.0.1.1/32"},
+						Ports:        []apiv3.EndpointPort{{Name: "http", Protocol: tcp, Port: 8080}},
+					},
+				},
+			},
+			HostEndpoints: []apiv3.HostEndpoint{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: host.Name, Labels: map[string]string{"host": "node1"}},
+					Spec:       apiv3.HostEndpointSpec{Node: "node1", InterfaceName: "eth0", ExpectedIPs: []string{"192.168.0.1"}},
+				},
+			},
+			GlobalNetworkSets: []apiv3.GlobalNetworkSet{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "blocked", Labels: map[string]string{"kind": "blocked"}},
+					Spec:       apiv3.GlobalNetworkSetSpec{Nets: []string{"203.0.113.0/24"}},
+				},
+			},
+		}
+	})
+
+	evaluate := func(f policyeval.Flow) *policyeval.Result {
+		e, err := policyeval.NewEvaluator(snapshot)
+		Expect(err).NotTo(HaveOccurred())
+		res, err := e.Evaluate(f)
+		Expect(err).NotTo(HaveOccurred())
+		return res
+	}
+
+	It("should apply the profiles when no policy applies", func() {
+		res := evaluate(flow(frontend, backend, 8080))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Egress.Rule).To(Equal(&policyeval.RuleHit{
+			Kind: apiv3.KindProfile, Name: "kns.frontend", Index: 0, Action: apiv3.Allow,
+		}))
+		// The backend endpoint does not list its namespace profile, but inherits it.
+		Expect(res.Ingress.Rule.Name).To(Equal("kns.backend"))
+	})
+
+	It("should deny a flow to an endpoint without profiles", func() {
+		res := evaluate(flow(frontend, host, 22))
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Egress.Action).To(BeEquivalentTo(apiv3.Allow))
+		Expect(res.Ingress.Action).To(BeEquivalentTo(apiv3.Deny))
+		Expect(res.Ingress.Rule).To(BeNil())
+		Expect(res.Ingress.Reason).To(Equal("no Ingress policy or profile rule matched"))
+	})
+
+	It("should honour NamespaceSelector, named port and end-of-tier deny", func() {
+		snapshot.NetworkPolicies = []apiv3.NetworkPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-web", Namespace: "backend"},
+			Spec: apiv3.NetworkPolicySpec{
+				Selector: "app == 'be'",
+				Types:    []apiv3.PolicyType{apiv3.PolicyTypeIngress},
+				Ingress: []apiv3.Rule{{
+					Action:      apiv3.Allow,
+					Protocol:    &tcp,
+					Source:      apiv3.EntityRule{NamespaceSelector: "team == 'web'"},
+					Destination: apiv3.EntityRule{Ports: []numorstring.Port{numorstring.NamedPort("http")}},
+				}},
+			},
+		}}
+
+		res := evaluate(flow(frontend, backend, 8080))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Ingress.Rule).To(Equal(&policyeval.RuleHit{
+			Kind: apiv3.KindNetworkPolicy, Namespace: "backend", Name: "allow-web", Tier: "default", Index: 0, Action: apiv3.Allow,
+		}))
+
+		res = evaluate(flow(frontend, backend, 9090))
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Ingress.Rule).To(BeNil())
+		Expect(res.Ingress.Reason).To(Equal("no Ingress rule matched in tier default"))
+
+		res = evaluate(flow(host, backend, 8080))
+		Expect(res.Allowed).To(BeFalse())
+	})
+
+	It("should honour ServiceAccounts matches", func() {
+		snapshot.NetworkPolicies = []apiv3.NetworkPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-api", Namespace: "frontend"},
+			Spec: apiv3.NetworkPolicySpec{
+				Selector: "all()",
+				Types:    []apiv3.PolicyType{apiv3.PolicyTypeIngress},
+				Ingress: []apiv3.Rule{{
+					Action: apiv3.Allow,
+					Source: apiv3.EntityRule{
+						NamespaceSelector: "all()",
+						ServiceAccounts:   &apiv3.ServiceAccountMatch{Selector: "role == 'api'"},
+					},
+				}},
+			},
+		}}
+
+		res := evaluate(flow(backend, frontend, 80))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Ingress.Rule.Name).To(Equal("allow-api"))
+
+		res = evaluate(flow(host, frontend, 80))
+		Expect(res.Allowed).To(BeFalse())
+	})
+
+	It("should apply tiers in order and honour Pass, Log and Deny", func() {
+		snapshot.Tiers = []apiv3.Tier{{
+			ObjectMeta: metav1.ObjectMeta{Name: "security"},
+			Spec:       apiv3.TierSpec{Order: &order10},
+		}}
+		snapshot.GlobalNetworkPolicies = []apiv3.GlobalNetworkPolicy{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "security.block"},
+				Spec: apiv3.GlobalNetworkPolicySpec{
+					Tier:     "security",
+					Selector: "all()",
+					Types:    []apiv3.PolicyType{apiv3.PolicyTypeEgress},
+					Egress: []apiv3.Rule{
+						{Action: apiv3.Log},
+						{Action: apiv3.Deny, Destination: apiv3.EntityRule{Selector: "kind == 'blocked'"}},
+						{Action: apiv3.Pass},
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "allow-all-egress"},
+				Spec: apiv3.GlobalNetworkPolicySpec{
+					Order:    &order1,
+					Selector: "app == 'fe'",
+					Types:    []apiv3.PolicyType{apiv3.PolicyTypeEgress},
+					Egress:   []apiv3.Rule{{Action: apiv3.Allow}},
+				},
+			},
+		}
+
+		res := evaluate(policyeval.Flow{Source: frontend, Destination: policyeval.Peer{IP: "203.0.113.5"}, Protocol: tcp, DestinationPort: 443})
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Ingress).To(BeNil())
+		Expect(res.Egress.Trace).To(Equal([]policyeval.RuleHit{
+			{Kind: apiv3.KindGlobalNetworkPolicy, Name: "security.block", Tier: "security", Index: 0, Action: apiv3.Log},
+			{Kind: apiv3.KindGlobalNetworkPolicy, Name: "security.block", Tier: "security", Index: 1, Action: apiv3.Deny},
+		}))
+		Expect(res.Egress.Rule).To(Equal(&res.Egress.Trace[1]))
+
+		res = evaluate(flow(frontend, backend, 8080))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Egress.Trace).To(HaveLen(3))
+		Expect(res.Egress.Trace[1].Action).To(BeEquivalentTo(apiv3.Pass))
+		Expect(res.Egress.Rule.Name).To(Equal("allow-all-egress"))
+
+		// The backend endpoint passes the security tier and no default tier policy applies,
+		// so its profile decides.
+		res = evaluate(flow(backend, frontend, 80))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Egress.Rule.Kind).To(Equal(apiv3.KindProfile))
+		Expect(res.Egress.Rule.Name).To(Equal("kns.backend"))
+	})
+
+	It("should honour NotNets and NotPorts", func() {
+		snapshot.GlobalNetworkPolicies = []apiv3.GlobalNetworkPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-only"},
+			Spec: apiv3.GlobalNetworkPolicySpec{
+				Selector: "app == 'fe'",
+				Types:    []apiv3.PolicyType{apiv3.PolicyTypeEgress},
+				Egress: []apiv3.Rule{
+					{Action: apiv3.Deny, Destination: apiv3.EntityRule{NotNets: []string{"10.0.0.0/16"}}},
+					{Action: apiv3.Allow, Protocol: &tcp, Destination: apiv3.EntityRule{NotPorts: []numorstring.Port{numorstring.SinglePort(22)}}},
+				},
+			},
+		}}
+
+		res := evaluate(policyeval.Flow{Source: frontend, Destination: policyeval.Peer{IP: "8.8.8.8"}, Protocol: tcp, DestinationPort: 443})
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Egress.Rule.Index).To(Equal(0))
+
+		res = evaluate(flow(frontend, backend, 443))
+		Expect(res.Allowed).To(BeTrue())
+		Expect(res.Egress.Rule.Index).To(Equal(1))
+
+		res = evaluate(flow(frontend, backend, 22))
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Egress.Reason).To(Equal("no Egress rule matched in tier default"))
+	})
+
+	It("should only apply a policy in the directions given by its Types", func() {
+		snapshot.GlobalNetworkPolicies = []apiv3.GlobalNetworkPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "egress-only"},
+			Spec: apiv3.GlobalNetworkPolicySpec{
+				Selector: "all()",
+				Types:    []apiv3.PolicyType{apiv3.PolicyTypeEgress},
+			},
+		}}
+
+		res := evaluate(flow(frontend, backend, 8080))
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Egress.Action).To(BeEquivalentTo(apiv3.Deny))
+		Expect(res.Ingress.Action).To(BeEquivalentTo(apiv3.Allow))
+		Expect(res.Ingress.Rule.Kind).To(Equal(apiv3.KindProfile))
+	})
+
+	It("should evaluate Kubernetes network policies", func() {
+		snapshot.KubernetesNetworkPolicies = []networkingv1.NetworkPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "backend"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		}}
+
+		res := evaluate(flow(frontend, backend, 8080))
+		Expect(res.Allowed).To(BeFalse())
+		Expect(res.Ingress.Reason).To(Equal("no Ingress rule matched in tier default"))
+	})
+
+	It("should resolve an IP address to the endpoint that has it", func() {
+		res := evaluate(policyeval.Flow{
+			Source:          policyeval.Peer{IP: "10.0.0.1"},
+			Destination:     policyeval.Peer{IP: "192.168.0.1"},
+			Protocol:        tcp,
+			DestinationPort: 22,
+		})
+		Expect(res.Egress).NotTo(BeNil())
+		Expect(res.Egress.Rule.Name).To(Equal("kns.frontend"))
+		Expect(res.Ingress).NotTo(BeNil())
+		Expect(res.Allowed).To(BeFalse())
+	})
+
+	It("should return an error for an invalid flow", func() {
+		e, err := policyeval.NewEvaluator(snapshot)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = e.Evaluate(flow(policyeval.Peer{Kind: apiv3.KindWorkloadEndpoint, Namespace: "x", Name: "y"}, backend, 80))
+		Expect(err).To(HaveOccurred())
+
+		_, err = e.Evaluate(flow(policyeval.Peer{IP: "1.1.1.1"}, policyeval.Peer{IP: "8.8.8.8"}, 80))
+		Expect(err).To(HaveOccurred())
+
+		_, err = e.Evaluate(flow(frontend, policyeval.Peer{IP: "fd00::1"}, 80))
+		Expect(err).To(HaveOccurred())
+	})
+})
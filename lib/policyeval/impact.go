@@ -0,0 +1,144 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policyeval
+
+import (
+	"sort"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+// Resource identifies an endpoint or network set.
+type Resource struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// RuleImpact lists the endpoints and network sets selected by the source and destination of
+// a rule.
+type RuleImpact struct {
+	// Index of the rule within the Ingress or Egress rules of the policy.
+	Index int
+	// Source lists the resources selected by the Selector, NotSelector, NamespaceSelector and
+	// ServiceAccounts fields of the rule's Source, or is nil if none of those fields is set.
+	Source []Resource
+	// Destination lists the resources selected by the rule's Destination, in the same way.
+	Destination []Resource
+}
+
+// PolicyImpact lists the resources that a policy would select.
+type PolicyImpact struct {
+	// Endpoints lists the workload and host endpoints that the policy applies to.
+	Endpoints []Resource
+	Ingress   []RuleImpact
+	Egress    []RuleImpact
+}
+
+// Select returns the workload endpoints, host endpoints and network sets that are selected by a
+// selector and an optional namespace selector.  The selectors have the semantics of the
+// Selector and NamespaceSelector of a GlobalNetworkPolicy: the namespace selector matches the
+// labels of the namespace of a namespaced resource, and "all()" as a namespace selector matches
+// every namespaced resource.  Endpoints match on the labels that Felix sees, which include the
+// projectcalico.org/namespace label, the service account labels, and labels inherited from
+// profiles.
+func (e *Evaluator) Select(sel, namespaceSelector string) ([]Resource, error) {
+	p, err := newPolicy(
+		updateprocessors.NewGlobalNetworkPolicyUpdateProcessor(), apiv3.KindGlobalNetworkPolicy, "", "select",
+		&apiv3.GlobalNetworkPolicy{
+			Spec: apiv3.GlobalNetworkPolicySpec{Selector: sel, NamespaceSelector: namespaceSelector},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return e.selected(p.selector, nil, true), nil
+}
+
+// GlobalNetworkPolicyImpact returns the resources that a GlobalNetworkPolicy would select if it
+// were applied.  The policy does not need to be in the snapshot.
+func (e *Evaluator) GlobalNetworkPolicyImpact(gnp *apiv3.GlobalNetworkPolicy) (*PolicyImpact, error) {
+	p, err := newPolicy(updateprocessors.NewGlobalNetworkPolicyUpdateProcessor(), apiv3.KindGlobalNetworkPolicy, "", gnp.Name, gnp)
+	if err != nil {
+		return nil, err
+	}
+	return e.impact(p), nil
+}
+
+// NetworkPolicyImpact returns the resources that a NetworkPolicy would select if it were
+// applied.  The policy does not need to be in the snapshot.
+func (e *Evaluator) NetworkPolicyImpact(np *apiv3.NetworkPolicy) (*PolicyImpact, error) {
+	p, err := newPolicy(updateprocessors.NewNetworkPolicyUpdateProcessor(), apiv3.KindNetworkPolicy, np.Namespace, np.Name, np)
+	if err != nil {
+		return nil, err
+	}
+	return e.impact(p), nil
+}
+
+func (e *Evaluator) impact(p *policy) *PolicyImpact {
+	impact := &PolicyImpact{
+		Endpoints: e.selected(p.selector, nil, false),
+	}
+	for i, r := range p.inbound {
+		impact.Ingress = append(impact.Ingress, e.ruleImpact(i, r))
+	}
+	for i, r := range p.outbound {
+		impact.Egress = append(impact.Egress, e.ruleImpact(i, r))
+	}
+	return impact
+}
+
+func (e *Evaluator) ruleImpact(index int, r *rule) RuleImpact {
+	ri := RuleImpact{Index: index}
+	if r.srcSelector != nil || r.notSrcSelector != nil {
+		ri.Source = e.selected(r.srcSelector, r.notSrcSelector, true)
+	}
+	if r.dstSelector != nil || r.notDstSelector != nil {
+		ri.Destination = e.selected(r.dstSelector, r.notDstSelector, true)
+	}
+	return ri
+}
+
+// selected returns the resources that match the selector, if not nil, and do not match the
+// negated selector, if not nil.  Network sets are included if requested; they are selected by
+// rules, but policy does not apply to them.
+func (e *Evaluator) selected(sel, notSel selector.Selector, networkSets bool) []Resource {
+	candidates := e.endpoints
+	if networkSets {
+		candidates = append(append([]*endpoint{}, e.endpoints...), e.networkSets...)
+	}
+	resources := []Resource{}
+	for _, ep := range candidates {
+		if sel != nil && !sel.EvaluateLabels(ep) {
+			continue
+		}
+		if notSel != nil && notSel.EvaluateLabels(ep) {
+			continue
+		}
+		resources = append(resources, Resource{Kind: ep.kind, Namespace: ep.namespace, Name: ep.name})
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		if resources[i].Namespace != resources[j].Namespace {
+			return resources[i].Namespace < resources[j].Namespace
+		}
+		return resources[i].Name < resources[j].Name
+	})
+	return resources
+}
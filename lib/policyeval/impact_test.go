@@ -0,0 +1,196 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policyeval_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	kapiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/projectcalico/libcalico-go/lib/apiconfig"
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/clientv3"
+	"github.com/projectcalico/libcalico-go/lib/options"
+	"github.com/projectcalico/libcalico-go/lib/policyeval"
+)
+
+var _ = Describe("Policy impact", func() {
+	var e *policyeval.Evaluator
+
+	wep := func(ns, name string, labels map[string]string, profiles ...string) apiv3.WorkloadEndpoint {
+		return apiv3.WorkloadEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: labels},
+			Spec: apiv3.WorkloadEndpointSpec{
+				Orchestrator: "k8s",
+				IPNetworks:   []string{"10.0.0.1/32"},
+				Profiles:     profiles,
+			},
+		}
+	}
+	res := func(kind, ns, name string) policyeval.Resource {
+		return policyeval.Resource{Kind: kind, Namespace: ns, Name: name}
+	}
+	web := res(apiv3.KindWorkloadEndpoint, "frontend", "node1-k8s-web-eth0")
+	api := res(apiv3.KindWorkloadEndpoint, "backend", "node1-k8s-api-eth0")
+	db := res(apiv3.KindWorkloadEndpoint, "backend", "node1-k8s-db-eth0")
+	hep := res(apiv3.KindHostEndpoint, "", "node1-eth0")
+	gns := res(apiv3.KindGlobalNetworkSet, "", "external")
+	ns := res(apiv3.KindNetworkSet, "backend", "partners")
+
+	BeforeEach(func() {
+		var err error
+		e, err = policyeval.NewEvaluator(&policyeval.Snapshot{
+			Namespaces: []kapiv1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "frontend", Labels: map[string]string{"team": "web"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "backend", Labels: map[string]string{"team": "data"}}},
+			},
+			ServiceAccounts: []kapiv1.ServiceAccount{
+				{ObjectMeta: metav1.ObjectMeta{Name: "api-sa", Namespace: "backend", Labels: map[string]string{"role": "api"}}},
+			},
+			Profiles: []apiv3.Profile{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "tenant"},
+					Spec:       apiv3.ProfileSpec{LabelsToApply: map[string]string{"tenant": "acme", "app": "inherited"}},
+				},
+			},
+			WorkloadEndpoints: []apiv3.WorkloadEndpoint{
+				wep(web.Namespace, web.Name, map[string]string{"app": "web"}, "tenant"),
+				wep(api.Namespace, api.Name, map[string]string{"app": "api", apiv3.LabelServiceAccount: "api-sa"}),
+				wep(db.Namespace, db.Name, map[string]string{"app": "db"}),
+			},
+			HostEndpoints: []apiv3.HostEndpoint{{
+				ObjectMeta: metav1.ObjectMeta{Name: hep.Name, Labels: map[string]string{"app": "host"}},
+				Spec:       apiv3.HostEndpointSpec{Node: "node1", InterfaceName: "eth0"},
+			}},
+			GlobalNetworkSets: []apiv3.GlobalNetworkSet{{
+				ObjectMeta: metav1.ObjectMeta{Name: gns.Name, Labels: map[string]string{"app": "external"}},
+				Spec:       apiv3.GlobalNetworkSetSpec{Nets: []string{"198.51.100.0/24"}},
+			}},
+			NetworkSets: []apiv3.NetworkSet{{
+				ObjectMeta: metav1.ObjectMeta{Name: ns.Name, Namespace: ns.Namespace, Labels: map[string]string{"app": "partners"}},
+				Spec:       apiv3.NetworkSetSpec{Nets: []string{"203.0.113.0/24"}},
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("selecting resources",
+		func(sel, nsSel string, expected ...policyeval.Resource) {
+			selected, err := e.Select(sel, nsSel)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selected).To(Equal(append([]policyeval.Resource{}, expected...)))
+		},
+		Entry("all()", "all()", "", gns, hep, ns, api, db, web),
+		Entry("own labels take precedence over profile labels", "app == 'web'", "", web),
+		Entry("labels inherited from a profile", "tenant == 'acme'", "", web),
+		Entry("the namespace label", "projectcalico.org/namespace == 'backend'", "", ns, api, db),
+		Entry("service account labels", "pcsa.role == 'api'", "", api),
+		Entry("a namespace selector", "has(app)", "team == 'data'", ns, api, db),
+		Entry("all() as a namespace selector", "", "all()", ns, api, db, web),
+		Entry("nothing", "app == 'none'", ""),
+	)
+
+	It("should return an error for an invalid selector", func() {
+		_, err := e.Select("app ==", "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should list the resources selected by a GlobalNetworkPolicy and its rules", func() {
+		impact, err := e.GlobalNetworkPolicyImpact(&apiv3.GlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "restrict-backend"},
+			Spec: apiv3.GlobalNetworkPolicySpec{
+				Selector:          "app in {'api', 'db'}",
+				NamespaceSelector: "team == 'data'",
+				Ingress: []apiv3.Rule{
+					{
+						Action: apiv3.Allow,
+						Source: apiv3.EntityRule{ServiceAccounts: &apiv3.ServiceAccountMatch{Names: []string{"api-sa"}}},
+					},
+					{
+						Action: apiv3.Allow,
+						Source: apiv3.EntityRule{NamespaceSelector: "global()", NotSelector: "app == 'host'"},
+					},
+					{
+						Action: apiv3.Deny,
+						Source: apiv3.EntityRule{Nets: []string{"10.0.0.0/8"}},
+					},
+				},
+				Egress: []apiv3.Rule{{
+					Action:      apiv3.Allow,
+					Destination: apiv3.EntityRule{NamespaceSelector: "all()", Selector: "app == 'partners'"},
+				}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(impact).To(Equal(&policyeval.PolicyImpact{
+			Endpoints: []policyeval.Resource{api, db},
+			Ingress: []policyeval.RuleImpact{
+				{Index: 0, Source: []policyeval.Resource{api}},
+				{Index: 1, Source: []policyeval.Resource{gns}},
+				{Index: 2},
+			},
+			Egress: []policyeval.RuleImpact{
+				{Index: 0, Destination: []policyeval.Resource{ns}},
+			},
+		}))
+	})
+
+	It("should limit the rules of a NetworkPolicy to its namespace", func() {
+		impact, err := e.NetworkPolicyImpact(&apiv3.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-app", Namespace: "backend"},
+			Spec: apiv3.NetworkPolicySpec{
+				Selector: "all()",
+				Ingress:  []apiv3.Rule{{Action: apiv3.Allow, Source: apiv3.EntityRule{Selector: "has(app)"}}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(impact.Endpoints).To(Equal([]policyeval.Resource{api, db}))
+		Expect(impact.Ingress).To(Equal([]policyeval.RuleImpact{{Index: 0, Source: []policyeval.Resource{ns, api, db}}}))
+	})
+
+	It("should load a snapshot from the datastore", func() {
+		ctx := context.Background()
+		c, err := clientv3.New(apiconfig.CalicoAPIConfig{
+			Spec: apiconfig.CalicoAPIConfigSpec{
+				DatastoreType: apiconfig.Memory,
+				MemoryConfig:  apiconfig.MemoryConfig{MemoryStoreName: "policyeval-snapshot"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.HostEndpoints().Create(ctx, &apiv3.HostEndpoint{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1-eth0", Labels: map[string]string{"app": "host"}},
+			Spec:       apiv3.HostEndpointSpec{Node: "node1", InterfaceName: "eth0"},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.GlobalNetworkSets().Create(ctx, &apiv3.GlobalNetworkSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "external", Labels: map[string]string{"app": "external"}},
+			Spec:       apiv3.GlobalNetworkSetSpec{Nets: []string{"198.51.100.0/24"}},
+		}, options.SetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		s, err := policyeval.LoadSnapshot(ctx, c)
+		Expect(err).NotTo(HaveOccurred())
+		e, err := policyeval.NewEvaluator(s)
+		Expect(err).NotTo(HaveOccurred())
+		selected, err := e.Select("has(app)", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selected).To(Equal([]policyeval.Resource{gns, hep}))
+	})
+})
@@ -0,0 +1,239 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policyeval
+
+import (
+	"strings"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/numorstring"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+// protocolNumbers maps the protocol names accepted in rules to their IANA numbers.
+var protocolNumbers = map[string]uint8{
+	"icmp":    1,
+	"tcp":     6,
+	"udp":     17,
+	"icmpv6":  58,
+	"sctp":    132,
+	"udplite": 136,
+}
+
+// protocolNumber returns the IANA number of the protocol, and false if the protocol name is not
+// recognised.
+func protocolNumber(p numorstring.Protocol) (uint8, bool) {
+	if p.Type == numorstring.NumOrStringNum {
+		return p.NumVal, true
+	}
+	n, ok := protocolNumbers[strings.ToLower(p.StrVal)]
+	return n, ok
+}
+
+// rule is a v1 rule with its selectors parsed.  The v1 selectors incorporate the EntityRule's
+// NamespaceSelector and ServiceAccounts matches, and the namespace of the policy.
+type rule struct {
+	model.Rule
+	srcSelector    selector.Selector
+	dstSelector    selector.Selector
+	notSrcSelector selector.Selector
+	notDstSelector selector.Selector
+}
+
+func newRules(rs []model.Rule) ([]*rule, error) {
+	rules := make([]*rule, len(rs))
+	for i, r := range rs {
+		cr := &rule{Rule: r}
+		for _, s := range []struct {
+			expr string
+			sel  *selector.Selector
+		}{
+			{r.SrcSelector, &cr.srcSelector},
+			{r.DstSelector, &cr.dstSelector},
+			{r.NotSrcSelector, &cr.notSrcSelector},
+			{r.NotDstSelector, &cr.notDstSelector},
+		} {
+			if s.expr == "" {
+				continue
+			}
+			sel, err := selector.Parse(s.expr)
+			if err != nil {
+				return nil, err
+			}
+			*s.sel = sel
+		}
+		rules[i] = cr
+	}
+	return rules, nil
+}
+
+// action returns the v3 action of the rule.
+func (r *rule) action() apiv3.Action {
+	switch r.Action {
+	case "allow":
+		return apiv3.Allow
+	case "deny":
+		return apiv3.Deny
+	case "log":
+		return apiv3.Log
+	case "next-tier", "pass":
+		return apiv3.Pass
+	}
+	return apiv3.Action(r.Action)
+}
+
+// peer is one end of a flow.  The endpoint is nil if the peer is not a Calico endpoint, and the
+// IP is nil if the peer is an endpoint without any addresses.
+type peer struct {
+	endpoint *endpoint
+	ip       *cnet.IP
+}
+
+// flow is a Flow with its peers resolved.
+type flow struct {
+	src       peer
+	dst       peer
+	ipVersion int
+	protocol  uint8
+	srcPort   uint16
+	dstPort   uint16
+	icmpType  *int
+	icmpCode  *int
+}
+
+// matches returns true if the rule matches the flow.  HTTP match criteria are enforced by the
+// application layer rather than by Felix, and are ignored here.
+func (e *Evaluator) matches(r *rule, f *flow) bool {
+	if r.IPVersion != nil && *r.IPVersion != f.ipVersion {
+		return false
+	}
+	if r.Protocol != nil && !protocolMatches(*r.Protocol, f.protocol) {
+		return false
+	}
+	if r.NotProtocol != nil && protocolMatches(*r.NotProtocol, f.protocol) {
+		return false
+	}
+	if r.ICMPType != nil && (f.icmpType == nil || *f.icmpType != *r.ICMPType) {
+		return false
+	}
+	if r.ICMPCode != nil && (f.icmpCode == nil || *f.icmpCode != *r.ICMPCode) {
+		return false
+	}
+	if r.NotICMPType != nil && f.icmpType != nil && *f.icmpType == *r.NotICMPType {
+		return false
+	}
+	if r.NotICMPCode != nil && f.icmpCode != nil && *f.icmpCode == *r.NotICMPCode {
+		return false
+	}
+
+	if nets := r.AllSrcNets(); len(nets) > 0 && !inNets(f.src.ip, nets) {
+		return false
+	}
+	if inNets(f.src.ip, r.AllNotSrcNets()) {
+		return false
+	}
+	if nets := r.AllDstNets(); len(nets) > 0 && !inNets(f.dst.ip, nets) {
+		return false
+	}
+	if inNets(f.dst.ip, r.AllNotDstNets()) {
+		return false
+	}
+
+	if r.srcSelector != nil && !e.selects(r.srcSelector, f.src) {
+		return false
+	}
+	if r.notSrcSelector != nil && e.selects(r.notSrcSelector, f.src) {
+		return false
+	}
+	if r.dstSelector != nil && !e.selects(r.dstSelector, f.dst) {
+		return false
+	}
+	if r.notDstSelector != nil && e.selects(r.notDstSelector, f.dst) {
+		return false
+	}
+
+	if len(r.SrcPorts) > 0 && !inPorts(f.srcPort, f.protocol, f.src.endpoint, r.SrcPorts) {
+		return false
+	}
+	if inPorts(f.srcPort, f.protocol, f.src.endpoint, r.NotSrcPorts) {
+		return false
+	}
+	if len(r.DstPorts) > 0 && !inPorts(f.dstPort, f.protocol, f.dst.endpoint, r.DstPorts) {
+		return false
+	}
+	if inPorts(f.dstPort, f.protocol, f.dst.endpoint, r.NotDstPorts) {
+		return false
+	}
+	return true
+}
+
+// selects returns true if the selector matches the peer.  As in Felix, a selector matches the
+// IP addresses of the endpoints and network sets that it selects, so an IP address matches if
+// it belongs to a selected network set as well as if it belongs to a selected endpoint.
+func (e *Evaluator) selects(sel selector.Selector, p peer) bool {
+	if p.endpoint != nil && sel.EvaluateLabels(p.endpoint) {
+		return true
+	}
+	if p.ip == nil {
+		return false
+	}
+	for _, ns := range e.networkSets {
+		if ns.contains(p.ip) && sel.EvaluateLabels(ns) {
+			return true
+		}
+	}
+	return false
+}
+
+func protocolMatches(p numorstring.Protocol, num uint8) bool {
+	n, ok := protocolNumber(p)
+	return ok && n == num
+}
+
+func inNets(ip *cnet.IP, nets []*cnet.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// inPorts returns true if the port is in any of the port ranges, or is the number of one of
+// the endpoint's named ports with a matching name and protocol.
+func inPorts(port uint16, protocol uint8, ep *endpoint, ports []numorstring.Port) bool {
+	for _, p := range ports {
+		if p.PortName == "" {
+			if port >= p.MinPort && port <= p.MaxPort {
+				return true
+			}
+			continue
+		}
+		if ep == nil {
+			continue
+		}
+		for _, np := range ep.ports {
+			if np.Name == p.PortName && np.Port == port && protocolMatches(np.Protocol, protocol) {
+				return true
+			}
+		}
+	}
+	return false
+}
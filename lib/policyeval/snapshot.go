@@ -0,0 +1,488 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policyeval
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	kapiv1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+
+	apiv3 "github.com/projectcalico/libcalico-go/lib/apis/v3"
+	"github.com/projectcalico/libcalico-go/lib/backend/k8s/conversion"
+	"github.com/projectcalico/libcalico-go/lib/backend/model"
+	"github.com/projectcalico/libcalico-go/lib/backend/syncersv1/updateprocessors"
+	"github.com/projectcalico/libcalico-go/lib/backend/watchersyncer"
+	cnet "github.com/projectcalico/libcalico-go/lib/net"
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+// Snapshot is a point-in-time copy of the resources that determine the policy applied to a
+// flow.  The Calico resources are in the form returned by the clientv3 List methods, and the
+// Kubernetes resources in the form returned by the Kubernetes API.
+//
+// Namespaces and ServiceAccounts are only required to evaluate rules that use a
+// NamespaceSelector or ServiceAccounts match; they are converted to profiles in the same way as
+// the Kubernetes datastore driver does.
+type Snapshot struct {
+	Tiers                     []apiv3.Tier
+	GlobalNetworkPolicies     []apiv3.GlobalNetworkPolicy
+	NetworkPolicies           []apiv3.NetworkPolicy
+	KubernetesNetworkPolicies []networkingv1.NetworkPolicy
+	Profiles                  []apiv3.Profile
+	Namespaces                []kapiv1.Namespace
+	ServiceAccounts           []kapiv1.ServiceAccount
+	GlobalNetworkSets         []apiv3.GlobalNetworkSet
+	NetworkSets               []apiv3.NetworkSet
+	WorkloadEndpoints         []apiv3.WorkloadEndpoint
+	HostEndpoints             []apiv3.HostEndpoint
+}
+
+// tier is a tier of policies, with the policies sorted into the order in which they are applied.
+type tier struct {
+	name     string
+	order    *float64
+	policies []*policy
+}
+
+// policy is a policy converted to the v1 data model, which is the form in which Felix applies it.
+type policy struct {
+	kind      string
+	namespace string
+	name      string
+	tier      string
+	order     *float64
+	untracked bool
+	selector  selector.Selector
+	ingress   bool
+	egress    bool
+	inbound   []*rule
+	outbound  []*rule
+}
+
+// profile is a profile converted to the v1 data model.
+type profile struct {
+	name     string
+	labels   map[string]string
+	inbound  []*rule
+	outbound []*rule
+}
+
+// endpoint is a workload or host endpoint, or a network set.  Network sets are not subject to
+// policy, but their labels are used to match the IP addresses they contain against selectors.
+type endpoint struct {
+	kind       string
+	namespace  string
+	name       string
+	labels     map[string]string
+	profileIDs []string
+	nets       []cnet.IPNet
+	ports      []model.EndpointPort
+
+	// parents holds the labels of the endpoint's profiles, in the order in which they are
+	// inherited.
+	parents []map[string]string
+}
+
+// Get implements the parser.Labels interface.  An endpoint's own labels take precedence over
+// those inherited from its profiles, and the labels of earlier profiles take precedence over
+// those of later profiles.
+func (e *endpoint) Get(name string) (string, bool) {
+	if v, ok := e.labels[name]; ok {
+		return v, true
+	}
+	for _, labels := range e.parents {
+		if v, ok := labels[name]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// contains returns true if the IP address is one of the endpoint's addresses.
+func (e *endpoint) contains(ip *cnet.IP) bool {
+	for _, n := range e.nets {
+		if n.Contains(ip.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a description of the endpoint for use in errors and logs.
+func (e *endpoint) String() string {
+	if e.namespace == "" {
+		return fmt.Sprintf("%s(%s)", e.kind, e.name)
+	}
+	return fmt.Sprintf("%s(%s/%s)", e.kind, e.namespace, e.name)
+}
+
+// process converts a v3 resource to the v1 data model using the supplied update processor,
+// returning the converted KVPairs that have a value.  The update processors filter out resources
+// that Felix would not act upon, such as workload endpoints without any IP networks, and those
+// resources are filtered out here too.
+func process(up watchersyncer.SyncerUpdateProcessor, kind, namespace, name string, res interface{}) ([]*model.KVPair, error) {
+	kvps, err := up.Process(&model.KVPair{
+		Key:   model.ResourceKey{Kind: kind, Namespace: namespace, Name: name},
+		Value: res,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var converted []*model.KVPair
+	for _, kvp := range kvps {
+		if kvp.Value != nil {
+			converted = append(converted, kvp)
+		}
+	}
+	if len(converted) == 0 {
+		log.WithFields(log.Fields{
+			"kind":      kind,
+			"namespace": namespace,
+			"name":      name,
+		}).Debug("Resource filtered out by update processor")
+	}
+	return converted, nil
+}
+
+// loadTiers creates the tiers from the snapshot.  The default tier is created if the snapshot
+// does not contain it.
+func (e *Evaluator) loadTiers(s *Snapshot) {
+	e.tiers = map[string]*tier{}
+	for _, t := range s.Tiers {
+		e.tiers[t.Name] = &tier{name: t.Name, order: t.Spec.Order}
+	}
+	if _, ok := e.tiers[apiv3.DefaultTierName]; !ok {
+		e.tiers[apiv3.DefaultTierName] = &tier{name: apiv3.DefaultTierName}
+	}
+}
+
+// loadPolicies converts the policies in the snapshot and adds them to their tiers.  Kubernetes
+// network policies are converted to Calico network policies first, as they are by the
+// Kubernetes datastore driver.
+func (e *Evaluator) loadPolicies(s *Snapshot) error {
+	for i := range s.GlobalNetworkPolicies {
+		p := &s.GlobalNetworkPolicies[i]
+		if err := e.addPolicy(
+			updateprocessors.NewGlobalNetworkPolicyUpdateProcessor(), apiv3.KindGlobalNetworkPolicy, "", p.Name, p,
+		); err != nil {
+			return err
+		}
+	}
+	for i := range s.NetworkPolicies {
+		p := &s.NetworkPolicies[i]
+		if err := e.addPolicy(
+			updateprocessors.NewNetworkPolicyUpdateProcessor(), apiv3.KindNetworkPolicy, p.Namespace, p.Name, p,
+		); err != nil {
+			return err
+		}
+	}
+	c := conversion.NewConverter()
+	for i := range s.KubernetesNetworkPolicies {
+		kvp, err := c.K8sNetworkPolicyToCalico(&s.KubernetesNetworkPolicies[i])
+		if err != nil {
+			return err
+		}
+		p := kvp.Value.(*apiv3.NetworkPolicy)
+		if err := e.addPolicy(
+			updateprocessors.NewNetworkPolicyUpdateProcessor(), apiv3.KindNetworkPolicy, p.Namespace, p.Name, p,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range e.tiers {
+		sort.Slice(t.policies, func(i, j int) bool {
+			return lessByOrderAndName(t.policies[i].order, t.policies[i].name, t.policies[j].order, t.policies[j].name)
+		})
+	}
+	return nil
+}
+
+func (e *Evaluator) addPolicy(up watchersyncer.SyncerUpdateProcessor, kind, namespace, name string, res interface{}) error {
+	p, err := newPolicy(up, kind, namespace, name, res)
+	if err != nil || p == nil {
+		return err
+	}
+	t, ok := e.tiers[p.tier]
+	if !ok {
+		// Felix does not apply the policies of a tier that does not exist.
+		log.WithFields(log.Fields{"kind": kind, "name": name, "tier": p.tier}).Debug("Ignoring policy in unknown tier")
+		return nil
+	}
+	if p.untracked {
+		// Untracked and pre-DNAT policies are applied to host endpoints before the normal
+		// policy, and only on the host; the normal policy decides the flow for both
+		// endpoints.
+		log.WithFields(log.Fields{"kind": kind, "name": name}).Debug("Ignoring untracked or pre-DNAT policy")
+		return nil
+	}
+	t.policies = append(t.policies, p)
+	return nil
+}
+
+// newPolicy converts a policy to the v1 data model and parses its selectors.  Returns nil if
+// the policy is filtered out by the update processor.
+func newPolicy(up watchersyncer.SyncerUpdateProcessor, kind, namespace, name string, res interface{}) (*policy, error) {
+	kvps, err := process(up, kind, namespace, name, res)
+	if err != nil {
+		return nil, err
+	}
+	for _, kvp := range kvps {
+		v1, ok := kvp.Value.(*model.Policy)
+		if !ok {
+			continue
+		}
+		p := &policy{
+			kind:      kind,
+			namespace: namespace,
+			name:      name,
+			tier:      v1.Tier,
+			order:     v1.Order,
+			untracked: v1.DoNotTrack || v1.PreDNAT,
+		}
+		if p.tier == "" {
+			p.tier = apiv3.DefaultTierName
+		}
+		if p.selector, err = selector.Parse(v1.Selector); err != nil {
+			return nil, fmt.Errorf("invalid selector in %s %s: %v", kind, name, err)
+		}
+		for _, t := range v1.Types {
+			switch t {
+			case string(apiv3.PolicyTypeIngress), "ingress":
+				p.ingress = true
+			case string(apiv3.PolicyTypeEgress), "egress":
+				p.egress = true
+			}
+		}
+		if p.inbound, err = newRules(v1.InboundRules); err != nil {
+			return nil, fmt.Errorf("invalid ingress rule in %s %s: %v", kind, name, err)
+		}
+		if p.outbound, err = newRules(v1.OutboundRules); err != nil {
+			return nil, fmt.Errorf("invalid egress rule in %s %s: %v", kind, name, err)
+		}
+		return p, nil
+	}
+	return nil, nil
+}
+
+// sortedTiers returns the tiers in the order in which they are applied.
+func (e *Evaluator) sortedTiers() []*tier {
+	tiers := make([]*tier, 0, len(e.tiers))
+	for _, t := range e.tiers {
+		tiers = append(tiers, t)
+	}
+	sort.Slice(tiers, func(i, j int) bool {
+		return lessByOrderAndName(tiers[i].order, tiers[i].name, tiers[j].order, tiers[j].name)
+	})
+	return tiers
+}
+
+// lessByOrderAndName orders tiers and policies: lower orders first, a nil order is treated as
+// infinite, and ties are broken by name.
+func lessByOrderAndName(o1 *float64, n1 string, o2 *float64, n2 string) bool {
+	switch {
+	case o1 != nil && o2 != nil && *o1 != *o2:
+		return *o1 < *o2
+	case o1 != nil && o2 == nil:
+		return true
+	case o1 == nil && o2 != nil:
+		return false
+	}
+	return n1 < n2
+}
+
+// loadProfiles converts the profiles in the snapshot, including those generated from Kubernetes
+// namespaces and service accounts.
+func (e *Evaluator) loadProfiles(s *Snapshot) error {
+	e.profiles = map[string]*profile{}
+	var v3profiles []*apiv3.Profile
+	for i := range s.Profiles {
+		v3profiles = append(v3profiles, &s.Profiles[i])
+	}
+	c := conversion.NewConverter()
+	for i := range s.Namespaces {
+		kvp, err := c.NamespaceToProfile(&s.Namespaces[i])
+		if err != nil {
+			return err
+		}
+		v3profiles = append(v3profiles, kvp.Value.(*apiv3.Profile))
+	}
+	for i := range s.ServiceAccounts {
+		kvp, err := c.ServiceAccountToProfile(&s.ServiceAccounts[i])
+		if err != nil {
+			return err
+		}
+		v3profiles = append(v3profiles, kvp.Value.(*apiv3.Profile))
+	}
+
+	for _, v3 := range v3profiles {
+		kvps, err := process(updateprocessors.NewProfileUpdateProcessor(), apiv3.KindProfile, "", v3.Name, v3)
+		if err != nil {
+			return err
+		}
+		p := &profile{name: v3.Name}
+		for _, kvp := range kvps {
+			switch v := kvp.Value.(type) {
+			case map[string]string:
+				p.labels = v
+			case *model.ProfileRules:
+				if p.inbound, err = newRules(v.InboundRules); err != nil {
+					return fmt.Errorf("invalid ingress rule in Profile %s: %v", v3.Name, err)
+				}
+				if p.outbound, err = newRules(v.OutboundRules); err != nil {
+					return fmt.Errorf("invalid egress rule in Profile %s: %v", v3.Name, err)
+				}
+			}
+		}
+		e.profiles[p.name] = p
+	}
+	return nil
+}
+
+// loadEndpoints converts the workload and host endpoints and the network sets in the snapshot.
+func (e *Evaluator) loadEndpoints(s *Snapshot) error {
+	for i := range s.WorkloadEndpoints {
+		wep := &s.WorkloadEndpoints[i]
+		kvps, err := process(updateprocessors.NewWorkloadEndpointUpdateProcessor(), apiv3.KindWorkloadEndpoint, wep.Namespace, wep.Name, wep)
+		if err != nil {
+			return fmt.Errorf("invalid WorkloadEndpoint %s/%s: %v", wep.Namespace, wep.Name, err)
+		}
+		for _, kvp := range kvps {
+			v1 := kvp.Value.(*model.WorkloadEndpoint)
+			ep := &endpoint{
+				kind:       apiv3.KindWorkloadEndpoint,
+				namespace:  wep.Namespace,
+				name:       wep.Name,
+				labels:     workloadLabels(wep, v1.Labels),
+				profileIDs: v1.ProfileIDs,
+				ports:      v1.Ports,
+			}
+			ep.nets = append(ep.nets, v1.IPv4Nets...)
+			ep.nets = append(ep.nets, v1.IPv6Nets...)
+			ep.profileIDs = e.withKubernetesProfiles(ep)
+			e.endpoints = append(e.endpoints, ep)
+		}
+	}
+
+	for i := range s.HostEndpoints {
+		hep := &s.HostEndpoints[i]
+		kvps, err := process(updateprocessors.NewHostEndpointUpdateProcessor(), apiv3.KindHostEndpoint, "", hep.Name, hep)
+		if err != nil {
+			return fmt.Errorf("invalid HostEndpoint %s: %v", hep.Name, err)
+		}
+		for _, kvp := range kvps {
+			v1 := kvp.Value.(*model.HostEndpoint)
+			ep := &endpoint{
+				kind:       apiv3.KindHostEndpoint,
+				name:       hep.Name,
+				labels:     v1.Labels,
+				profileIDs: v1.ProfileIDs,
+				ports:      v1.Ports,
+			}
+			for _, ip := range append(v1.ExpectedIPv4Addrs, v1.ExpectedIPv6Addrs...) {
+				ep.nets = append(ep.nets, *ip.Network())
+			}
+			e.endpoints = append(e.endpoints, ep)
+		}
+	}
+
+	for i := range s.GlobalNetworkSets {
+		gns := &s.GlobalNetworkSets[i]
+		kvps, err := process(updateprocessors.NewGlobalNetworkSetUpdateProcessor(), apiv3.KindGlobalNetworkSet, "", gns.Name, gns)
+		if err != nil {
+			return fmt.Errorf("invalid GlobalNetworkSet %s: %v", gns.Name, err)
+		}
+		e.addNetworkSet(apiv3.KindGlobalNetworkSet, "", gns.Name, kvps)
+	}
+
+	for i := range s.NetworkSets {
+		ns := &s.NetworkSets[i]
+		kvps, err := process(updateprocessors.NewNetworkSetUpdateProcessor(), apiv3.KindNetworkSet, ns.Namespace, ns.Name, ns)
+		if err != nil {
+			return fmt.Errorf("invalid NetworkSet %s/%s: %v", ns.Namespace, ns.Name, err)
+		}
+		e.addNetworkSet(apiv3.KindNetworkSet, ns.Namespace, ns.Name, kvps)
+	}
+
+	// Now that all of the profiles are known, fill in the labels that each endpoint inherits.
+	for _, ep := range append(e.endpoints, e.networkSets...) {
+		for _, id := range ep.profileIDs {
+			if p, ok := e.profiles[id]; ok && p.labels != nil {
+				ep.parents = append(ep.parents, p.labels)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) addNetworkSet(kind, namespace, name string, kvps []*model.KVPair) {
+	for _, kvp := range kvps {
+		v1 := kvp.Value.(*model.NetworkSet)
+		ep := &endpoint{
+			kind:       kind,
+			namespace:  namespace,
+			name:       name,
+			labels:     v1.Labels,
+			profileIDs: v1.ProfileIDs,
+			nets:       v1.Nets,
+		}
+		e.networkSets = append(e.networkSets, ep)
+	}
+}
+
+// workloadLabels returns the labels of a workload endpoint, including the namespace and
+// orchestrator labels that clientv3 adds when the endpoint is written.
+func workloadLabels(wep *apiv3.WorkloadEndpoint, labels map[string]string) map[string]string {
+	l := map[string]string{}
+	for k, v := range labels {
+		l[k] = v
+	}
+	if _, ok := l[apiv3.LabelNamespace]; !ok {
+		l[apiv3.LabelNamespace] = wep.Namespace
+	}
+	if _, ok := l[apiv3.LabelOrchestrator]; !ok && wep.Spec.Orchestrator != "" {
+		l[apiv3.LabelOrchestrator] = wep.Spec.Orchestrator
+	}
+	return l
+}
+
+// withKubernetesProfiles returns the profile IDs of a workload endpoint, adding the profiles of
+// its namespace and service account if they are in the snapshot but not already listed.  This
+// is how the Kubernetes datastore driver presents pods, and it is what allows NamespaceSelector
+// and ServiceAccounts matches to select the endpoint.
+func (e *Evaluator) withKubernetesProfiles(ep *endpoint) []string {
+	ids := ep.profileIDs
+	has := func(id string) bool {
+		for _, existing := range ids {
+			if existing == id {
+				return true
+			}
+		}
+		return false
+	}
+	candidates := []string{conversion.NamespaceProfileNamePrefix + ep.namespace}
+	if sa, ok := ep.labels[apiv3.LabelServiceAccount]; ok {
+		candidates = append(candidates, conversion.ServiceAccountProfileNamePrefix+ep.namespace+"."+sa)
+	}
+	for _, id := range candidates {
+		if _, ok := e.profiles[id]; ok && !has(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
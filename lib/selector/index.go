@@ -0,0 +1,397 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selector
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+	"github.com/projectcalico/libcalico-go/lib/set"
+)
+
+// MatchCallback is called by an Index when a selector starts or stops matching a set of labels.
+type MatchCallback func(selID, labelsID interface{})
+
+// Index matches many selectors against many sets of labels.  Rather than evaluating every selector
+// against every set of labels, it keeps inverted indexes from the label values and labels that
+// each selector requires (its "==", "in" and "has()" terms) to the selectors, and from label values
+// and labels to the sets of labels that have them, and only evaluates the candidates that the
+// indexes return.  Selectors that require no particular label, such as "all()" or "a != 'b'", are
+// evaluated against every set of labels.
+//
+// The Index tracks which selectors match which of the sets of labels added with UpdateLabels and
+// calls the onMatchStarted and onMatchStopped callbacks as that changes.  Match can be used to
+// query a set of labels that the Index is not tracking.
+//
+// An Index is not safe for concurrent use.
+type Index struct {
+	selectors map[interface{}]*indexedSelector
+	labels    map[interface{}]map[string]string
+
+	// Selector IDs by the label value or label that the selector requires, and the IDs of the
+	// selectors that have no index keys.
+	selIDsByLabelValue map[string]map[string]set.Set
+	selIDsByLabel      map[string]set.Set
+	unindexedSelIDs    set.Set
+
+	// Labels IDs by the label values and labels in the set of labels.
+	labelsIDsByLabelValue map[string]map[string]set.Set
+	labelsIDsByLabel      map[string]set.Set
+
+	// The current matches in both directions.
+	labelsIDsBySelID map[interface{}]set.Set
+	selIDsByLabelsID map[interface{}]set.Set
+
+	onMatchStarted MatchCallback
+	onMatchStopped MatchCallback
+}
+
+type indexedSelector struct {
+	selector Selector
+	keys     []parser.IndexKey
+	indexed  bool
+}
+
+// NewIndex creates an empty Index.  Either callback may be nil.
+func NewIndex(onMatchStarted, onMatchStopped MatchCallback) *Index {
+	return &Index{
+		selectors:             map[interface{}]*indexedSelector{},
+		labels:                map[interface{}]map[string]string{},
+		selIDsByLabelValue:    map[string]map[string]set.Set{},
+		selIDsByLabel:         map[string]set.Set{},
+		unindexedSelIDs:       set.New(),
+		labelsIDsByLabelValue: map[string]map[string]set.Set{},
+		labelsIDsByLabel:      map[string]set.Set{},
+		labelsIDsBySelID:      map[interface{}]set.Set{},
+		selIDsByLabelsID:      map[interface{}]set.Set{},
+		onMatchStarted:        onMatchStarted,
+		onMatchStopped:        onMatchStopped,
+	}
+}
+
+// UpdateSelector adds a selector to the index, or replaces the selector with the same ID, and
+// updates its matches against the tracked sets of labels.
+func (idx *Index) UpdateSelector(id interface{}, sel Selector) {
+	if sel == nil {
+		log.WithField("selID", id).Panic("Selector should not be nil")
+	}
+	if old, ok := idx.selectors[id]; ok {
+		if old.selector.UniqueID() == sel.UniqueID() {
+			log.WithField("selID", id).Debug("Selector unchanged")
+			return
+		}
+		idx.unindexSelector(id, old)
+	}
+
+	is := &indexedSelector{selector: sel}
+	if s, ok := sel.(parser.Selector); ok {
+		is.keys, is.indexed = parser.IndexKeys(s)
+	}
+	idx.selectors[id] = is
+	idx.indexSelector(id, is)
+
+	// Work out the new set of matches from the candidate sets of labels.
+	newMatches := set.New()
+	idx.visitCandidateLabels(is, func(labelsID interface{}) {
+		if sel.Evaluate(idx.labels[labelsID]) {
+			newMatches.Add(labelsID)
+		}
+	})
+	oldMatches := idx.labelsIDsBySelID[id]
+	if oldMatches != nil {
+		oldMatches.Iter(func(labelsID interface{}) error {
+			if !newMatches.Contains(labelsID) {
+				idx.removeMatch(id, labelsID)
+			}
+			return nil
+		})
+	}
+	newMatches.Iter(func(labelsID interface{}) error {
+		if oldMatches == nil || !oldMatches.Contains(labelsID) {
+			idx.addMatch(id, labelsID)
+		}
+		return nil
+	})
+}
+
+// DeleteSelector removes a selector from the index.  The onMatchStopped callback is called for
+// each set of labels that it matched.
+func (idx *Index) DeleteSelector(id interface{}) {
+	is, ok := idx.selectors[id]
+	if !ok {
+		return
+	}
+	if matches := idx.labelsIDsBySelID[id]; matches != nil {
+		matches.Copy().Iter(func(labelsID interface{}) error {
+			idx.removeMatch(id, labelsID)
+			return nil
+		})
+	}
+	idx.unindexSelector(id, is)
+	delete(idx.selectors, id)
+}
+
+// UpdateLabels adds a set of labels to the index, or replaces the set of labels with the same ID,
+// and updates its matches against the selectors in the index.
+func (idx *Index) UpdateLabels(id interface{}, labels map[string]string) {
+	if old, ok := idx.labels[id]; ok {
+		idx.unindexLabels(id, old)
+	}
+	idx.labels[id] = labels
+	idx.indexLabels(id, labels)
+
+	newMatches := set.New()
+	idx.visitCandidateSelectors(labels, func(selID interface{}) {
+		if idx.selectors[selID].selector.Evaluate(labels) {
+			newMatches.Add(selID)
+		}
+	})
+	oldMatches := idx.selIDsByLabelsID[id]
+	if oldMatches != nil {
+		oldMatches.Iter(func(selID interface{}) error {
+			if !newMatches.Contains(selID) {
+				idx.removeMatch(selID, id)
+			}
+			return nil
+		})
+	}
+	newMatches.Iter(func(selID interface{}) error {
+		if oldMatches == nil || !oldMatches.Contains(selID) {
+			idx.addMatch(selID, id)
+		}
+		return nil
+	})
+}
+
+// DeleteLabels removes a set of labels from the index.  The onMatchStopped callback is called
+// for each selector that matched it.
+func (idx *Index) DeleteLabels(id interface{}) {
+	labels, ok := idx.labels[id]
+	if !ok {
+		return
+	}
+	if matches := idx.selIDsByLabelsID[id]; matches != nil {
+		matches.Copy().Iter(func(selID interface{}) error {
+			idx.removeMatch(selID, id)
+			return nil
+		})
+	}
+	idx.unindexLabels(id, labels)
+	delete(idx.labels, id)
+}
+
+// Match returns the IDs of the selectors in the index that match the given labels, in no
+// particular order.  The labels do not need to have been added to the index.
+func (idx *Index) Match(labels map[string]string) []interface{} {
+	var ids []interface{}
+	idx.visitCandidateSelectors(labels, func(selID interface{}) {
+		if idx.selectors[selID].selector.Evaluate(labels) {
+			ids = append(ids, selID)
+		}
+	})
+	return ids
+}
+
+// SelectorsMatching returns the IDs of the selectors that match the set of labels with the given
+// ID, in no particular order.
+func (idx *Index) SelectorsMatching(labelsID interface{}) []interface{} {
+	return setMembers(idx.selIDsByLabelsID[labelsID])
+}
+
+// LabelsMatching returns the IDs of the sets of labels that the selector with the given ID
+// matches, in no particular order.
+func (idx *Index) LabelsMatching(selID interface{}) []interface{} {
+	return setMembers(idx.labelsIDsBySelID[selID])
+}
+
+func (idx *Index) indexSelector(id interface{}, is *indexedSelector) {
+	if !is.indexed {
+		idx.unindexedSelIDs.Add(id)
+		return
+	}
+	for _, k := range is.keys {
+		if k.AnyValue {
+			addToIndex(idx.selIDsByLabel, k.LabelName, id)
+		} else {
+			addToValueIndex(idx.selIDsByLabelValue, k.LabelName, k.Value, id)
+		}
+	}
+}
+
+func (idx *Index) unindexSelector(id interface{}, is *indexedSelector) {
+	if !is.indexed {
+		idx.unindexedSelIDs.Discard(id)
+		return
+	}
+	for _, k := range is.keys {
+		if k.AnyValue {
+			discardFromIndex(idx.selIDsByLabel, k.LabelName, id)
+		} else {
+			discardFromValueIndex(idx.selIDsByLabelValue, k.LabelName, k.Value, id)
+		}
+	}
+}
+
+func (idx *Index) indexLabels(id interface{}, labels map[string]string) {
+	for k, v := range labels {
+		addToIndex(idx.labelsIDsByLabel, k, id)
+		addToValueIndex(idx.labelsIDsByLabelValue, k, v, id)
+	}
+}
+
+func (idx *Index) unindexLabels(id interface{}, labels map[string]string) {
+	for k, v := range labels {
+		discardFromIndex(idx.labelsIDsByLabel, k, id)
+		discardFromValueIndex(idx.labelsIDsByLabelValue, k, v, id)
+	}
+}
+
+// visitCandidateSelectors calls visit once for each selector that may match the labels.
+func (idx *Index) visitCandidateSelectors(labels map[string]string, visit func(selID interface{})) {
+	seen := set.New()
+	visitOnce := func(selID interface{}) error {
+		if !seen.Contains(selID) {
+			seen.Add(selID)
+			visit(selID)
+		}
+		return nil
+	}
+	idx.unindexedSelIDs.Iter(visitOnce)
+	for k, v := range labels {
+		if ids := idx.selIDsByLabel[k]; ids != nil {
+			ids.Iter(visitOnce)
+		}
+		if ids := idx.selIDsByLabelValue[k][v]; ids != nil {
+			ids.Iter(visitOnce)
+		}
+	}
+}
+
+// visitCandidateLabels calls visit once for each set of labels that the selector may match.
+func (idx *Index) visitCandidateLabels(is *indexedSelector, visit func(labelsID interface{})) {
+	if !is.indexed {
+		for labelsID := range idx.labels {
+			visit(labelsID)
+		}
+		return
+	}
+	seen := set.New()
+	visitOnce := func(labelsID interface{}) error {
+		if !seen.Contains(labelsID) {
+			seen.Add(labelsID)
+			visit(labelsID)
+		}
+		return nil
+	}
+	for _, k := range is.keys {
+		var ids set.Set
+		if k.AnyValue {
+			ids = idx.labelsIDsByLabel[k.LabelName]
+		} else {
+			ids = idx.labelsIDsByLabelValue[k.LabelName][k.Value]
+		}
+		if ids != nil {
+			ids.Iter(visitOnce)
+		}
+	}
+}
+
+func (idx *Index) addMatch(selID, labelsID interface{}) {
+	addMatchToIndex(idx.labelsIDsBySelID, selID, labelsID)
+	addMatchToIndex(idx.selIDsByLabelsID, labelsID, selID)
+	if idx.onMatchStarted != nil {
+		idx.onMatchStarted(selID, labelsID)
+	}
+}
+
+func (idx *Index) removeMatch(selID, labelsID interface{}) {
+	discardMatchFromIndex(idx.labelsIDsBySelID, selID, labelsID)
+	discardMatchFromIndex(idx.selIDsByLabelsID, labelsID, selID)
+	if idx.onMatchStopped != nil {
+		idx.onMatchStopped(selID, labelsID)
+	}
+}
+
+func addToIndex(index map[string]set.Set, key string, id interface{}) {
+	ids := index[key]
+	if ids == nil {
+		ids = set.New()
+		index[key] = ids
+	}
+	ids.Add(id)
+}
+
+func discardFromIndex(index map[string]set.Set, key string, id interface{}) {
+	ids := index[key]
+	if ids == nil {
+		return
+	}
+	ids.Discard(id)
+	if ids.Len() == 0 {
+		delete(index, key)
+	}
+}
+
+func addToValueIndex(index map[string]map[string]set.Set, key, value string, id interface{}) {
+	values := index[key]
+	if values == nil {
+		values = map[string]set.Set{}
+		index[key] = values
+	}
+	addToIndex(values, value, id)
+}
+
+func discardFromValueIndex(index map[string]map[string]set.Set, key, value string, id interface{}) {
+	values := index[key]
+	if values == nil {
+		return
+	}
+	discardFromIndex(values, value, id)
+	if len(values) == 0 {
+		delete(index, key)
+	}
+}
+
+func addMatchToIndex(index map[interface{}]set.Set, key, id interface{}) {
+	ids := index[key]
+	if ids == nil {
+		ids = set.New()
+		index[key] = ids
+	}
+	ids.Add(id)
+}
+
+func discardMatchFromIndex(index map[interface{}]set.Set, key, id interface{}) {
+	ids := index[key]
+	if ids == nil {
+		return
+	}
+	ids.Discard(id)
+	if ids.Len() == 0 {
+		delete(index, key)
+	}
+}
+
+func setMembers(s set.Set) []interface{} {
+	if s == nil {
+		return nil
+	}
+	members := make([]interface{}, 0, s.Len())
+	s.Iter(func(item interface{}) error {
+		members = append(members, item)
+		return nil
+	})
+	return members
+}
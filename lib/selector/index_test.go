@@ -0,0 +1,198 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selector_test
+
+import (
+	"fmt"
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/selector"
+)
+
+var indexTestSelectors = []string{
+	`all()`,
+	`!all()`,
+	`a == 'x'`,
+	`a == 'y'`,
+	`a != 'x'`,
+	`a in {'x', 'z'}`,
+	`a not in {'x', 'y'}`,
+	`has(a)`,
+	`!has(a)`,
+	`has(b) && a == 'x'`,
+	`b == 'x' || b == 'y'`,
+	`a == 'x' || c == 'z'`,
+	`a == 'x' || !has(c)`,
+	`b starts with 'y' && has(c)`,
+	`c ends with 'x'`,
+	`!(a != 'z')`,
+	`(a == 'x' && b == 'y') || (a == 'y' && c in {'x', 'y', 'z'})`,
+	`a in {}`,
+}
+
+var indexTestLabels = []map[string]string{
+	{},
+	{"a": "x"},
+	{"a": "y"},
+	{"a": "z"},
+	{"b": "x"},
+	{"a": "x", "b": "y"},
+	{"a": "y", "c": "z"},
+	{"a": "x", "b": "y", "c": "x"},
+	{"b": "yy", "c": ""},
+	{"d": "x"},
+}
+
+var _ = Describe("Selector index", func() {
+	var (
+		idx       *selector.Index
+		selectors map[string]selector.Selector
+		labels    map[string]map[string]string
+		matches   map[string]bool
+	)
+
+	matchKey := func(selID, labelsID interface{}) string {
+		return fmt.Sprintf("%v/%v", selID, labelsID)
+	}
+
+	BeforeEach(func() {
+		selectors = map[string]selector.Selector{}
+		labels = map[string]map[string]string{}
+		matches = map[string]bool{}
+		idx = selector.NewIndex(
+			func(selID, labelsID interface{}) {
+				key := matchKey(selID, labelsID)
+				Expect(matches).NotTo(HaveKey(key), "Match started twice")
+				matches[key] = true
+			},
+			func(selID, labelsID interface{}) {
+				key := matchKey(selID, labelsID)
+				Expect(matches).To(HaveKey(key), "Match stopped before it started")
+				delete(matches, key)
+			},
+		)
+	})
+
+	updateSelector := func(id, s string) {
+		sel, err := selector.Parse(s)
+		Expect(err).NotTo(HaveOccurred())
+		selectors[id] = sel
+		idx.UpdateSelector(id, sel)
+	}
+
+	// expectConsistent checks the index's view of the matches against evaluating every selector
+	// against every set of labels.
+	expectConsistent := func() {
+		expected := map[string]bool{}
+		for selID, sel := range selectors {
+			var expLabels []interface{}
+			for labelsID, l := range labels {
+				if sel.Evaluate(l) {
+					expected[matchKey(selID, labelsID)] = true
+					expLabels = append(expLabels, labelsID)
+				}
+			}
+			Expect(idx.LabelsMatching(selID)).To(ConsistOf(expLabels), "Wrong matches for "+sel.String())
+		}
+		for labelsID, l := range labels {
+			var expSels []interface{}
+			for selID, sel := range selectors {
+				if sel.Evaluate(l) {
+					expSels = append(expSels, selID)
+				}
+			}
+			Expect(idx.SelectorsMatching(labelsID)).To(ConsistOf(expSels))
+			Expect(idx.Match(l)).To(ConsistOf(expSels))
+		}
+		Expect(matches).To(Equal(expected))
+	}
+
+	It("should match labels against the selectors", func() {
+		for i, s := range indexTestSelectors {
+			updateSelector(fmt.Sprint("sel-", i), s)
+		}
+		for _, l := range indexTestLabels {
+			var expected []interface{}
+			for id, sel := range selectors {
+				if sel.Evaluate(l) {
+					expected = append(expected, id)
+				}
+			}
+			Expect(idx.Match(l)).To(ConsistOf(expected), fmt.Sprintf("Wrong matches for %v", l))
+		}
+	})
+
+	It("should track matches as selectors and labels are added", func() {
+		updateSelector("sel-a", `a == 'x'`)
+		Expect(matches).To(BeEmpty())
+
+		idx.UpdateLabels("labels-1", map[string]string{"a": "x"})
+		labels["labels-1"] = map[string]string{"a": "x"}
+		Expect(matches).To(Equal(map[string]bool{"sel-a/labels-1": true}))
+
+		idx.UpdateLabels("labels-1", map[string]string{"a": "y"})
+		labels["labels-1"] = map[string]string{"a": "y"}
+		Expect(matches).To(BeEmpty())
+
+		updateSelector("sel-a", `a in {'x', 'y'}`)
+		Expect(matches).To(Equal(map[string]bool{"sel-a/labels-1": true}))
+		expectConsistent()
+
+		idx.DeleteSelector("sel-a")
+		delete(selectors, "sel-a")
+		Expect(matches).To(BeEmpty())
+		Expect(idx.SelectorsMatching("labels-1")).To(BeEmpty())
+	})
+
+	It("should stop matches when labels are deleted", func() {
+		updateSelector("sel-all", `all()`)
+		updateSelector("sel-has", `has(a)`)
+		idx.UpdateLabels("labels-1", map[string]string{"a": "x"})
+		Expect(matches).To(HaveLen(2))
+
+		idx.DeleteLabels("labels-1")
+		Expect(matches).To(BeEmpty())
+		Expect(idx.LabelsMatching("sel-all")).To(BeEmpty())
+		Expect(idx.LabelsMatching("sel-has")).To(BeEmpty())
+	})
+
+	It("should stay consistent through random updates", func() {
+		r := rand.New(rand.NewSource(42))
+		for i := 0; i < 2000; i++ {
+			switch r.Intn(4) {
+			case 0:
+				id := fmt.Sprint("sel-", r.Intn(10))
+				updateSelector(id, indexTestSelectors[r.Intn(len(indexTestSelectors))])
+			case 1:
+				id := fmt.Sprint("sel-", r.Intn(10))
+				idx.DeleteSelector(id)
+				delete(selectors, id)
+			case 2:
+				id := fmt.Sprint("labels-", r.Intn(10))
+				l := indexTestLabels[r.Intn(len(indexTestLabels))]
+				idx.UpdateLabels(id, l)
+				labels[id] = l
+			case 3:
+				id := fmt.Sprint("labels-", r.Intn(10))
+				idx.DeleteLabels(id)
+				delete(labels, id)
+			}
+			expectConsistent()
+		}
+	})
+})
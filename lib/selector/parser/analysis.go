@@ -0,0 +1,342 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"strings"
+)
+
+// maxDNFTerms limits the size of the disjunctive normal form that the analysis functions will
+// build.  Selectors that exceed it are conservatively assumed to be satisfiable.
+const maxDNFTerms = 4096
+
+// Satisfiable returns false if there is no set of labels that the selector matches.  Selectors
+// that are too complex to analyse are assumed to be satisfiable.
+func Satisfiable(sel Selector) bool {
+	root, ok := sel.(*selectorRoot)
+	if !ok {
+		return true
+	}
+	return satisfiable(root.root, nil, false)
+}
+
+// Overlaps returns true if there is some set of labels that both selectors match.  Selectors that
+// are too complex to analyse are assumed to overlap.
+func Overlaps(a, b Selector) bool {
+	rootA, okA := a.(*selectorRoot)
+	rootB, okB := b.(*selectorRoot)
+	if !okA || !okB {
+		return true
+	}
+	return satisfiable(rootA.root, rootB.root, false)
+}
+
+// Subsumes returns true if every set of labels that b matches is also matched by a.  Selectors
+// that are too complex to analyse are assumed not to subsume each other.
+func Subsumes(a, b Selector) bool {
+	rootA, okA := a.(*selectorRoot)
+	rootB, okB := b.(*selectorRoot)
+	if !okA || !okB {
+		return false
+	}
+	// a subsumes b if no labels match b but not a.
+	return !satisfiable(rootB.root, rootA.root, true)
+}
+
+// satisfiable returns true if some set of labels matches a && b, or a && !b if negateB is set.
+// A nil b is ignored.
+func satisfiable(a, b node, negateB bool) bool {
+	terms, ok := toDNF(a, false)
+	if !ok {
+		return true
+	}
+	if b != nil {
+		termsB, ok := toDNF(b, negateB)
+		if !ok {
+			return true
+		}
+		if terms, ok = andDNF(terms, termsB); !ok {
+			return true
+		}
+	}
+	for _, t := range terms {
+		if t.satisfiable() {
+			return true
+		}
+	}
+	return false
+}
+
+type literalKind int
+
+const (
+	literalHas literalKind = iota
+	literalEq
+	literalIn
+	literalContains
+	literalStartsWith
+	literalEndsWith
+)
+
+// literal is a single test on a label.  A literal holds if the label is present and its value
+// satisfies the test, or, if the literal is negated, if the label is absent or its value does not
+// satisfy the test.  For example, "a != 'b'" is a negated literalEq.
+type literal struct {
+	kind    literalKind
+	label   string
+	value   string
+	set     StringSet
+	negated bool
+}
+
+// matches returns true if the literal's test holds for the given value of a present label,
+// ignoring negation.
+func (l literal) matches(v string) bool {
+	switch l.kind {
+	case literalEq:
+		return v == l.value
+	case literalIn:
+		return l.set.Contains(v)
+	case literalContains:
+		return strings.Contains(v, l.value)
+	case literalStartsWith:
+		return strings.HasPrefix(v, l.value)
+	case literalEndsWith:
+		return strings.HasSuffix(v, l.value)
+	}
+	return true
+}
+
+// conjunction is a set of literals that must all hold.
+type conjunction []literal
+
+// toDNF converts the node, or its negation, to disjunctive normal form.  It returns false if the
+// node cannot be analysed or the result would be too large.
+func toDNF(n node, negate bool) ([]conjunction, bool) {
+	lit := func(l literal) ([]conjunction, bool) {
+		l.negated = negate
+		return []conjunction{{l}}, true
+	}
+	switch n := n.(type) {
+	case *AllNode, *GlobalNode:
+		if negate {
+			// Matches nothing.
+			return []conjunction{}, true
+		}
+		return []conjunction{{}}, true
+	case *HasNode:
+		return lit(literal{kind: literalHas, label: n.LabelName})
+	case *LabelEqValueNode:
+		return lit(literal{kind: literalEq, label: n.LabelName, value: n.Value})
+	case *LabelNeValueNode:
+		negate = !negate
+		return lit(literal{kind: literalEq, label: n.LabelName, value: n.Value})
+	case *LabelInSetNode:
+		return lit(literal{kind: literalIn, label: n.LabelName, set: n.Value})
+	case *LabelNotInSetNode:
+		negate = !negate
+		return lit(literal{kind: literalIn, label: n.LabelName, set: n.Value})
+	case *LabelContainsValueNode:
+		return lit(literal{kind: literalContains, label: n.LabelName, value: n.Value})
+	case *LabelStartsWithValueNode:
+		return lit(literal{kind: literalStartsWith, label: n.LabelName, value: n.Value})
+	case *LabelEndsWithValueNode:
+		return lit(literal{kind: literalEndsWith, label: n.LabelName, value: n.Value})
+	case *LabelCompareValueNode, *LabelMatchesRegexNode:
+		// Numeric comparisons and regular expressions aren't analysed.
+		return nil, false
+	case *NotNode:
+		return toDNF(n.Operand, !negate)
+	case *AndNode:
+		// !(a && b) == !a || !b
+		return combineDNF(n.Operands, negate, negate)
+	case *OrNode:
+		// !(a || b) == !a && !b
+		return combineDNF(n.Operands, negate, !negate)
+	}
+	return nil, false
+}
+
+// combineDNF converts each of the operands, or their negations, to DNF and combines them with
+// either && or ||.
+func combineDNF(operands []node, negate, or bool) ([]conjunction, bool) {
+	var result []conjunction
+	for i, op := range operands {
+		terms, ok := toDNF(op, negate)
+		if !ok {
+			return nil, false
+		}
+		if i == 0 {
+			result = terms
+		} else if or {
+			result = append(result, terms...)
+		} else if result, ok = andDNF(result, terms); !ok {
+			return nil, false
+		}
+		if len(result) > maxDNFTerms {
+			return nil, false
+		}
+	}
+	return result, true
+}
+
+// andDNF returns the DNF of the conjunction of the two DNFs.
+func andDNF(a, b []conjunction) ([]conjunction, bool) {
+	if len(a)*len(b) > maxDNFTerms {
+		return nil, false
+	}
+	result := make([]conjunction, 0, len(a)*len(b))
+	for _, ta := range a {
+		for _, tb := range b {
+			t := make(conjunction, 0, len(ta)+len(tb))
+			t = append(t, ta...)
+			t = append(t, tb...)
+			result = append(result, t)
+		}
+	}
+	return result, true
+}
+
+// satisfiable returns true if some set of labels satisfies all of the literals.  Since each
+// literal only tests a single label, that is the case if the literals on each label can be
+// satisfied.
+func (c conjunction) satisfiable() bool {
+	byLabel := map[string][]literal{}
+	for _, l := range c {
+		byLabel[l.label] = append(byLabel[l.label], l)
+	}
+	for _, lits := range byLabel {
+		if !labelSatisfiable(lits) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelSatisfiable returns true if the literals, which all test the same label, hold either when
+// the label is absent or for some value of the label.
+func labelSatisfiable(lits []literal) bool {
+	absentOK := true
+	for _, l := range lits {
+		if !l.negated {
+			absentOK = false
+			break
+		}
+	}
+	return absentOK || valueSatisfiable(lits)
+}
+
+// valueSatisfiable returns true if there is a value of the label for which all of the literals hold.
+func valueSatisfiable(lits []literal) bool {
+	holds := func(v string) bool {
+		for _, l := range lits {
+			if l.matches(v) == l.negated {
+				return false
+			}
+		}
+		return true
+	}
+
+	// If the value is constrained to a finite set of candidates, try each of them.
+	var candidates StringSet
+	constrained := false
+	for _, l := range lits {
+		if l.negated {
+			continue
+		}
+		var values StringSet
+		switch l.kind {
+		case literalEq:
+			values = StringSet{l.value}
+		case literalIn:
+			values = l.set
+		default:
+			continue
+		}
+		if !constrained {
+			candidates, constrained = values, true
+			continue
+		}
+		var both []string
+		for _, v := range candidates {
+			if values.Contains(v) {
+				both = append(both, v)
+			}
+		}
+		candidates = both
+	}
+	if constrained {
+		for _, v := range candidates {
+			if holds(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Otherwise, the value must start with the longest required prefix, end with the longest
+	// required suffix and contain each of the required substrings.  If those are compatible, then
+	// joining them with a character that none of the literals mention gives a value that satisfies
+	// the positive literals, and only fails a negative literal if that literal's string is part of
+	// one of the joined strings.
+	var prefix, suffix string
+	var pieces []string
+	for _, l := range lits {
+		if l.negated {
+			continue
+		}
+		switch l.kind {
+		case literalStartsWith:
+			if strings.HasPrefix(l.value, prefix) {
+				prefix = l.value
+			} else if !strings.HasPrefix(prefix, l.value) {
+				return false
+			}
+		case literalEndsWith:
+			if strings.HasSuffix(l.value, suffix) {
+				suffix = l.value
+			} else if !strings.HasSuffix(suffix, l.value) {
+				return false
+			}
+		case literalContains:
+			pieces = append(pieces, l.value)
+		}
+	}
+	pieces = append(pieces, prefix, suffix)
+	for _, l := range lits {
+		if !l.negated {
+			continue
+		}
+		switch l.kind {
+		case literalHas:
+			return false
+		case literalStartsWith:
+			if strings.HasPrefix(prefix, l.value) {
+				return false
+			}
+		case literalEndsWith:
+			if strings.HasSuffix(suffix, l.value) {
+				return false
+			}
+		case literalContains:
+			for _, p := range pieces {
+				if strings.Contains(p, l.value) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+)
+
+var _ = Describe("Selector analysis", func() {
+	mustParse := func(s string) parser.Selector {
+		sel, err := parser.Parse(s)
+		Expect(err).NotTo(HaveOccurred())
+		return sel
+	}
+
+	DescribeTable("Satisfiable",
+		func(sel string, expected bool) {
+			Expect(parser.Satisfiable(mustParse(sel))).To(Equal(expected))
+		},
+		Entry("all()", "all()", true),
+		Entry("!all()", "!all()", false),
+		Entry("a single equality", `a == "x"`, true),
+		Entry("conflicting equalities", `a == "x" && a == "y"`, false),
+		Entry("equality and inequality", `a == "x" && a != "x"`, false),
+		Entry("equalities on different labels", `a == "x" && b == "y"`, true),
+		Entry("has and not has", `has(a) && !has(a)`, false),
+		Entry("inequality and not has", `a != "x" && !has(a)`, true),
+		Entry("equality and not has", `a == "x" && !has(a)`, false),
+		Entry("disjoint sets", `a in {"x", "y"} && a in {"z"}`, false),
+		Entry("overlapping sets", `a in {"x", "y"} && a in {"y", "z"}`, true),
+		Entry("set and not in set", `a in {"x", "y"} && a not in {"x", "y"}`, false),
+		Entry("equality and matching prefix", `a == "xyz" && a starts with "xy"`, true),
+		Entry("equality and other prefix", `a == "xyz" && a starts with "y"`, false),
+		Entry("incompatible prefixes", `a starts with "ab" && a starts with "ac"`, false),
+		Entry("compatible prefixes", `a starts with "ab" && a starts with "a"`, true),
+		Entry("incompatible suffixes", `a ends with "ab" && a ends with "cb"`, false),
+		Entry("prefix and excluded prefix", `a starts with "abc" && !(a starts with "ab")`, false),
+		Entry("prefix and longer excluded prefix", `a starts with "ab" && !(a starts with "abc")`, true),
+		Entry("contains and excluded substring", `a contains "xyz" && !(a contains "y")`, false),
+		Entry("prefix and excluded substring", `a starts with "xyz" && !(a contains "y")`, false),
+		Entry("prefix, suffix and excluded join", `a starts with "x" && a ends with "y" && !(a contains "xy")`, true),
+		Entry("excluded empty substring", `has(a) && !(a contains "")`, false),
+		Entry("contradiction in one branch of an or", `(a == "x" && a == "y") || b == "z"`, true),
+		Entry("contradiction in every branch of an or", `(a == "x" || a == "y") && a == "z"`, false),
+		Entry("negated or", `!(a == "x" || has(b)) && b == "y"`, false),
+		Entry("negated and", `!(a == "x" && has(b)) && b == "y"`, true),
+	)
+
+	DescribeTable("Overlaps",
+		func(a, b string, expected bool) {
+			Expect(parser.Overlaps(mustParse(a), mustParse(b))).To(Equal(expected))
+			Expect(parser.Overlaps(mustParse(b), mustParse(a))).To(Equal(expected))
+		},
+		Entry("same selector", `a == "x"`, `a == "x"`, true),
+		Entry("different values", `a == "x"`, `a == "y"`, false),
+		Entry("different labels", `a == "x"`, `b == "y"`, true),
+		Entry("all()", `all()`, `a == "y"`, true),
+		Entry("has and not has", `has(a)`, `!has(a)`, false),
+		Entry("set and value", `a in {"x", "y"}`, `a == "y" && b == "z"`, true),
+		Entry("prefixes", `a starts with "prod-"`, `a starts with "dev-"`, false),
+	)
+
+	DescribeTable("Subsumes",
+		func(a, b string, expected bool) {
+			Expect(parser.Subsumes(mustParse(a), mustParse(b))).To(Equal(expected))
+		},
+		Entry("all() subsumes anything", `all()`, `a == "x"`, true),
+		Entry("nothing else subsumes all()", `a == "x"`, `all()`, false),
+		Entry("selector subsumes itself", `a == "x" && has(b)`, `a == "x" && has(b)`, true),
+		Entry("weaker conjunction", `a == "x"`, `a == "x" && b == "y"`, true),
+		Entry("stronger conjunction", `a == "x" && b == "y"`, `a == "x"`, false),
+		Entry("disjunction", `a == "x" || a == "y"`, `a == "y"`, true),
+		Entry("set and member", `a in {"x", "y"}`, `a == "x"`, true),
+		Entry("set and non-member", `a in {"x", "y"}`, `a == "z"`, false),
+		Entry("has and equality", `has(a)`, `a == "x"`, true),
+		Entry("shorter prefix", `a starts with "prod"`, `a starts with "prod-eu"`, true),
+		Entry("longer prefix", `a starts with "prod-eu"`, `a starts with "prod"`, false),
+		Entry("inequality and other value", `a != "x"`, `a == "y"`, true),
+		Entry("inequality and absent label", `a != "x"`, `!has(a)`, true),
+		Entry("not in set and subset", `a not in {"x"}`, `a not in {"x", "y"}`, true),
+		Entry("unsatisfiable selector", `a == "z"`, `a == "x" && a == "y"`, true),
+	)
+
+	It("should never report an unsatisfiable selector that matches some labels", func() {
+		values := []string{"", "x", "y", "xy", "yx", "xyz"}
+		var labelSets []map[string]string
+		for _, a := range append(values, "<absent>") {
+			for _, b := range append(values, "<absent>") {
+				labels := map[string]string{}
+				if a != "<absent>" {
+					labels["a"] = a
+				}
+				if b != "<absent>" {
+					labels["b"] = b
+				}
+				labelSets = append(labelSets, labels)
+			}
+		}
+		for _, s := range []string{
+			`a == "x" && b != "x"`,
+			`a starts with "x" && a ends with "y" && !(a contains "yx")`,
+			`a contains "y" && a not in {"y", "xy"} && !(a ends with "z")`,
+			`!(a starts with "x" || b in {"y", "x"}) && has(b)`,
+			`(a == "y" || has(b)) && !(a contains "")`,
+		} {
+			sel := mustParse(s)
+			matchesSome := false
+			for _, labels := range labelSets {
+				if sel.Evaluate(labels) {
+					matchesSome = true
+					break
+				}
+			}
+			if matchesSome {
+				Expect(parser.Satisfiable(sel)).To(BeTrue(), s)
+			}
+		}
+	})
+})
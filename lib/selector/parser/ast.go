@@ -17,11 +17,14 @@ package parser
 import (
 	_ "crypto/sha256" // register hash func
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/projectcalico/libcalico-go/lib/hash"
+	"github.com/projectcalico/libcalico-go/lib/selector/tokenizer"
 )
 
 // Labels defines the interface of labels that can be used by selector
@@ -81,6 +84,10 @@ func (v PrefixVisitor) Visit(n interface{}) {
 		np.LabelName = fmt.Sprintf("%s%s", v.Prefix, np.LabelName)
 	case *LabelEndsWithValueNode:
 		np.LabelName = fmt.Sprintf("%s%s", v.Prefix, np.LabelName)
+	case *LabelCompareValueNode:
+		np.LabelName = fmt.Sprintf("%s%s", v.Prefix, np.LabelName)
+	case *LabelMatchesRegexNode:
+		np.LabelName = fmt.Sprintf("%s%s", v.Prefix, np.LabelName)
 	case *HasNode:
 		np.LabelName = fmt.Sprintf("%s%s", v.Prefix, np.LabelName)
 	case *LabelInSetNode:
@@ -219,6 +226,89 @@ func (node *LabelEndsWithValueNode) collectFragments(fragments []string) []strin
 	return appendLabelOpAndQuotedString(fragments, node.LabelName, " ends with ", node.Value)
 }
 
+// ComparisonOperator is a numeric comparison operator.
+type ComparisonOperator string
+
+const (
+	LessThan           ComparisonOperator = "<"
+	LessThanOrEqual    ComparisonOperator = "<="
+	GreaterThan        ComparisonOperator = ">"
+	GreaterThanOrEqual ComparisonOperator = ">="
+)
+
+// numericLabelValueRegex matches label values that are numbers, using the same grammar as the
+// number literals of a selector.  In particular, "Inf", "NaN", hex values and values with
+// underscores are not numbers, even though strconv.ParseFloat would accept them.
+var numericLabelValueRegex = regexp.MustCompile("^" + tokenizer.NumberMatcher + "$")
+
+// LabelCompareValueNode compares the value of a label, parsed as a number, with a number.  It
+// doesn't match if the label is missing or its value is not a number.  A label value is a number
+// if it is a decimal number literal, as accepted by the selector syntax, e.g. "10", "-1.5" or
+// "2e3"; surrounding whitespace is not allowed.
+type LabelCompareValueNode struct {
+	LabelName string
+	Operator  ComparisonOperator
+	Value     float64
+}
+
+func (node *LabelCompareValueNode) Evaluate(labels Labels) bool {
+	val, ok := labels.Get(node.LabelName)
+	if !ok {
+		return false
+	}
+	if !numericLabelValueRegex.MatchString(val) {
+		return false
+	}
+	num, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		// The value is out of range.
+		return false
+	}
+	switch node.Operator {
+	case LessThan:
+		return num < node.Value
+	case LessThanOrEqual:
+		return num <= node.Value
+	case GreaterThan:
+		return num > node.Value
+	case GreaterThanOrEqual:
+		return num >= node.Value
+	}
+	return false
+}
+
+func (node *LabelCompareValueNode) AcceptVisitor(v Visitor) {
+	v.Visit(node)
+}
+
+func (node *LabelCompareValueNode) collectFragments(fragments []string) []string {
+	return append(fragments, node.LabelName, " ", string(node.Operator), " ",
+		strconv.FormatFloat(node.Value, 'g', -1, 64))
+}
+
+// LabelMatchesRegexNode matches if the label is present and some part of its value matches the
+// regular expression.
+type LabelMatchesRegexNode struct {
+	LabelName string
+	Regex     *regexp.Regexp
+}
+
+func (node *LabelMatchesRegexNode) Evaluate(labels Labels) bool {
+	val, ok := labels.Get(node.LabelName)
+	if ok {
+		return node.Regex.MatchString(val)
+	}
+	return false
+}
+
+func (node *LabelMatchesRegexNode) AcceptVisitor(v Visitor) {
+	v.Visit(node)
+}
+
+func (node *LabelMatchesRegexNode) collectFragments(fragments []string) []string {
+	return appendLabelOpAndQuotedString(fragments, node.LabelName, " =~ ", node.Regex.String())
+}
+
 type LabelInSetNode struct {
 	LabelName string
 	Value     StringSet
@@ -0,0 +1,160 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+// presenceKeyCost weights keys that only require a label to be present against keys that require
+// a particular value when choosing which operand of an && to index on, since far more label sets
+// have a given label than have a given label and value.
+const presenceKeyCost = 8
+
+// IndexKey identifies a label, or a label and value, that a set of labels may contain.
+type IndexKey struct {
+	LabelName string
+	Value     string
+	// AnyValue is set if the key only requires the label to be present.
+	AnyValue bool
+}
+
+// IndexKeys returns a set of keys such that every set of labels that the selector matches contains
+// at least one of them, for use in an inverted index.  An empty set of keys means the selector
+// matches nothing.  ok is false if the selector has no such set of keys, for example "all()" or
+// "a != 'b'", and so must be evaluated against every set of labels.
+func IndexKeys(sel Selector) (keys []IndexKey, ok bool) {
+	root, ok := sel.(*selectorRoot)
+	if !ok {
+		return nil, false
+	}
+	return indexKeys(root.root, false)
+}
+
+// indexKeys returns the index keys of n, or of !n if negate is set.
+func indexKeys(n node, negate bool) ([]IndexKey, bool) {
+	switch n := n.(type) {
+	case *LabelEqValueNode:
+		if negate {
+			return nil, false
+		}
+		return []IndexKey{{LabelName: n.LabelName, Value: n.Value}}, true
+	case *LabelNeValueNode:
+		if !negate {
+			return nil, false
+		}
+		return []IndexKey{{LabelName: n.LabelName, Value: n.Value}}, true
+	case *LabelInSetNode:
+		if negate {
+			return nil, false
+		}
+		return setIndexKeys(n.LabelName, n.Value), true
+	case *LabelNotInSetNode:
+		if !negate {
+			return nil, false
+		}
+		return setIndexKeys(n.LabelName, n.Value), true
+	case *HasNode:
+		return presenceIndexKeys(n.LabelName, negate)
+	case *LabelContainsValueNode:
+		return presenceIndexKeys(n.LabelName, negate)
+	case *LabelStartsWithValueNode:
+		return presenceIndexKeys(n.LabelName, negate)
+	case *LabelEndsWithValueNode:
+		return presenceIndexKeys(n.LabelName, negate)
+	case *LabelCompareValueNode:
+		return presenceIndexKeys(n.LabelName, negate)
+	case *LabelMatchesRegexNode:
+		return presenceIndexKeys(n.LabelName, negate)
+	case *AllNode:
+		if negate {
+			// !all() matches nothing.
+			return []IndexKey{}, true
+		}
+		return nil, false
+	case *NotNode:
+		return indexKeys(n.Operand, !negate)
+	case *AndNode:
+		if negate {
+			return anyOperandIndexKeys(n.Operands, true)
+		}
+		return bestOperandIndexKeys(n.Operands, false)
+	case *OrNode:
+		if negate {
+			return bestOperandIndexKeys(n.Operands, true)
+		}
+		return anyOperandIndexKeys(n.Operands, false)
+	}
+	return nil, false
+}
+
+func setIndexKeys(labelName string, values StringSet) []IndexKey {
+	keys := make([]IndexKey, 0, len(values))
+	for _, v := range values {
+		keys = append(keys, IndexKey{LabelName: labelName, Value: v})
+	}
+	return keys
+}
+
+func presenceIndexKeys(labelName string, negate bool) ([]IndexKey, bool) {
+	if negate {
+		return nil, false
+	}
+	return []IndexKey{{LabelName: labelName, AnyValue: true}}, true
+}
+
+// bestOperandIndexKeys returns the cheapest index keys of the operands of a conjunction: labels
+// that match it must match every operand, so any operand's keys will do.
+func bestOperandIndexKeys(operands []node, negate bool) ([]IndexKey, bool) {
+	var best []IndexKey
+	bestCost := -1
+	for _, op := range operands {
+		keys, ok := indexKeys(op, negate)
+		if !ok {
+			continue
+		}
+		cost := 0
+		for _, k := range keys {
+			if k.AnyValue {
+				cost += presenceKeyCost
+			} else {
+				cost++
+			}
+		}
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = keys, cost
+		}
+	}
+	return best, bestCost >= 0
+}
+
+// anyOperandIndexKeys returns the union of the index keys of the operands of a disjunction, which
+// only has index keys if every operand has them.
+func anyOperandIndexKeys(operands []node, negate bool) ([]IndexKey, bool) {
+	var keys []IndexKey
+	seen := map[IndexKey]bool{}
+	for _, op := range operands {
+		opKeys, ok := indexKeys(op, negate)
+		if !ok {
+			return nil, false
+		}
+		for _, k := range opKeys {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	if keys == nil {
+		keys = []IndexKey{}
+	}
+	return keys, true
+}
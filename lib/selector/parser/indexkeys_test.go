@@ -0,0 +1,73 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+)
+
+var _ = Describe("Selector index keys", func() {
+	eq := func(label, value string) parser.IndexKey {
+		return parser.IndexKey{LabelName: label, Value: value}
+	}
+	has := func(label string) parser.IndexKey {
+		return parser.IndexKey{LabelName: label, AnyValue: true}
+	}
+
+	DescribeTable("should return the keys that a match requires",
+		func(selector string, expected []parser.IndexKey) {
+			sel, err := parser.Parse(selector)
+			Expect(err).NotTo(HaveOccurred())
+			keys, ok := parser.IndexKeys(sel)
+			Expect(ok).To(BeTrue())
+			Expect(keys).To(ConsistOf(expected))
+		},
+		Entry("equality", `a == 'x'`, []parser.IndexKey{eq("a", "x")}),
+		Entry("set", `a in {'x', 'y'}`, []parser.IndexKey{eq("a", "x"), eq("a", "y")}),
+		Entry("empty set", `a in {}`, []parser.IndexKey{}),
+		Entry("has", `has(a)`, []parser.IndexKey{has("a")}),
+		Entry("string test", `a starts with 'x'`, []parser.IndexKey{has("a")}),
+		Entry("comparison", `a >= 2`, []parser.IndexKey{has("a")}),
+		Entry("regular expression", `a =~ 'x'`, []parser.IndexKey{has("a")}),
+		Entry("negated inequality", `!(a != 'x')`, []parser.IndexKey{eq("a", "x")}),
+		Entry("negated not in", `!(a not in {'x'})`, []parser.IndexKey{eq("a", "x")}),
+		Entry("and prefers values", `has(b) && a == 'x' && c != 'y'`, []parser.IndexKey{eq("a", "x")}),
+		Entry("and prefers fewer keys", `a in {'x', 'y'} && b == 'z'`, []parser.IndexKey{eq("b", "z")}),
+		Entry("or takes the union", `a == 'x' || has(b) || a == 'x'`, []parser.IndexKey{eq("a", "x"), has("b")}),
+		Entry("negated or", `!(a != 'x' || !has(b))`, []parser.IndexKey{eq("a", "x")}),
+		Entry("negated all", `!all()`, []parser.IndexKey{}),
+	)
+
+	DescribeTable("should report selectors without keys",
+		func(selector string) {
+			sel, err := parser.Parse(selector)
+			Expect(err).NotTo(HaveOccurred())
+			_, ok := parser.IndexKeys(sel)
+			Expect(ok).To(BeFalse())
+		},
+		Entry("all", `all()`),
+		Entry("global", `global()`),
+		Entry("inequality", `a != 'x'`),
+		Entry("not in", `a not in {'x'}`),
+		Entry("not has", `!has(a)`),
+		Entry("negated comparison", `!(a < 1)`),
+		Entry("or with an unindexed operand", `a == 'x' || b != 'y'`),
+		Entry("negated and", `!(a == 'x' && has(b))`),
+	)
+})
@@ -0,0 +1,240 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// Normalize returns a selector that matches the same labels as sel, in a canonical form: nested
+// "&&" and "||" expressions are flattened, "!" is pushed inward, equality and set tests on the same
+// label are merged into a single set test, and operands are deduplicated and sorted.  Selectors
+// that differ only in those respects normalize to the same selector, and so have the same UniqueID.
+func Normalize(sel Selector) Selector {
+	root, ok := sel.(*selectorRoot)
+	if !ok {
+		return sel
+	}
+	return &selectorRoot{root: normalize(root.root, false)}
+}
+
+// normalize returns a new node in canonical form that is equivalent to n, or to its negation.
+func normalize(n node, negate bool) node {
+	not := func(n node) node {
+		if negate {
+			return &NotNode{n}
+		}
+		return n
+	}
+	switch n := n.(type) {
+	case *AllNode:
+		return not(&AllNode{})
+	case *GlobalNode:
+		return not(&GlobalNode{})
+	case *HasNode:
+		return not(&HasNode{LabelName: n.LabelName})
+	case *LabelContainsValueNode:
+		return not(&LabelContainsValueNode{LabelName: n.LabelName, Value: n.Value})
+	case *LabelStartsWithValueNode:
+		return not(&LabelStartsWithValueNode{LabelName: n.LabelName, Value: n.Value})
+	case *LabelEndsWithValueNode:
+		return not(&LabelEndsWithValueNode{LabelName: n.LabelName, Value: n.Value})
+	case *LabelCompareValueNode:
+		return not(&LabelCompareValueNode{LabelName: n.LabelName, Operator: n.Operator, Value: n.Value})
+	case *LabelMatchesRegexNode:
+		return not(&LabelMatchesRegexNode{LabelName: n.LabelName, Regex: n.Regex})
+	case *LabelEqValueNode:
+		return setNode(n.LabelName, StringSet{n.Value}, negate)
+	case *LabelNeValueNode:
+		return setNode(n.LabelName, StringSet{n.Value}, !negate)
+	case *LabelInSetNode:
+		return setNode(n.LabelName, n.Value, negate)
+	case *LabelNotInSetNode:
+		return setNode(n.LabelName, n.Value, !negate)
+	case *NotNode:
+		return normalize(n.Operand, !negate)
+	case *AndNode:
+		// !(a && b) == !a || !b
+		return combineOperands(n.Operands, negate, negate)
+	case *OrNode:
+		// !(a || b) == !a && !b
+		return combineOperands(n.Operands, negate, !negate)
+	}
+	return n
+}
+
+// setNode returns the canonical node that tests whether the label's value is in the set, or, if
+// negated, that it is not.  Single-valued sets are tested with "==" and "!=".
+func setNode(label string, values StringSet, negate bool) node {
+	values = ConvertToStringSetInPlace(append([]string(nil), values...))
+	switch {
+	case len(values) == 1 && negate:
+		return &LabelNeValueNode{LabelName: label, Value: values[0]}
+	case len(values) == 1:
+		return &LabelEqValueNode{LabelName: label, Value: values[0]}
+	case negate:
+		return &LabelNotInSetNode{LabelName: label, Value: values}
+	}
+	return &LabelInSetNode{LabelName: label, Value: values}
+}
+
+// setTest is the set of values that a set node tests for, and whether it tests that the value is
+// not in the set.
+type setTest struct {
+	values  StringSet
+	negated bool
+}
+
+func asSetTest(n node) (string, setTest, bool) {
+	switch n := n.(type) {
+	case *LabelEqValueNode:
+		return n.LabelName, setTest{values: StringSet{n.Value}}, true
+	case *LabelNeValueNode:
+		return n.LabelName, setTest{values: StringSet{n.Value}, negated: true}, true
+	case *LabelInSetNode:
+		return n.LabelName, setTest{values: n.Value}, true
+	case *LabelNotInSetNode:
+		return n.LabelName, setTest{values: n.Value, negated: true}, true
+	}
+	return "", setTest{}, false
+}
+
+// combineOperands normalizes the operands, or their negations, and combines them with "||" if or
+// is set, or "&&" otherwise.
+func combineOperands(operands []node, negate, or bool) node {
+	// Normalize and flatten the operands, dropping those that don't affect the result.  In an "||",
+	// all() makes the result all() and !all() can be dropped; in an "&&" it is the other way round.
+	var flat []node
+	var addOperand func(op node) bool
+	addOperand = func(op node) bool {
+		switch op := op.(type) {
+		case *OrNode:
+			if or {
+				for _, o := range op.Operands {
+					if !addOperand(o) {
+						return false
+					}
+				}
+				return true
+			}
+		case *AndNode:
+			if !or {
+				for _, o := range op.Operands {
+					if !addOperand(o) {
+						return false
+					}
+				}
+				return true
+			}
+		case *AllNode:
+			return !or
+		case *NotNode:
+			if _, ok := op.Operand.(*AllNode); ok {
+				return or
+			}
+		}
+		flat = append(flat, op)
+		return true
+	}
+	for _, op := range operands {
+		if !addOperand(normalize(op, negate)) {
+			// The result is decided by this operand.
+			if or {
+				return &AllNode{}
+			}
+			return &NotNode{&AllNode{}}
+		}
+	}
+
+	// Merge the set tests on each label.  In an "&&", values must be in the intersection of the
+	// tested sets and not in their union; in an "||" it is the other way round.
+	type labelSets struct {
+		in, notIn *setTest
+	}
+	sets := map[string]*labelSets{}
+	var merged []node
+	for _, op := range flat {
+		label, test, ok := asSetTest(op)
+		if !ok {
+			merged = append(merged, op)
+			continue
+		}
+		ls := sets[label]
+		if ls == nil {
+			ls = &labelSets{}
+			sets[label] = ls
+		}
+		existing := &ls.in
+		if test.negated {
+			existing = &ls.notIn
+		}
+		if *existing == nil {
+			*existing = &test
+			continue
+		}
+		if test.negated == or {
+			(*existing).values = intersectSets((*existing).values, test.values)
+		} else {
+			(*existing).values = append(append([]string(nil), (*existing).values...), test.values...)
+		}
+	}
+	for label, ls := range sets {
+		for _, test := range []*setTest{ls.in, ls.notIn} {
+			if test != nil {
+				merged = append(merged, setNode(label, test.values, test.negated))
+			}
+		}
+	}
+
+	// Deduplicate and sort the operands by their string form.
+	byString := map[string]node{}
+	var keys []string
+	for _, op := range merged {
+		key := strings.Join(op.collectFragments(nil), "")
+		if _, ok := byString[key]; ok {
+			continue
+		}
+		byString[key] = op
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]node, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, byString[k])
+	}
+
+	switch {
+	case len(result) == 0 && or:
+		return &NotNode{&AllNode{}}
+	case len(result) == 0:
+		return &AllNode{}
+	case len(result) == 1:
+		return result[0]
+	case or:
+		return &OrNode{result}
+	}
+	return &AndNode{result}
+}
+
+func intersectSets(a, b StringSet) StringSet {
+	result := StringSet{}
+	for _, v := range a {
+		if b.Contains(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
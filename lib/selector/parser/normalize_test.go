@@ -0,0 +1,84 @@
+// Copyright (c) 2020 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+)
+
+var _ = Describe("Selector normalization", func() {
+	normalize := func(s string) parser.Selector {
+		sel, err := parser.Parse(s)
+		Expect(err).NotTo(HaveOccurred())
+		return parser.Normalize(sel)
+	}
+
+	DescribeTable("should normalize to the canonical form",
+		func(input, expected string) {
+			norm := normalize(input)
+			Expect(norm.String()).To(Equal(expected))
+
+			// The canonical form is stable.
+			Expect(normalize(norm.String()).String()).To(Equal(expected))
+		},
+		Entry("a simple selector", `a == 'x'`, `a == "x"`),
+		Entry("sorted operands", `b == '2' && a == '1'`, `(a == "1" && b == "2")`),
+		Entry("flattened operands", `a == '1' && (c == '3' && b == '2')`, `(a == "1" && b == "2" && c == "3")`),
+		Entry("deduplicated operands", `has(a) || has(b) || has(a)`, `(has(a) || has(b))`),
+		Entry("double negation", `!!has(a)`, `has(a)`),
+		Entry("negated equality", `!(a == 'x')`, `a != "x"`),
+		Entry("negated set", `!(a in {'x', 'y'})`, `a not in {"x", "y"}`),
+		Entry("negated and", `!(has(a) && b == 'x')`, `(!has(a) || b != "x")`),
+		Entry("negated or", `!(has(a) || b not in {'x', 'y'})`, `(!has(a) && b in {"x", "y"})`),
+		Entry("equalities merged into a set", `a == 'y' || a == 'x'`, `a in {"x", "y"}`),
+		Entry("sets merged", `a in {'x'} || a in {'z', 'y'}`, `a in {"x", "y", "z"}`),
+		Entry("sets intersected", `a in {'x', 'y'} && a in {'y', 'z'}`, `a == "y"`),
+		Entry("inequalities merged", `a != 'x' && a != 'y'`, `a not in {"x", "y"}`),
+		Entry("all() dropped from an and", `all() && has(a)`, `has(a)`),
+		Entry("all() absorbs an or", `all() || has(a)`, `all()`),
+		Entry("nested ors flattened inside an and", `(has(b) || (has(a) || has(c))) && has(d)`, `((has(a) || has(b) || has(c)) && has(d))`),
+		Entry("string tests kept", `!(a starts with 'x') && a contains 'y'`, `(!a starts with "x" && a contains "y")`),
+		Entry("comparisons kept", `!!(b >= 2) && !(a < 1.0)`, `(!a < 1 && b >= 2)`),
+		Entry("regular expressions kept", `!(a =~ 'x' || a =~ 'y')`, `(!a =~ "x" && !a =~ "y")`),
+	)
+
+	DescribeTable("should give equivalent selectors the same UniqueID",
+		func(a, b string) {
+			Expect(normalize(a).UniqueID()).To(Equal(normalize(b).UniqueID()))
+		},
+		Entry("reordered and", `a == '1' && b == '2'`, `b == '2' && a == '1'`),
+		Entry("reordered or", `has(a) || has(b)`, `has(b) || has(a)`),
+		Entry("regrouped and", `(a == '1' && b == '2') && c == '3'`, `a == '1' && (b == '2' && c == '3')`),
+		Entry("set and equalities", `a in {'x', 'y'}`, `a == 'y' || a == 'x'`),
+		Entry("de morgan", `!(has(a) || has(b))`, `!has(b) && !has(a)`),
+		Entry("not in and negated in", `a not in {'x'}`, `!(a == 'x')`),
+	)
+
+	It("should not change which labels are matched", func() {
+		for _, test := range selectorTests {
+			norm := normalize(test.sel)
+			for _, labels := range test.expMatches {
+				Expect(norm.Evaluate(labels)).To(BeTrue(), "%s (normalized to %s) should match %v", test.sel, norm, labels)
+			}
+			for _, labels := range test.expNonMatches {
+				Expect(norm.Evaluate(labels)).To(BeFalse(), "%s (normalized to %s) should not match %v", test.sel, norm, labels)
+			}
+		}
+	})
+})
@@ -15,8 +15,9 @@
 package parser
 
 import (
-	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -25,11 +26,72 @@ import (
 
 const parserDebug = false
 
-// Parse parses a string representation of a selector expression into a Selector.
+// operandKinds are the kinds of token that may start an operation.
+var operandKinds = []tokenizer.Kind{
+	tokenizer.TokNot, tokenizer.TokLParen, tokenizer.TokLabel, tokenizer.TokHas, tokenizer.TokAll,
+	tokenizer.TokGlobal,
+}
+
+// ParseError is returned by Parse when the selector is malformed.  It records where in the
+// selector parsing failed and what was expected there.
+type ParseError struct {
+	// Selector is the selector that failed to parse.
+	Selector string
+	// Offset is the byte offset in Selector at which parsing failed.
+	Offset int
+	// Token is the kind of the offending token, or TokNone if the characters at Offset are not a
+	// valid token.
+	Token tokenizer.Kind
+	// Expected lists the kinds of token that would have been valid at Offset, if known.
+	Expected []tokenizer.Kind
+	// Message describes the error.
+	Message string
+
+	// remaining is the number of tokens, starting with the offending token, that were left to
+	// parse.  Parse uses it to fill in the Offset.
+	remaining int
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s at offset %d", e.Message, e.Offset)
+	switch len(e.Expected) {
+	case 0:
+	case 1:
+		msg += fmt.Sprintf(", expected %v", e.Expected[0])
+	default:
+		expected := make([]string, len(e.Expected))
+		for i, k := range e.Expected {
+			expected[i] = k.String()
+		}
+		msg += ", expected one of " + strings.Join(expected, ", ")
+	}
+	return msg
+}
+
+// unexpected returns a ParseError for the first of the remaining tokens.
+func unexpected(remTokens []tokenizer.Token, expected ...tokenizer.Kind) *ParseError {
+	return &ParseError{
+		Token:     remTokens[0].Kind,
+		Expected:  expected,
+		Message:   fmt.Sprintf("unexpected %v", remTokens[0].Kind),
+		remaining: len(remTokens),
+	}
+}
+
+// Parse parses a string representation of a selector expression into a Selector.  If the
+// selector is malformed, the error is a *ParseError.
 func Parse(selector string) (sel Selector, err error) {
 	log.Debugf("Parsing %#v", selector)
-	tokens, err := tokenizer.Tokenize(selector)
+	tokens, offsets, err := tokenizer.TokenizeWithOffsets(selector)
 	if err != nil {
+		tokErr := err.(*tokenizer.Error)
+		err = &ParseError{
+			Selector: selector,
+			Offset:   tokErr.Offset,
+			Token:    tokenizer.TokNone,
+			Expected: tokErr.Expected,
+			Message:  tokErr.Message,
+		}
 		return
 	}
 	if tokens[0].Kind == tokenizer.TokEOF {
@@ -38,12 +100,14 @@ func Parse(selector string) (sel Selector, err error) {
 	log.Debugf("Tokens %v", tokens)
 	// The "||" operator has the lowest precedence so we start with that.
 	node, remTokens, err := parseOrExpression(tokens)
-	if err != nil {
-		return
+	if err == nil && len(remTokens) != 1 {
+		err = unexpected(remTokens, tokenizer.TokAnd, tokenizer.TokOr, tokenizer.TokEOF)
 	}
-	if len(remTokens) != 1 {
-		err = errors.New(fmt.Sprint("unexpected content at end of selector ", remTokens))
-		return
+	if err != nil {
+		parseErr := err.(*ParseError)
+		parseErr.Selector = selector
+		parseErr.Offset = offsets[len(tokens)-parseErr.remaining]
+		return nil, parseErr
 	}
 	sel = &selectorRoot{root: node}
 	return
@@ -123,10 +187,6 @@ func parseOperation(tokens []tokenizer.Token) (sel node, remTokens []tokenizer.T
 	if parserDebug {
 		log.Debugf("Parsing op from %v", tokens)
 	}
-	if len(tokens) == 0 {
-		err = errors.New("Unexpected end of string looking for op")
-		return
-	}
 
 	// First, collapse any leading "!" operators to a single boolean.
 	negated := false
@@ -153,7 +213,7 @@ func parseOperation(tokens []tokenizer.Token) (sel node, remTokens []tokenizer.T
 	case tokenizer.TokLabel:
 		// should have an operator and a literal.
 		if len(tokens) < 3 {
-			err = errors.New(fmt.Sprint("Unexpected end of string in middle of op", tokens))
+			err = unexpected(tokens[1:], tokenizer.LabelOperators...)
 			return
 		}
 		switch tokens[1].Kind {
@@ -162,40 +222,74 @@ func parseOperation(tokens []tokenizer.Token) (sel node, remTokens []tokenizer.T
 				sel = &LabelEqValueNode{tokens[0].Value.(string), tokens[2].Value.(string)}
 				remTokens = tokens[3:]
 			} else {
-				err = errors.New("Expected string")
+				err = unexpected(tokens[2:], tokenizer.TokStringLiteral)
 			}
 		case tokenizer.TokNe:
 			if tokens[2].Kind == tokenizer.TokStringLiteral {
 				sel = &LabelNeValueNode{tokens[0].Value.(string), tokens[2].Value.(string)}
 				remTokens = tokens[3:]
 			} else {
-				err = errors.New("Expected string")
+				err = unexpected(tokens[2:], tokenizer.TokStringLiteral)
 			}
 		case tokenizer.TokContains:
 			if tokens[2].Kind == tokenizer.TokStringLiteral {
 				sel = &LabelContainsValueNode{tokens[0].Value.(string), tokens[2].Value.(string)}
 				remTokens = tokens[3:]
 			} else {
-				err = errors.New("Expected string")
+				err = unexpected(tokens[2:], tokenizer.TokStringLiteral)
 			}
 		case tokenizer.TokStartsWith:
 			if tokens[2].Kind == tokenizer.TokStringLiteral {
 				sel = &LabelStartsWithValueNode{tokens[0].Value.(string), tokens[2].Value.(string)}
 				remTokens = tokens[3:]
 			} else {
-				err = errors.New("Expected string")
+				err = unexpected(tokens[2:], tokenizer.TokStringLiteral)
 			}
 		case tokenizer.TokEndsWith:
 			if tokens[2].Kind == tokenizer.TokStringLiteral {
 				sel = &LabelEndsWithValueNode{tokens[0].Value.(string), tokens[2].Value.(string)}
 				remTokens = tokens[3:]
 			} else {
-				err = errors.New("Expected string")
+				err = unexpected(tokens[2:], tokenizer.TokStringLiteral)
+			}
+		case tokenizer.TokLt, tokenizer.TokLe, tokenizer.TokGt, tokenizer.TokGe:
+			if tokens[2].Kind == tokenizer.TokNumberLiteral {
+				op := LessThan
+				switch tokens[1].Kind {
+				case tokenizer.TokLe:
+					op = LessThanOrEqual
+				case tokenizer.TokGt:
+					op = GreaterThan
+				case tokenizer.TokGe:
+					op = GreaterThanOrEqual
+				}
+				sel = &LabelCompareValueNode{tokens[0].Value.(string), op, tokens[2].Value.(float64)}
+				remTokens = tokens[3:]
+			} else {
+				err = unexpected(tokens[2:], tokenizer.TokNumberLiteral)
+			}
+		case tokenizer.TokRegexMatch:
+			if tokens[2].Kind == tokenizer.TokStringLiteral {
+				var re *regexp.Regexp
+				re, err = regexp.Compile(tokens[2].Value.(string))
+				if err != nil {
+					err = &ParseError{
+						Token:     tokenizer.TokStringLiteral,
+						Message:   fmt.Sprintf("invalid regular expression (%v)", err),
+						remaining: len(tokens) - 2,
+					}
+					return
+				}
+				sel = &LabelMatchesRegexNode{tokens[0].Value.(string), re}
+				remTokens = tokens[3:]
+			} else {
+				err = unexpected(tokens[2:], tokenizer.TokStringLiteral)
 			}
 		case tokenizer.TokIn, tokenizer.TokNotIn:
 			if tokens[2].Kind == tokenizer.TokLBrace {
 				remTokens = tokens[3:]
 				values := []string{}
+				expectValue := true
 				for {
 					if remTokens[0].Kind == tokenizer.TokStringLiteral {
 						value := remTokens[0].Value.(string)
@@ -204,6 +298,7 @@ func parseOperation(tokens []tokenizer.Token) (sel node, remTokens []tokenizer.T
 						if remTokens[0].Kind == tokenizer.TokComma {
 							remTokens = remTokens[1:]
 						} else {
+							expectValue = false
 							break
 						}
 					} else {
@@ -211,7 +306,11 @@ func parseOperation(tokens []tokenizer.Token) (sel node, remTokens []tokenizer.T
 					}
 				}
 				if remTokens[0].Kind != tokenizer.TokRBrace {
-					err = errors.New("Expected }")
+					if expectValue {
+						err = unexpected(remTokens, tokenizer.TokStringLiteral, tokenizer.TokRBrace)
+					} else {
+						err = unexpected(remTokens, tokenizer.TokComma, tokenizer.TokRBrace)
+					}
 				} else {
 					// Skip over the }
 					remTokens = remTokens[1:]
@@ -225,10 +324,10 @@ func parseOperation(tokens []tokenizer.Token) (sel node, remTokens []tokenizer.T
 					}
 				}
 			} else {
-				err = errors.New("Expected set literal")
+				err = unexpected(tokens[2:], tokenizer.TokLBrace)
 			}
 		default:
-			err = errors.New(fmt.Sprint("Expected == or != not ", tokens[1]))
+			err = unexpected(tokens[1:], tokenizer.LabelOperators...)
 			return
 		}
 	case tokenizer.TokLParen:
@@ -239,13 +338,13 @@ func parseOperation(tokens []tokenizer.Token) (sel node, remTokens []tokenizer.T
 		}
 		// After parsing the nested expression, there should be
 		// a matching paren.
-		if len(remTokens) < 1 || remTokens[0].Kind != tokenizer.TokRParen {
-			err = errors.New("Expected )")
+		if remTokens[0].Kind != tokenizer.TokRParen {
+			err = unexpected(remTokens, tokenizer.TokAnd, tokenizer.TokOr, tokenizer.TokRParen)
 			return
 		}
 		remTokens = remTokens[1:]
 	default:
-		err = errors.New(fmt.Sprint("Unexpected token: ", tokens[0]))
+		err = unexpected(tokens, operandKinds...)
 		return
 	}
 	if negated && err == nil {
@@ -16,6 +16,7 @@ package parser_test
 
 import (
 	"github.com/projectcalico/libcalico-go/lib/selector/parser"
+	"github.com/projectcalico/libcalico-go/lib/selector/tokenizer"
 
 	"fmt"
 
@@ -165,6 +166,27 @@ var selectorTests = []selectorTest{
 	{`a != 'a1' || b == 'b1'`, []map[string]string{{"a": "a1", "b": "b1"}}, []map[string]string{}},
 	{`a != 'a1' || b != 'b1'`, []map[string]string{}, []map[string]string{{"a": "a1", "b": "b1"}}},
 	{`! a == 'a1' || ! b == 'b1'`, []map[string]string{}, []map[string]string{{"a": "a1", "b": "b1"}}},
+
+	// Numeric comparisons...
+	{`a < 10`,
+		[]map[string]string{{"a": "9"}, {"a": "-1"}, {"a": "9.99"}, {"a": "1e0"}},
+		[]map[string]string{{}, {"a": "10"}, {"a": "11"}, {"a": "x"}, {"a": ""}, {"b": "1"}}},
+	{`a <= 10`, []map[string]string{{"a": "10"}, {"a": "10.0"}}, []map[string]string{{"a": "10.5"}}},
+	{`a > -1.5`, []map[string]string{{"a": "0"}, {"a": "-1"}}, []map[string]string{{"a": "-1.5"}, {"a": "-2"}}},
+	{`a>=2`, []map[string]string{{"a": "2"}, {"a": "3"}}, []map[string]string{{"a": "1.9"}, {"a": "v2"}}},
+	{`!(a < 10)`, []map[string]string{{}, {"a": "10"}, {"a": "x"}}, []map[string]string{{"a": "9"}}},
+	{`a >= 1 && a < 3`, []map[string]string{{"a": "1"}, {"a": "2.5"}}, []map[string]string{{"a": "3"}, {"a": "0"}}},
+	{`a > 5`,
+		[]map[string]string{{"a": "6"}, {"a": "+6"}, {"a": "6."}, {"a": ".6e2"}, {"a": "1E3"}},
+		[]map[string]string{{"a": "Inf"}, {"a": "+inf"}, {"a": "infinity"}, {"a": "NaN"}, {"a": "1_000"},
+			{"a": "0x10"}, {"a": " 6"}, {"a": "6 "}, {"a": "1e400"}}},
+	{`a < 5`, []map[string]string{{"a": "-6"}}, []map[string]string{{"a": "-Inf"}, {"a": "NaN"}, {"a": "-1e400"}}},
+
+	// Regular expressions...
+	{`a =~ "^v1\."`, []map[string]string{{"a": "v1.2"}, {"a": "v1.10.3"}}, []map[string]string{{}, {"a": "v10"}, {"a": "xv1.2"}}},
+	{`a =~ 'b'`, []map[string]string{{"a": "b"}, {"a": "abc"}}, []map[string]string{{"a": "c"}, {"b": "b"}}},
+	{`a =~ '^(x|y)$'`, []map[string]string{{"a": "x"}, {"a": "y"}}, []map[string]string{{"a": "xy"}}},
+	{`!a =~ 'b'`, []map[string]string{{}, {"a": "c"}}, []map[string]string{{"a": "b"}}},
 }
 
 var badSelectors = []string{
@@ -194,6 +216,13 @@ var badSelectors = []string{
 	`a == "b" || %`,   // Unexpected char
 	`a `,              // should be followed by operator
 	`has(foo) &&`,     // should be followed by operator
+	`a < "10"`,        // Expect number
+	`a < b`,           // Expect number
+	`a < 1 2`,         // Garbage
+	`a <`,             // Expect number
+	`a =~ b`,          // Expect string
+	`a =~ "("`,        // Invalid regex
+	`a = "b"`,         // Expect == or =~
 }
 
 var canonicalisationTests = []struct {
@@ -219,6 +248,13 @@ var canonicalisationTests = []struct {
 	{`a in {"d", "a", "b"}`, `a in {"a", "b", "d"}`, ""},
 	{`a in {"z", "x", "y", "a"}`, `a in {"a", "x", "y", "z"}`, ""},
 	{`a in {"z", "z", "x", "y", "x", "a"}`, `a in {"a", "x", "y", "z"}`, ""},
+	// Numbers are formatted in their shortest form.
+	{`a<10`, `a < 10`, ""},
+	{`a <= 1.50`, `a <= 1.5`, ""},
+	{`a > -.5`, `a > -0.5`, ""},
+	{`a >= 1e6`, `a >= 1e+06`, ""},
+	{`a=~'^v[0-9]+$'`, `a =~ "^v[0-9]+$"`, ""},
+	{`a =~ '"'`, `a =~ '"'`, ""},
 }
 
 var _ = Describe("Parser", func() {
@@ -305,6 +341,56 @@ var _ = Describe("Parser", func() {
 	}
 })
 
+var _ = Describe("Parse errors", func() {
+	operands := []tokenizer.Kind{
+		tokenizer.TokNot, tokenizer.TokLParen, tokenizer.TokLabel, tokenizer.TokHas, tokenizer.TokAll,
+		tokenizer.TokGlobal,
+	}
+
+	DescribeTable("should report where parsing failed",
+		func(selector string, offset int, token tokenizer.Kind, expected []tokenizer.Kind) {
+			_, err := parser.Parse(selector)
+			Expect(err).To(HaveOccurred())
+			parseErr, ok := err.(*parser.ParseError)
+			Expect(ok).To(BeTrue(), "Expected a *ParseError, not %T", err)
+			Expect(parseErr.Selector).To(Equal(selector))
+			Expect(parseErr.Offset).To(Equal(offset))
+			Expect(parseErr.Token).To(Equal(token))
+			Expect(parseErr.Expected).To(Equal(expected))
+		},
+		Entry("repeated operator", `has(foo) && && bar == 'x'`, 12, tokenizer.TokAnd, operands),
+		Entry("missing operand", `a == 'x' ||`, 11, tokenizer.TokEOF, operands),
+		Entry("missing operator", `a`, 1, tokenizer.TokEOF, tokenizer.LabelOperators),
+		Entry("label instead of string", `a == b`, 5, tokenizer.TokLabel,
+			[]tokenizer.Kind{tokenizer.TokStringLiteral}),
+		Entry("string instead of number", `a < 'x'`, 4, tokenizer.TokStringLiteral,
+			[]tokenizer.Kind{tokenizer.TokNumberLiteral}),
+		Entry("unterminated paren", `(a == 'x'`, 9, tokenizer.TokEOF,
+			[]tokenizer.Kind{tokenizer.TokAnd, tokenizer.TokOr, tokenizer.TokRParen}),
+		Entry("missing comma", `a in {'x' 'y'}`, 10, tokenizer.TokStringLiteral,
+			[]tokenizer.Kind{tokenizer.TokComma, tokenizer.TokRBrace}),
+		Entry("missing value", `a in {,}`, 6, tokenizer.TokComma,
+			[]tokenizer.Kind{tokenizer.TokStringLiteral, tokenizer.TokRBrace}),
+		Entry("missing set", `a in 'x'`, 5, tokenizer.TokStringLiteral, []tokenizer.Kind{tokenizer.TokLBrace}),
+		Entry("trailing content", `a == 'x' b`, 9, tokenizer.TokLabel,
+			[]tokenizer.Kind{tokenizer.TokAnd, tokenizer.TokOr, tokenizer.TokEOF}),
+		Entry("invalid regex", `a =~ '('`, 5, tokenizer.TokStringLiteral, []tokenizer.Kind(nil)),
+		Entry("invalid operator", `a = 'x'`, 2, tokenizer.TokNone,
+			[]tokenizer.Kind{tokenizer.TokEq, tokenizer.TokRegexMatch}),
+		Entry("unterminated string", `a == "x`, 5, tokenizer.TokNone, []tokenizer.Kind(nil)),
+		Entry("unexpected characters", `has(a) && %`, 10, tokenizer.TokNone, []tokenizer.Kind(nil)),
+	)
+
+	It("should describe the error", func() {
+		_, err := parser.Parse(`has(foo) && && bar == 'x'`)
+		Expect(err).To(MatchError(`unexpected "&&" at offset 12, expected one of "!", "(", label, ` +
+			`"has(label)", "all()", "global()"`))
+
+		_, err = parser.Parse(`a == `)
+		Expect(err).To(MatchError(`unexpected end of selector at offset 5, expected string`))
+	})
+})
+
 var _ = Describe("Visitor", func() {
 
 	testVisitor := parser.PrefixVisitor{Prefix: "visited/"}
@@ -334,6 +420,8 @@ var _ = Describe("Visitor", func() {
 		Entry("should visit a NotNode", "!(k == 'v')", "!visited/k == \"v\"", testVisitor),
 		Entry("should visit a LabelInSetNode", "k in {'v'}", "visited/k in {\"v\"}", testVisitor),
 		Entry("should visit a LabelNotInSetNode", "k not in {'v'}", "visited/k not in {\"v\"}", testVisitor),
+		Entry("should visit a LabelCompareValueNode", "k < 1", "visited/k < 1", testVisitor),
+		Entry("should visit a LabelMatchesRegexNode", "k =~ 'v'", "visited/k =~ \"v\"", testVisitor),
 		Entry("should visit a big complex selector",
 			"!(!(k == 'v' && has(t) || all()) && (a in {'b', 'c'}))",
 			"!(!((visited/k == \"v\" && has(visited/t)) || all()) && visited/a in {\"b\", \"c\"})",
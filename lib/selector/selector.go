@@ -32,7 +32,49 @@ type Selector interface {
 	UniqueID() string
 }
 
-// Parse a string representation of a selector expression into a Selector.
+// Parse a string representation of a selector expression into a Selector.  If the selector is
+// malformed, the error is a *parser.ParseError, which records where and why parsing failed.
 func Parse(selector string) (sel Selector, err error) {
 	return parser.Parse(selector)
 }
+
+// Normalize returns a selector that matches the same labels as sel, in a canonical form, so that
+// selectors that differ only in the order, grouping or duplication of their operands, in where
+// negations are applied, or in how set tests are written have the same UniqueID.
+func Normalize(sel Selector) Selector {
+	if s, ok := sel.(parser.Selector); ok {
+		return parser.Normalize(s)
+	}
+	return sel
+}
+
+// Satisfiable returns false if there is no set of labels that the selector matches, for example
+// "a == 'x' && a == 'y'".  Selectors that are too complex to analyse are assumed to be satisfiable.
+func Satisfiable(sel Selector) bool {
+	if s, ok := sel.(parser.Selector); ok {
+		return parser.Satisfiable(s)
+	}
+	return true
+}
+
+// Overlaps returns true if there is some set of labels that both selectors match.  Selectors that
+// are too complex to analyse are assumed to overlap.
+func Overlaps(a, b Selector) bool {
+	sa, okA := a.(parser.Selector)
+	sb, okB := b.(parser.Selector)
+	if !okA || !okB {
+		return true
+	}
+	return parser.Overlaps(sa, sb)
+}
+
+// Subsumes returns true if every set of labels that b matches is also matched by a.  Selectors that
+// are too complex to analyse are assumed not to subsume each other.
+func Subsumes(a, b Selector) bool {
+	sa, okA := a.(parser.Selector)
+	sb, okB := b.(parser.Selector)
+	if !okA || !okB {
+		return false
+	}
+	return parser.Subsumes(sa, sb)
+}
@@ -15,18 +15,19 @@
 package tokenizer
 
 import (
-	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
 
-type tokenKind uint8
+// Kind is the kind of a token.
+type Kind uint8
 
 const (
-	TokNone tokenKind = iota
+	TokNone Kind = iota
 	TokLabel
 	TokStringLiteral
 	TokLBrace
@@ -47,19 +48,82 @@ const (
 	TokAnd
 	TokOr
 	TokGlobal
+	TokLt
+	TokLe
+	TokGt
+	TokGe
+	TokRegexMatch
+	TokNumberLiteral
 	TokEOF
 )
 
+var kindNames = map[Kind]string{
+	TokNone:          "nothing",
+	TokLabel:         "label",
+	TokStringLiteral: "string",
+	TokLBrace:        `"{"`,
+	TokRBrace:        `"}"`,
+	TokComma:         `","`,
+	TokEq:            `"=="`,
+	TokNe:            `"!="`,
+	TokIn:            `"in"`,
+	TokNot:           `"!"`,
+	TokNotIn:         `"not in"`,
+	TokContains:      `"contains"`,
+	TokStartsWith:    `"starts with"`,
+	TokEndsWith:      `"ends with"`,
+	TokAll:           `"all()"`,
+	TokHas:           `"has(label)"`,
+	TokLParen:        `"("`,
+	TokRParen:        `")"`,
+	TokAnd:           `"&&"`,
+	TokOr:            `"||"`,
+	TokGlobal:        `"global()"`,
+	TokLt:            `"<"`,
+	TokLe:            `"<="`,
+	TokGt:            `">"`,
+	TokGe:            `">="`,
+	TokRegexMatch:    `"=~"`,
+	TokNumberLiteral: "number",
+	TokEOF:           "end of selector",
+}
+
+// String returns a description of the kind of token for use in error messages.
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("token %d", uint8(k))
+}
+
 const tokenizerDebug = false
 
 var whitespace = " \t"
 
 // Token has a kind and a value
 type Token struct {
-	Kind  tokenKind
+	Kind  Kind
 	Value interface{}
 }
 
+// Error is returned by Tokenize when the input contains characters that don't form a valid token.
+type Error struct {
+	// Offset is the byte offset in the input of the invalid characters.
+	Offset int
+	// Expected lists the kinds of token that would have been valid at Offset, if known.
+	Expected []Kind
+	// Message describes the error.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Message, e.Offset)
+}
+
+func newError(offset int, msg string, expected ...Kind) error {
+	return &Error{Offset: offset, Expected: expected, Message: msg}
+}
+
 const (
 	// LabelKeyMatcher is the base regex for a valid label key.
 	LabelKeyMatcher = `[a-zA-Z0-9_./-]{1,512}`
@@ -68,8 +132,17 @@ const (
 	notInExpr       = `not\s*in\b`
 	inExpr          = `in\b`
 	globalExpr      = `global\(\s*\)`
+	// NumberMatcher is the base regex for a number literal.  Label values that match it in full
+	// are numbers for the purposes of the numeric comparison operators.
+	NumberMatcher = `[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?`
 )
 
+// LabelOperators are the kinds of token that may follow a label.
+var LabelOperators = []Kind{
+	TokEq, TokNe, TokIn, TokNotIn, TokContains, TokStartsWith, TokEndsWith,
+	TokLt, TokLe, TokGt, TokGe, TokRegexMatch,
+}
+
 var (
 	identifierRegex = regexp.MustCompile("^" + LabelKeyMatcher)
 	containsRegex   = regexp.MustCompile(`^contains`)
@@ -80,18 +153,29 @@ var (
 	notInRegex      = regexp.MustCompile("^" + notInExpr)
 	inRegex         = regexp.MustCompile("^" + inExpr)
 	globalRegex     = regexp.MustCompile("^" + globalExpr)
+	numberRegex     = regexp.MustCompile("^" + NumberMatcher)
 )
 
 // Tokenize transforms string to token slice
 func Tokenize(input string) (tokens []Token, err error) {
+	tokens, _, err = TokenizeWithOffsets(input)
+	return
+}
+
+// TokenizeWithOffsets transforms string to token slice, and also returns the byte offset in the
+// string of each token.  Errors are of type *Error.
+func TokenizeWithOffsets(selector string) (tokens []Token, offsets []int, err error) {
+	input := selector
 	for {
 		if tokenizerDebug {
 			log.Debug("Remaining input: ", input)
 		}
 		startLen := len(input)
 		input = strings.TrimLeft(input, whitespace)
+		offset := len(selector) - len(input)
 		if len(input) == 0 {
 			tokens = append(tokens, Token{TokEOF, nil})
+			offsets = append(offsets, offset)
 			return
 		}
 		var lastTokKind = TokNone
@@ -109,7 +193,7 @@ func Tokenize(input string) (tokens []Token, err error) {
 			input = input[1:]
 			index := strings.Index(input, `"`)
 			if index == -1 {
-				return nil, errors.New("unterminated string")
+				return nil, nil, newError(offset, "unterminated string")
 			}
 			value := input[0:index]
 			tokens = append(tokens, Token{TokStringLiteral, value})
@@ -118,7 +202,7 @@ func Tokenize(input string) (tokens []Token, err error) {
 			input = input[1:]
 			index := strings.Index(input, `'`)
 			if index == -1 {
-				return nil, errors.New("unterminated string")
+				return nil, nil, newError(offset, "unterminated string")
 			}
 			value := input[0:index]
 			tokens = append(tokens, Token{TokStringLiteral, value})
@@ -136,8 +220,27 @@ func Tokenize(input string) (tokens []Token, err error) {
 			if len(input) > 1 && input[1] == '=' {
 				tokens = append(tokens, Token{TokEq, nil})
 				input = input[2:]
+			} else if len(input) > 1 && input[1] == '~' {
+				tokens = append(tokens, Token{TokRegexMatch, nil})
+				input = input[2:]
+			} else {
+				return nil, nil, newError(offset, "unexpected \"=\"", TokEq, TokRegexMatch)
+			}
+		case '<':
+			if len(input) > 1 && input[1] == '=' {
+				tokens = append(tokens, Token{TokLe, nil})
+				input = input[2:]
 			} else {
-				return nil, errors.New("expected ==")
+				tokens = append(tokens, Token{TokLt, nil})
+				input = input[1:]
+			}
+		case '>':
+			if len(input) > 1 && input[1] == '=' {
+				tokens = append(tokens, Token{TokGe, nil})
+				input = input[2:]
+			} else {
+				tokens = append(tokens, Token{TokGt, nil})
+				input = input[1:]
 			}
 		case '!':
 			if len(input) > 1 && input[1] == '=' {
@@ -152,18 +255,30 @@ func Tokenize(input string) (tokens []Token, err error) {
 				tokens = append(tokens, Token{TokAnd, nil})
 				input = input[2:]
 			} else {
-				return nil, errors.New("expected &&")
+				return nil, nil, newError(offset, "unexpected \"&\"", TokAnd)
 			}
 		case '|':
 			if len(input) > 1 && input[1] == '|' {
 				tokens = append(tokens, Token{TokOr, nil})
 				input = input[2:]
 			} else {
-				return nil, errors.New("expected ||")
+				return nil, nil, newError(offset, "unexpected \"|\"", TokOr)
 			}
 		default:
 			// Handle less-simple cases with regex matches.  We've already stripped any whitespace.
-			if lastTokKind == TokLabel {
+			if lastTokKind == TokLt || lastTokKind == TokLe || lastTokKind == TokGt || lastTokKind == TokGe {
+				// A numeric comparison operator must be followed by a number.
+				idxs := numberRegex.FindStringIndex(input)
+				if idxs == nil {
+					return nil, nil, newError(offset, "unexpected characters", TokNumberLiteral)
+				}
+				value, parseErr := strconv.ParseFloat(input[:idxs[1]], 64)
+				if parseErr != nil {
+					return nil, nil, newError(offset, fmt.Sprintf("invalid number %q", input[:idxs[1]]))
+				}
+				tokens = append(tokens, Token{TokNumberLiteral, value})
+				input = input[idxs[1]:]
+			} else if lastTokKind == TokLabel {
 				// If we just saw a label, look for a contains/starts with/ends with operator instead of another label.
 				if idxs := containsRegex.FindStringIndex(input); idxs != nil {
 					// Found "all"
@@ -186,9 +301,8 @@ func Tokenize(input string) (tokens []Token, err error) {
 					tokens = append(tokens, Token{TokIn, nil})
 					input = input[idxs[1]:]
 				} else {
-					err = fmt.Errorf("unexpected characters after label '%v', was expecting an operator",
-						tokens[len(tokens)-1].Value)
-					return
+					msg := fmt.Sprintf("unexpected characters after label '%v'", tokens[len(tokens)-1].Value)
+					return nil, nil, newError(offset, msg, LabelOperators...)
 				}
 			} else if idxs := hasRegex.FindStringSubmatchIndex(input); idxs != nil {
 				// Found "has(label)"
@@ -214,13 +328,12 @@ func Tokenize(input string) (tokens []Token, err error) {
 				tokens = append(tokens, Token{TokLabel, identifier})
 				input = input[endIndex:]
 			} else {
-				err = errors.New("unexpected characters")
-				return
+				return nil, nil, newError(offset, "unexpected characters")
 			}
 		}
 		if len(input) >= startLen {
-			err = errors.New("infinite loop detected in tokenizer")
-			return
+			return nil, nil, newError(offset, "infinite loop detected in tokenizer")
 		}
+		offsets = append(offsets, offset)
 	}
 }
@@ -215,6 +215,36 @@ var tokenTests = []struct {
 		{tokenizer.TokAll, nil},
 		{tokenizer.TokEOF, nil},
 	}},
+	{`a < 10`, []tokenizer.Token{
+		{tokenizer.TokLabel, "a"},
+		{tokenizer.TokLt, nil},
+		{tokenizer.TokNumberLiteral, 10.0},
+		{tokenizer.TokEOF, nil},
+	}},
+	{`a<=-1.5`, []tokenizer.Token{
+		{tokenizer.TokLabel, "a"},
+		{tokenizer.TokLe, nil},
+		{tokenizer.TokNumberLiteral, -1.5},
+		{tokenizer.TokEOF, nil},
+	}},
+	{`a > .5 && b >= 1e3`, []tokenizer.Token{
+		{tokenizer.TokLabel, "a"},
+		{tokenizer.TokGt, nil},
+		{tokenizer.TokNumberLiteral, 0.5},
+		{tokenizer.TokAnd, nil},
+		{tokenizer.TokLabel, "b"},
+		{tokenizer.TokGe, nil},
+		{tokenizer.TokNumberLiteral, 1000.0},
+		{tokenizer.TokEOF, nil},
+	}},
+	{`a =~ "^v1\."`, []tokenizer.Token{
+		{tokenizer.TokLabel, "a"},
+		{tokenizer.TokRegexMatch, nil},
+		{tokenizer.TokStringLiteral, `^v1\.`},
+		{tokenizer.TokEOF, nil},
+	}},
+	{`a > b`, nil},
+	{`a = "b"`, nil},
 }
 
 var _ = Describe("Token", func() {
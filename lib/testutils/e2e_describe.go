@@ -28,6 +28,10 @@ const (
 	DatastoreK8s
 	DatastoreK8sInline
 
+	// DatastoreMemory uses the in-memory backend.  All clients created from the config
+	// share the same in-process store.
+	DatastoreMemory
+
 	DatastoreAll   = DatastoreEtcdV3 | DatastoreK8s
 	k8sAPIEndpoint = "http://localhost:8080"
 )
@@ -100,5 +104,16 @@ preferences: {}
 			})
 	}
 
+	if datastores&DatastoreMemory != 0 {
+		Describe(fmt.Sprintf("%s [Datastore] (memory backend)", description),
+			func() {
+				body(apiconfig.CalicoAPIConfig{
+					Spec: apiconfig.CalicoAPIConfigSpec{
+						DatastoreType: apiconfig.Memory,
+					},
+				})
+			})
+	}
+
 	return true
 }
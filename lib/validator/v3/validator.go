@@ -64,6 +64,11 @@ var (
 	// GlobalNetworkPolicy names must be a simple DNS1123 label format (nameLabelFmt).
 	globalNetworkPolicyNameRegex = regexp.MustCompile("^(" + nameLabelFmt + ")$")
 
+	// Tier names must be a simple DNS1123 label format (nameLabelFmt).  Policies in a tier other
+	// than the default tier are named with the tier name and a "." prefixed to a name of the same
+	// format.
+	tierNameRegex = regexp.MustCompile("^(" + nameLabelFmt + ")$")
+
 	// Hostname  have to be valid ipv4, ipv6 or strings up to 64 characters.
 	prometheusHostRegexp = regexp.MustCompile(`^[a-zA-Z0-9:._+-]{1,64}$`)
 
@@ -77,6 +82,7 @@ var (
 	protocolRegex         = regexp.MustCompile("^(TCP|UDP|ICMP|ICMPv6|SCTP|UDPLite)$")
 	ipipModeRegex         = regexp.MustCompile("^(Always|CrossSubnet|Never)$")
 	vxlanModeRegex        = regexp.MustCompile("^(Always|CrossSubnet|Never)$")
+	assignmentModeRegex   = regexp.MustCompile("^(LeastRecentlyReleased|Sequential|Random)$")
 	logLevelRegex         = regexp.MustCompile("^(Debug|Info|Warning|Error|Fatal)$")
 	bpfLogLevelRegex      = regexp.MustCompile("^(Debug|Info|Off)$")
 	bpfServiceModeRegex   = regexp.MustCompile("^(Tunnel|DSR)$")
@@ -149,7 +155,9 @@ func init() {
 	registerFieldValidator("ipVersion", validateIPVersion)
 	registerFieldValidator("ipIpMode", validateIPIPMode)
 	registerFieldValidator("vxlanMode", validateVXLANMode)
+	registerFieldValidator("assignmentMode", validateAssignmentMode)
 	registerFieldValidator("policyType", validatePolicyType)
+	registerFieldValidator("stagedAction", validateStagedAction)
 	registerFieldValidator("logLevel", validateLogLevel)
 	registerFieldValidator("bpfLogLevel", validateBPFLogLevel)
 	registerFieldValidator("bpfServiceMode", validateBPFServiceMode)
@@ -198,6 +206,10 @@ func init() {
 	registerStructValidator(validate, validateBGPPeerSpec, api.BGPPeerSpec{})
 	registerStructValidator(validate, validateNetworkPolicy, api.NetworkPolicy{})
 	registerStructValidator(validate, validateGlobalNetworkPolicy, api.GlobalNetworkPolicy{})
+	registerStructValidator(validate, validateStagedNetworkPolicy, api.StagedNetworkPolicy{})
+	registerStructValidator(validate, validateStagedGlobalNetworkPolicy, api.StagedGlobalNetworkPolicy{})
+	registerStructValidator(validate, validateStagedKubernetesNetworkPolicy, api.StagedKubernetesNetworkPolicy{})
+	registerStructValidator(validate, validateTier, api.Tier{})
 	registerStructValidator(validate, validateGlobalNetworkSet, api.GlobalNetworkSet{})
 	registerStructValidator(validate, validateNetworkSet, api.NetworkSet{})
 	registerStructValidator(validate, validateRuleMetadata, api.RuleMetadata{})
@@ -218,6 +230,14 @@ func extractReason(e validator.FieldError) string {
 	if strings.HasPrefix(e.Tag(), reasonString) {
 		return strings.TrimPrefix(e.Tag(), reasonString)
 	}
+	if e.Tag() == "selector" {
+		// Re-parse the selector to report where it is malformed.
+		if s, ok := e.Value().(string); ok {
+			if _, err := selector.Parse(s); err != nil {
+				return fmt.Sprintf("invalid selector: %v", err)
+			}
+		}
+	}
 	return fmt.Sprintf("%sfailed to validate Field: %s because of Tag: %s ",
 		reasonString,
 		e.Field(),
@@ -332,6 +352,12 @@ func validateVXLANMode(fl validator.FieldLevel) bool {
 	return vxlanModeRegex.MatchString(s)
 }
 
+func validateAssignmentMode(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	log.Debugf("Validate Assignment Mode: %s", s)
+	return assignmentModeRegex.MatchString(s)
+}
+
 func validateMAC(fl validator.FieldLevel) bool {
 	s := fl.Field().String()
 	log.Debugf("Validate MAC Address: %s", s)
@@ -419,6 +445,15 @@ func validatePolicyType(fl validator.FieldLevel) bool {
 	return false
 }
 
+func validateStagedAction(fl validator.FieldLevel) bool {
+	s := fl.Field().String()
+	log.Debugf("Validate staged action: %s", s)
+	if s == string(api.StagedActionSet) || s == string(api.StagedActionDelete) {
+		return true
+	}
+	return false
+}
+
 func validateProtocol(structLevel validator.StructLevel) {
 	p := structLevel.Current().Interface().(numorstring.Protocol)
 	log.Debugf("Validate protocol: %v %s %d", p.Type, p.StrVal, p.NumVal)
@@ -866,6 +901,12 @@ func validateIPPoolSpec(structLevel validator.StructLevel) {
 			"IPpool.IPIPMode", "", reason("IPIPMode and VXLANMode cannot both be enabled on the same IP pool"), "")
 	}
 
+	// The release cool-down cannot be negative.
+	if pool.ReleaseCooldown != nil && pool.ReleaseCooldown.Duration < 0 {
+		structLevel.ReportError(reflect.ValueOf(pool.ReleaseCooldown),
+			"IPpool.ReleaseCooldown", "", reason("ReleaseCooldown must not be negative"), "")
+	}
+
 	// Default the blockSize
 	if pool.BlockSize == 0 {
 		if ipAddr.Version() == 4 {
@@ -1132,7 +1173,10 @@ func validateObjectMeta(structLevel validator.StructLevel) {
 }
 
 func validateNetworkPolicy(structLevel validator.StructLevel) {
-	np := structLevel.Current().Interface().(api.NetworkPolicy)
+	validateNP(structLevel, structLevel.Current().Interface().(api.NetworkPolicy))
+}
+
+func validateNP(structLevel validator.StructLevel, np api.NetworkPolicy) {
 	spec := np.Spec
 
 	// Check (and disallow) any repeats in Types field.
@@ -1158,8 +1202,10 @@ func validateNetworkPolicy(structLevel validator.StructLevel) {
 	}
 
 	// Uses the k8s DN1123 label format for policy names (plus knp.default prefixed k8s policies).
-	matched := networkPolicyNameRegex.MatchString(np.Name)
-	if !matched {
+	// Policies in a tier other than the default tier must be prefixed with the tier name.
+	if isTiered(spec.Tier) {
+		validateTieredPolicyName(structLevel, spec.Tier, np.Name)
+	} else if !networkPolicyNameRegex.MatchString(np.Name) {
 		structLevel.ReportError(
 			reflect.ValueOf(np.Name),
 			"Metadata.Name",
@@ -1237,8 +1283,72 @@ func validateGlobalNetworkSet(structLevel validator.StructLevel) {
 	}
 }
 
+func validateTier(structLevel validator.StructLevel) {
+	tier := structLevel.Current().Interface().(api.Tier)
+
+	// Check the name is within the max length.
+	if len(tier.Name) > k8svalidation.DNS1123LabelMaxLength {
+		structLevel.ReportError(
+			reflect.ValueOf(tier.Name),
+			"Metadata.Name",
+			"",
+			reason(fmt.Sprintf("name is too long by %d bytes", len(tier.Name)-k8svalidation.DNS1123LabelMaxLength)),
+			"",
+		)
+	}
+
+	// Uses the k8s DN1123 label format for tier names, since the tier name is used as a prefix
+	// of the names of the policies in the tier.
+	if !tierNameRegex.MatchString(tier.Name) {
+		structLevel.ReportError(
+			reflect.ValueOf(tier.Name),
+			"Metadata.Name",
+			"",
+			reason("name must consist of lower case alphanumeric characters or '-' (regex: "+nameLabelFmt+")"),
+			"",
+		)
+	}
+
+	validateObjectMetaAnnotations(structLevel, tier.Annotations)
+	validateObjectMetaLabels(structLevel, tier.Labels)
+}
+
+// isTiered returns true if the policy tier is a tier other than the default tier.
+func isTiered(tier string) bool {
+	return tier != "" && tier != api.DefaultTierName
+}
+
+// validateTieredPolicyName checks that the name of a policy in a tier other than the default
+// tier consists of the tier name, a "." and a name in the DNS1123 label format.
+func validateTieredPolicyName(structLevel validator.StructLevel, tier, name string) {
+	if !tierNameRegex.MatchString(tier) {
+		structLevel.ReportError(
+			reflect.ValueOf(tier),
+			"Spec.Tier",
+			"",
+			reason("tier must consist of lower case alphanumeric characters or '-' (regex: "+nameLabelFmt+")"),
+			"",
+		)
+		return
+	}
+
+	prefix := tier + "."
+	if !strings.HasPrefix(name, prefix) || !tierNameRegex.MatchString(strings.TrimPrefix(name, prefix)) {
+		structLevel.ReportError(
+			reflect.ValueOf(name),
+			"Metadata.Name",
+			"",
+			reason("name must be prefixed with the tier name and '.', followed by lower case alphanumeric characters or '-' (regex: "+nameLabelFmt+")"),
+			"",
+		)
+	}
+}
+
 func validateGlobalNetworkPolicy(structLevel validator.StructLevel) {
-	gnp := structLevel.Current().Interface().(api.GlobalNetworkPolicy)
+	validateGNP(structLevel, structLevel.Current().Interface().(api.GlobalNetworkPolicy))
+}
+
+func validateGNP(structLevel validator.StructLevel, gnp api.GlobalNetworkPolicy) {
 	spec := gnp.Spec
 
 	// Check the name is within the max length.
@@ -1252,9 +1362,11 @@ func validateGlobalNetworkPolicy(structLevel validator.StructLevel) {
 		)
 	}
 
-	// Uses the k8s DN1123 label format for policy names.
-	matched := globalNetworkPolicyNameRegex.MatchString(gnp.Name)
-	if !matched {
+	// Uses the k8s DN1123 label format for policy names.  Policies in a tier other than the
+	// default tier must be prefixed with the tier name.
+	if isTiered(spec.Tier) {
+		validateTieredPolicyName(structLevel, spec.Tier, gnp.Name)
+	} else if !globalNetworkPolicyNameRegex.MatchString(gnp.Name) {
 		structLevel.ReportError(
 			reflect.ValueOf(gnp.Name),
 			"Metadata.Name",
@@ -1342,6 +1454,64 @@ func validateGlobalNetworkPolicy(structLevel validator.StructLevel) {
 	}
 }
 
+func validateStagedNetworkPolicy(structLevel validator.StructLevel) {
+	staged := structLevel.Current().Interface().(api.StagedNetworkPolicy)
+
+	// A staged delete only identifies the policy to be deleted, so the spec must not contain
+	// anything other than the action and the tier.
+	if staged.Spec.StagedAction == api.StagedActionDelete {
+		spec := staged.Spec
+		spec.StagedAction = ""
+		spec.Tier = ""
+		if !reflect.DeepEqual(spec, api.StagedNetworkPolicySpec{}) {
+			structLevel.ReportError(reflect.ValueOf(staged.Spec),
+				"StagedNetworkPolicySpec", "", reason("spec must be empty for stagedAction Delete"), "")
+		}
+	}
+
+	// The staged policy must be valid as an enforced policy.
+	_, np := api.ConvertStagedPolicyToEnforced(&staged)
+	validateNP(structLevel, *np)
+}
+
+func validateStagedGlobalNetworkPolicy(structLevel validator.StructLevel) {
+	staged := structLevel.Current().Interface().(api.StagedGlobalNetworkPolicy)
+
+	// A staged delete only identifies the policy to be deleted, so the spec must not contain
+	// anything other than the action and the tier.
+	if staged.Spec.StagedAction == api.StagedActionDelete {
+		spec := staged.Spec
+		spec.StagedAction = ""
+		spec.Tier = ""
+		if !reflect.DeepEqual(spec, api.StagedGlobalNetworkPolicySpec{}) {
+			structLevel.ReportError(reflect.ValueOf(staged.Spec),
+				"StagedGlobalNetworkPolicySpec", "", reason("spec must be empty for stagedAction Delete"), "")
+		}
+	}
+
+	// The staged policy must be valid as an enforced policy.
+	_, gnp := api.ConvertStagedGlobalPolicyToEnforced(&staged)
+	validateGNP(structLevel, *gnp)
+}
+
+func validateStagedKubernetesNetworkPolicy(structLevel validator.StructLevel) {
+	staged := structLevel.Current().Interface().(api.StagedKubernetesNetworkPolicy)
+
+	// A staged delete only identifies the policy to be deleted, so the spec must not contain
+	// anything other than the action.
+	if staged.Spec.StagedAction == api.StagedActionDelete {
+		spec := staged.Spec
+		spec.StagedAction = ""
+		if !reflect.DeepEqual(spec, api.StagedKubernetesNetworkPolicySpec{}) {
+			structLevel.ReportError(reflect.ValueOf(staged.Spec),
+				"StagedKubernetesNetworkPolicySpec", "", reason("spec must be empty for stagedAction Delete"), "")
+		}
+	}
+
+	validateObjectMetaAnnotations(structLevel, staged.Annotations)
+	validateObjectMetaLabels(structLevel, staged.Labels)
+}
+
 func validateObjectMetaAnnotations(structLevel validator.StructLevel, annotations map[string]string) {
 	var totalSize int64
 	for k, v := range annotations {
@@ -88,6 +88,8 @@ func init() {
 	// Max name length
 	maxNameLength := 253
 
+	tierOrder := 100.0
+
 	// Perform validation on error messages from validator
 	DescribeTable("Validator errors",
 		func(input interface{}, e string) {
@@ -106,6 +108,13 @@ func init() {
 					}},
 				},
 			}, "error with field Port = '0' (port range invalid, port number must be between 1 and 65535)"),
+		Entry("should report where a selector is malformed",
+			api.EntityRule{Selector: "has(foo) && && bar == 'x'"},
+			`error with field Selector = 'has(foo) && && bar == 'x'' (invalid selector: unexpected "&&" at offset 12, `+
+				`expected one of "!", "(", label, "has(label)", "all()", "global()")`),
+		Entry("should report an unterminated string in a selector",
+			api.NetworkPolicySpec{Selector: "foo == 'bar"},
+			"error with field Selector = 'foo == 'bar' (invalid selector: unterminated string at offset 7)"),
 	)
 
 	// Perform basic validation of different fields and structures to test simple valid/invalid
@@ -297,6 +306,25 @@ func init() {
 			},
 			false,
 		),
+		Entry("should accept IPReservationSpec with CIDRs and IPs",
+			api.IPReservationSpec{
+				ReservedCIDRs: []string{
+					"10.0.0.1",
+					"11.0.0.0/8",
+					"dead:beef::",
+					"dead:beef::/96",
+				},
+			},
+			true,
+		),
+		Entry("should reject IPReservationSpec with bad CIDR",
+			api.IPReservationSpec{
+				ReservedCIDRs: []string{
+					"10.0.0.0/33",
+				},
+			},
+			false,
+		),
 		Entry("should accept GlobalNetworkSet with labels",
 			api.GlobalNetworkSet{
 				ObjectMeta: v1.ObjectMeta{
@@ -424,6 +452,10 @@ func init() {
 		Entry("should accept valid selector with 'has' and two '/'", api.EntityRule{Selector: "has(calico/k8s_ns/role)"}, true),
 		Entry("should accept valid selector with 'has' and two '/' and '-.'", api.EntityRule{Selector: "has(calico/k8s_NS-.1/role)"}, true),
 		Entry("should reject invalid selector", api.EntityRule{Selector: "thing=hello &"}, false),
+		Entry("should accept valid selector with numeric comparisons", api.EntityRule{Selector: "port >= 8000 && port < 9000"}, true),
+		Entry("should accept valid selector with a regex", api.EntityRule{Selector: "version =~ '^v1\\.[0-9]+$'"}, true),
+		Entry("should reject selector comparing with a string", api.EntityRule{Selector: "port >= '8000'"}, false),
+		Entry("should reject selector with an invalid regex", api.EntityRule{Selector: "version =~ 'v1('"}, false),
 
 		// (API) Labels and Annotations.
 		Entry("should accept a valid labelsToApply", api.ProfileSpec{LabelsToApply: map[string]string{"project.calico.org/my-valid-label": value63}}, true),
@@ -563,6 +595,58 @@ func init() {
 				Spec: api.IPPoolSpec{CIDR: netv4_3, NodeSelector: "this is not valid selector syntax"},
 			}, false,
 		),
+		Entry("should allow a valid namespaceSelector and workloadSelector",
+			api.IPPool{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "pool.name",
+				},
+				Spec: api.IPPoolSpec{CIDR: netv4_3, NamespaceSelector: `tenant == "a"`, WorkloadSelector: "has(app)"},
+			}, true,
+		),
+		Entry("should disallow a invalid namespaceSelector",
+			api.IPPool{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "pool.name",
+				},
+				Spec: api.IPPoolSpec{CIDR: netv4_3, NamespaceSelector: "this is not valid selector syntax"},
+			}, false,
+		),
+		Entry("should disallow a invalid workloadSelector",
+			api.IPPool{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "pool.name",
+				},
+				Spec: api.IPPoolSpec{CIDR: netv4_3, WorkloadSelector: "this is not valid selector syntax"},
+			}, false,
+		),
+		Entry("should allow a valid assignmentMode and releaseCooldown",
+			api.IPPool{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "pool.name",
+				},
+				Spec: api.IPPoolSpec{
+					CIDR:            netv4_3,
+					AssignmentMode:  api.AssignmentModeSequential,
+					ReleaseCooldown: &v1.Duration{Duration: 5 * time.Minute},
+				},
+			}, true,
+		),
+		Entry("should disallow an invalid assignmentMode",
+			api.IPPool{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "pool.name",
+				},
+				Spec: api.IPPoolSpec{CIDR: netv4_3, AssignmentMode: "Highest"},
+			}, false,
+		),
+		Entry("should disallow a negative releaseCooldown",
+			api.IPPool{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "pool.name",
+				},
+				Spec: api.IPPoolSpec{CIDR: netv4_3, ReleaseCooldown: &v1.Duration{Duration: -time.Second}},
+			}, false,
+		),
 
 		// (API) Interface.
 		Entry("should accept a valid interface", api.WorkloadEndpointSpec{InterfaceName: "Valid_Iface.0-9"}, true),
@@ -1441,6 +1525,12 @@ func init() {
 		Entry("allow valid name", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}}, true),
 		Entry("disallow k8s policy name", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "knp.default.thing"}}, false),
 		Entry("disallow name with dot", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "t.h.i.ng"}}, false),
+		Entry("allow name in the default tier", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.GlobalNetworkPolicySpec{Tier: "default"}}, true),
+		Entry("allow name prefixed with the tier", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "platform.thing"}, Spec: api.GlobalNetworkPolicySpec{Tier: "platform"}}, true),
+		Entry("disallow name not prefixed with the tier", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.GlobalNetworkPolicySpec{Tier: "platform"}}, false),
+		Entry("disallow name prefixed with a different tier", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "other.thing"}, Spec: api.GlobalNetworkPolicySpec{Tier: "platform"}}, false),
+		Entry("disallow name with dot after the tier prefix", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "platform.t.hing"}, Spec: api.GlobalNetworkPolicySpec{Tier: "platform"}}, false),
+		Entry("disallow tier with dot", &api.GlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "plat.form.thing"}, Spec: api.GlobalNetworkPolicySpec{Tier: "plat.form"}}, false),
 		Entry("should reject GlobalNetworkPolicy with both PreDNAT and DoNotTrack",
 			&api.GlobalNetworkPolicy{
 				ObjectMeta: v1.ObjectMeta{Name: "thing"},
@@ -1771,6 +1861,35 @@ func init() {
 		Entry("allow valid name of 253 chars", &api.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: string(longValue[:maxNameLength])}}, true),
 		Entry("disallow a name of 254 chars", &api.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: string(longValue[:maxNameLength+1])}}, false),
 		Entry("allow k8s policy name", &api.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "knp.default.thing"}}, true),
+		Entry("allow name prefixed with the tier", &api.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "platform.thing"}, Spec: api.NetworkPolicySpec{Tier: "platform"}}, true),
+		Entry("disallow name not prefixed with the tier", &api.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.NetworkPolicySpec{Tier: "platform"}}, false),
+		Entry("disallow k8s policy name in a tier", &api.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "knp.default.thing"}, Spec: api.NetworkPolicySpec{Tier: "platform"}}, false),
+
+		// Tier validation.
+		Entry("allow valid tier name", &api.Tier{ObjectMeta: v1.ObjectMeta{Name: "platform"}}, true),
+		Entry("allow tier with an order", &api.Tier{ObjectMeta: v1.ObjectMeta{Name: "platform"}, Spec: api.TierSpec{Order: &tierOrder}}, true),
+		Entry("disallow tier name with dot", &api.Tier{ObjectMeta: v1.ObjectMeta{Name: "plat.form"}}, false),
+		Entry("disallow tier name with mixed case", &api.Tier{ObjectMeta: v1.ObjectMeta{Name: "Platform"}}, false),
+		Entry("disallow tier name of 64 chars", &api.Tier{ObjectMeta: v1.ObjectMeta{Name: value64}}, false),
+
+		// Staged policy validation.
+		Entry("allow staged policy with no action", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedNetworkPolicySpec{Selector: "has(foo)"}}, true),
+		Entry("allow staged policy with Set action", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedNetworkPolicySpec{StagedAction: api.StagedActionSet, Selector: "has(foo)"}}, true),
+		Entry("allow staged policy with Delete action and empty spec", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedNetworkPolicySpec{StagedAction: api.StagedActionDelete}}, true),
+		Entry("allow staged policy with Delete action in a tier", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "platform.thing"}, Spec: api.StagedNetworkPolicySpec{StagedAction: api.StagedActionDelete, Tier: "platform"}}, true),
+		Entry("disallow staged policy with Delete action and a selector", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedNetworkPolicySpec{StagedAction: api.StagedActionDelete, Selector: "has(foo)"}}, false),
+		Entry("disallow staged policy with unknown action", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedNetworkPolicySpec{StagedAction: "Apply"}}, false),
+		Entry("disallow staged policy name not prefixed with the tier", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedNetworkPolicySpec{Tier: "platform"}}, false),
+		Entry("disallow staged policy with global() selector", &api.StagedNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedNetworkPolicySpec{Selector: "global()"}}, false),
+		Entry("allow staged global policy with Set action", &api.StagedGlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedGlobalNetworkPolicySpec{StagedAction: api.StagedActionSet, Selector: "has(foo)"}}, true),
+		Entry("allow staged global policy with Delete action and empty spec", &api.StagedGlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedGlobalNetworkPolicySpec{StagedAction: api.StagedActionDelete}}, true),
+		Entry("disallow staged global policy with Delete action and rules", &api.StagedGlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedGlobalNetworkPolicySpec{StagedAction: api.StagedActionDelete, Ingress: []api.Rule{{Action: "Allow"}}}}, false),
+		Entry("disallow staged global policy with PreDNAT and DoNotTrack", &api.StagedGlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedGlobalNetworkPolicySpec{PreDNAT: true, DoNotTrack: true, ApplyOnForward: true}}, false),
+		Entry("disallow staged global policy with dot in name", &api.StagedGlobalNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "t.h.i.ng"}}, false),
+		Entry("allow staged kubernetes policy with Set action", &api.StagedKubernetesNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedKubernetesNetworkPolicySpec{StagedAction: api.StagedActionSet, PodSelector: v1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}}}}, true),
+		Entry("allow staged kubernetes policy with Delete action and empty spec", &api.StagedKubernetesNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedKubernetesNetworkPolicySpec{StagedAction: api.StagedActionDelete}}, true),
+		Entry("disallow staged kubernetes policy with Delete action and a pod selector", &api.StagedKubernetesNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedKubernetesNetworkPolicySpec{StagedAction: api.StagedActionDelete, PodSelector: v1.LabelSelector{MatchLabels: map[string]string{"foo": "bar"}}}}, false),
+		Entry("disallow staged kubernetes policy with unknown action", &api.StagedKubernetesNetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "thing"}, Spec: api.StagedKubernetesNetworkPolicySpec{StagedAction: "Apply"}}, false),
 		Entry("allow missing Types",
 			&api.NetworkPolicy{
 				ObjectMeta: v1.ObjectMeta{Name: "thing"},